@@ -101,7 +101,10 @@ func (d Direction) HasVerticalOrientation() bool { return d&verticalOrientationS
 //
 // When shaping vertical text, 'sideways' means that the glyphs are rotated
 // by 90°, clock-wise. This flag should be used by renderers to properly
-// rotate the glyphs when drawing.
+// rotate the glyphs when drawing : such runs are shaped horizontally, so that the glyph
+// positions and advances are already expressed along the vertical axis, but the glyph
+// outlines are not rotated. Upright vertical text (like CJK) and horizontal text
+// never need a rotation.
 func (d Direction) IsSideways() bool { return d.IsVertical() && d&verticalSideways != 0 }
 
 // SetSideways makes d vertical with 'sideways' or 'upright' orientation, preserving only the
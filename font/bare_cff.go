@@ -0,0 +1,484 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package font
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/go-text/typesetting/font/cff"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
+)
+
+// NewLoaders is the same as [ot.NewLoaders], but also supports
+// bare CFF files (.cff), which are wrapped into a synthetic OpenType font.
+//
+// The required tables of the synthetic font are built from the CFF data :
+// the 'cmap' table is deduced from the glyph names, the advances are the
+// charstring widths and the font name is used as family.
+// Only the names of the CFF standard strings and the "uniXXXX" and "uXXXX[XX]"
+// names are mapped : the other glyphs, including the glyphs of CIDFonts,
+// which have no names, are not reachable from text.
+func NewLoaders(file Resource) ([]*ot.Loader, error) {
+	lds, err := ot.NewLoaders(file)
+	if !errors.Is(err, ot.ErrBareCFF) {
+		return lds, err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := wrapBareCFF(data)
+	if err != nil {
+		return nil, err
+	}
+	return ot.NewLoaders(bytes.NewReader(wrapped))
+}
+
+// wrapBareCFF returns an OpenType font file containing [data] as 'CFF ' table.
+func wrapBareCFF(data []byte) ([]byte, error) {
+	ft, err := cff.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	numGlyphs := len(ft.Charstrings)
+	if numGlyphs == 0 || numGlyphs > math.MaxUint16 {
+		return nil, errors.New("invalid number of glyphs in bare CFF font")
+	}
+
+	var (
+		advances = make([]uint16, numGlyphs)
+		lsbs     = make([]int16, numGlyphs)
+		box      [4]int16 // xMin, yMin, xMax, yMax
+		hasBox   bool
+		runes    = map[rune]uint16{}
+	)
+	for gid := range ft.Charstrings {
+		advance, err := ft.GlyphAdvance(tables.GlyphID(gid))
+		if err != nil {
+			return nil, err
+		}
+		advances[gid] = uint16(math.Round(math.Max(advance, 0)))
+
+		segments, bounds, err := ft.LoadGlyph(tables.GlyphID(gid))
+		if err != nil {
+			return nil, err
+		}
+		if len(segments) != 0 {
+			glyphBox := [4]int16{
+				int16(math.Floor(float64(bounds.Min.X))), int16(math.Floor(float64(bounds.Min.Y))),
+				int16(math.Ceil(float64(bounds.Max.X))), int16(math.Ceil(float64(bounds.Max.Y))),
+			}
+			lsbs[gid] = glyphBox[0]
+			if !hasBox {
+				box, hasBox = glyphBox, true
+			} else {
+				box = [4]int16{
+					min16(box[0], glyphBox[0]), min16(box[1], glyphBox[1]),
+					max16(box[2], glyphBox[2]), max16(box[3], glyphBox[3]),
+				}
+			}
+		}
+
+		if r, ok := runeFromGlyphName(ft.GlyphName(GID(gid))); ok {
+			if _, has := runes[r]; !has { // the first glyph wins
+				runes[r] = uint16(gid)
+			}
+		}
+	}
+
+	var advanceMax uint16
+	for _, advance := range advances {
+		if advance > advanceMax {
+			advanceMax = advance
+		}
+	}
+
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint32(head, 0x00010000)     // version
+	binary.BigEndian.PutUint32(head[4:], 0x00010000) // fontRevision
+	binary.BigEndian.PutUint32(head[12:], 0x5F0F3CF5)
+	binary.BigEndian.PutUint16(head[16:], 1<<0|1<<1|1<<3) // flags
+	binary.BigEndian.PutUint16(head[18:], ft.Upem())
+	for i, v := range box {
+		binary.BigEndian.PutUint16(head[36+2*i:], uint16(v))
+	}
+	binary.BigEndian.PutUint16(head[46:], 8) // lowestRecPPEM
+	binary.BigEndian.PutUint16(head[48:], 2) // fontDirectionHint
+
+	// the ascender and descender are deduced from the font bounding box
+	hhea := make([]byte, 36)
+	binary.BigEndian.PutUint32(hhea, 0x00010000)
+	binary.BigEndian.PutUint16(hhea[4:], uint16(box[3]))
+	binary.BigEndian.PutUint16(hhea[6:], uint16(box[1]))
+	binary.BigEndian.PutUint16(hhea[10:], advanceMax)
+	binary.BigEndian.PutUint16(hhea[18:], 1) // caretSlopeRise
+	binary.BigEndian.PutUint16(hhea[34:], uint16(numGlyphs))
+
+	hmtx := make([]byte, 0, 4*numGlyphs)
+	for i, advance := range advances {
+		hmtx = binary.BigEndian.AppendUint16(hmtx, advance)
+		hmtx = binary.BigEndian.AppendUint16(hmtx, uint16(lsbs[i]))
+	}
+
+	maxp := binary.BigEndian.AppendUint16([]byte{0, 0, 0x50, 0}, uint16(numGlyphs)) // version 0.5
+
+	out := []ot.Table{
+		{Tag: ot.MustNewTag("CFF "), Content: data},
+		{Tag: ot.MustNewTag("cmap"), Content: bareCFFCmap(runes)},
+		{Tag: ot.MustNewTag("head"), Content: head},
+		{Tag: ot.MustNewTag("hhea"), Content: hhea},
+		{Tag: ot.MustNewTag("hmtx"), Content: hmtx},
+		{Tag: ot.MustNewTag("maxp"), Content: maxp},
+		{Tag: ot.MustNewTag("name"), Content: bareCFFName(ft.FontName())},
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Tag < out[j].Tag })
+	return ot.WriteOTF(out), nil
+}
+
+// bareCFFCmap returns a 'cmap' table with one format 12 subtable,
+// grouping consecutive runes mapped to consecutive glyphs.
+func bareCFFCmap(runes map[rune]uint16) []byte {
+	sorted := make([]rune, 0, len(runes))
+	for r := range runes {
+		sorted = append(sorted, r)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	type group struct {
+		start, end rune
+		gid        uint16
+	}
+	var groups []group
+	for _, r := range sorted {
+		gid := runes[r]
+		if L := len(groups); L != 0 {
+			if last := &groups[L-1]; last.end+1 == r && rune(last.gid)+(r-last.start) == rune(gid) {
+				last.end = r
+				continue
+			}
+		}
+		groups = append(groups, group{r, r, gid})
+	}
+
+	const headerSize = 4 + 8 // version, numTables and one encoding record
+	out := make([]byte, headerSize+16, headerSize+16+12*len(groups))
+	binary.BigEndian.PutUint16(out[2:], 1)
+	binary.BigEndian.PutUint16(out[4:], 3)  // platformID : Windows
+	binary.BigEndian.PutUint16(out[6:], 10) // encodingID : Unicode full repertoire
+	binary.BigEndian.PutUint32(out[8:], headerSize)
+	format12 := out[headerSize:]
+	binary.BigEndian.PutUint16(format12, 12)
+	binary.BigEndian.PutUint32(format12[4:], uint32(16+12*len(groups)))
+	binary.BigEndian.PutUint32(format12[12:], uint32(len(groups)))
+	for _, g := range groups {
+		out = binary.BigEndian.AppendUint32(out, uint32(g.start))
+		out = binary.BigEndian.AppendUint32(out, uint32(g.end))
+		out = binary.BigEndian.AppendUint32(out, uint32(g.gid))
+	}
+	return out
+}
+
+// bareCFFName returns a 'name' table with [fontName] as family
+// and PostScript name, for the Windows platform
+func bareCFFName(fontName string) []byte {
+	records := [...]struct {
+		nameID uint16
+		value  string
+	}{
+		{1, fontName},
+		{2, "Regular"},
+		{4, fontName},
+		{6, fontName},
+	}
+
+	const headerSize, recordSize = 6, 12
+	out := make([]byte, headerSize+len(records)*recordSize)
+	binary.BigEndian.PutUint16(out[2:], uint16(len(records)))
+	binary.BigEndian.PutUint16(out[4:], uint16(len(out)))
+	var storage []byte
+	for i, record := range records {
+		start := len(storage)
+		for _, u := range utf16.Encode([]rune(record.value)) {
+			storage = binary.BigEndian.AppendUint16(storage, u)
+		}
+		slice := out[headerSize+i*recordSize:]
+		binary.BigEndian.PutUint16(slice, 3)          // platformID : Windows
+		binary.BigEndian.PutUint16(slice[2:], 1)      // encodingID : Unicode BMP
+		binary.BigEndian.PutUint16(slice[4:], 0x0409) // languageID : English (US)
+		binary.BigEndian.PutUint16(slice[6:], record.nameID)
+		binary.BigEndian.PutUint16(slice[8:], uint16(len(storage)-start))
+		binary.BigEndian.PutUint16(slice[10:], uint16(start))
+	}
+	return append(out, storage...)
+}
+
+// runeFromGlyphName returns the character named by [name], using
+// the standard CFF strings and the "uniXXXX" and "uXXXX[XX]" conventions
+// of the Adobe Glyph List specification.
+// Names with a suffix (like "a.sc") are variants, which are not mapped.
+func runeFromGlyphName(name string) (rune, bool) {
+	if r, ok := standardGlyphNames[name]; ok {
+		return r, true
+	}
+	var digits string
+	if s := strings.TrimPrefix(name, "uni"); len(s) == 4 && s != name {
+		digits = s
+	} else if s := strings.TrimPrefix(name, "u"); 4 <= len(s) && len(s) <= 6 && s != name {
+		digits = s
+	} else {
+		return 0, false
+	}
+	if strings.ToUpper(digits) != digits { // hexadecimal digits must be upper case
+		return 0, false
+	}
+	v, err := strconv.ParseUint(digits, 16, 32)
+	if err != nil || v > 0x10FFFF || (0xD800 <= v && v <= 0xDFFF) {
+		return 0, false
+	}
+	return rune(v), true
+}
+
+// standardGlyphNames maps the glyph names of the CFF standard strings
+// (ISOAdobe charset) to their Unicode value, as given by the Adobe Glyph List.
+var standardGlyphNames = map[string]rune{
+	"space":          0x0020,
+	"exclam":         0x0021,
+	"quotedbl":       0x0022,
+	"numbersign":     0x0023,
+	"dollar":         0x0024,
+	"percent":        0x0025,
+	"ampersand":      0x0026,
+	"quoteright":     0x2019,
+	"parenleft":      0x0028,
+	"parenright":     0x0029,
+	"asterisk":       0x002A,
+	"plus":           0x002B,
+	"comma":          0x002C,
+	"hyphen":         0x002D,
+	"period":         0x002E,
+	"slash":          0x002F,
+	"zero":           0x0030,
+	"one":            0x0031,
+	"two":            0x0032,
+	"three":          0x0033,
+	"four":           0x0034,
+	"five":           0x0035,
+	"six":            0x0036,
+	"seven":          0x0037,
+	"eight":          0x0038,
+	"nine":           0x0039,
+	"colon":          0x003A,
+	"semicolon":      0x003B,
+	"less":           0x003C,
+	"equal":          0x003D,
+	"greater":        0x003E,
+	"question":       0x003F,
+	"at":             0x0040,
+	"A":              0x0041,
+	"B":              0x0042,
+	"C":              0x0043,
+	"D":              0x0044,
+	"E":              0x0045,
+	"F":              0x0046,
+	"G":              0x0047,
+	"H":              0x0048,
+	"I":              0x0049,
+	"J":              0x004A,
+	"K":              0x004B,
+	"L":              0x004C,
+	"M":              0x004D,
+	"N":              0x004E,
+	"O":              0x004F,
+	"P":              0x0050,
+	"Q":              0x0051,
+	"R":              0x0052,
+	"S":              0x0053,
+	"T":              0x0054,
+	"U":              0x0055,
+	"V":              0x0056,
+	"W":              0x0057,
+	"X":              0x0058,
+	"Y":              0x0059,
+	"Z":              0x005A,
+	"bracketleft":    0x005B,
+	"backslash":      0x005C,
+	"bracketright":   0x005D,
+	"asciicircum":    0x005E,
+	"underscore":     0x005F,
+	"quoteleft":      0x2018,
+	"a":              0x0061,
+	"b":              0x0062,
+	"c":              0x0063,
+	"d":              0x0064,
+	"e":              0x0065,
+	"f":              0x0066,
+	"g":              0x0067,
+	"h":              0x0068,
+	"i":              0x0069,
+	"j":              0x006A,
+	"k":              0x006B,
+	"l":              0x006C,
+	"m":              0x006D,
+	"n":              0x006E,
+	"o":              0x006F,
+	"p":              0x0070,
+	"q":              0x0071,
+	"r":              0x0072,
+	"s":              0x0073,
+	"t":              0x0074,
+	"u":              0x0075,
+	"v":              0x0076,
+	"w":              0x0077,
+	"x":              0x0078,
+	"y":              0x0079,
+	"z":              0x007A,
+	"braceleft":      0x007B,
+	"bar":            0x007C,
+	"braceright":     0x007D,
+	"asciitilde":     0x007E,
+	"exclamdown":     0x00A1,
+	"cent":           0x00A2,
+	"sterling":       0x00A3,
+	"fraction":       0x2044,
+	"yen":            0x00A5,
+	"florin":         0x0192,
+	"section":        0x00A7,
+	"currency":       0x00A4,
+	"quotesingle":    0x0027,
+	"quotedblleft":   0x201C,
+	"guillemotleft":  0x00AB,
+	"guilsinglleft":  0x2039,
+	"guilsinglright": 0x203A,
+	"fi":             0xFB01,
+	"fl":             0xFB02,
+	"endash":         0x2013,
+	"dagger":         0x2020,
+	"daggerdbl":      0x2021,
+	"periodcentered": 0x00B7,
+	"paragraph":      0x00B6,
+	"bullet":         0x2022,
+	"quotesinglbase": 0x201A,
+	"quotedblbase":   0x201E,
+	"quotedblright":  0x201D,
+	"guillemotright": 0x00BB,
+	"ellipsis":       0x2026,
+	"perthousand":    0x2030,
+	"questiondown":   0x00BF,
+	"grave":          0x0060,
+	"acute":          0x00B4,
+	"circumflex":     0x02C6,
+	"tilde":          0x02DC,
+	"macron":         0x00AF,
+	"breve":          0x02D8,
+	"dotaccent":      0x02D9,
+	"dieresis":       0x00A8,
+	"ring":           0x02DA,
+	"cedilla":        0x00B8,
+	"hungarumlaut":   0x02DD,
+	"ogonek":         0x02DB,
+	"caron":          0x02C7,
+	"emdash":         0x2014,
+	"AE":             0x00C6,
+	"ordfeminine":    0x00AA,
+	"Lslash":         0x0141,
+	"Oslash":         0x00D8,
+	"OE":             0x0152,
+	"ordmasculine":   0x00BA,
+	"ae":             0x00E6,
+	"dotlessi":       0x0131,
+	"lslash":         0x0142,
+	"oslash":         0x00F8,
+	"oe":             0x0153,
+	"germandbls":     0x00DF,
+	"onesuperior":    0x00B9,
+	"logicalnot":     0x00AC,
+	"mu":             0x00B5,
+	"trademark":      0x2122,
+	"Eth":            0x00D0,
+	"onehalf":        0x00BD,
+	"plusminus":      0x00B1,
+	"Thorn":          0x00DE,
+	"onequarter":     0x00BC,
+	"divide":         0x00F7,
+	"brokenbar":      0x00A6,
+	"degree":         0x00B0,
+	"thorn":          0x00FE,
+	"threequarters":  0x00BE,
+	"twosuperior":    0x00B2,
+	"registered":     0x00AE,
+	"minus":          0x2212,
+	"eth":            0x00F0,
+	"multiply":       0x00D7,
+	"threesuperior":  0x00B3,
+	"copyright":      0x00A9,
+	"Aacute":         0x00C1,
+	"Acircumflex":    0x00C2,
+	"Adieresis":      0x00C4,
+	"Agrave":         0x00C0,
+	"Aring":          0x00C5,
+	"Atilde":         0x00C3,
+	"Ccedilla":       0x00C7,
+	"Eacute":         0x00C9,
+	"Ecircumflex":    0x00CA,
+	"Edieresis":      0x00CB,
+	"Egrave":         0x00C8,
+	"Iacute":         0x00CD,
+	"Icircumflex":    0x00CE,
+	"Idieresis":      0x00CF,
+	"Igrave":         0x00CC,
+	"Ntilde":         0x00D1,
+	"Oacute":         0x00D3,
+	"Ocircumflex":    0x00D4,
+	"Odieresis":      0x00D6,
+	"Ograve":         0x00D2,
+	"Otilde":         0x00D5,
+	"Scaron":         0x0160,
+	"Uacute":         0x00DA,
+	"Ucircumflex":    0x00DB,
+	"Udieresis":      0x00DC,
+	"Ugrave":         0x00D9,
+	"Yacute":         0x00DD,
+	"Ydieresis":      0x0178,
+	"Zcaron":         0x017D,
+	"aacute":         0x00E1,
+	"acircumflex":    0x00E2,
+	"adieresis":      0x00E4,
+	"agrave":         0x00E0,
+	"aring":          0x00E5,
+	"atilde":         0x00E3,
+	"ccedilla":       0x00E7,
+	"eacute":         0x00E9,
+	"ecircumflex":    0x00EA,
+	"edieresis":      0x00EB,
+	"egrave":         0x00E8,
+	"iacute":         0x00ED,
+	"icircumflex":    0x00EE,
+	"idieresis":      0x00EF,
+	"igrave":         0x00EC,
+	"ntilde":         0x00F1,
+	"oacute":         0x00F3,
+	"ocircumflex":    0x00F4,
+	"odieresis":      0x00F6,
+	"ograve":         0x00F2,
+	"otilde":         0x00F5,
+	"scaron":         0x0161,
+	"uacute":         0x00FA,
+	"ucircumflex":    0x00FB,
+	"udieresis":      0x00FC,
+	"ugrave":         0x00F9,
+	"yacute":         0x00FD,
+	"ydieresis":      0x00FF,
+	"zcaron":         0x017E,
+}
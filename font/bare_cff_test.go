@@ -0,0 +1,33 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package font
+
+import (
+	"testing"
+
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+func TestRuneFromGlyphName(t *testing.T) {
+	for _, test := range []struct {
+		name string
+		r    rune
+		ok   bool
+	}{
+		{"A", 'A', true},
+		{"quoteright", '’', true},
+		{"fi", 'ﬁ', true},
+		{"uni0416", 'Ж', true},
+		{"u1F600", '😀', true},
+		{"uni0416.sc", 0, false},
+		{"uni04", 0, false},
+		{"uni00e9", 0, false}, // lower case
+		{"uD800", 0, false},   // surrogate
+		{"u110000", 0, false},
+		{"unknown", 0, false},
+		{"", 0, false},
+	} {
+		r, ok := runeFromGlyphName(test.name)
+		tu.AssertC(t, r == test.r && ok == test.ok, test.name)
+	}
+}
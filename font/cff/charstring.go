@@ -14,26 +14,43 @@ import (
 // LoadGlyph parses the glyph charstring to compute segments and path bounds.
 // It returns an error if the glyph is invalid or if decoding the charstring fails.
 func (f *CFF) LoadGlyph(glyph tables.GlyphID) ([]ot.Segment, ps.PathBounds, error) {
-	if int(glyph) >= len(f.Charstrings) {
-		return nil, ps.PathBounds{}, errGlyph
-	}
+	loader, err := f.runCharstring(glyph)
+	return loader.cs.Segments, loader.cs.Bounds, err
+}
 
+// GlyphAdvance returns the advance width of the glyph, in font units,
+// either encoded in its charstring or given by the default width of the font.
+// This is only useful for bare CFF fonts, since the OpenType fonts store
+// the advances in the 'hmtx' table.
+func (f *CFF) GlyphAdvance(glyph tables.GlyphID) (float64, error) {
+	loader, err := f.runCharstring(glyph)
+	return loader.width, err
+}
+
+func (f *CFF) runCharstring(glyph tables.GlyphID) (type2CharstringHandler, error) {
 	var (
 		psi    ps.Machine
 		loader type2CharstringHandler
 		index  byte = 0
 		err    error
 	)
+	if int(glyph) >= len(f.Charstrings) {
+		return loader, errGlyph
+	}
 	if f.fdSelect != nil {
 		index, err = f.fdSelect.fontDictIndex(glyph)
 		if err != nil {
-			return nil, ps.PathBounds{}, err
+			return loader, err
 		}
 	}
+	if int(index) < len(f.privateDicts) {
+		priv := f.privateDicts[index]
+		loader.nominalWidthX, loader.width = priv.nominalWidthX, priv.defaultWidthX
+	}
 
 	subrs := f.localSubrs[index]
 	err = psi.Run(f.Charstrings[glyph], subrs, f.globalSubrs, &loader)
-	return loader.cs.Segments, loader.cs.Bounds, err
+	return loader, err
 }
 
 // type2CharstringHandler implements operators needed to fetch Type2 charstring metrics
@@ -45,6 +62,7 @@ type type2CharstringHandler struct {
 	// `width` must be initialized to default width
 	nominalWidthX float64
 	width         float64
+	widthParsed   bool
 }
 
 func (type2CharstringHandler) Context() ps.Context { return ps.Type2Charstring }
@@ -56,9 +74,7 @@ func (met *type2CharstringHandler) Apply(state *ps.Machine, op ps.Operator) erro
 		case 11: // return
 			return state.Return() // do not clear the arg stack
 		case 14: // endchar
-			if state.ArgStack.Top > 0 { // width is optional
-				met.width = met.nominalWidthX + state.ArgStack.Vals[0]
-			}
+			met.parseWidth(state, state.ArgStack.Top == 1 || state.ArgStack.Top == 5)
 			met.cs.ClosePath()
 			return ps.ErrInterrupt
 		case 10: // callsubr
@@ -66,30 +82,24 @@ func (met *type2CharstringHandler) Apply(state *ps.Machine, op ps.Operator) erro
 		case 29: // callgsubr
 			return ps.GlobalSubr(state) // do not clear the arg stack
 		case 21: // rmoveto
-			if state.ArgStack.Top > 2 { // width is optional
-				met.width = met.nominalWidthX + state.ArgStack.Vals[0]
-			}
+			met.parseWidth(state, state.ArgStack.Top > 2)
 			err = met.cs.Rmoveto(state)
 		case 22: // hmoveto
-			if state.ArgStack.Top > 1 { // width is optional
-				met.width = met.nominalWidthX + state.ArgStack.Vals[0]
-			}
+			met.parseWidth(state, state.ArgStack.Top > 1)
 			err = met.cs.Hmoveto(state)
 		case 4: // vmoveto
-			if state.ArgStack.Top > 1 { // width is optional
-				met.width = met.nominalWidthX + state.ArgStack.Vals[0]
-			}
+			met.parseWidth(state, state.ArgStack.Top > 1)
 			err = met.cs.Vmoveto(state)
 		case 1, 18: // hstem, hstemhm
+			met.parseWidth(state, state.ArgStack.Top&1 != 0)
 			met.cs.Hstem(state)
 		case 3, 23: // vstem, vstemhm
+			met.parseWidth(state, state.ArgStack.Top&1 != 0)
 			met.cs.Vstem(state)
 		case 19, 20: // hintmask, cntrmask
 			// variable number of arguments, but always even
 			// for xxxmask, if there are arguments on the stack, then this is an impliied stem
-			if state.ArgStack.Top&1 != 0 {
-				met.width = met.nominalWidthX + state.ArgStack.Vals[0]
-			}
+			met.parseWidth(state, state.ArgStack.Top&1 != 0)
 			met.cs.Hintmask(state)
 			// the stack is managed by the previous call
 			return nil
@@ -137,6 +147,18 @@ func (met *type2CharstringHandler) Apply(state *ps.Machine, op ps.Operator) erro
 	return err
 }
 
+// parseWidth reads the optional width, which may only be given
+// before the first stack-clearing operator, and is present if [hasWidth] is true
+func (met *type2CharstringHandler) parseWidth(state *ps.Machine, hasWidth bool) {
+	if met.widthParsed {
+		return
+	}
+	met.widthParsed = true
+	if hasWidth {
+		met.width = met.nominalWidthX + state.ArgStack.Vals[0]
+	}
+}
+
 // ---------------------------- CFF2 format ----------------------------
 
 // LoadGlyph parses the glyph charstring to compute segments and path bounds.
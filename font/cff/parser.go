@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 
 	ps "github.com/go-text/typesetting/font/cff/interpreter"
 	"github.com/go-text/typesetting/font/opentype"
@@ -34,6 +35,10 @@ type CFF struct {
 	// array of length 1 for non CIDFonts
 	// For CIDFonts, it can be safely indexed by `fdSelect` output
 	localSubrs [][][]byte
+	// same length as localSubrs, used for the glyph widths
+	privateDicts []privateDict
+
+	fontMatrix [6]float64 // zero if not set
 }
 
 // Parse parses a .cff font file.
@@ -62,6 +67,19 @@ func Parse(file []byte) (*CFF, error) {
 	return &out[0], nil
 }
 
+// FontName returns the name of the font, as stored in the Name INDEX,
+// which is usually its PostScript name.
+func (f *CFF) FontName() string { return string(f.fontName) }
+
+// Upem returns the number of units per em of the font,
+// deduced from its FontMatrix (1000 if not set).
+func (f *CFF) Upem() uint16 {
+	if scale := f.fontMatrix[0]; scale > 0 && 1/scale <= 0xFFFF {
+		return uint16(math.Round(1 / scale))
+	}
+	return 1000
+}
+
 // GlyphName returns the name of the glyph or an empty string if not found.
 func (f *CFF) GlyphName(glyph opentype.GID) string {
 	if f.fdSelect != nil || int(glyph) >= len(f.charset) {
@@ -166,6 +184,7 @@ func (p *cffParser) parse() ([]CFF, error) {
 	for i, topDict := range topDicts {
 		out[i].fontName = fontNames[i]
 		out[i].userStrings = strs
+		out[i].fontMatrix = topDict.fontMatrix
 
 		// skip PSInfo, and cidFontName
 
@@ -204,12 +223,16 @@ func (p *cffParser) parse() ([]CFF, error) {
 
 		if !topDict.isCIDFont {
 			// Parse the Private DICT, whose location was found in the Top DICT.
-			var localSubrs [][]byte
-			localSubrs, err = p.parsePrivateDICT(topDict.privateDictOffset, topDict.privateDictLength)
+			var (
+				localSubrs [][]byte
+				priv       privateDict
+			)
+			localSubrs, priv, err = p.parsePrivateDICT(topDict.privateDictOffset, topDict.privateDictLength)
 			if err != nil {
 				return nil, err
 			}
 			out[i].localSubrs = [][][]byte{localSubrs}
+			out[i].privateDicts = []privateDict{priv}
 		} else {
 			// Parse the Font Dict Select data, whose location was found in the Top
 			// DICT.
@@ -232,13 +255,15 @@ func (p *cffParser) parse() ([]CFF, error) {
 					len(topDicts), indexExtent)
 			}
 			multiSubrs := make([][][]byte, len(topDicts))
+			privates := make([]privateDict, len(topDicts))
 			for i, topDict := range topDicts {
-				multiSubrs[i], err = p.parsePrivateDICT(topDict.privateDictOffset, topDict.privateDictLength)
+				multiSubrs[i], privates[i], err = p.parsePrivateDICT(topDict.privateDictOffset, topDict.privateDictLength)
 				if err != nil {
 					return nil, err
 				}
 			}
 			out[i].localSubrs = multiSubrs
+			out[i].privateDicts = privates
 		}
 	}
 
@@ -416,38 +441,36 @@ func (p *cffParser) parseFDSelect(offset int32, numGlyphs uint16) (fdSelect, err
 }
 
 // Parse Private DICT and the Local Subrs [Subroutines] INDEX
-func (p *cffParser) parsePrivateDICT(offset, length int32) ([][]byte, error) {
+func (p *cffParser) parsePrivateDICT(offset, length int32) ([][]byte, privateDict, error) {
+	var priv privateDict
 	if length == 0 {
-		return nil, nil
+		return nil, priv, nil
 	}
 	if err := p.seek(offset); err != nil {
-		return nil, err
+		return nil, priv, err
 	}
 	buf, err := p.read(int(length))
 	if err != nil {
-		return nil, err
+		return nil, priv, err
 	}
-	var (
-		psi  ps.Machine
-		priv privateDict
-	)
+	var psi ps.Machine
 	if err = psi.Run(buf, nil, nil, &priv); err != nil {
-		return nil, err
+		return nil, priv, err
 	}
 
 	if priv.subrsOffset == 0 {
-		return nil, nil
+		return nil, priv, nil
 	}
 
 	// "The local subrs offset is relative to the beginning of the Private DICT data"
 	if err = p.seek(offset + priv.subrsOffset); err != nil {
-		return nil, errors.New("invalid local subroutines offset")
+		return nil, priv, errors.New("invalid local subroutines offset")
 	}
 	subrs, err := p.parseIndex()
 	if err != nil {
-		return nil, err
+		return nil, priv, err
 	}
-	return subrs, nil
+	return subrs, priv, nil
 }
 
 // read returns the n bytes from p.offset and advances p.offset by n.
@@ -520,6 +543,7 @@ type topDict struct {
 	cidFontName                                        uint16
 	privateDictOffset                                  int32
 	privateDictLength                                  int32
+	fontMatrix                                         [6]float64
 }
 
 func (tp *topDict) Context() ps.Context { return ps.TopDict }
@@ -631,7 +655,12 @@ var topDictOperators = [2][]topDictOperator{
 			}
 			return nil
 		}, +1 /*CharstringType*/},
-		7:  {topDictNoOp, -1 /*FontMatrix*/},
+		7: {func(t *topDict, s *ps.Machine) error {
+			if s.ArgStack.Top == 6 {
+				copy(t.fontMatrix[:], s.ArgStack.Vals[:6])
+			}
+			return nil
+		}, -1 /*FontMatrix*/},
 		8:  {topDictNoOp, +1 /*StrokeWidth*/},
 		20: {topDictNoOp, +1 /*SyntheticBase*/},
 		21: {topDictNoOp, +1 /*PostScript*/},
@@ -59,6 +59,18 @@ type Cmap interface {
 // The returned values are copied from the input 'cmap', meaning they do not
 // retain any reference on the input storage.
 func ProcessCmap(cmap tables.Cmap, os2FontPage tables.FontPage) (Cmap, UnicodeVariations, error) {
+	cm, uv, _, err := processCmap(cmap, os2FontPage)
+	return cm, uv, err
+}
+
+// CmapEncoding identifies a 'cmap' subtable, by its platform and encoding.
+type CmapEncoding struct {
+	Platform tables.PlatformID
+	Encoding tables.EncodingID
+}
+
+// processCmap implements [ProcessCmap], also returning the encoding of the selected subtable
+func processCmap(cmap tables.Cmap, os2FontPage tables.FontPage) (Cmap, UnicodeVariations, CmapEncoding, error) {
 	var (
 		candidateIds []cmapID
 		candidates   []Cmap
@@ -76,7 +88,7 @@ func ProcessCmap(cmap tables.Cmap, os2FontPage tables.FontPage) (Cmap, UnicodeVa
 		case tables.CmapSubtable4:
 			cmap, err := newCmap4(table)
 			if err != nil {
-				return nil, nil, err
+				return nil, nil, CmapEncoding{}, err
 			}
 			candidates = append(candidates, cmap)
 			candidateIds = append(candidateIds, id)
@@ -96,7 +108,7 @@ func ProcessCmap(cmap tables.Cmap, os2FontPage tables.FontPage) (Cmap, UnicodeVa
 			// quoting the spec :
 			// This subtable format must only be used under platform ID 0 and encoding ID 5.
 			if !(id.platform == 0 && id.encoding == 5) {
-				return nil, nil, errors.New("invalid cmap subtable format 14 platform or encoding")
+				return nil, nil, CmapEncoding{}, errors.New("invalid cmap subtable format 14 platform or encoding")
 			}
 			uv = newUnicodeVariations(table)
 		}
@@ -115,53 +127,53 @@ func ProcessCmap(cmap tables.Cmap, os2FontPage tables.FontPage) (Cmap, UnicodeVa
 		case tables.FPTradArabic:
 			cm = remaperPUATrad{cm}
 		}
-		return cm, uv, nil
+		return cm, uv, candidateIds[index].toEncoding(), nil
 	}
 
 	/* 32-bit subtables. */
 	if index := findSubtable(cmapID{tables.PlatformMicrosoft, tables.PEMicrosoftUcs4}, candidateIds); index != -1 {
-		return candidates[index], uv, nil
+		return candidates[index], uv, candidateIds[index].toEncoding(), nil
 	}
 	if index := findSubtable(cmapID{tables.PlatformUnicode, tables.PEUnicodeFull13}, candidateIds); index != -1 {
-		return candidates[index], uv, nil
+		return candidates[index], uv, candidateIds[index].toEncoding(), nil
 	}
 	if index := findSubtable(cmapID{tables.PlatformUnicode, tables.PEUnicodeFull}, candidateIds); index != -1 {
-		return candidates[index], uv, nil
+		return candidates[index], uv, candidateIds[index].toEncoding(), nil
 	}
 
 	/* 16-bit subtables. */
 	if index := findSubtable(cmapID{tables.PlatformMicrosoft, tables.PEMicrosoftUnicodeCs}, candidateIds); index != -1 {
-		return candidates[index], uv, nil
+		return candidates[index], uv, candidateIds[index].toEncoding(), nil
 	}
 	if index := findSubtable(cmapID{tables.PlatformUnicode, tables.PEUnicodeBMP}, candidateIds); index != -1 {
-		return candidates[index], uv, nil
+		return candidates[index], uv, candidateIds[index].toEncoding(), nil
 	}
 	if index := findSubtable(cmapID{tables.PlatformUnicode, 2}, candidateIds); index != -1 { // deprecated
-		return candidates[index], uv, nil
+		return candidates[index], uv, candidateIds[index].toEncoding(), nil
 	}
 	if index := findSubtable(cmapID{tables.PlatformUnicode, 1}, candidateIds); index != -1 { // deprecated
-		return candidates[index], uv, nil
+		return candidates[index], uv, candidateIds[index].toEncoding(), nil
 	}
 	if index := findSubtable(cmapID{tables.PlatformUnicode, 0}, candidateIds); index != -1 { // deprecated
-		return candidates[index], uv, nil
+		return candidates[index], uv, candidateIds[index].toEncoding(), nil
 	}
 
 	/* MacRoman subtable. */
 	if index := findSubtable(cmapID{tables.PlatformMac, 0}, candidateIds); index != -1 {
 		cm := candidates[index]
-		return remaperMacroman{cm}, uv, nil
+		return remaperMacroman{cm}, uv, candidateIds[index].toEncoding(), nil
 	}
 	/* Any other Mac subtable; we just map ASCII for these. */
 	if index := findSubtable(cmapID{tables.PlatformMac, 0xFFFF}, candidateIds); index != -1 {
 		cm := candidates[index]
-		return remaperAscii{cm}, uv, nil
+		return remaperAscii{cm}, uv, candidateIds[index].toEncoding(), nil
 	}
 
 	// uuh... fallback to the first cmap and hope for the best
 	if len(candidates) != 0 {
-		return candidates[0], uv, nil
+		return candidates[0], uv, candidateIds[0].toEncoding(), nil
 	}
-	return nil, nil, errors.New("unsupported cmap table")
+	return nil, nil, CmapEncoding{}, errors.New("unsupported cmap table")
 }
 
 // cmapID groups the platform and encoding of a Cmap subtable.
@@ -170,6 +182,8 @@ type cmapID struct {
 	encoding tables.EncodingID
 }
 
+func (c cmapID) toEncoding() CmapEncoding { return CmapEncoding{c.platform, c.encoding} }
+
 func (c cmapID) key(ignoreEncoding bool) uint32 {
 	if ignoreEncoding {
 		c.encoding = 0
@@ -889,3 +903,51 @@ func (cm cmap12) RuneRanges(dst [][2]rune) [][2]rune {
 }
 
 func (cm cmap13) RuneRanges(dst [][2]rune) [][2]rune { return cmap12(cm).RuneRanges(dst) }
+
+// cmapSortedCoverer is implemented by cmaps able to resolve the coverage
+// of a sorted list of runes in one pass.
+type cmapSortedCoverer interface {
+	// coverSorted sets out[i] to true if runes[i] is mapped.
+	// runes must be sorted in increasing order and len(out) == len(runes)
+	coverSorted(runes []rune, out []bool)
+}
+
+var (
+	_ cmapSortedCoverer = cmap4(nil)
+	_ cmapSortedCoverer = cmap12(nil)
+)
+
+func (cm cmap4) coverSorted(runes []rune, out []bool) {
+	i := 0
+	for k, r := range runes {
+		if r < 0 || r > 0xffff {
+			out[k] = false
+			continue
+		}
+		c := uint16(r)
+		for i < len(cm) && cm[i].end < c {
+			i++
+		}
+		if i == len(cm) || c < cm[i].start {
+			out[k] = false
+			continue
+		}
+		entry := cm[i]
+		out[k] = entry.indexes == nil || entry.indexes[c-entry.start] != 0
+	}
+}
+
+func (cm cmap12) coverSorted(runes []rune, out []bool) {
+	i := 0
+	for k, r := range runes {
+		if r < 0 {
+			out[k] = false
+			continue
+		}
+		c := uint32(r)
+		for i < len(cm) && cm[i].EndCharCode < c {
+			i++
+		}
+		out[k] = i < len(cm) && cm[i].StartCharCode <= c
+	}
+}
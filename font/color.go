@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/go-text/typesetting/font/opentype/tables"
+	"golang.org/x/image/math/fixed"
 )
 
 // Support for COLR and CPAL tables
@@ -35,3 +36,90 @@ func newCPAL(table tables.CPAL) (CPAL, error) {
 	}
 	return out, nil
 }
+
+// ErrNotColorGlyph is returned by [Face.ColorGlyph] for glyphs
+// without color description.
+var ErrNotColorGlyph = errors.New("not a color glyph")
+
+// ColorGlyphKind identifies the representation of a [ColorGlyph].
+type ColorGlyphKind uint8
+
+const (
+	_ ColorGlyphKind = iota
+	// ColorGlyphLayers is used for COLR version 0 glyphs : see [ColorGlyph.Layers].
+	ColorGlyphLayers
+	// ColorGlyphPaint is used for COLR version 1 glyphs : see [ColorGlyph.Paint].
+	ColorGlyphPaint
+	// ColorGlyphBitmap is used for color images found in 'sbix' or 'CBDT' tables :
+	// see [ColorGlyph.Bitmap].
+	ColorGlyphBitmap
+)
+
+// ColorLayer is one layer of a COLR version 0 glyph, to be drawn
+// with a solid color.
+type ColorLayer struct {
+	// Glyph is the glyph providing the outline of the layer.
+	Glyph GID
+	// Color is the color of the layer, from the default palette.
+	// It is zero when [Foreground] is true.
+	Color tables.ColorRecord
+	// Foreground is true when the layer should use the text color.
+	Foreground bool
+}
+
+// ColorGlyph describes a color glyph. Only the field selected by [Kind] is set.
+type ColorGlyph struct {
+	Kind ColorGlyphKind
+
+	// Layers are drawn in order, the first one being at the bottom.
+	Layers []ColorLayer
+	// Paint is the root of the paint graph, whose layers may be resolved
+	// with [tables.LayerList.Resolve], using the [Font.COLR] table, and whose colors
+	// are indices into [Font.CPAL].
+	Paint tables.PaintTable
+	// Bitmap is the image for the strike closest to the requested size.
+	Bitmap GlyphBitmap
+}
+
+// ColorGlyph returns the color description of [gid], which is, by order of preference,
+// a list of layers (COLR version 0), a paint graph (COLR version 1), or a color image ('sbix' and 'CBDT' tables).
+// [size] is the font size, in pixels, used to select the best bitmap strike.
+//
+// [ErrNotColorGlyph] is returned if [gid] has no color description,
+// in which case [Face.GlyphDataOutline] should be used.
+func (f *Face) ColorGlyph(gid GID, size fixed.Int26_6) (ColorGlyph, error) {
+	if paint, ok := f.COLR.Search(gID(gid)); ok {
+		if layers, isV0 := paint.(tables.PaintColrLayersResolved); isV0 {
+			out := make([]ColorLayer, len(layers))
+			for i, layer := range layers {
+				out[i] = ColorLayer{Glyph: GID(layer.GlyphID)}
+				if layer.PaletteIndex == 0xFFFF {
+					out[i].Foreground = true
+				} else if len(f.CPAL) != 0 && int(layer.PaletteIndex) < len(f.CPAL[0]) {
+					out[i].Color = f.CPAL[0][layer.PaletteIndex]
+				} else {
+					return ColorGlyph{}, fmt.Errorf("invalid palette index %d for glyph %d", layer.PaletteIndex, gid)
+				}
+			}
+			return ColorGlyph{Kind: ColorGlyphLayers, Layers: out}, nil
+		}
+		return ColorGlyph{Kind: ColorGlyphPaint, Paint: paint}, nil
+	}
+
+	ppem := uint16(0) // select the largest strike
+	if px := size.Round(); px > 0 && px <= 0xFFFF {
+		ppem = uint16(px)
+	}
+	bitmap, err := f.sbix.glyphData(gID(gid), ppem, ppem)
+	if err != nil {
+		bitmap, err = f.bitmap.glyphData(gID(gid), ppem, ppem)
+	}
+	if err == nil {
+		switch bitmap.Format {
+		case PNG, JPG, TIFF: // monochrome bitmaps from 'EBDT' are not color glyphs
+			return ColorGlyph{Kind: ColorGlyphBitmap, Bitmap: bitmap}, nil
+		}
+	}
+
+	return ColorGlyph{}, ErrNotColorGlyph
+}
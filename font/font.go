@@ -9,10 +9,12 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"unicode/utf8"
 
 	"github.com/go-text/typesetting/font/cff"
 	ot "github.com/go-text/typesetting/font/opentype"
 	"github.com/go-text/typesetting/font/opentype/tables"
+	"golang.org/x/image/math/fixed"
 )
 
 type (
@@ -43,10 +45,18 @@ type (
 	GlyphExtents = ot.GlyphExtents
 )
 
-// ParseTTF parse an Opentype font file (.otf, .ttf).
+// ParseTTF parse an Opentype font file (.otf, .ttf), a WOFF file (.woff, .woff2),
+// an uncompressed EOT file (.eot) or a bare CFF file (.cff), see [NewLoaders].
 // See ParseTTC for support for collections.
 func ParseTTF(file Resource) (*Face, error) {
 	ld, err := ot.NewLoader(file)
+	if errors.Is(err, ot.ErrBareCFF) {
+		var lds []*ot.Loader
+		lds, err = NewLoaders(file)
+		if err == nil {
+			ld = lds[0]
+		}
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -60,7 +70,7 @@ func ParseTTF(file Resource) (*Face, error) {
 // ParseTTC parse an Opentype font file, with support for collections.
 // Single font files are supported, returning a slice with length 1.
 func ParseTTC(file Resource) ([]*Face, error) {
-	lds, err := ot.NewLoaders(file)
+	lds, err := NewLoaders(file)
 	if err != nil {
 		return nil, err
 	}
@@ -116,6 +126,23 @@ const (
 	XHeight
 )
 
+// FaceMetrics gathers the vertical metrics of a face, scaled to a size,
+// as returned by [Face.Metrics].
+// As for [FontExtents], the values are positive above the baseline.
+type FaceMetrics struct {
+	Ascent  fixed.Int26_6 // Typographic ascender, typically positive.
+	Descent fixed.Int26_6 // Typographic descender, typically negative.
+	LineGap fixed.Int26_6 // Suggested gap between two lines.
+
+	XHeight   fixed.Int26_6 // Height of lowercase letters, like 'x'.
+	CapHeight fixed.Int26_6 // Height of uppercase letters, like 'H'.
+
+	UnderlinePosition  fixed.Int26_6 // Top of the underline, typically negative.
+	UnderlineThickness fixed.Int26_6
+	StrikeoutPosition  fixed.Int26_6 // Top of the strikeout line.
+	StrikeoutThickness fixed.Int26_6
+}
+
 // FontID represents an identifier of a font (possibly in a collection),
 // and an optional variable instance.
 type FontID struct {
@@ -141,8 +168,9 @@ type Font struct {
 	Flavor Tag
 
 	// Cmap is the 'cmap' table
-	Cmap    Cmap
-	cmapVar UnicodeVariations
+	Cmap         Cmap
+	cmapVar      UnicodeVariations
+	cmapEncoding CmapEncoding
 
 	hhea *tables.Hhea
 	vhea *tables.Vhea
@@ -188,6 +216,7 @@ type Font struct {
 	Kerx Kernx
 	GSUB GSUB // An absent table has a nil slice of lookups
 	GPOS GPOS // An absent table has a nil slice of lookups
+	JSTF tables.JSTF
 
 	upem    uint16 // cached value
 	nGlyphs int
@@ -217,7 +246,7 @@ func NewFont(ld *ot.Loader) (*Font, error) {
 	if err != nil {
 		return nil, err
 	}
-	out.Cmap, out.cmapVar, err = ProcessCmap(tb, fontPage)
+	out.Cmap, out.cmapVar, out.cmapEncoding, err = processCmap(tb, fontPage)
 	if err != nil {
 		return nil, err
 	}
@@ -347,6 +376,9 @@ func NewFont(ld *ot.Loader) (*Font, error) {
 
 	out.GDEF, _ = loadGDEF(ld, len(out.fvar), gsubRaw, gposRaw)
 
+	raw, _ = ld.RawTable(ot.MustNewTag("JSTF"))
+	out.JSTF, _, _ = tables.ParseJSTF(raw)
+
 	raw, _ = ld.RawTable(ot.MustNewTag("morx"))
 	morx, _, _ := tables.ParseMorx(raw, out.nGlyphs)
 	out.Morx = newMorx(morx)
@@ -625,6 +657,8 @@ type Face struct {
 
 	coords       []tables.Coord
 	xPpem, yPpem uint16
+
+	glyphNames map[string]GID // lazily built by GIDForName
 }
 
 // NewFace wraps [font] and initializes glyph caches.
@@ -649,6 +683,63 @@ func (f *Face) NominalGlyph(ch rune) (GID, bool) {
 	return g, ok
 }
 
+// CoverageOf sets out[i] to true if runes[i] has a nominal glyph, as
+// reported by [Face.NominalGlyph], and to false otherwise.
+// Surrogates and invalid runes (negative or above U+10FFFF) always map to false.
+// It does not allocate and, when [runes] is sorted in increasing order,
+// the common 'cmap' formats are traversed only once.
+//
+// It panics if len(out) < len(runes).
+func (f *Face) CoverageOf(runes []rune, out []bool) {
+	out = out[:len(runes)]
+	sorted := true
+	for i, r := range runes {
+		if i > 0 && r < runes[i-1] {
+			sorted = false
+			break
+		}
+	}
+	if cm, ok := f.Cmap.(cmapSortedCoverer); ok && sorted {
+		cm.coverSorted(runes, out)
+		for i, r := range runes {
+			if !utf8.ValidRune(r) {
+				out[i] = false
+			}
+		}
+		return
+	}
+	for i, r := range runes {
+		if !utf8.ValidRune(r) {
+			out[i] = false
+			continue
+		}
+		_, out[i] = f.NominalGlyph(r)
+	}
+}
+
+// NominalGlyphVariant returns the glyph used to represent the Unicode Variation Sequence
+// made of [ch] followed by [variationSelector] (like U+845B U+E0101), as defined by the
+// 'cmap' subtable with format 14. For the sequences using the default glyph, this is
+// the glyph returned by [Face.NominalGlyph].
+// It returns false if the sequence is not supported by the font : the caller should
+// then typically ignore the variation selector and use [Face.NominalGlyph].
+func (f *Face) NominalGlyphVariant(ch, variationSelector rune) (GID, bool) {
+	gid, kind := f.cmapVar.GetGlyphVariant(ch, variationSelector)
+	switch kind {
+	case VariantFound:
+		return gid, true
+	case VariantUseDefault:
+		return f.NominalGlyph(ch)
+	default:
+		return 0, false
+	}
+}
+
+// CmapEncoding returns the platform and encoding of the 'cmap' subtable
+// used for [Font.Cmap], selected among the subtables provided by the font.
+// As in HarfBuzz, symbol subtables are preferred, then the 32-bit and 16-bit Unicode ones.
+func (f *Font) CmapEncoding() CmapEncoding { return f.cmapEncoding }
+
 // Ppem returns the horizontal and vertical pixels-per-em (ppem), used to select bitmap sizes.
 func (f *Face) Ppem() (x, y uint16) { return f.xPpem, f.yPpem }
 
@@ -4,6 +4,12 @@ package font
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
 	"testing"
 
 	hb "github.com/go-text/typesetting-utils/harfbuzz"
@@ -11,6 +17,7 @@ import (
 	ot "github.com/go-text/typesetting/font/opentype"
 	"github.com/go-text/typesetting/font/opentype/tables"
 	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
 )
 
 // wrap td.Files.ReadFile
@@ -181,3 +188,379 @@ func TestBitmapExtents(t *testing.T) {
 	extents, ok := face.GlyphExtents(41)
 	tu.Assert(t, ok && extents.Width == 819.2 && extents.Height == -1433.6)
 }
+
+func TestFaceWithTableOverride(t *testing.T) {
+	openLoader := func(filename string) *ot.Loader {
+		f, err := os.Open(filename)
+		tu.AssertNoErr(t, err)
+		t.Cleanup(func() { f.Close() })
+		ld, err := ot.NewLoader(f)
+		tu.AssertNoErr(t, err)
+		return ld
+	}
+	latin, arabic := openLoader("testdata/Roboto-Regular.ttf"), openLoader("testdata/Amiri-Regular.ttf")
+	ft, err := NewFont(latin)
+	tu.AssertNoErr(t, err)
+	base := NewFace(ft)
+	base.SetPpem(12, 12)
+
+	_, ok := base.NominalGlyph('ب')
+	tu.Assert(t, !ok)
+
+	face, err := FaceWithTableOverride(base, ot.MustNewTag("cmap"), readTable(t, arabic, "cmap"))
+	tu.AssertNoErr(t, err)
+	_, ok = face.NominalGlyph('ب')
+	tu.Assert(t, ok)
+	tu.Assert(t, face.Font != base.Font)
+	x, y := face.Ppem()
+	tu.Assert(t, x == 12 && y == 12)
+	// other tables are shared
+	tu.Assert(t, len(face.GSUB.Lookups) == len(base.GSUB.Lookups))
+	// base is not modified
+	_, ok = base.NominalGlyph('ب')
+	tu.Assert(t, !ok)
+
+	face, err = FaceWithTableOverride(base, ot.MustNewTag("GSUB"), readTable(t, arabic, "GSUB"))
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, len(face.GSUB.Lookups) != len(base.GSUB.Lookups))
+
+	// the coordinates are copied
+	base.SetCoords([]tables.Coord{1})
+	face, err = FaceWithTableOverride(base, ot.MustNewTag("cmap"), readTable(t, arabic, "cmap"))
+	tu.AssertNoErr(t, err)
+	base.Coords()[0] = 2
+	tu.Assert(t, len(face.Coords()) == 1 && face.Coords()[0] == 1)
+
+	_, err = FaceWithTableOverride(base, ot.MustNewTag("GPOS"), []byte{0, 1})
+	tu.Assert(t, err != nil)
+	_, err = FaceWithTableOverride(base, ot.MustNewTag("glyf"), readTable(t, latin, "glyf"))
+	tu.Assert(t, err != nil)
+}
+
+func TestSVGGlyph(t *testing.T) {
+	doc1 := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><path id="glyph2" d="M0 0"/><path id="glyph3" d="M0 0"/></svg>`)
+	doc2 := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><path id="glyph5" d="M0 0"/></svg>`)
+	var compressed bytes.Buffer
+	w := gzip.NewWriter(&compressed)
+	w.Write(doc1)
+	w.Close()
+
+	// build a minimal 'SVG ' table
+	const listOffset = 10
+	table := []byte{0, 0, 0, 0, 0, listOffset, 0, 0, 0, 0}
+	list := []byte{0, 2}
+	docsOffset := 2 + 2*12
+	for _, rec := range []struct {
+		first, last uint16
+		data        []byte
+	}{{2, 3, compressed.Bytes()}, {5, 5, doc2}} {
+		list = binary.BigEndian.AppendUint16(list, rec.first)
+		list = binary.BigEndian.AppendUint16(list, rec.last)
+		list = binary.BigEndian.AppendUint32(list, uint32(docsOffset))
+		list = binary.BigEndian.AppendUint32(list, uint32(len(rec.data)))
+		docsOffset += len(rec.data)
+	}
+	table = append(append(append(table, list...), compressed.Bytes()...), doc2...)
+
+	f, err := os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	ft, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+	_, ok := ft.SVGGlyph(2)
+	tu.Assert(t, !ok)
+
+	face, err := FaceWithTableOverride(ft, ot.MustNewTag("SVG "), table)
+	tu.AssertNoErr(t, err)
+
+	doc, ok := face.SVGGlyph(3)
+	tu.Assert(t, ok && bytes.Equal(doc, doc1)) // decompressed
+	first, last, ok := face.SVGGlyphRange(3)
+	tu.Assert(t, ok && first == 2 && last == 3)
+	// the document is shared by the glyphs 2 and 3
+	tu.Assert(t, SVGGlyphElementID(3) == "glyph3")
+	for gid := first; gid <= last; gid++ {
+		tu.Assert(t, bytes.Contains(doc, []byte(`id="`+SVGGlyphElementID(gid)+`"`)))
+	}
+	doc, ok = face.SVGGlyph(5)
+	tu.Assert(t, ok && bytes.Equal(doc, doc2))
+	_, ok = face.SVGGlyph(4)
+	tu.Assert(t, !ok)
+	_, _, ok = face.SVGGlyphRange(4)
+	tu.Assert(t, !ok)
+}
+
+func TestEmbedPermission(t *testing.T) {
+	for _, test := range []struct {
+		fsType EmbedPermission
+		usage  EmbedPermission
+	}{
+		{0, EmbedInstallable},
+		{0x0001, EmbedInstallable},
+		{EmbedRestricted, EmbedRestricted},
+		{EmbedRestricted | EmbedBitmapOnly, EmbedRestricted},
+		{EmbedPreviewAndPrint | EmbedNoSubsetting, EmbedPreviewAndPrint},
+		{EmbedEditable, EmbedEditable},
+		{EmbedRestricted | EmbedPreviewAndPrint | EmbedEditable, EmbedEditable}, // invalid
+	} {
+		tu.Assert(t, test.fsType.Usage() == test.usage)
+		tu.Assert(t, test.fsType.CanEmbed() == (test.usage != EmbedRestricted))
+	}
+
+	for _, test := range []struct {
+		filename string
+		expected EmbedPermission
+	}{
+		{"testdata/Roboto-Regular.ttf", EmbedInstallable},
+		{"testdata/Selawik-VF-Subset.ttf", EmbedEditable},
+	} {
+		f, err := os.Open(test.filename)
+		tu.AssertNoErr(t, err)
+		face, err := ParseTTF(f)
+		tu.AssertNoErr(t, err)
+		tu.Assert(t, face.EmbedPermission() == test.expected)
+		f.Close()
+	}
+}
+
+func TestVerticalGlyphMetrics(t *testing.T) {
+	// no vertical metrics : use the em box
+	f, err := os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+	extents, _ := face.FontHExtents()
+	upem := float32(face.Upem())
+	advance, origin, ok := face.VerticalGlyphMetrics(10, fixed.I(int(upem)))
+	tu.Assert(t, !ok)
+	tu.Assert(t, advance == fixed.I(int(upem)))
+	tu.Assert(t, origin == fixed.Int26_6((extents.Ascender+extents.Descender+upem)/2*64))
+
+	file, err := td.Files.ReadFile("collections/NotoSansCJK-Bold.ttc")
+	tu.AssertNoErr(t, err)
+	lds, err := ot.NewLoaders(bytes.NewReader(file))
+	tu.AssertNoErr(t, err)
+	ft, err := NewFont(lds[0])
+	tu.AssertNoErr(t, err)
+	face = NewFace(ft)
+	tu.Assert(t, ft.Upem() == 1000)
+
+	advance, origin, ok = face.VerticalGlyphMetrics(736, fixed.I(1000))
+	tu.Assert(t, ok)
+	tu.Assert(t, advance == fixed.I(int(-face.VerticalAdvance(736))))
+	tu.Assert(t, origin == fixed.I(870)) // from VORG
+	_, origin, _ = face.VerticalGlyphMetrics(736, fixed.I(10))
+	tu.Assert(t, origin == 557) // 8.7 * 64, rounded
+}
+
+func TestKern(t *testing.T) {
+	f, err := os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+
+	gid := func(r rune) GID {
+		g, ok := face.NominalGlyph(r)
+		tu.Assert(t, ok)
+		return g
+	}
+	size := fixed.I(int(face.Upem()))
+	tu.Assert(t, face.KernPair(gid('A'), gid('V'), size) < 0)
+	tu.Assert(t, face.KernPair(gid('V'), gid('A'), size) < 0)
+	tu.Assert(t, face.KernPair(gid('o'), gid('o'), size) == 0)
+	tu.Assert(t, face.KernPair(gid('A'), gid('V'), fixed.I(12)) > face.KernPair(gid('A'), gid('V'), size))
+
+	// legacy 'kern' table
+	font := *face.Font
+	font.GPOS = GPOS{}
+	font.Kern = Kernx{{Data: Kern0{{Left: 1, Right: 2, Value: -100}}}}
+	legacy := NewFace(&font)
+	tu.Assert(t, legacy.KernPair(1, 2, size) == fixed.I(-100))
+	tu.Assert(t, legacy.KernPair(2, 1, size) == 0)
+}
+
+func TestAdvancesF(t *testing.T) {
+	f, err := os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+
+	gid, _ := face.NominalGlyph('a')
+	adv := face.HorizontalAdvance(gid)
+	tu.Assert(t, face.HAdvanceF(gid, 0) == adv)
+	tu.Assert(t, face.HAdvanceF(gid, float32(face.Upem())) == adv)
+	tu.Assert(t, face.HAdvanceF(gid, 12.5) == adv*12.5/float32(face.Upem()))
+	tu.Assert(t, face.VAdvanceF(gid, 0) == face.VerticalAdvance(gid))
+	tu.Assert(t, face.VAdvanceF(gid, 12.5) < 0)
+}
+
+func TestFaceMetrics(t *testing.T) {
+	f, err := os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+
+	size := fixed.I(int(face.Upem()))
+	metrics := face.Metrics(size)
+	extents, ok := face.FontHExtents()
+	tu.Assert(t, ok)
+	tu.Assert(t, metrics.Ascent == fixed.I(int(extents.Ascender)) && metrics.Ascent > 0)
+	tu.Assert(t, metrics.Descent == fixed.I(int(extents.Descender)) && metrics.Descent < 0)
+	tu.Assert(t, metrics.LineGap == fixed.I(int(extents.LineGap)))
+	tu.Assert(t, metrics.XHeight == fixed.I(int(face.LineMetric(XHeight))))
+	tu.Assert(t, 0 < metrics.XHeight && metrics.XHeight < metrics.CapHeight && metrics.CapHeight < metrics.Ascent)
+	tu.Assert(t, metrics.UnderlinePosition < 0 && metrics.UnderlineThickness > 0)
+	tu.Assert(t, metrics.StrikeoutPosition > 0 && metrics.StrikeoutThickness > 0)
+
+	half := face.Metrics(size / 2)
+	tu.Assert(t, half.Ascent == metrics.Ascent/2)
+
+	// USE_TYPO_METRICS
+	font := *face.Font
+	font.os2.useTypoMetrics = true
+	font.os2.sTypoAscender, font.os2.sTypoDescender, font.os2.sTypoLineGap = 1000, -300, 100
+	typo := NewFace(&font).Metrics(size)
+	tu.Assert(t, typo.Ascent == fixed.I(1000) && typo.Descent == fixed.I(-300) && typo.LineGap == fixed.I(100))
+}
+
+func TestFaceGlyphNames(t *testing.T) {
+	for _, file := range []string{"testdata/Roboto-Regular.ttf", "testdata/Amiri-Regular.ttf"} {
+		f, err := os.Open(file)
+		tu.AssertNoErr(t, err)
+		face, err := ParseTTF(f)
+		f.Close()
+		tu.AssertNoErr(t, err)
+
+		for gid := 0; gid < face.nGlyphs; gid++ {
+			name := face.GlyphNameOrSynthetic(GID(gid))
+			tu.Assert(t, name != "")
+			if fontName := face.GlyphName(GID(gid)); fontName != "" {
+				tu.Assert(t, name == fontName)
+			}
+			got, ok := face.GIDForName(name)
+			tu.Assert(t, ok && face.GlyphNameOrSynthetic(got) == name)
+		}
+		tu.Assert(t, face.GlyphNameOrSynthetic(GID(face.nGlyphs)) == "")
+		_, ok := face.GIDForName("not a glyph name")
+		tu.Assert(t, !ok)
+	}
+
+	// names from the 'post' table
+	f, err := os.Open("testdata/Amiri-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	amiri, err := ParseTTF(f)
+	f.Close()
+	tu.AssertNoErr(t, err)
+	tatweel, _ := amiri.NominalGlyph(0x0640)
+	gid, ok := amiri.GIDForName("uni0640")
+	tu.Assert(t, ok && gid == tatweel)
+
+	// Roboto has no names : they are synthesized
+	f, err = os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, face.GlyphName(12) == "")
+	tu.Assert(t, face.GlyphNameOrSynthetic(12) == "gid12")
+	gid, ok = face.GIDForName("gid12")
+	tu.Assert(t, ok && gid == 12)
+}
+
+func TestNominalGlyphVariant(t *testing.T) {
+	f, err := os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	face, err := ParseTTF(f)
+	f.Close()
+	tu.AssertNoErr(t, err)
+
+	tu.Assert(t, face.CmapEncoding() == CmapEncoding{tables.PlatformMicrosoft, tables.PEMicrosoftUnicodeCs})
+
+	// no format 14 subtable
+	_, ok := face.NominalGlyphVariant('A', 0xFE00)
+	tu.Assert(t, !ok)
+
+	gidA, _ := face.NominalGlyph('A')
+	face.cmapVar = newUnicodeVariations(tables.CmapSubtable14{VarSelectors: []tables.VariationSelector{
+		{
+			VarSelector:   [3]byte{0, 0xFE, 0x00},
+			DefaultUVS:    tables.DefaultUVSTable{Ranges: []tables.UnicodeRange{{StartUnicodeValue: [3]byte{0, 0, 'A'}}}},
+			NonDefaultUVS: tables.UVSMappingTable{Ranges: []tables.UvsMappingRecord{{UnicodeValue: [3]byte{0, 0, 'B'}, GlyphID: 7}}},
+		},
+	}})
+	gid, ok := face.NominalGlyphVariant('A', 0xFE00)
+	tu.Assert(t, ok && gid == gidA)
+	gid, ok = face.NominalGlyphVariant('B', 0xFE00)
+	tu.Assert(t, ok && gid == 7)
+	_, ok = face.NominalGlyphVariant('C', 0xFE00)
+	tu.Assert(t, !ok)
+	_, ok = face.NominalGlyphVariant('A', 0xFE01)
+	tu.Assert(t, !ok)
+}
+
+func TestCoverageOf(t *testing.T) {
+	for _, file := range []string{"testdata/Roboto-Regular.ttf", "testdata/Amiri-Regular.ttf", "testdata/UbuntuMono-R.ttf"} {
+		f, err := os.Open(file)
+		tu.AssertNoErr(t, err)
+		face, err := ParseTTF(f)
+		f.Close()
+		tu.AssertNoErr(t, err)
+
+		var sorted []rune
+		for r := rune(-2); r < 0x11000; r += 7 {
+			sorted = append(sorted, r)
+		}
+		sorted = append(sorted, 0xD800, 0xDFFF, 0x10FFFF, 0x110000)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		reversed := make([]rune, len(sorted))
+		for i, r := range sorted {
+			reversed[len(sorted)-1-i] = r
+		}
+
+		for _, runes := range [][]rune{sorted, reversed} {
+			out := make([]bool, len(runes)+1)
+			face.CoverageOf(runes, out)
+			covered := 0
+			for i, r := range runes {
+				_, exp := face.NominalGlyph(r)
+				if 0xD800 <= r && r <= 0xDFFF {
+					exp = false
+				}
+				tu.AssertC(t, out[i] == exp, fmt.Sprintf("%s: rune %U", file, r))
+				if out[i] {
+					covered++
+				}
+			}
+			tu.Assert(t, covered > 0)
+
+			allocs := testing.AllocsPerRun(10, func() { face.CoverageOf(runes, out) })
+			tu.Assert(t, allocs == 0)
+		}
+	}
+}
+
+func TestParseWOFF2(t *testing.T) {
+	loadFace := func(file string) *Face {
+		f, err := os.Open(file)
+		tu.AssertNoErr(t, err)
+		defer f.Close()
+		face, err := ParseTTF(f)
+		tu.AssertNoErr(t, err)
+		return face
+	}
+	exp, got := loadFace("testdata/Amiri-Regular.ttf"), loadFace("testdata/Amiri-Regular.woff2")
+
+	// the glyphs are decoded from the transformed 'glyf' table
+	tu.Assert(t, len(exp.glyf) == len(got.glyf) && len(got.glyf) > 0)
+	for gid := range exp.glyf {
+		tu.AssertC(t, reflect.DeepEqual(exp.GlyphData(GID(gid)), got.GlyphData(GID(gid))), fmt.Sprint(gid))
+		tu.Assert(t, exp.HorizontalAdvance(GID(gid)) == got.HorizontalAdvance(GID(gid)))
+		extentsExp, _ := exp.GlyphExtents(GID(gid))
+		extentsGot, _ := got.GlyphExtents(GID(gid))
+		tu.Assert(t, extentsExp == extentsGot)
+	}
+}
@@ -44,23 +44,45 @@ const (
 
 type glyphSet map[tables.GlyphID]struct{}
 
-func (f *Face) getPointsForGlyph(gid tables.GlyphID) []contourPoint {
+// getPointsForGlyph returns the contour points of the glyph, including
+// the phantom points, or an [*errInvalidComposite] error if the glyph is an invalid composite
+// (too deeply nested, too complex or with out of range components).
+func (f *Face) getPointsForGlyph(gid tables.GlyphID) ([]contourPoint, error) {
 	var out []contourPoint
-	seenGlyphs := make(glyphSet) // used to deny loops
-	f.getPointsForGlyphRec(gid, 0, seenGlyphs, &out)
-	return out
+	seenGlyphs := glyphSet{gid: {}} // used to deny loops
+	operations := 0
+	if err := f.getPointsForGlyphRec(gid, 0, seenGlyphs, &operations, &out); err != nil {
+		err.(*errInvalidComposite).gid = gid
+		return nil, err
+	}
+	return out, nil
 }
 
-const maxCompositeNesting = 20 // protect against malicious fonts
+const (
+	maxCompositeNesting    = 20   // protect against malicious fonts
+	maxCompositeOperations = 2048 // protect against (non cyclic) composites with exponential size
+)
 
 // use the `glyf` table to fetch the contour points,
 // applying variation if needed.
 // for composite, recursively calls itself; allPoints includes phantom points and will be at least of length 4
-func (f *Face) getPointsForGlyphRec(gid tables.GlyphID, currentDepth int, currentGlyphs glyphSet, allPoints *[]contourPoint /* OUT */) {
+//
+// Components already being visited (that is, loops in the composite graph) are skipped,
+// and an [*errInvalidComposite] error is returned if the nesting or the number of visited components is too high,
+// or if a component is out of range. Its gid field is left to the caller.
+func (f *Face) getPointsForGlyphRec(gid tables.GlyphID, currentDepth int, currentGlyphs glyphSet, operations *int, allPoints *[]contourPoint /* OUT */) error {
 	// adapted from harfbuzz/src/OT/glyf/Glyph.hh
 
-	if currentDepth > maxCompositeNesting || int(gid) >= len(f.glyf) {
-		return
+	if currentDepth > maxCompositeNesting {
+		return &errInvalidComposite{cause: compositeTooDeep}
+	}
+	if *operations >= maxCompositeOperations {
+		return &errInvalidComposite{cause: compositeTooComplex}
+	}
+	*operations++
+
+	if int(gid) >= len(f.glyf) {
+		return &errInvalidComposite{cause: compositeOutOfRange, component: gid}
 	}
 
 	g := f.glyf[gid]
@@ -102,13 +124,10 @@ func (f *Face) getPointsForGlyphRec(gid tables.GlyphID, currentDepth int, curren
 			// recurse on component
 			var compPoints []contourPoint
 
-			f.getPointsForGlyphRec(item.GlyphIndex, currentDepth+1, currentGlyphs, &compPoints)
-
-			LC := len(compPoints)
-			if LC < phantomCount { // in case of max depth reached
-				delete(currentGlyphs, item.GlyphIndex)
-				return
+			if err := f.getPointsForGlyphRec(item.GlyphIndex, currentDepth+1, currentGlyphs, operations, &compPoints); err != nil {
+				return err
 			}
+			LC := len(compPoints)
 
 			/* Copy phantom points from component if USE_MY_METRICS flag set */
 			if item.HasUseMyMetrics() {
@@ -153,6 +172,7 @@ func (f *Face) getPointsForGlyphRec(gid tables.GlyphID, currentDepth int, curren
 			(*allPoints)[i].translate(tx, 0)
 		}
 	}
+	return nil
 }
 
 // does not includes phantom points
@@ -209,7 +229,10 @@ func (f *Face) getGlyfPoints(gid tables.GlyphID, computeExtents bool) (ext Glyph
 	if int(gid) >= len(f.glyf) {
 		return
 	}
-	allPoints := f.getPointsForGlyph(gid)
+	allPoints, err := f.getPointsForGlyph(gid)
+	if err != nil { // invalid composite
+		return
+	}
 
 	copy(ph[:], allPoints[len(allPoints)-phantomCount:])
 
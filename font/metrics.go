@@ -4,9 +4,11 @@ package font
 
 import (
 	"math"
+	"strconv"
 
 	ot "github.com/go-text/typesetting/font/opentype"
 	"github.com/go-text/typesetting/font/opentype/tables"
+	"golang.org/x/image/math/fixed"
 )
 
 type gID = tables.GlyphID
@@ -30,6 +32,36 @@ func (f *Font) GlyphName(glyph GID) string {
 	return ""
 }
 
+// GlyphNameOrSynthetic returns the name of the given glyph, as given by [Font.GlyphName],
+// or a synthesized name of the form "gid<N>" (like "gid12") if the font does not
+// provide one (for instance for 'post' tables with version 3.0), so that
+// each glyph has a name. An empty string is returned for invalid glyphs.
+func (f *Face) GlyphNameOrSynthetic(glyph GID) string {
+	if int(glyph) >= f.nGlyphs {
+		return ""
+	}
+	if name := f.Font.GlyphName(glyph); name != "" {
+		return name
+	}
+	return "gid" + strconv.Itoa(int(glyph))
+}
+
+// GIDForName returns the glyph with the given name, as returned by [Face.GlyphNameOrSynthetic],
+// or false if not found. When several glyphs share the same name, the first one is returned.
+//
+// The reverse mapping is built on the first call and stored in the face,
+// meaning that this method modifies [f].
+func (f *Face) GIDForName(name string) (GID, bool) {
+	if f.glyphNames == nil {
+		f.glyphNames = make(map[string]GID, f.nGlyphs)
+		for gid := f.nGlyphs - 1; gid >= 0; gid-- { // so that the first glyph wins
+			f.glyphNames[f.GlyphNameOrSynthetic(GID(gid))] = GID(gid)
+		}
+	}
+	gid, ok := f.glyphNames[name]
+	return gid, ok
+}
+
 // Upem returns the units per em of the font file.
 // This value is only relevant for scalable fonts.
 func (f *Font) Upem() uint16 { return f.upem }
@@ -183,6 +215,36 @@ func (f *Face) LineMetric(metric LineMetric) float32 {
 	}
 }
 
+// Metrics returns the vertical metrics of the face, scaled to [size]
+// (the size of the em box), including the variations of the 'MVAR' table
+// for the current coordinates.
+//
+// The ascender, descender and line gap are the ones returned by [Face.FontHExtents] :
+// they are read from the 'OS/2' typographic values if its USE_TYPO_METRICS flag is set,
+// from the 'hhea' table otherwise. If they are not available, an ascender
+// of 0.8 em and a descender of -0.2 em are used.
+// The other values are given by [Face.LineMetric].
+func (f *Face) Metrics(size fixed.Int26_6) FaceMetrics {
+	scale := func(v float32) fixed.Int26_6 {
+		return fixed.Int26_6(math.Round(float64(v) * float64(size) / float64(f.upem)))
+	}
+	extents, ok := f.FontHExtents()
+	if !ok {
+		extents = FontExtents{Ascender: 0.8 * float32(f.upem), Descender: -0.2 * float32(f.upem)}
+	}
+	return FaceMetrics{
+		Ascent:             scale(extents.Ascender),
+		Descent:            scale(extents.Descender),
+		LineGap:            scale(extents.LineGap),
+		XHeight:            scale(f.LineMetric(XHeight)),
+		CapHeight:          scale(f.LineMetric(CapHeight)),
+		UnderlinePosition:  scale(f.LineMetric(UnderlinePosition)),
+		UnderlineThickness: scale(f.LineMetric(UnderlineThickness)),
+		StrikeoutPosition:  scale(f.LineMetric(StrikethroughPosition)),
+		StrikeoutThickness: scale(f.LineMetric(StrikethroughThickness)),
+	}
+}
+
 // NominalGlyph returns the glyph used to represent the given rune,
 // or false if not found.
 // Note that it only looks into the cmap, without taking account substitutions
@@ -266,6 +328,29 @@ func (f *Face) VerticalAdvance(gid GID) float32 {
 	return -f.getGlyphAdvanceVar(gID(gid), true)
 }
 
+// HAdvanceF returns the horizontal advance of [gid], scaled to [size] (the size
+// of the em box, for instance in pixels), without the rounding implied by
+// fixed point values. A zero [size] returns the advance in font units,
+// as [Face.HorizontalAdvance].
+func (f *Face) HAdvanceF(gid GID, size float32) float32 {
+	return f.scaleF(f.HorizontalAdvance(gid), size)
+}
+
+// VAdvanceF is the same as [Face.HAdvanceF] for the vertical advance,
+// which is negative, as returned by [Face.VerticalAdvance].
+func (f *Face) VAdvanceF(gid GID, size float32) float32 {
+	return f.scaleF(f.VerticalAdvance(gid), size)
+}
+
+// scaleF scales [v], expressed in font units, to [size],
+// or returns it if [size] is zero
+func (f *Face) scaleF(v float32, size float32) float32 {
+	if size == 0 || f.upem == 0 {
+		return v
+	}
+	return v * size / float32(f.upem)
+}
+
 func (f *Font) GlyphHOrigin(GID) (x, y int32, found bool) {
 	// zero is the right value here
 	return 0, 0, true
@@ -306,6 +391,127 @@ func (f *Face) GlyphVOrigin(glyph GID) (x, y float32) {
 	return x, y
 }
 
+// VerticalGlyphMetrics returns the vertical advance (as a positive value) and the
+// vertical origin (the distance between the baseline and the top of the glyph
+// vertical origin) of [gid], scaled to [size] (the size of the em box).
+//
+// The metrics are read from the 'vmtx' and 'VORG' tables (with variations applied).
+// When one of them is missing, the metrics are synthesized from the em box,
+// centered between the ascender and the descender.
+// If the font has no vertical metrics at all, the synthesized
+// metrics are returned with [ok] set to false.
+func (f *Face) VerticalGlyphMetrics(gid GID, size fixed.Int26_6) (advance, origin fixed.Int26_6, ok bool) {
+	scale := func(v float32) fixed.Int26_6 {
+		return fixed.Int26_6(math.Round(float64(v) * float64(size) / float64(f.upem)))
+	}
+
+	// em box centered on the font horizontal extents
+	emAdvance := float32(f.upem)
+	fontExtents, _ := f.FontHExtents()
+	emOrigin := (fontExtents.Ascender + fontExtents.Descender + emAdvance) / 2
+
+	hasVmtx := f.HasVerticalMetrics()
+	if !hasVmtx && f.vorg == nil {
+		return scale(emAdvance), scale(emOrigin), false
+	}
+
+	if hasVmtx {
+		advance = scale(-f.VerticalAdvance(gid))
+	} else {
+		advance = scale(emAdvance)
+	}
+
+	_, y := f.GlyphVOrigin(gid)
+	return advance, scale(y), true
+}
+
+// KernPair returns the horizontal adjustment to apply between the glyphs [left] and [right],
+// scaled to [size] (the size of the em box), or 0 if no kerning applies.
+//
+// The pair positioning lookups of the GPOS 'kern' feature are used when available,
+// the legacy 'kern' table otherwise.
+// This is only a convenience for simple cases : contextual kerning, device
+// tables and the lookup flags are ignored, so that the result may differ from
+// the one given by a full shaping of the text.
+func (f *Face) KernPair(left, right GID, size fixed.Int26_6) fixed.Int26_6 {
+	v, ok := f.gposKern(left, right)
+	if !ok {
+		v = f.kernTableKern(left, right)
+	}
+	return fixed.Int26_6(math.Round(float64(v) * float64(size) / float64(f.upem)))
+}
+
+// gposKern returns false if the font has no GPOS 'kern' feature
+func (f *Face) gposKern(left, right GID) (int16, bool) {
+	kernTag := ot.MustNewTag("kern")
+	found := false
+	for _, feature := range f.GPOS.Features {
+		if feature.Tag != kernTag {
+			continue
+		}
+		found = true
+		for _, index := range feature.LookupListIndices {
+			if int(index) >= len(f.GPOS.Lookups) {
+				continue
+			}
+			for _, subtable := range f.GPOS.Lookups[index].Subtables {
+				pair, ok := subtable.(tables.PairPos)
+				if !ok {
+					continue
+				}
+				if v, ok := pairPosKern(pair, gID(left), gID(right)); ok {
+					return v, true
+				}
+			}
+		}
+	}
+	return 0, found
+}
+
+// pairPosKern returns the adjustment defined by [pair] for the given glyphs,
+// or false if the pair is not covered.
+func pairPosKern(pair tables.PairPos, left, right gID) (int16, bool) {
+	index, ok := pair.Cov().Index(left)
+	if !ok {
+		return 0, false
+	}
+	switch data := pair.Data.(type) {
+	case tables.PairPosData1:
+		if index >= len(data.PairSets) {
+			return 0, false
+		}
+		record, ok := data.PairSets[index].FindGlyph(right)
+		if !ok {
+			return 0, false
+		}
+		return record.ValueRecord1.XAdvance + record.ValueRecord2.XPlacement, true
+	case tables.PairPosData2:
+		class2, ok := data.ClassDef2.Class(right)
+		if !ok {
+			return 0, false
+		}
+		class1, _ := data.ClassDef1.Class(left)
+		record := data.Record(class1, class2)
+		return record.ValueRecord1.XAdvance + record.ValueRecord2.XPlacement, true
+	}
+	return 0, false
+}
+
+// kernTableKern sums the values of the horizontal, simple subtables
+// of the legacy 'kern' table
+func (f *Face) kernTableKern(left, right GID) int16 {
+	var v int16
+	for _, subtable := range f.Kern {
+		if !subtable.IsHorizontal() || subtable.IsCrossStream() || subtable.IsVariation() {
+			continue
+		}
+		if kerns, ok := subtable.Data.(SimpleKerns); ok {
+			v += kerns.KernPair(left, right)
+		}
+	}
+	return v
+}
+
 func (f *Face) getVOriginWithVar(gid gID) float32 {
 	if int(gid) >= f.nGlyphs {
 		return 0
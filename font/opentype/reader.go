@@ -25,6 +25,8 @@ var (
 
 	// signatureWOFF is the magic number at the start of a WOFF file.
 	signatureWOFF = MustNewTag("wOFF")
+	// signatureWOFF2 is the magic number at the start of a WOFF2 file.
+	signatureWOFF2 = MustNewTag("wOF2")
 
 	ttcTag = MustNewTag("ttcf")
 
@@ -101,8 +103,13 @@ func NewLoaders(file Resource) ([]*Loader, error) {
 	case dfontResourceDataOffset:
 		offsets, err = parseDfont(file)
 		relativeOffset = true
+	case signatureWOFF2:
+		return parseWOFF2(file)
 	default:
-		return nil, fmt.Errorf("unsupported font format %v", bytes)
+		pr, err = parseLegacyFont(file, bytes)
+		if err == errUnknownFormat {
+			return nil, fmt.Errorf("unsupported font format %v", bytes)
+		}
 	}
 	if err != nil {
 		return nil, err
@@ -207,8 +214,25 @@ func parseOneFont(file Resource, offset uint32, relativeOffset bool) (parser *Lo
 		parser, err = parseOTF(file, offset, relativeOffset)
 	case ttcTag, dfontResourceDataOffset: // no more collections allowed here
 		return nil, errors.New("collections not allowed")
+	case signatureWOFF2:
+		if offset != 0 || relativeOffset { // no WOFF2 in collections
+			return nil, fmt.Errorf("unknown font format tag %v", bytes)
+		}
+		var lds []*Loader
+		if lds, err = parseWOFF2(file); err == nil {
+			if len(lds) != 1 {
+				return nil, errors.New("collections not allowed")
+			}
+			parser = lds[0]
+		}
 	default:
-		return nil, fmt.Errorf("unknown font format tag %v", bytes)
+		if offset != 0 || relativeOffset { // legacy formats are not allowed in collections
+			return nil, fmt.Errorf("unknown font format tag %v", bytes)
+		}
+		parser, err = parseLegacyFont(file, bytes)
+		if err == errUnknownFormat {
+			return nil, fmt.Errorf("unknown font format tag %v", bytes)
+		}
 	}
 
 	if err != nil {
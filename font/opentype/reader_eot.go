@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package opentype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Embedded OpenType (EOT) files start with a header, followed by
+// the (possibly obfuscated) font data.
+// See https://www.w3.org/submissions/EOT/
+const (
+	eotHeaderSize      = 36 // up to the magic number
+	eotMagic           = 0x504C
+	eotFlagCompressed  = 0x00000004 // MicroType Express compression
+	eotFlagXOREncrypt  = 0x10000000
+	eotXORKey          = 0x50
+	cffMajorVersion    = 1
+	cffHeaderMinLength = 4
+)
+
+// errUnknownFormat is returned by [parseLegacyFont] for unrecognized files
+var errUnknownFormat = errors.New("unknown font format")
+
+// ErrBareCFF is returned by [NewLoader] and [NewLoaders] for bare CFF files (.cff),
+// without sfnt wrapper. Such files are supported by the font package, which
+// wraps them into a synthetic OpenType font (see font.NewLoaders).
+var ErrBareCFF = errors.New("unsupported font format: bare CFF (without sfnt wrapper)")
+
+// parseLegacyFont handles the font files without a sfnt signature :
+// EOT files are unwrapped and parsed, and bare CFF files are reported with [ErrBareCFF],
+// since building the required tables needs to parse the CFF font (which is done by the
+// font/cff package, importing this one).
+// [magic] is the start of the file.
+// It returns [errUnknownFormat] if [file] is not recognized.
+func parseLegacyFont(file Resource, magic [4]byte) (*Loader, error) {
+	if isBareCFF(magic) {
+		return nil, ErrBareCFF
+	}
+
+	data, err := eotFontData(file)
+	if err != nil {
+		return nil, err
+	}
+	return parseOneFont(data, 0, false)
+}
+
+// isBareCFF returns true if [magic], the first four bytes of a file,
+// is the header of a bare CFF font : major version, minor version, header size
+// and offset size.
+func isBareCFF(magic [4]byte) bool {
+	return magic[0] == cffMajorVersion && magic[2] >= cffHeaderMinLength && 1 <= magic[3] && magic[3] <= 4
+}
+
+// eotFontData returns the font data wrapped in the EOT [file],
+// or [errUnknownFormat] if [file] is not an EOT file.
+func eotFontData(file Resource) (Resource, error) {
+	var header [eotHeaderSize]byte
+	if _, err := file.ReadAt(header[:], 0); err != nil {
+		return nil, errUnknownFormat
+	}
+	if binary.LittleEndian.Uint16(header[34:]) != eotMagic {
+		return nil, errUnknownFormat
+	}
+	eotSize := binary.LittleEndian.Uint32(header[0:])
+	fontDataSize := binary.LittleEndian.Uint32(header[4:])
+	flags := binary.LittleEndian.Uint32(header[12:])
+
+	fileSize, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	// check eotSize first, so that eotSize-eotHeaderSize does not wrap around
+	if eotSize < eotHeaderSize || int64(eotSize) > fileSize ||
+		int64(fontDataSize) > fileSize || fontDataSize > eotSize-eotHeaderSize {
+		return nil, fmt.Errorf("invalid EOT font: EOT size %d, font data size %d, file size %d", eotSize, fontDataSize, fileSize)
+	}
+	if flags&eotFlagCompressed != 0 {
+		return nil, errors.New("unsupported font format: EOT with MicroType Express compression")
+	}
+
+	// the font data is always stored at the end of the EOT structure
+	data := make([]byte, fontDataSize)
+	if _, err := file.ReadAt(data, int64(eotSize-fontDataSize)); err != nil {
+		return nil, fmt.Errorf("invalid EOT font: %s", err)
+	}
+	if flags&eotFlagXOREncrypt != 0 {
+		for i := range data {
+			data[i] ^= eotXORKey
+		}
+	}
+	return bytes.NewReader(data), nil
+}
@@ -4,7 +4,14 @@ package opentype
 
 import (
 	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
 	"math/rand"
+	"os"
+	"sort"
+	"strings"
 	"testing"
 
 	td "github.com/go-text/typesetting-utils/opentype"
@@ -78,3 +85,192 @@ func TestRawTable(t *testing.T) {
 		tu.AssertC(t, err == nil, filename)
 	}
 }
+
+// toWOFF wraps the tables of the [ttf] font into a WOFF file,
+// with compressed tables.
+func toWOFF(t *testing.T, ttf []byte) []byte {
+	ld, err := NewLoader(bytes.NewReader(ttf))
+	tu.AssertNoErr(t, err)
+	var tags []Tag
+	for tag := range ld.tables {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+
+	var (
+		directory []byte
+		data      bytes.Buffer
+	)
+	offset := woffHeaderSize + woffEntrySize*len(tags)
+	for _, tag := range tags {
+		table, err := ld.RawTable(tag)
+		tu.AssertNoErr(t, err)
+		var compressed bytes.Buffer
+		w := zlib.NewWriter(&compressed)
+		w.Write(table)
+		w.Close()
+		stored := compressed.Bytes()
+		if len(stored) >= len(table) { // tables are only stored compressed if smaller
+			stored = table
+		}
+		directory = binary.BigEndian.AppendUint32(directory, uint32(tag))
+		directory = binary.BigEndian.AppendUint32(directory, uint32(offset+data.Len()))
+		directory = binary.BigEndian.AppendUint32(directory, uint32(len(stored)))
+		directory = binary.BigEndian.AppendUint32(directory, uint32(len(table)))
+		directory = binary.BigEndian.AppendUint32(directory, 0) // checksum, not checked
+		data.Write(stored)
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+	}
+
+	header := make([]byte, woffHeaderSize)
+	binary.BigEndian.PutUint32(header[0:], uint32(signatureWOFF))
+	binary.BigEndian.PutUint32(header[4:], uint32(ld.Type))
+	binary.BigEndian.PutUint32(header[8:], uint32(offset+data.Len()))
+	binary.BigEndian.PutUint16(header[12:], uint16(len(tags)))
+	return append(append(header, directory...), data.Bytes()...)
+}
+
+func TestWOFF(t *testing.T) {
+	ttf, err := os.ReadFile("../testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	woff := toWOFF(t, ttf)
+	tu.Assert(t, len(woff) < len(ttf))
+
+	ref, err := NewLoader(bytes.NewReader(ttf))
+	tu.AssertNoErr(t, err)
+	for _, load := range []func(Resource) ([]*Loader, error){
+		NewLoaders,
+		func(r Resource) ([]*Loader, error) { ld, err := NewLoader(r); return []*Loader{ld}, err },
+	} {
+		lds, err := load(bytes.NewReader(woff))
+		tu.AssertNoErr(t, err)
+		tu.Assert(t, len(lds) == 1)
+		ld := lds[0]
+		tu.Assert(t, ld.Type == ref.Type)
+		tu.Assert(t, len(ld.tables) == len(ref.tables))
+		// the decompressed tables are identical
+		for tag := range ref.tables {
+			exp, err := ref.RawTable(tag)
+			tu.AssertNoErr(t, err)
+			got, err := ld.RawTable(tag)
+			tu.AssertNoErr(t, err)
+			tu.AssertC(t, bytes.Equal(exp, got), tag.String())
+		}
+	}
+}
+
+func TestWOFF2(t *testing.T) {
+	ttf, err := os.ReadFile("../testdata/Amiri-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	woff2, err := os.ReadFile("../testdata/Amiri-Regular.woff2")
+	tu.AssertNoErr(t, err)
+
+	ref, err := NewLoader(bytes.NewReader(ttf))
+	tu.AssertNoErr(t, err)
+	for _, load := range []func(Resource) ([]*Loader, error){
+		NewLoaders,
+		func(r Resource) ([]*Loader, error) { ld, err := NewLoader(r); return []*Loader{ld}, err },
+	} {
+		lds, err := load(bytes.NewReader(woff2))
+		tu.AssertNoErr(t, err)
+		tu.Assert(t, len(lds) == 1)
+		ld := lds[0]
+		tu.Assert(t, ld.Type == ref.Type)
+		tu.Assert(t, len(ld.tables) == len(ref.tables))
+		for tag := range ref.tables {
+			exp, err := ref.RawTable(tag)
+			tu.AssertNoErr(t, err)
+			got, err := ld.RawTable(tag)
+			tu.AssertNoErr(t, err)
+			switch tag {
+			case MustNewTag("glyf"): // the glyphs are re-encoded, see the font package tests
+			case MustNewTag("loca"):
+				tu.AssertC(t, len(exp) == len(got), tag.String())
+			default: // including the reconstructed 'hmtx' table
+				tu.AssertC(t, bytes.Equal(exp, got), tag.String())
+			}
+		}
+	}
+
+	// truncated files are rejected
+	for _, size := range []int{20, woff2HeaderSize, woff2HeaderSize + 10, len(woff2) / 2, len(woff2) - 10} {
+		_, err = NewLoader(bytes.NewReader(woff2[:size]))
+		tu.AssertC(t, err != nil, fmt.Sprint(size))
+	}
+}
+
+// toEOT wraps the [ttf] font into an uncompressed EOT file,
+// with a minimal (version 1) header.
+func toEOT(ttf []byte, xor bool) []byte {
+	const headerSize = 36 + 46 + 4*4 // fixed fields and empty names
+	out := make([]byte, headerSize, headerSize+len(ttf))
+	binary.LittleEndian.PutUint32(out[0:], uint32(headerSize+len(ttf)))
+	binary.LittleEndian.PutUint32(out[4:], uint32(len(ttf)))
+	binary.LittleEndian.PutUint32(out[8:], 0x00010000)
+	binary.LittleEndian.PutUint16(out[34:], eotMagic)
+	out = append(out, ttf...)
+	if xor {
+		binary.LittleEndian.PutUint32(out[12:], eotFlagXOREncrypt)
+		for i := headerSize; i < len(out); i++ {
+			out[i] ^= eotXORKey
+		}
+	}
+	return out
+}
+
+func TestEOT(t *testing.T) {
+	ttf, err := os.ReadFile("../testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	ref, err := NewLoader(bytes.NewReader(ttf))
+	tu.AssertNoErr(t, err)
+	exp, err := ref.RawTable(MustNewTag("cmap"))
+	tu.AssertNoErr(t, err)
+
+	for _, xor := range []bool{false, true} {
+		eot := toEOT(ttf, xor)
+		ld, err := NewLoader(bytes.NewReader(eot))
+		tu.AssertNoErr(t, err)
+		tu.Assert(t, len(ld.tables) == len(ref.tables))
+		got, err := ld.RawTable(MustNewTag("cmap"))
+		tu.AssertNoErr(t, err)
+		tu.Assert(t, bytes.Equal(exp, got))
+
+		lds, err := NewLoaders(bytes.NewReader(eot))
+		tu.AssertNoErr(t, err)
+		tu.Assert(t, len(lds) == 1 && len(lds[0].tables) == len(ref.tables))
+	}
+
+	// compressed fonts are not supported
+	eot := toEOT(ttf, false)
+	binary.LittleEndian.PutUint32(eot[12:], eotFlagCompressed)
+	_, err = NewLoaders(bytes.NewReader(eot))
+	tu.Assert(t, err != nil && strings.Contains(err.Error(), "EOT"))
+
+	// invalid sizes
+	eot = toEOT(ttf, false)
+	binary.LittleEndian.PutUint32(eot[4:], uint32(len(eot)))
+	_, err = NewLoader(bytes.NewReader(eot))
+	tu.Assert(t, err != nil)
+	// EOT size smaller than the header
+	eot = toEOT(ttf, false)
+	binary.LittleEndian.PutUint32(eot[0:], 10)
+	binary.LittleEndian.PutUint32(eot[4:], 0xFFFFFFF0)
+	_, err = NewLoader(bytes.NewReader(eot))
+	tu.Assert(t, err != nil && strings.Contains(err.Error(), "EOT"))
+	// font data larger than the file
+	eot = toEOT(ttf, false)
+	binary.LittleEndian.PutUint32(eot[0:], 0xFFFFFFFF)
+	binary.LittleEndian.PutUint32(eot[4:], 0xFFFFFF00)
+	_, err = NewLoader(bytes.NewReader(eot))
+	tu.Assert(t, err != nil && strings.Contains(err.Error(), "EOT"))
+}
+
+func TestBareCFF(t *testing.T) {
+	cff := []byte{1, 0, 4, 2, 0, 0, 0, 0}
+	_, err := NewLoaders(bytes.NewReader(cff))
+	tu.Assert(t, errors.Is(err, ErrBareCFF))
+	_, err = NewLoader(bytes.NewReader(cff))
+	tu.Assert(t, errors.Is(err, ErrBareCFF))
+}
@@ -0,0 +1,732 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package opentype
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+
+	"github.com/andybalholm/brotli"
+)
+
+// WOFF2 files store all their tables in one Brotli stream,
+// with the 'glyf', 'loca' and 'hmtx' tables possibly transformed.
+// See https://www.w3.org/TR/WOFF2/
+//
+// The whole file is decompressed (and the transformed tables
+// reconstructed) when parsing, so that the resulting [Loader]s
+// read their tables from memory.
+
+const (
+	woff2HeaderSize = 48
+	// security implementation limit, for both the file
+	// and the decompressed tables
+	woff2MaxSize = 1 << 28
+)
+
+// woff2KnownTags maps the 6 bits table index to the table tag,
+// 63 meaning an explicit tag.
+var woff2KnownTags = [63]Tag{
+	MustNewTag("cmap"), MustNewTag("head"), MustNewTag("hhea"), MustNewTag("hmtx"),
+	MustNewTag("maxp"), MustNewTag("name"), MustNewTag("OS/2"), MustNewTag("post"),
+	MustNewTag("cvt "), MustNewTag("fpgm"), MustNewTag("glyf"), MustNewTag("loca"),
+	MustNewTag("prep"), MustNewTag("CFF "), MustNewTag("VORG"), MustNewTag("EBDT"),
+	MustNewTag("EBLC"), MustNewTag("gasp"), MustNewTag("hdmx"), MustNewTag("kern"),
+	MustNewTag("LTSH"), MustNewTag("PCLT"), MustNewTag("VDMX"), MustNewTag("vhea"),
+	MustNewTag("vmtx"), MustNewTag("BASE"), MustNewTag("GDEF"), MustNewTag("GPOS"),
+	MustNewTag("GSUB"), MustNewTag("EBSC"), MustNewTag("JSTF"), MustNewTag("MATH"),
+	MustNewTag("CBDT"), MustNewTag("CBLC"), MustNewTag("COLR"), MustNewTag("CPAL"),
+	MustNewTag("SVG "), MustNewTag("sbix"), MustNewTag("acnt"), MustNewTag("avar"),
+	MustNewTag("bdat"), MustNewTag("bloc"), MustNewTag("bsln"), MustNewTag("cvar"),
+	MustNewTag("fdsc"), MustNewTag("feat"), MustNewTag("fmtx"), MustNewTag("fvar"),
+	MustNewTag("gvar"), MustNewTag("hsty"), MustNewTag("just"), MustNewTag("lcar"),
+	MustNewTag("mort"), MustNewTag("morx"), MustNewTag("opbd"), MustNewTag("prop"),
+	MustNewTag("trak"), MustNewTag("Zapf"), MustNewTag("Silf"), MustNewTag("Glat"),
+	MustNewTag("Gloc"), MustNewTag("Feat"), MustNewTag("Sill"),
+}
+
+var (
+	woff2Glyf = MustNewTag("glyf")
+	woff2Loca = MustNewTag("loca")
+	woff2Hmtx = MustNewTag("hmtx")
+	woff2Hhea = MustNewTag("hhea")
+
+	errWOFF2EOF = errors.New("invalid WOFF2 font: unexpected end of data")
+)
+
+// woff2Entry is a table directory entry
+type woff2Entry struct {
+	tag         Tag
+	transformed bool
+	origLength  uint32
+	offset      uint32 // in the decompressed stream
+	length      uint32 // in the decompressed stream (transformLength if [transformed])
+}
+
+// woff2Stream is a cursor over WOFF2 data, which
+// records the first out of bounds read in [err].
+type woff2Stream struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+func (s *woff2Stream) bytes(n int) []byte {
+	if s.err != nil {
+		return nil
+	}
+	if n < 0 || len(s.data)-s.pos < n {
+		s.err = errWOFF2EOF
+		return nil
+	}
+	out := s.data[s.pos : s.pos+n]
+	s.pos += n
+	return out
+}
+
+func (s *woff2Stream) u8() uint8 {
+	if b := s.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (s *woff2Stream) u16() uint16 {
+	if b := s.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (s *woff2Stream) u32() uint32 {
+	if b := s.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// uintBase128 reads a variable length UIntBase128 number
+func (s *woff2Stream) uintBase128() uint32 {
+	var acc uint32
+	for i := 0; i < 5; i++ {
+		b := s.u8()
+		if s.err != nil {
+			return 0
+		}
+		if i == 0 && b == 0x80 { // leading zeros
+			s.err = errors.New("invalid WOFF2 font: invalid UIntBase128 value")
+			return 0
+		}
+		if acc&0xFE000000 != 0 { // overflow
+			s.err = errors.New("invalid WOFF2 font: invalid UIntBase128 value")
+			return 0
+		}
+		acc = acc<<7 | uint32(b&0x7F)
+		if b&0x80 == 0 {
+			return acc
+		}
+	}
+	s.err = errors.New("invalid WOFF2 font: invalid UIntBase128 value")
+	return 0
+}
+
+// u255 reads a variable length 255UInt16 number
+func (s *woff2Stream) u255() uint16 {
+	switch code := s.u8(); code {
+	case 253: // wordCode
+		return s.u16()
+	case 254: // oneMoreByteCode2
+		return 253*2 + uint16(s.u8())
+	case 255: // oneMoreByteCode1
+		return 253 + uint16(s.u8())
+	default:
+		return uint16(code)
+	}
+}
+
+// parseWOFF2 decompresses the WOFF2 [file], returning one
+// loader for each font (several for collections).
+func parseWOFF2(file Resource) ([]*Loader, error) {
+	size, err := file.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if size > woff2MaxSize {
+		return nil, fmt.Errorf("WOFF2 file size (%d) exceeds implementation limit (%d)", size, woff2MaxSize)
+	}
+	data := make([]byte, size)
+	if _, err = file.ReadAt(data, 0); err != nil {
+		return nil, err
+	}
+
+	s := woff2Stream{data: data}
+	s.u32() // signature
+	flavor := Tag(s.u32())
+	s.u32() // length
+	numTables := int(s.u16())
+	s.u16() // reserved
+	s.u32() // totalSfntSize
+	totalCompressedSize := s.u32()
+	s.bytes(woff2HeaderSize - s.pos) // versions, metadata and private block
+	if s.err != nil {
+		return nil, s.err
+	}
+	if numTables == 0 {
+		return nil, errors.New("invalid WOFF2 font: no tables")
+	}
+
+	entries := make([]woff2Entry, numTables)
+	var totalLength uint64
+	for i := range entries {
+		flags := s.u8()
+		entry := &entries[i]
+		if index := flags & 0x3F; index == 0x3F {
+			entry.tag = Tag(s.u32())
+		} else {
+			entry.tag = woff2KnownTags[index]
+		}
+		// 'glyf' and 'loca' use 3 for the null transform, other tables use 0
+		version := flags >> 6
+		if entry.tag == woff2Glyf || entry.tag == woff2Loca {
+			entry.transformed = version != 3
+		} else {
+			entry.transformed = version != 0
+		}
+		switch {
+		case !entry.transformed,
+			(entry.tag == woff2Glyf || entry.tag == woff2Loca) && version == 0,
+			entry.tag == woff2Hmtx && version == 1:
+		default:
+			return nil, fmt.Errorf("invalid WOFF2 font: unsupported transform %d for table %s", version, entry.tag)
+		}
+		entry.origLength = s.uintBase128()
+		entry.length = entry.origLength
+		if entry.transformed {
+			entry.length = s.uintBase128()
+			if entry.tag == woff2Loca && entry.length != 0 {
+				return nil, errors.New("invalid WOFF2 font: transformed 'loca' table with non zero length")
+			}
+		}
+		entry.offset = uint32(totalLength)
+		totalLength += uint64(entry.length)
+		if totalLength > woff2MaxSize {
+			return nil, fmt.Errorf("WOFF2 decompressed size exceeds implementation limit (%d)", woff2MaxSize)
+		}
+	}
+
+	// each font is a list of indices into [entries]
+	var (
+		fonts   [][]int
+		flavors []Tag
+	)
+	if flavor == ttcTag {
+		s.u32() // version
+		numFonts := int(s.u255())
+		if numFonts == 0 {
+			return nil, errors.New("empty font collection")
+		}
+		for i := 0; i < numFonts; i++ {
+			fontTables := make([]int, s.u255())
+			flavors = append(flavors, Tag(s.u32()))
+			for j := range fontTables {
+				fontTables[j] = int(s.u255())
+				if fontTables[j] >= numTables {
+					return nil, errors.New("invalid WOFF2 font: invalid table index in collection")
+				}
+			}
+			fonts = append(fonts, fontTables)
+		}
+	} else {
+		fontTables := make([]int, numTables)
+		for i := range fontTables {
+			fontTables[i] = i
+		}
+		fonts, flavors = [][]int{fontTables}, []Tag{flavor}
+	}
+
+	compressed := s.bytes(int(totalCompressedSize))
+	if s.err != nil {
+		return nil, s.err
+	}
+	buffer := make([]byte, totalLength)
+	if _, err = io.ReadFull(brotli.NewReader(bytes.NewReader(compressed)), buffer); err != nil {
+		return nil, fmt.Errorf("invalid WOFF2 font: %s", err)
+	}
+
+	// the reconstructed tables are appended to [buffer],
+	// and may be shared between the fonts of a collection
+	sections := make([]tableSection, numTables)
+	decoded := make([]bool, numTables)
+	xMins := map[int][]int16{} // by 'glyf' entry
+	appendTable := func(entry int, table []byte) {
+		sections[entry] = tableSection{offset: uint32(len(buffer)), length: uint32(len(table)), zLength: uint32(len(table))}
+		decoded[entry] = true
+		buffer = append(buffer, table...)
+	}
+	for i, e := range entries {
+		if !e.transformed {
+			sections[i] = tableSection{offset: e.offset, length: e.length, zLength: e.length}
+			decoded[i] = true
+		}
+	}
+
+	out := make([]*Loader, len(fonts))
+	for i, fontTables := range fonts {
+		glyf, loca, hhea, hmtx := -1, -1, -1, -1
+		for _, index := range fontTables {
+			switch entries[index].tag {
+			case woff2Glyf:
+				glyf = index
+			case woff2Loca:
+				loca = index
+			case woff2Hhea:
+				hhea = index
+			case woff2Hmtx:
+				hmtx = index
+			}
+		}
+
+		if (glyf != -1 && entries[glyf].transformed) != (loca != -1 && entries[loca].transformed) {
+			return nil, errors.New("invalid WOFF2 font: 'glyf' and 'loca' tables must be transformed together")
+		}
+		if glyf != -1 && entries[glyf].transformed && !decoded[glyf] {
+			e := entries[glyf]
+			glyfTable, locaTable, mins, err := reconstructWOFF2Glyf(buffer[e.offset : e.offset+e.length])
+			if err != nil {
+				return nil, err
+			}
+			if len(locaTable) != int(entries[loca].origLength) {
+				return nil, errors.New("invalid WOFF2 font: invalid 'loca' table length")
+			}
+			appendTable(glyf, glyfTable)
+			appendTable(loca, locaTable)
+			xMins[glyf] = mins
+		}
+
+		if hmtx != -1 && entries[hmtx].transformed && !decoded[hmtx] {
+			mins, ok := xMins[glyf]
+			if glyf == -1 || !ok || hhea == -1 {
+				return nil, errors.New("invalid WOFF2 font: transformed 'hmtx' table without transformed 'glyf' or 'hhea' table")
+			}
+			sec := sections[hhea]
+			hheaTable := buffer[sec.offset : sec.offset+sec.length]
+			if len(hheaTable) < 36 {
+				return nil, errors.New("invalid WOFF2 font: invalid 'hhea' table")
+			}
+			numHMetrics := int(binary.BigEndian.Uint16(hheaTable[34:]))
+			e := entries[hmtx]
+			hmtxTable, err := reconstructWOFF2Hmtx(buffer[e.offset:e.offset+e.length], numHMetrics, mins)
+			if err != nil {
+				return nil, err
+			}
+			appendTable(hmtx, hmtxTable)
+		}
+
+		if len(buffer) > math.MaxUint32 {
+			return nil, errors.New("invalid WOFF2 font: decompressed tables too large")
+		}
+
+		ld := &Loader{tables: make(map[Tag]tableSection, len(fontTables)), Type: flavors[i]}
+		for _, index := range fontTables {
+			if !decoded[index] {
+				return nil, fmt.Errorf("invalid WOFF2 font: unsupported transformed table %s", entries[index].tag)
+			}
+			if _, found := ld.tables[entries[index].tag]; found {
+				// ignore duplicate tables – the first one wins
+				continue
+			}
+			ld.tables[entries[index].tag] = sections[index]
+		}
+		out[i] = ld
+	}
+
+	tables := bytes.NewReader(buffer)
+	for _, ld := range out {
+		ld.file = tables
+	}
+	return out, nil
+}
+
+// flags used in the 'glyf' table
+const (
+	glyfOnCurve       = 0x01
+	glyfXShort        = 0x02
+	glyfYShort        = 0x04
+	glyfRepeat        = 0x08
+	glyfXSame         = 0x10 // or positive short X
+	glyfYSame         = 0x20 // or positive short Y
+	glyfOverlapSimple = 0x40
+
+	compositeArgsAreWords    = 0x0001
+	compositeHaveScale       = 0x0008
+	compositeMoreComponents  = 0x0020
+	compositeHaveXYScale     = 0x0040
+	compositeHaveTwoByTwo    = 0x0080
+	compositeHaveInstruction = 0x0100
+)
+
+// reconstructWOFF2Glyf reverses the 'glyf' transform, returning the 'glyf' and 'loca' tables,
+// and the xMin of each glyph (used by the 'hmtx' transform).
+func reconstructWOFF2Glyf(data []byte) (glyf, loca []byte, xMins []int16, err error) {
+	s := woff2Stream{data: data}
+	s.u16() // reserved
+	optionFlags := s.u16()
+	numGlyphs := int(s.u16())
+	indexFormat := s.u16()
+	var streams [7]woff2Stream // nContour, nPoints, flag, glyph, composite, bbox, instruction
+	offset := 36
+	for i := range streams {
+		size := int(s.u32())
+		if s.err != nil || size > len(data)-offset {
+			return nil, nil, nil, errors.New("invalid WOFF2 font: invalid transformed 'glyf' table")
+		}
+		streams[i].data = data[offset : offset+size]
+		offset += size
+	}
+	nContourStream, nPointsStream, flagStream, glyphStream := &streams[0], &streams[1], &streams[2], &streams[3]
+	compositeStream, bboxStream, instructionStream := &streams[4], &streams[5], &streams[6]
+
+	bboxBitmap := bboxStream.bytes(4 * ((numGlyphs + 31) / 32))
+	if bboxStream.err != nil {
+		return nil, nil, nil, bboxStream.err
+	}
+	var overlapBitmap []byte
+	if optionFlags&1 != 0 {
+		if (numGlyphs+7)/8 > len(data)-offset {
+			return nil, nil, nil, errors.New("invalid WOFF2 font: invalid transformed 'glyf' table")
+		}
+		overlapBitmap = data[offset : offset+(numGlyphs+7)/8]
+	}
+
+	offsets := make([]int, numGlyphs+1)
+	xMins = make([]int16, numGlyphs)
+	var (
+		endPts []uint16
+		points []woff2Point
+	)
+	for gid := 0; gid < numGlyphs; gid++ {
+		offsets[gid] = len(glyf)
+		nContours := int16(nContourStream.u16())
+		hasBbox := bboxBitmap[gid>>3]&(0x80>>(gid&7)) != 0
+		switch {
+		case nContours == 0: // empty glyph
+			if hasBbox {
+				return nil, nil, nil, fmt.Errorf("invalid WOFF2 font: empty glyph %d with bounding box", gid)
+			}
+		case nContours == -1: // composite glyph
+			if !hasBbox {
+				return nil, nil, nil, fmt.Errorf("invalid WOFF2 font: composite glyph %d without bounding box", gid)
+			}
+			bbox := bboxStream.bytes(8)
+			components, haveInstructions := readWOFF2Composite(compositeStream)
+			if bbox == nil || compositeStream.err != nil {
+				return nil, nil, nil, errWOFF2EOF
+			}
+			glyf = append(glyf, 0xFF, 0xFF)
+			glyf = append(glyf, bbox...)
+			glyf = append(glyf, components...)
+			if haveInstructions {
+				length := glyphStream.u255()
+				glyf = binary.BigEndian.AppendUint16(glyf, length)
+				glyf = append(glyf, instructionStream.bytes(int(length))...)
+			}
+			xMins[gid] = int16(binary.BigEndian.Uint16(bbox))
+		case nContours > 0: // simple glyph
+			endPts = endPts[:0]
+			numPoints := 0
+			for c := 0; c < int(nContours); c++ {
+				numPoints += int(nPointsStream.u255())
+				if numPoints > math.MaxUint16+1 {
+					return nil, nil, nil, fmt.Errorf("invalid WOFF2 font: too many points in glyph %d", gid)
+				}
+				endPts = append(endPts, uint16(numPoints-1))
+			}
+			points = decodeWOFF2Triplets(flagStream.bytes(numPoints), glyphStream, points[:0])
+			instructionsLength := glyphStream.u255()
+			instructions := instructionStream.bytes(int(instructionsLength))
+
+			var bbox [4]int16
+			if hasBbox {
+				b := bboxStream.bytes(8)
+				if b == nil {
+					return nil, nil, nil, errWOFF2EOF
+				}
+				for i := range bbox {
+					bbox[i] = int16(binary.BigEndian.Uint16(b[2*i:]))
+				}
+			} else if len(points) != 0 {
+				bbox = [4]int16{points[0].x, points[0].y, points[0].x, points[0].y}
+				for _, p := range points {
+					bbox[0], bbox[1] = min16(bbox[0], p.x), min16(bbox[1], p.y)
+					bbox[2], bbox[3] = max16(bbox[2], p.x), max16(bbox[3], p.y)
+				}
+			}
+			overlap := overlapBitmap != nil && overlapBitmap[gid>>3]&(0x80>>(gid&7)) != 0
+			glyf = appendSimpleGlyph(glyf, bbox, endPts, instructions, points, overlap)
+			xMins[gid] = bbox[0]
+		default:
+			return nil, nil, nil, fmt.Errorf("invalid WOFF2 font: invalid number of contours for glyph %d", gid)
+		}
+
+		for _, stream := range streams {
+			if stream.err != nil {
+				return nil, nil, nil, stream.err
+			}
+		}
+		// align glyph data
+		for len(glyf)%4 != 0 {
+			glyf = append(glyf, 0)
+		}
+	}
+	offsets[numGlyphs] = len(glyf)
+
+	switch indexFormat {
+	case 0:
+		if len(glyf)/2 > math.MaxUint16 {
+			return nil, nil, nil, errors.New("invalid WOFF2 font: 'glyf' table too large for short 'loca' format")
+		}
+		loca = make([]byte, 0, 2*len(offsets))
+		for _, o := range offsets {
+			loca = binary.BigEndian.AppendUint16(loca, uint16(o/2))
+		}
+	case 1:
+		loca = make([]byte, 0, 4*len(offsets))
+		for _, o := range offsets {
+			loca = binary.BigEndian.AppendUint32(loca, uint32(o))
+		}
+	default:
+		return nil, nil, nil, fmt.Errorf("invalid WOFF2 font: invalid 'loca' format %d", indexFormat)
+	}
+
+	return glyf, loca, xMins, nil
+}
+
+// readWOFF2Composite returns the components of one composite glyph
+func readWOFF2Composite(s *woff2Stream) (components []byte, haveInstructions bool) {
+	start := s.pos
+	for {
+		flags := s.u16()
+		haveInstructions = haveInstructions || flags&compositeHaveInstruction != 0
+		size := 2 + 2 // glyph index and arguments
+		if flags&compositeArgsAreWords != 0 {
+			size += 2
+		}
+		switch {
+		case flags&compositeHaveScale != 0:
+			size += 2
+		case flags&compositeHaveXYScale != 0:
+			size += 4
+		case flags&compositeHaveTwoByTwo != 0:
+			size += 8
+		}
+		s.bytes(size)
+		if s.err != nil {
+			return nil, false
+		}
+		if flags&compositeMoreComponents == 0 {
+			return s.data[start:s.pos], haveInstructions
+		}
+	}
+}
+
+type woff2Point struct {
+	x, y    int16
+	onCurve bool
+}
+
+// decodeWOFF2Triplets decodes the points of a simple glyph, using one
+// flag and a variable number of bytes from [glyphStream] for each point.
+func decodeWOFF2Triplets(flags []byte, glyphStream *woff2Stream, dst []woff2Point) []woff2Point {
+	withSign := func(flag byte, v int) int {
+		if flag&1 != 0 {
+			return v
+		}
+		return -v
+	}
+	var x, y int
+	for _, flag := range flags {
+		onCurve := flag>>7 == 0
+		flag &= 0x7F
+		var dx, dy int
+		switch {
+		case flag < 10:
+			b := glyphStream.bytes(1)
+			if b == nil {
+				return dst
+			}
+			dy = withSign(flag, int(flag&14)<<7+int(b[0]))
+		case flag < 20:
+			b := glyphStream.bytes(1)
+			if b == nil {
+				return dst
+			}
+			dx = withSign(flag, int((flag-10)&14)<<7+int(b[0]))
+		case flag < 84:
+			b := glyphStream.bytes(1)
+			if b == nil {
+				return dst
+			}
+			b0 := int(flag - 20)
+			dx = withSign(flag, 1+(b0&0x30)+int(b[0]>>4))
+			dy = withSign(flag>>1, 1+(b0&0x0c)<<2+int(b[0]&0x0f))
+		case flag < 120:
+			b := glyphStream.bytes(2)
+			if b == nil {
+				return dst
+			}
+			b0 := int(flag - 84)
+			dx = withSign(flag, 1+(b0/12)<<8+int(b[0]))
+			dy = withSign(flag>>1, 1+((b0%12)>>2)<<8+int(b[1]))
+		case flag < 124:
+			b := glyphStream.bytes(3)
+			if b == nil {
+				return dst
+			}
+			dx = withSign(flag, int(b[0])<<4+int(b[1]>>4))
+			dy = withSign(flag>>1, int(b[1]&0x0f)<<8+int(b[2]))
+		default:
+			b := glyphStream.bytes(4)
+			if b == nil {
+				return dst
+			}
+			dx = withSign(flag, int(b[0])<<8+int(b[1]))
+			dy = withSign(flag>>1, int(b[2])<<8+int(b[3]))
+		}
+		x, y = x+dx, y+dy
+		dst = append(dst, woff2Point{x: int16(x), y: int16(y), onCurve: onCurve})
+	}
+	return dst
+}
+
+// appendSimpleGlyph encodes a simple glyph in the 'glyf' format,
+// using short coordinates and repeated flags when possible
+func appendSimpleGlyph(dst []byte, bbox [4]int16, endPts []uint16, instructions []byte, points []woff2Point, overlap bool) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(endPts)))
+	for _, v := range bbox {
+		dst = binary.BigEndian.AppendUint16(dst, uint16(v))
+	}
+	for _, v := range endPts {
+		dst = binary.BigEndian.AppendUint16(dst, v)
+	}
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(instructions)))
+	dst = append(dst, instructions...)
+
+	flags := make([]byte, len(points))
+	var xs, ys []byte
+	var lastX, lastY int16
+	for i, p := range points {
+		var flag byte
+		if p.onCurve {
+			flag |= glyfOnCurve
+		}
+		if i == 0 && overlap {
+			flag |= glyfOverlapSimple
+		}
+		dx, dy := int(p.x)-int(lastX), int(p.y)-int(lastY)
+		lastX, lastY = p.x, p.y
+		switch {
+		case dx == 0:
+			flag |= glyfXSame
+		case -0xFF <= dx && dx <= 0xFF:
+			flag |= glyfXShort
+			if dx > 0 {
+				flag |= glyfXSame
+			} else {
+				dx = -dx
+			}
+			xs = append(xs, byte(dx))
+		default:
+			xs = binary.BigEndian.AppendUint16(xs, uint16(dx))
+		}
+		switch {
+		case dy == 0:
+			flag |= glyfYSame
+		case -0xFF <= dy && dy <= 0xFF:
+			flag |= glyfYShort
+			if dy > 0 {
+				flag |= glyfYSame
+			} else {
+				dy = -dy
+			}
+			ys = append(ys, byte(dy))
+		default:
+			ys = binary.BigEndian.AppendUint16(ys, uint16(dy))
+		}
+		flags[i] = flag
+	}
+
+	for i := 0; i < len(flags); {
+		flag, j := flags[i], i+1
+		for j < len(flags) && flags[j] == flag && j-i <= 0xFF {
+			j++
+		}
+		if j-i > 1 {
+			dst = append(dst, flag|glyfRepeat, byte(j-i-1))
+		} else {
+			dst = append(dst, flag)
+		}
+		i = j
+	}
+	dst = append(dst, xs...)
+	dst = append(dst, ys...)
+	return dst
+}
+
+// reconstructWOFF2Hmtx reverses the 'hmtx' transform, which
+// may omit the left side bearings equal to the glyph xMin.
+func reconstructWOFF2Hmtx(data []byte, numHMetrics int, xMins []int16) ([]byte, error) {
+	s := woff2Stream{data: data}
+	flags := s.u8()
+	numGlyphs := len(xMins)
+	if flags&0x03 == 0 || flags&0xFC != 0 || numHMetrics < 1 || numHMetrics > numGlyphs {
+		return nil, errors.New("invalid WOFF2 font: invalid transformed 'hmtx' table")
+	}
+	advances := s.bytes(2 * numHMetrics)
+	var lsbs, monoLsbs []byte
+	if flags&0x01 == 0 {
+		lsbs = s.bytes(2 * numHMetrics)
+	}
+	if flags&0x02 == 0 {
+		monoLsbs = s.bytes(2 * (numGlyphs - numHMetrics))
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	out := make([]byte, 0, 4*numHMetrics+2*(numGlyphs-numHMetrics))
+	for i := 0; i < numHMetrics; i++ {
+		out = append(out, advances[2*i:2*i+2]...)
+		if lsbs != nil {
+			out = append(out, lsbs[2*i:2*i+2]...)
+		} else {
+			out = binary.BigEndian.AppendUint16(out, uint16(xMins[i]))
+		}
+	}
+	for i := numHMetrics; i < numGlyphs; i++ {
+		if monoLsbs != nil {
+			j := i - numHMetrics
+			out = append(out, monoLsbs[2*j:2*j+2]...)
+		} else {
+			out = binary.BigEndian.AppendUint16(out, uint16(xMins[i]))
+		}
+	}
+	return out, nil
+}
+
+func min16(a, b int16) int16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max16(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+	return b
+}
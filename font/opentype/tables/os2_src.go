@@ -35,6 +35,9 @@ type Os2 struct {
 	HigherVersionData   []byte `arrayCount:"ToEnd"`
 }
 
+// FsType returns the embedding licensing rights of the font.
+func (os *Os2) FsType() uint16 { return os.fSType }
+
 func (os *Os2) FontPage() FontPage {
 	if os.Version == 0 {
 		return FontPage(os.FsSelection & 0xFF00)
@@ -0,0 +1,328 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package tables
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// Code generated by binarygen from ot_jstf_src.go. DO NOT EDIT
+
+func ParseExtenderGlyph(src []byte) (ExtenderGlyph, int, error) {
+	var item ExtenderGlyph
+	n := 0
+	if L := len(src); L < 2 {
+		return item, 0, fmt.Errorf("reading ExtenderGlyph: "+"EOF: expected length: 2, got %d", L)
+	}
+	arrayLengthExtenderGlyphs := int(binary.BigEndian.Uint16(src[0:]))
+	n += 2
+
+	{
+
+		if L := len(src); L < 2+arrayLengthExtenderGlyphs*2 {
+			return item, 0, fmt.Errorf("reading ExtenderGlyph: "+"EOF: expected length: %d, got %d", 2+arrayLengthExtenderGlyphs*2, L)
+		}
+
+		item.ExtenderGlyphs = make([]GlyphID, arrayLengthExtenderGlyphs) // allocation guarded by the previous check
+		for i := range item.ExtenderGlyphs {
+			item.ExtenderGlyphs[i] = binary.BigEndian.Uint16(src[2+i*2:])
+		}
+		n += arrayLengthExtenderGlyphs * 2
+	}
+	return item, n, nil
+}
+
+func ParseJSTF(src []byte) (JSTF, int, error) {
+	var item JSTF
+	n := 0
+	if L := len(src); L < 6 {
+		return item, 0, fmt.Errorf("reading JSTF: "+"EOF: expected length: 6, got %d", L)
+	}
+	_ = src[5] // early bound checking
+	item.majorVersion = binary.BigEndian.Uint16(src[0:])
+	item.minorVersion = binary.BigEndian.Uint16(src[2:])
+	arrayLengthRecords := int(binary.BigEndian.Uint16(src[4:]))
+	n += 6
+
+	{
+
+		if L := len(src); L < 6+arrayLengthRecords*6 {
+			return item, 0, fmt.Errorf("reading JSTF: "+"EOF: expected length: %d, got %d", 6+arrayLengthRecords*6, L)
+		}
+
+		item.Records = make([]TagOffsetRecord, arrayLengthRecords) // allocation guarded by the previous check
+		for i := range item.Records {
+			item.Records[i].mustParse(src[6+i*6:])
+		}
+		n += arrayLengthRecords * 6
+	}
+	{
+
+		err := item.parseScripts(src[:])
+		if err != nil {
+			return item, 0, fmt.Errorf("reading JSTF: %s", err)
+		}
+	}
+	return item, n, nil
+}
+
+func ParseJstfLangSys(src []byte) (JstfLangSys, int, error) {
+	var item JstfLangSys
+	n := 0
+	if L := len(src); L < 2 {
+		return item, 0, fmt.Errorf("reading JstfLangSys: "+"EOF: expected length: 2, got %d", L)
+	}
+	arrayLengthJstfPriorities := int(binary.BigEndian.Uint16(src[0:]))
+	n += 2
+
+	{
+
+		if L := len(src); L < 2+arrayLengthJstfPriorities*2 {
+			return item, 0, fmt.Errorf("reading JstfLangSys: "+"EOF: expected length: %d, got %d", 2+arrayLengthJstfPriorities*2, L)
+		}
+
+		item.JstfPriorities = make([]JstfPriority, arrayLengthJstfPriorities) // allocation guarded by the previous check
+		for i := range item.JstfPriorities {
+			offset := int(binary.BigEndian.Uint16(src[2+i*2:]))
+			// ignore null offsets
+			if offset == 0 {
+				continue
+			}
+
+			if L := len(src); L < offset {
+				return item, 0, fmt.Errorf("reading JstfLangSys: "+"EOF: expected length: %d, got %d", offset, L)
+			}
+
+			var err error
+			item.JstfPriorities[i], _, err = ParseJstfPriority(src[offset:])
+			if err != nil {
+				return item, 0, fmt.Errorf("reading JstfLangSys: %s", err)
+			}
+		}
+		n += arrayLengthJstfPriorities * 2
+	}
+	return item, n, nil
+}
+
+func ParseJstfModList(src []byte) (JstfModList, int, error) {
+	var item JstfModList
+	n := 0
+	if L := len(src); L < 2 {
+		return item, 0, fmt.Errorf("reading JstfModList: "+"EOF: expected length: 2, got %d", L)
+	}
+	arrayLengthLookupIndices := int(binary.BigEndian.Uint16(src[0:]))
+	n += 2
+
+	{
+
+		if L := len(src); L < 2+arrayLengthLookupIndices*2 {
+			return item, 0, fmt.Errorf("reading JstfModList: "+"EOF: expected length: %d, got %d", 2+arrayLengthLookupIndices*2, L)
+		}
+
+		item.LookupIndices = make([]uint16, arrayLengthLookupIndices) // allocation guarded by the previous check
+		for i := range item.LookupIndices {
+			item.LookupIndices[i] = binary.BigEndian.Uint16(src[2+i*2:])
+		}
+		n += arrayLengthLookupIndices * 2
+	}
+	return item, n, nil
+}
+
+func ParseJstfPriority(src []byte) (JstfPriority, int, error) {
+	var item JstfPriority
+	n := 0
+	if L := len(src); L < 20 {
+		return item, 0, fmt.Errorf("reading JstfPriority: "+"EOF: expected length: 20, got %d", L)
+	}
+	_ = src[19] // early bound checking
+	offsetShrinkageEnableGSUB := int(binary.BigEndian.Uint16(src[0:]))
+	offsetShrinkageDisableGSUB := int(binary.BigEndian.Uint16(src[2:]))
+	offsetShrinkageEnableGPOS := int(binary.BigEndian.Uint16(src[4:]))
+	offsetShrinkageDisableGPOS := int(binary.BigEndian.Uint16(src[6:]))
+	item.shrinkageJstfMax = binary.BigEndian.Uint16(src[8:])
+	offsetExtensionEnableGSUB := int(binary.BigEndian.Uint16(src[10:]))
+	offsetExtensionDisableGSUB := int(binary.BigEndian.Uint16(src[12:]))
+	offsetExtensionEnableGPOS := int(binary.BigEndian.Uint16(src[14:]))
+	offsetExtensionDisableGPOS := int(binary.BigEndian.Uint16(src[16:]))
+	item.extensionJstfMax = binary.BigEndian.Uint16(src[18:])
+	n += 20
+
+	{
+		if offsetShrinkageEnableGSUB != 0 { // ignore null offset
+			if L := len(src); L < offsetShrinkageEnableGSUB {
+				return item, 0, fmt.Errorf("reading JstfPriority: "+"EOF: expected length: %d, got %d", offsetShrinkageEnableGSUB, L)
+			}
+
+			var err error
+			item.ShrinkageEnableGSUB, _, err = ParseJstfModList(src[offsetShrinkageEnableGSUB:])
+			if err != nil {
+				return item, 0, fmt.Errorf("reading JstfPriority: %s", err)
+			}
+
+		}
+	}
+	{
+		if offsetShrinkageDisableGSUB != 0 { // ignore null offset
+			if L := len(src); L < offsetShrinkageDisableGSUB {
+				return item, 0, fmt.Errorf("reading JstfPriority: "+"EOF: expected length: %d, got %d", offsetShrinkageDisableGSUB, L)
+			}
+
+			var err error
+			item.ShrinkageDisableGSUB, _, err = ParseJstfModList(src[offsetShrinkageDisableGSUB:])
+			if err != nil {
+				return item, 0, fmt.Errorf("reading JstfPriority: %s", err)
+			}
+
+		}
+	}
+	{
+		if offsetShrinkageEnableGPOS != 0 { // ignore null offset
+			if L := len(src); L < offsetShrinkageEnableGPOS {
+				return item, 0, fmt.Errorf("reading JstfPriority: "+"EOF: expected length: %d, got %d", offsetShrinkageEnableGPOS, L)
+			}
+
+			var err error
+			item.ShrinkageEnableGPOS, _, err = ParseJstfModList(src[offsetShrinkageEnableGPOS:])
+			if err != nil {
+				return item, 0, fmt.Errorf("reading JstfPriority: %s", err)
+			}
+
+		}
+	}
+	{
+		if offsetShrinkageDisableGPOS != 0 { // ignore null offset
+			if L := len(src); L < offsetShrinkageDisableGPOS {
+				return item, 0, fmt.Errorf("reading JstfPriority: "+"EOF: expected length: %d, got %d", offsetShrinkageDisableGPOS, L)
+			}
+
+			var err error
+			item.ShrinkageDisableGPOS, _, err = ParseJstfModList(src[offsetShrinkageDisableGPOS:])
+			if err != nil {
+				return item, 0, fmt.Errorf("reading JstfPriority: %s", err)
+			}
+
+		}
+	}
+	{
+		if offsetExtensionEnableGSUB != 0 { // ignore null offset
+			if L := len(src); L < offsetExtensionEnableGSUB {
+				return item, 0, fmt.Errorf("reading JstfPriority: "+"EOF: expected length: %d, got %d", offsetExtensionEnableGSUB, L)
+			}
+
+			var err error
+			item.ExtensionEnableGSUB, _, err = ParseJstfModList(src[offsetExtensionEnableGSUB:])
+			if err != nil {
+				return item, 0, fmt.Errorf("reading JstfPriority: %s", err)
+			}
+
+		}
+	}
+	{
+		if offsetExtensionDisableGSUB != 0 { // ignore null offset
+			if L := len(src); L < offsetExtensionDisableGSUB {
+				return item, 0, fmt.Errorf("reading JstfPriority: "+"EOF: expected length: %d, got %d", offsetExtensionDisableGSUB, L)
+			}
+
+			var err error
+			item.ExtensionDisableGSUB, _, err = ParseJstfModList(src[offsetExtensionDisableGSUB:])
+			if err != nil {
+				return item, 0, fmt.Errorf("reading JstfPriority: %s", err)
+			}
+
+		}
+	}
+	{
+		if offsetExtensionEnableGPOS != 0 { // ignore null offset
+			if L := len(src); L < offsetExtensionEnableGPOS {
+				return item, 0, fmt.Errorf("reading JstfPriority: "+"EOF: expected length: %d, got %d", offsetExtensionEnableGPOS, L)
+			}
+
+			var err error
+			item.ExtensionEnableGPOS, _, err = ParseJstfModList(src[offsetExtensionEnableGPOS:])
+			if err != nil {
+				return item, 0, fmt.Errorf("reading JstfPriority: %s", err)
+			}
+
+		}
+	}
+	{
+		if offsetExtensionDisableGPOS != 0 { // ignore null offset
+			if L := len(src); L < offsetExtensionDisableGPOS {
+				return item, 0, fmt.Errorf("reading JstfPriority: "+"EOF: expected length: %d, got %d", offsetExtensionDisableGPOS, L)
+			}
+
+			var err error
+			item.ExtensionDisableGPOS, _, err = ParseJstfModList(src[offsetExtensionDisableGPOS:])
+			if err != nil {
+				return item, 0, fmt.Errorf("reading JstfPriority: %s", err)
+			}
+
+		}
+	}
+	return item, n, nil
+}
+
+func ParseJstfScript(src []byte) (JstfScript, int, error) {
+	var item JstfScript
+	n := 0
+	if L := len(src); L < 6 {
+		return item, 0, fmt.Errorf("reading JstfScript: "+"EOF: expected length: 6, got %d", L)
+	}
+	_ = src[5] // early bound checking
+	offsetExtenderGlyph := int(binary.BigEndian.Uint16(src[0:]))
+	offsetDefJstfLangSys := int(binary.BigEndian.Uint16(src[2:]))
+	arrayLengthLangSysRecords := int(binary.BigEndian.Uint16(src[4:]))
+	n += 6
+
+	{
+		if offsetExtenderGlyph != 0 { // ignore null offset
+			if L := len(src); L < offsetExtenderGlyph {
+				return item, 0, fmt.Errorf("reading JstfScript: "+"EOF: expected length: %d, got %d", offsetExtenderGlyph, L)
+			}
+
+			var err error
+			item.ExtenderGlyph, _, err = ParseExtenderGlyph(src[offsetExtenderGlyph:])
+			if err != nil {
+				return item, 0, fmt.Errorf("reading JstfScript: %s", err)
+			}
+
+		}
+	}
+	{
+		if offsetDefJstfLangSys != 0 { // ignore null offset
+			if L := len(src); L < offsetDefJstfLangSys {
+				return item, 0, fmt.Errorf("reading JstfScript: "+"EOF: expected length: %d, got %d", offsetDefJstfLangSys, L)
+			}
+
+			var tmpDefJstfLangSys JstfLangSys
+			var err error
+			tmpDefJstfLangSys, _, err = ParseJstfLangSys(src[offsetDefJstfLangSys:])
+			if err != nil {
+				return item, 0, fmt.Errorf("reading JstfScript: %s", err)
+			}
+
+			item.DefJstfLangSys = &tmpDefJstfLangSys
+		}
+	}
+	{
+
+		if L := len(src); L < 6+arrayLengthLangSysRecords*6 {
+			return item, 0, fmt.Errorf("reading JstfScript: "+"EOF: expected length: %d, got %d", 6+arrayLengthLangSysRecords*6, L)
+		}
+
+		item.LangSysRecords = make([]TagOffsetRecord, arrayLengthLangSysRecords) // allocation guarded by the previous check
+		for i := range item.LangSysRecords {
+			item.LangSysRecords[i].mustParse(src[6+i*6:])
+		}
+		n += arrayLengthLangSysRecords * 6
+	}
+	{
+
+		err := item.parseLangSys(src[:])
+		if err != nil {
+			return item, 0, fmt.Errorf("reading JstfScript: %s", err)
+		}
+	}
+	return item, n, nil
+}
@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package tables
+
+import "fmt"
+
+// JSTF is the justification table.
+// See https://learn.microsoft.com/typography/opentype/spec/jstf
+type JSTF struct {
+	majorVersion uint16            // Major version of the JSTF table, = 1
+	minorVersion uint16            // Minor version of the JSTF table, = 0
+	Records      []TagOffsetRecord `arrayCount:"FirstUint16"` // [jstfScriptCount] Array of JstfScriptRecords, in alphabetical order by jstfScriptTag
+	Scripts      []JstfScript      `isOpaque:""`              // same length as Records
+}
+
+func (jt *JSTF) parseScripts(src []byte) error {
+	jt.Scripts = make([]JstfScript, len(jt.Records))
+	for i, rec := range jt.Records {
+		var err error
+		if L := len(src); L < int(rec.Offset) {
+			return fmt.Errorf("EOF: expected length: %d, got %d", rec.Offset, L)
+		}
+		jt.Scripts[i], _, err = ParseJstfScript(src[rec.Offset:])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Script returns the justification data for the given script tag,
+// or false if the table does not support it.
+func (jt JSTF) Script(tag Tag) (JstfScript, bool) {
+	for i, rec := range jt.Records {
+		if rec.Tag == tag {
+			return jt.Scripts[i], true
+		}
+	}
+	return JstfScript{}, false
+}
+
+type JstfScript struct {
+	ExtenderGlyph  ExtenderGlyph     `offsetSize:"Offset16"`    // Offset to ExtenderGlyph table, from beginning of JstfScript table (may be NULL)
+	DefJstfLangSys *JstfLangSys      `offsetSize:"Offset16"`    // Offset to default JstfLangSys table, from beginning of JstfScript table (may be NULL)
+	LangSysRecords []TagOffsetRecord `arrayCount:"FirstUint16"` // [jstfLangSysCount] Array of JstfLangSysRecords, in alphabetical order by JstfLangSysTag
+	LangSys        []JstfLangSys     `isOpaque:""`              // same length as LangSysRecords
+}
+
+func (js *JstfScript) parseLangSys(src []byte) error {
+	js.LangSys = make([]JstfLangSys, len(js.LangSysRecords))
+	for i, rec := range js.LangSysRecords {
+		var err error
+		if L := len(src); L < int(rec.Offset) {
+			return fmt.Errorf("EOF: expected length: %d, got %d", rec.Offset, L)
+		}
+		js.LangSys[i], _, err = ParseJstfLangSys(src[rec.Offset:])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExtenderGlyph lists the glyphs which may be inserted
+// to extend the inter-glyph distance, like the Arabic kashida.
+type ExtenderGlyph struct {
+	ExtenderGlyphs []GlyphID `arrayCount:"FirstUint16"` // [glyphCount] Extender glyph IDs — in increasing numerical order
+}
+
+type JstfLangSys struct {
+	JstfPriorities []JstfPriority `arrayCount:"FirstUint16" offsetsArray:"Offset16"` // [jstfPriorityCount] Array of offsets to JstfPriority tables, from beginning of JstfLangSys table, in priority order
+}
+
+// JstfPriority lists the GSUB and GPOS lookups to enable or disable
+// when shrinking or extending a line, at one priority level.
+// The JstfMax tables, which embed their own GPOS lookups, are not supported.
+type JstfPriority struct {
+	ShrinkageEnableGSUB  JstfModList `offsetSize:"Offset16"` // Offset to shrinkage-enable JstfGSUBModList table, from beginning of JstfPriority table (may be NULL)
+	ShrinkageDisableGSUB JstfModList `offsetSize:"Offset16"` // Offset to shrinkage-disable JstfGSUBModList table, from beginning of JstfPriority table (may be NULL)
+	ShrinkageEnableGPOS  JstfModList `offsetSize:"Offset16"` // Offset to shrinkage-enable JstfGPOSModList table, from beginning of JstfPriority table (may be NULL)
+	ShrinkageDisableGPOS JstfModList `offsetSize:"Offset16"` // Offset to shrinkage-disable JstfGPOSModList table, from beginning of JstfPriority table (may be NULL)
+	shrinkageJstfMax     uint16      // Offset to shrinkage JstfMax table, from beginning of JstfPriority table (may be NULL)
+	ExtensionEnableGSUB  JstfModList `offsetSize:"Offset16"` // Offset to extension-enable JstfGSUBModList table, from beginning of JstfPriority table (may be NULL)
+	ExtensionDisableGSUB JstfModList `offsetSize:"Offset16"` // Offset to extension-disable JstfGSUBModList table, from beginning of JstfPriority table (may be NULL)
+	ExtensionEnableGPOS  JstfModList `offsetSize:"Offset16"` // Offset to extension-enable JstfGPOSModList table, from beginning of JstfPriority table (may be NULL)
+	ExtensionDisableGPOS JstfModList `offsetSize:"Offset16"` // Offset to extension-disable JstfGPOSModList table, from beginning of JstfPriority table (may be NULL)
+	extensionJstfMax     uint16      // Offset to extension JstfMax table, from beginning of JstfPriority table (may be NULL)
+}
+
+// JstfModList is either a JstfGSUBModList or a JstfGPOSModList
+type JstfModList struct {
+	LookupIndices []uint16 `arrayCount:"FirstUint16"` // [lookupCount] Array of indices into the GSUB or GPOS LookupList, in increasing numerical order
+}
@@ -8,6 +8,7 @@ import (
 	"testing"
 
 	td "github.com/go-text/typesetting-utils/opentype"
+	ot "github.com/go-text/typesetting/font/opentype"
 	tu "github.com/go-text/typesetting/testutils"
 )
 
@@ -398,3 +399,36 @@ func TestGPOS2_1(t *testing.T) {
 	tu.Assert(t, reflect.DeepEqual(v1, v1g))
 	tu.Assert(t, reflect.DeepEqual(v2, v2g))
 }
+
+func TestParseJSTF(t *testing.T) {
+	src := []byte{
+		0, 1, 0, 0, // version
+		0, 1, 'a', 'r', 'a', 'b', 0, 12, // one script record
+		// JstfScript
+		0, 6, // ExtenderGlyph offset
+		0, 12, // DefJstfLangSys offset
+		0, 0, // no JstfLangSysRecord
+		0, 2, 0, 5, 0, 7, // ExtenderGlyph
+		0, 1, 0, 4, // JstfLangSys : one priority
+		// JstfPriority
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 20, 0, 0, 0, 0, 0, 0, 0, 0,
+		0, 2, 0, 3, 0, 4, // JstfModList
+	}
+	jstf, _, err := ParseJSTF(src)
+	tu.AssertNoErr(t, err)
+
+	script, ok := jstf.Script(ot.MustNewTag("arab"))
+	tu.Assert(t, ok)
+	tu.Assert(t, reflect.DeepEqual(script.ExtenderGlyph.ExtenderGlyphs, []GlyphID{5, 7}))
+	tu.Assert(t, script.DefJstfLangSys != nil && len(script.DefJstfLangSys.JstfPriorities) == 1)
+	priority := script.DefJstfLangSys.JstfPriorities[0]
+	tu.Assert(t, reflect.DeepEqual(priority.ExtensionEnableGSUB.LookupIndices, []uint16{3, 4}))
+	tu.Assert(t, priority.ShrinkageEnableGSUB.LookupIndices == nil)
+
+	_, ok = jstf.Script(ot.MustNewTag("latn"))
+	tu.Assert(t, !ok)
+
+	_, _, err = ParseJSTF(src[:30])
+	tu.Assert(t, err != nil)
+}
@@ -13,11 +13,17 @@ type Table struct {
 
 // WriteTTF creates a single Truetype font file (.ttf) from the given [tables] slice,
 // which must be sorted by Tag
-func WriteTTF(tables []Table) []byte {
+func WriteTTF(tables []Table) []byte { return writeFont(TrueType, tables) }
+
+// WriteOTF is the same as [WriteTTF], but marks the font
+// as containing CFF outlines (.otf).
+func WriteOTF(tables []Table) []byte { return writeFont(OpenType, tables) }
+
+func writeFont(flavor Tag, tables []Table) []byte {
 	introLength := uint32(otfHeaderSize + len(tables)*otfEntrySize)
 	buffer := make([]byte, introLength)
 
-	writeTTFHeader(len(tables), buffer)
+	writeTTFHeader(flavor, len(tables), buffer)
 
 	tableOffset := introLength // the actual content will start after the header + table directory
 	for i, table := range tables {
@@ -47,7 +53,7 @@ func WriteTTF(tables []Table) []byte {
 }
 
 // out is assumed to have a length >= ttfHeaderSize
-func writeTTFHeader(nTables int, out []byte) {
+func writeTTFHeader(flavor Tag, nTables int, out []byte) {
 	log2 := math.Floor(math.Log2(float64(nTables)))
 	// Maximum power of 2 less than or equal to numTables, times 16 ((2**floor(log2(numTables))) * 16, where “**” is an exponentiation operator).
 	searchRange := math.Pow(2, log2) * 16
@@ -56,7 +62,7 @@ func writeTTFHeader(nTables int, out []byte) {
 	// numTables times 16, minus searchRange ((numTables * 16) - searchRange).
 	rangeShift := nTables*16 - int(searchRange)
 
-	binary.BigEndian.PutUint32(out[:], uint32(TrueType))
+	binary.BigEndian.PutUint32(out[:], uint32(flavor))
 	binary.BigEndian.PutUint16(out[4:], uint16(nTables))
 	binary.BigEndian.PutUint16(out[6:], uint16(searchRange))
 	binary.BigEndian.PutUint16(out[8:], uint16(entrySelector))
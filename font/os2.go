@@ -12,6 +12,7 @@ import (
 type os2 struct {
 	version       uint16
 	xAvgCharWidth uint16
+	fsType        EmbedPermission
 
 	*os2Desc
 
@@ -37,6 +38,7 @@ func newOs2(os tables.Os2) (os2, error) {
 	out := os2{
 		version:             os.Version,
 		xAvgCharWidth:       os.XAvgCharWidth,
+		fsType:              EmbedPermission(os.FsType()),
 		os2Desc:             newOS2Desc(os),
 		ySubscriptXSize:     float32(os.YSubscriptXSize),
 		ySubscriptYSize:     float32(os.YSubscriptYSize),
@@ -67,3 +69,54 @@ func newOs2(os tables.Os2) (os2, error) {
 
 	return out, nil
 }
+
+// EmbedPermission stores the embedding licensing rights of a font,
+// as defined by the 'fsType' field of the 'OS/2' table.
+//
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/os2#fstype
+type EmbedPermission uint16
+
+const (
+	// EmbedInstallable means the font may be embedded, and permanently
+	// installed on the remote system.
+	EmbedInstallable EmbedPermission = 0x0000
+	// EmbedRestricted means the font must not be embedded.
+	EmbedRestricted EmbedPermission = 0x0002
+	// EmbedPreviewAndPrint means the font may be embedded, but only
+	// in documents opened read-only.
+	EmbedPreviewAndPrint EmbedPermission = 0x0004
+	// EmbedEditable means the font may be embedded in documents
+	// which may be edited.
+	EmbedEditable EmbedPermission = 0x0008
+	// EmbedNoSubsetting means the font must not be subsetted prior to embedding.
+	EmbedNoSubsetting EmbedPermission = 0x0100
+	// EmbedBitmapOnly means only the bitmaps contained in the font may be embedded.
+	EmbedBitmapOnly EmbedPermission = 0x0200
+)
+
+// Usage returns the usage permission, that is one of [EmbedInstallable],
+// [EmbedRestricted], [EmbedPreviewAndPrint] or [EmbedEditable].
+// If several bits are set (which is invalid but found in older fonts),
+// the least restrictive permission is returned, as advised by the specification.
+func (ep EmbedPermission) Usage() EmbedPermission {
+	switch {
+	case ep&0x000F == 0:
+		return EmbedInstallable
+	case ep&EmbedEditable != 0:
+		return EmbedEditable
+	case ep&EmbedPreviewAndPrint != 0:
+		return EmbedPreviewAndPrint
+	case ep&EmbedRestricted != 0:
+		return EmbedRestricted
+	default: // only the reserved bit 0 is set
+		return EmbedInstallable
+	}
+}
+
+// CanEmbed returns true if the font may be embedded in a document,
+// either with read-only or editable access.
+func (ep EmbedPermission) CanEmbed() bool { return ep.Usage() != EmbedRestricted }
+
+// EmbedPermission returns the embedding licensing rights of the font.
+// Fonts without an 'OS/2' table are considered installable.
+func (f *Font) EmbedPermission() EmbedPermission { return f.os2.fsType }
@@ -2,7 +2,12 @@
 
 package font
 
-import "github.com/go-text/typesetting/font/opentype/tables"
+import (
+	"sort"
+
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
+)
 
 // shared between GSUB and GPOS
 type Layout struct {
@@ -120,6 +125,75 @@ func (la *Layout) FindFeatureIndex(featureTag Tag) (uint16, bool) {
 	return 0, false
 }
 
+var (
+	// DefaultScriptTag is the OpenType script tag used for features which are not script-specific.
+	DefaultScriptTag = ot.MustNewTag("DFLT")
+	// DefaultLanguageTag is the OpenType language tag used for the
+	// default language system of a script.
+	DefaultLanguageTag = ot.MustNewTag("dflt")
+)
+
+// SupportedScripts returns the sorted list of the OpenType script tags
+// defined in the GSUB and GPOS tables of the font, that is the scripts for which
+// the font provides layout features.
+// For fonts without scripts (for instance without GSUB and GPOS tables),
+// only [DefaultScriptTag] is returned.
+func (f *Font) SupportedScripts() []Tag {
+	var out []Tag
+	for _, la := range [2]*Layout{&f.GSUB.Layout, &f.GPOS.Layout} {
+		for _, script := range la.Scripts {
+			out = append(out, script.Tag)
+		}
+	}
+	if len(out) == 0 {
+		return []Tag{DefaultScriptTag}
+	}
+	return sortedUniqueTags(out)
+}
+
+// SupportedLanguages returns the sorted list of the OpenType language tags
+// defined for [script] in the GSUB and GPOS tables of the font, including
+// [DefaultLanguageTag] if the script has a default language system.
+// It returns nil if [script] is not supported (see [Font.SupportedScripts]), and
+// only [DefaultLanguageTag] for [DefaultScriptTag] when the font has no scripts.
+func (f *Font) SupportedLanguages(script Tag) []Tag {
+	var out []Tag
+	hasScripts := false
+	for _, la := range [2]*Layout{&f.GSUB.Layout, &f.GPOS.Layout} {
+		hasScripts = hasScripts || len(la.Scripts) != 0
+		index := la.FindScript(script)
+		if index == -1 {
+			continue
+		}
+		sc := la.Scripts[index]
+		if sc.DefaultLangSys != nil {
+			out = append(out, DefaultLanguageTag)
+		}
+		for _, rec := range sc.LangSysRecords {
+			out = append(out, rec.Tag)
+		}
+	}
+	if !hasScripts && script == DefaultScriptTag {
+		return []Tag{DefaultLanguageTag}
+	}
+	return sortedUniqueTags(out)
+}
+
+// sortedUniqueTags sorts [tags] in place, and removes the duplicates
+func sortedUniqueTags(tags []Tag) []Tag {
+	if len(tags) == 0 {
+		return nil
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i] < tags[j] })
+	out := tags[:1]
+	for _, tag := range tags[1:] {
+		if tag != out[len(out)-1] {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
 // ---------------------------------- GSUB ----------------------------------
 
 type GSUB struct {
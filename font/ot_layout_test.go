@@ -3,10 +3,12 @@
 package font
 
 import (
+	"os"
 	"reflect"
 	"sort"
 	"testing"
 
+	ot "github.com/go-text/typesetting/font/opentype"
 	"github.com/go-text/typesetting/font/opentype/tables"
 	tu "github.com/go-text/typesetting/testutils"
 )
@@ -74,3 +76,36 @@ func TestOTFeatureVariation(t *testing.T) {
 	tu.Assert(t, gsub.FindVariationIndex([]VarCoord{tables.NewCoord(0.8)}) == 0)
 	tu.Assert(t, gsub.FindVariationIndex([]VarCoord{tables.NewCoord(0.4)}) == -1)
 }
+
+func TestSupportedScriptsLanguages(t *testing.T) {
+	f, err := os.Open("testdata/Amiri-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+
+	tags := func(ls ...string) []Tag {
+		out := make([]Tag, len(ls))
+		for i, s := range ls {
+			out[i] = ot.MustNewTag(s)
+		}
+		return out
+	}
+
+	tu.Assert(t, reflect.DeepEqual(face.SupportedScripts(), tags("DFLT", "arab", "latn")))
+	tu.Assert(t, reflect.DeepEqual(face.SupportedLanguages(ot.MustNewTag("arab")), tags("ARA ", "KSH ", "MLY ", "SND ", "URD ", "dflt")))
+	tu.Assert(t, reflect.DeepEqual(face.SupportedLanguages(ot.MustNewTag("latn")), tags("TRK ", "dflt")))
+	tu.Assert(t, reflect.DeepEqual(face.SupportedLanguages(DefaultScriptTag), tags("dflt")))
+	tu.Assert(t, face.SupportedLanguages(ot.MustNewTag("cyrl")) == nil)
+
+	// only in GPOS
+	ft := *face.Font
+	ft.GSUB = GSUB{}
+	tu.Assert(t, reflect.DeepEqual(ft.SupportedScripts(), tags("DFLT", "arab", "latn")))
+
+	// no layout tables
+	ft.GPOS = GPOS{}
+	tu.Assert(t, reflect.DeepEqual(ft.SupportedScripts(), tags("DFLT")))
+	tu.Assert(t, reflect.DeepEqual(ft.SupportedLanguages(DefaultScriptTag), tags("dflt")))
+	tu.Assert(t, ft.SupportedLanguages(ot.MustNewTag("arab")) == nil)
+}
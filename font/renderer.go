@@ -8,9 +8,12 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"strconv"
 
 	ot "github.com/go-text/typesetting/font/opentype"
 	"github.com/go-text/typesetting/font/opentype/tables"
+	"golang.org/x/image/math/fixed"
 )
 
 var (
@@ -56,6 +59,85 @@ func (o GlyphOutline) Sideways(yOffset float32) {
 	}
 }
 
+// InkExtents returns the exact bounding box of the outline, in font units.
+// Contrary to [Face.GlyphExtents], which uses the control points, the extrema
+// of the curves are computed, so that the box is tight around the drawn shape.
+// An empty outline has zero extents.
+func (o GlyphOutline) InkExtents() GlyphExtents {
+	var (
+		minX, minY, maxX, maxY float32
+		started                bool
+		current                SegmentPoint
+	)
+	include := func(x, y float32) {
+		if !started {
+			minX, minY, maxX, maxY = x, y, x, y
+			started = true
+			return
+		}
+		minX, minY = minF(minX, x), minF(minY, y)
+		maxX, maxY = maxF(maxX, x), maxF(maxY, y)
+	}
+	for _, seg := range o.Segments {
+		switch seg.Op {
+		case ot.SegmentOpMoveTo, ot.SegmentOpLineTo:
+			current = seg.Args[0]
+		case ot.SegmentOpQuadTo:
+			p0, p1, p2 := current, seg.Args[0], seg.Args[1]
+			for _, t := range [2]float32{quadExtremum(p0.X, p1.X, p2.X), quadExtremum(p0.Y, p1.Y, p2.Y)} {
+				if 0 < t && t < 1 {
+					u := 1 - t
+					include(u*u*p0.X+2*u*t*p1.X+t*t*p2.X, u*u*p0.Y+2*u*t*p1.Y+t*t*p2.Y)
+				}
+			}
+			current = p2
+		case ot.SegmentOpCubeTo:
+			p0, p1, p2, p3 := current, seg.Args[0], seg.Args[1], seg.Args[2]
+			ts := append(cubicExtrema(p0.X, p1.X, p2.X, p3.X), cubicExtrema(p0.Y, p1.Y, p2.Y, p3.Y)...)
+			for _, t := range ts {
+				if 0 < t && t < 1 {
+					u := 1 - t
+					a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+					include(a*p0.X+b*p1.X+c*p2.X+d*p3.X, a*p0.Y+b*p1.Y+c*p2.Y+d*p3.Y)
+				}
+			}
+			current = p3
+		}
+		include(current.X, current.Y)
+	}
+	return GlyphExtents{XBearing: minX, YBearing: maxY, Width: maxX - minX, Height: minY - maxY}
+}
+
+// quadExtremum returns the parameter of the extremum of the quadratic
+// Bézier curve with control values p0, p1, p2, or -1 if there is none.
+func quadExtremum(p0, p1, p2 float32) float32 {
+	denom := p0 - 2*p1 + p2
+	if denom == 0 {
+		return -1
+	}
+	return (p0 - p1) / denom
+}
+
+// cubicExtrema returns the parameters of the extrema of the cubic
+// Bézier curve with control values p0, p1, p2, p3, which are the roots of its derivative.
+func cubicExtrema(p0, p1, p2, p3 float32) []float32 {
+	a := -p0 + 3*p1 - 3*p2 + p3
+	b := 2 * (p0 - 2*p1 + p2)
+	c := p1 - p0
+	if a == 0 { // the derivative is linear
+		if b == 0 {
+			return nil
+		}
+		return []float32{-c / b}
+	}
+	delta := b*b - 4*a*c
+	if delta < 0 {
+		return nil
+	}
+	sq := float32(math.Sqrt(float64(delta)))
+	return []float32{(-b - sq) / (2 * a), (-b + sq) / (2 * a)}
+}
+
 // GlyphSVG is an SVG description for the glyph,
 // as found in Opentype SVG table.
 type GlyphSVG struct {
@@ -330,12 +412,43 @@ func (e errGlyphOutOfRange) Error() string {
 	return fmt.Sprintf("out of range glyph %d", e)
 }
 
+// compositeCause is the reason why a composite glyph is invalid
+type compositeCause uint8
+
+const (
+	compositeTooDeep    compositeCause = iota + 1 // more than maxCompositeNesting levels
+	compositeTooComplex                           // more than maxCompositeOperations components
+	compositeOutOfRange                           // component index out of range
+)
+
+// errInvalidComposite is returned when the points of the composite glyph [gid]
+// can't be resolved.
+type errInvalidComposite struct {
+	gid       gID
+	component gID // for compositeOutOfRange
+	cause     compositeCause
+}
+
+func (e *errInvalidComposite) Error() string {
+	switch e.cause {
+	case compositeTooDeep:
+		return fmt.Sprintf("invalid composite glyph %d (too deeply nested)", e.gid)
+	case compositeTooComplex:
+		return fmt.Sprintf("invalid composite glyph %d (more than %d components)", e.gid, maxCompositeOperations)
+	default:
+		return fmt.Sprintf("invalid composite glyph %d (out of range component %d)", e.gid, e.component)
+	}
+}
+
 // apply variation when needed
 func (f *Face) glyphDataFromGlyf(glyph gID) (GlyphOutline, error) {
 	if int(glyph) >= len(f.glyf) {
 		return GlyphOutline{}, errGlyphOutOfRange(glyph)
 	}
-	points := f.getPointsForGlyph(glyph)
+	points, err := f.getPointsForGlyph(glyph)
+	if err != nil {
+		return GlyphOutline{}, err
+	}
 	segments := buildSegments(points[:len(points)-phantomCount])
 	return GlyphOutline{Segments: segments}, nil
 }
@@ -445,6 +558,21 @@ func (f *Face) GlyphDataOutline(gid GID) (GlyphOutline, bool) {
 	return GlyphOutline{}, false
 }
 
+// GlyphBounds returns the exact ink bounds of [gid], in font units, computed
+// from its outline (see [GlyphOutline.InkExtents]), with the current variation coordinates applied.
+// The Y axis increases up.
+// Empty glyphs (like spaces) have a zero box, and [ok] is false
+// only if the glyph has no outline.
+func (f *Face) GlyphBounds(gid GID) (minX, minY, maxX, maxY fixed.Int26_6, ok bool) {
+	outline, ok := f.GlyphDataOutline(gid)
+	if !ok {
+		return 0, 0, 0, 0, false
+	}
+	ext := outline.InkExtents()
+	toFixed := func(v float32) fixed.Int26_6 { return fixed.Int26_6(math.Round(float64(v) * 64)) }
+	return toFixed(ext.XBearing), toFixed(ext.YBearing + ext.Height), toFixed(ext.XBearing + ext.Width), toFixed(ext.YBearing), true
+}
+
 // GlyphDataSVG looks for glyph data in the 'SVG ' table.
 func (f *Face) GlyphDataSVG(gid GID) (GlyphSVG, bool) {
 	outS, ok := f.svg.glyphData(gID(gid))
@@ -458,6 +586,28 @@ func (f *Face) GlyphDataSVG(gid GID) (GlyphSVG, bool) {
 	return outS, true
 }
 
+// SVGGlyph returns the SVG document from the 'SVG ' table covering [gid],
+// decompressed if needed. The description of [gid] is the element with id "glyph<gid>"
+// in the document, which may also describe other glyphs (see [Face.SVGGlyphRange]).
+// It returns false if the font has no 'SVG ' table, or no document for [gid].
+func (f *Face) SVGGlyph(gid GID) (doc []byte, ok bool) {
+	outS, ok := f.svg.glyphData(gID(gid))
+	return outS.Source, ok
+}
+
+// SVGGlyphElementID returns the id of the element describing [gid]
+// in the document returned by [Face.SVGGlyph], that is "glyph<gid>".
+// Renderers should only draw this element (and the ones it references),
+// since a document may describe several glyphs.
+func SVGGlyphElementID(gid GID) string { return "glyph" + strconv.Itoa(int(gid)) }
+
+// SVGGlyphRange returns the (inclusive) range of glyphs described by
+// the SVG document covering [gid], or false if there is no such document.
+func (f *Face) SVGGlyphRange(gid GID) (first, last GID, ok bool) {
+	entry, ok := f.svg.document(gID(gid))
+	return GID(entry.first), GID(entry.last), ok
+}
+
 // GlyphDataColor looks for glyph data in the 'COLR' table.
 func (f *Face) GlyphDataColor(gid GID) (GlyphColor, bool) {
 	v, ok := f.COLR.Search(gID(gid))
@@ -487,3 +637,32 @@ func (f *Face) GlyphDataBitmap(gid GID) (GlyphBitmap, bool) {
 
 	return GlyphBitmap{}, false
 }
+
+// SbixGlyph returns the raw image data stored in the 'sbix' table
+// for [gid], using the strike best matching [ppem] (see also [Face.SetPpem]).
+// A ppem of 0 selects the largest strike.
+// The data is not decoded, and is usually a PNG image.
+// It returns false if the font has no 'sbix' table, or no (supported) image for [gid].
+func (f *Face) SbixGlyph(gid GID, ppem int) (image []byte, format BitmapFormat, ok bool) {
+	if ppem < 0 {
+		ppem = 0
+	} else if ppem > math.MaxUint16 {
+		ppem = math.MaxUint16
+	}
+	st := f.sbix.chooseStrike(uint16(ppem), uint16(ppem))
+	if st == nil {
+		return nil, 0, false
+	}
+	glyph := strikeGlyph(st, gID(gid), 0)
+	switch glyph.GraphicType {
+	case tagPNG:
+		format = PNG
+	case tagTIFF:
+		format = TIFF
+	case tagJPG:
+		format = JPG
+	default: // missing glyph or unsupported format
+		return nil, 0, false
+	}
+	return glyph.Data, format, true
+}
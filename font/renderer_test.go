@@ -4,6 +4,7 @@ package font
 
 import (
 	"bytes"
+	"os"
 	"reflect"
 	"strings"
 	"testing"
@@ -12,6 +13,7 @@ import (
 	ot "github.com/go-text/typesetting/font/opentype"
 	"github.com/go-text/typesetting/font/opentype/tables"
 	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
 )
 
 func loadFont(t testing.TB, filename string) *Font {
@@ -215,7 +217,8 @@ func TestGlyfSegments1(t *testing.T) {
 
 	face := Face{Font: f}
 	for i, expected := range expecteds {
-		points := face.getPointsForGlyph(gID(i))
+		points, err := face.getPointsForGlyph(gID(i))
+		tu.AssertNoErr(t, err)
 
 		got := buildSegments(points[:len(points)-phantomCount])
 		if len(expected) == 0 {
@@ -233,7 +236,7 @@ func BenchmarkBuildSegments(b *testing.B) {
 	if !ok {
 		b.Fatal("did not find & in the font")
 	}
-	points := face.getPointsForGlyph(uint16(gid))
+	points, _ := face.getPointsForGlyph(uint16(gid))
 
 	b.ResetTimer()
 
@@ -382,7 +385,8 @@ func TestGlyfSegments2(t *testing.T) {
 
 	face := Face{Font: font}
 	for i, expected := range expecteds {
-		points := face.getPointsForGlyph(gID(i))
+		points, err := face.getPointsForGlyph(gID(i))
+		tu.AssertNoErr(t, err)
 		got := buildSegments(points[:len(points)-phantomCount])
 		if len(expected) == 0 {
 			expected = nil
@@ -514,6 +518,19 @@ func TestSbixGlyph(t *testing.T) {
 	asBitmap, ok = data.(GlyphBitmap)
 	tu.Assert(t, ok)
 	tu.Assert(t, asBitmap.Format == PNG)
+
+	img, format, ok := face.SbixGlyph(4, 100)
+	tu.Assert(t, ok && format == PNG)
+	tu.Assert(t, bytes.Equal(img, asBitmap.Data))
+	_, _, ok = face.SbixGlyph(4, 0) // largest strike
+	tu.Assert(t, ok)
+	_, _, ok = face.SbixGlyph(GID(len(ft.sbix[0].GlyphDatas)), 100)
+	tu.Assert(t, !ok)
+
+	// no 'sbix' table
+	face = Face{Font: loadFont(t, "common/Roboto-BoldItalic.ttf")}
+	_, _, ok = face.SbixGlyph(4, 100)
+	tu.Assert(t, !ok)
 }
 
 func TestCblcGlyph(t *testing.T) {
@@ -642,3 +659,180 @@ func TestColorGlyphs(t *testing.T) {
 	_, ok = face.GlyphDataColor(0)
 	tu.Assert(t, ok)
 }
+
+func TestLoopingComposites(t *testing.T) {
+	f, err := os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+
+	composite := func(components ...tables.GlyphID) tables.Glyph {
+		var cg tables.CompositeGlyph
+		for _, c := range components {
+			cg.Glyphs = append(cg.Glyphs, tables.CompositeGlyphPart{GlyphIndex: c, Scale: [4]float32{1, 0, 0, 1}})
+		}
+		return tables.Glyph{Data: cg}
+	}
+
+	// craft a font with broken composites
+	ft := *face.Font
+	ft.glyf = append(tables.Glyf(nil), ft.glyf...)
+	ft.glyf[1] = composite(1)    // self reference
+	ft.glyf[2] = composite(3, 4) // loop 2 -> 3 -> 2
+	ft.glyf[3] = composite(2)
+	for gid := 10; gid < 50; gid++ { // too deeply nested
+		ft.glyf[gid] = composite(tables.GlyphID(gid + 1))
+	}
+	for gid := 60; gid < 90; gid++ { // exponential size
+		ft.glyf[gid] = composite(tables.GlyphID(gid+1), tables.GlyphID(gid+1))
+	}
+	ft.glyf[95] = composite(5, 0xFFFF) // out of range component
+	broken := NewFace(&ft)
+
+	for _, gid := range []GID{1, 2, 3, 10, 40, 60, 80, 95} {
+		// must not hang or crash
+		broken.GlyphData(gid)
+		broken.GlyphExtents(gid)
+		broken.HorizontalAdvance(gid)
+	}
+
+	// loops are ignored...
+	_, ok := broken.GlyphDataOutline(1)
+	tu.Assert(t, ok)
+	_, ok = broken.GlyphDataOutline(2)
+	tu.Assert(t, ok)
+
+	// ... but too complex glyphs are reported as invalid
+	_, ok = broken.GlyphDataOutline(10)
+	tu.Assert(t, !ok)
+	_, ok = broken.GlyphDataOutline(60)
+	tu.Assert(t, !ok)
+	// nesting below the limit is fine
+	_, ok = broken.GlyphDataOutline(40)
+	tu.Assert(t, ok)
+
+	// the reason is reported
+	for _, test := range []struct {
+		gid   gID
+		cause compositeCause
+	}{
+		{10, compositeTooDeep},
+		{70, compositeTooComplex}, // 60 is also too deeply nested
+		{95, compositeOutOfRange},
+	} {
+		_, err := broken.glyphDataFromGlyf(test.gid)
+		invalid, ok := err.(*errInvalidComposite)
+		tu.Assert(t, ok && invalid.gid == test.gid && invalid.cause == test.cause)
+	}
+	_, err = broken.glyphDataFromGlyf(95)
+	tu.Assert(t, err.Error() == "invalid composite glyph 95 (out of range component 65535)")
+}
+
+func TestColorGlyph(t *testing.T) {
+	f, err := os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+
+	_, err = face.ColorGlyph(5, fixed.I(12))
+	tu.Assert(t, err == ErrNotColorGlyph)
+
+	// craft a COLR version 0 table, with one base glyph (5) and three layers
+	colr := []byte{
+		0, 0, // version
+		0, 1, // numBaseGlyphRecords
+		0, 0, 0, 14, // offset to base glyphs
+		0, 0, 0, 20, // offset to layers
+		0, 3, // numLayerRecords
+		0, 5, 0, 0, 0, 3, // base glyph 5
+		0, 10, 0, 0, // layer with palette index 0
+		0, 11, 0xFF, 0xFF, // foreground layer
+		0, 12, 0, 1, // layer with palette index 1
+	}
+	table, err := tables.ParseCOLR(colr)
+	tu.AssertNoErr(t, err)
+	red, blue := tables.ColorRecord{Red: 0xFF, Alpha: 0xFF}, tables.ColorRecord{Blue: 0xFF, Alpha: 0xFF}
+	ft := *face.Font
+	ft.COLR = &table
+	ft.CPAL = CPAL{{red, blue}}
+	color := NewFace(&ft)
+
+	glyph, err := color.ColorGlyph(5, fixed.I(12))
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, glyph.Kind == ColorGlyphLayers)
+	tu.Assert(t, reflect.DeepEqual(glyph.Layers, []ColorLayer{
+		{Glyph: 10, Color: red},
+		{Glyph: 11, Foreground: true},
+		{Glyph: 12, Color: blue},
+	}))
+	_, err = color.ColorGlyph(6, fixed.I(12))
+	tu.Assert(t, err == ErrNotColorGlyph)
+
+	// invalid palette index
+	ft.CPAL = CPAL{{red}}
+	_, err = NewFace(&ft).ColorGlyph(5, fixed.I(12))
+	tu.Assert(t, err != nil && err != ErrNotColorGlyph)
+}
+
+func TestInkExtents(t *testing.T) {
+	pt := func(x, y float32) SegmentPoint { return SegmentPoint{X: x, Y: y} }
+	tu.Assert(t, GlyphOutline{}.InkExtents() == GlyphExtents{})
+
+	// the control points are outside the curves
+	quad := GlyphOutline{Segments: []Segment{
+		{Op: ot.SegmentOpMoveTo, Args: [3]SegmentPoint{pt(0, 0)}},
+		{Op: ot.SegmentOpQuadTo, Args: [3]SegmentPoint{pt(50, 100), pt(100, 0)}},
+	}}
+	tu.Assert(t, quad.InkExtents() == GlyphExtents{XBearing: 0, YBearing: 50, Width: 100, Height: -50})
+	cubic := GlyphOutline{Segments: []Segment{
+		{Op: ot.SegmentOpMoveTo, Args: [3]SegmentPoint{pt(0, 0)}},
+		{Op: ot.SegmentOpCubeTo, Args: [3]SegmentPoint{pt(0, 100), pt(100, 100), pt(100, 0)}},
+		{Op: ot.SegmentOpLineTo, Args: [3]SegmentPoint{pt(50, -10)}},
+	}}
+	tu.Assert(t, cubic.InkExtents() == GlyphExtents{XBearing: 0, YBearing: 75, Width: 100, Height: -85})
+
+	// the ink extents are included in the extents computed from the control points
+	f, err := os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+	for _, r := range "aoS@&" {
+		gid, _ := face.NominalGlyph(r)
+		outline, ok := face.GlyphDataOutline(gid)
+		tu.Assert(t, ok)
+		ink, _ := face.GlyphExtents(gid)
+		exact := outline.InkExtents()
+		tu.Assert(t, exact.Width > 0 && exact.Height < 0)
+		tu.Assert(t, exact.XBearing >= ink.XBearing && exact.YBearing <= ink.YBearing)
+		tu.Assert(t, exact.XBearing+exact.Width <= ink.XBearing+ink.Width)
+		tu.Assert(t, exact.YBearing+exact.Height >= ink.YBearing+ink.Height)
+	}
+}
+
+func TestGlyphBounds(t *testing.T) {
+	f, err := os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+
+	gid, _ := face.NominalGlyph('o')
+	minX, minY, maxX, maxY, ok := face.GlyphBounds(gid)
+	tu.Assert(t, ok)
+	outline, _ := face.GlyphDataOutline(gid)
+	ext := outline.InkExtents()
+	tu.Assert(t, minX == fixed.Int26_6(ext.XBearing*64) && maxY == fixed.Int26_6(ext.YBearing*64))
+	tu.Assert(t, minX < maxX && minY < maxY && minY < 0) // 'o' overshoots the baseline
+
+	// empty glyph
+	gid, _ = face.NominalGlyph(' ')
+	minX, minY, maxX, maxY, ok = face.GlyphBounds(gid)
+	tu.Assert(t, ok && minX == 0 && minY == 0 && maxX == 0 && maxY == 0)
+
+	// out of range glyph
+	_, _, _, _, ok = face.GlyphBounds(GID(face.Upem() * 1000))
+	tu.Assert(t, !ok)
+}
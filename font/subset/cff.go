@@ -0,0 +1,293 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package subset
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+)
+
+// Type 2 charstring operators
+const (
+	csRLineTo   = 5
+	csRRCurveTo = 8
+	csEndChar   = 14
+	csRMoveTo   = 21
+)
+
+// Top and Private DICT operators
+const (
+	dictFontBBox      = 5
+	dictCharset       = 15
+	dictCharStrings   = 17
+	dictPrivate       = 18
+	dictDefaultWidthX = 20
+	dictNominalWidthX = 21
+	dictEscape        = 12
+	dictFontMatrix    = 7 // escaped
+)
+
+// the first String ID which is not a standard string
+const firstCustomSID = 391
+
+// writeCFF returns a 'CFF ' table containing one (non CID-keyed) font
+// with the outlines of [glyphs]. Quadratic curves are converted to cubic ones.
+func writeCFF(face *font.Face, glyphs glyphSet) []byte {
+	psName := postscriptName(face.Describe())
+
+	// glyph names, stored in the String INDEX
+	names := make([][]byte, 0, len(glyphs.gids)-1)
+	seen := map[string]bool{".notdef": true}
+	for _, gid := range glyphs.gids[1:] {
+		name := face.GlyphName(gid)
+		if name == "" || seen[name] {
+			name = "gid" + strconv.Itoa(int(gid))
+		}
+		seen[name] = true
+		names = append(names, []byte(name))
+	}
+
+	charstrings := make([][]byte, len(glyphs.outlines))
+	for i, segments := range glyphs.outlines {
+		charstrings[i] = type2Charstring(segments, glyphs.advances[i])
+	}
+
+	var private []byte
+	private = appendDictInt(private, 0)
+	private = append(private, dictDefaultWidthX)
+	private = appendDictInt(private, 0)
+	private = append(private, dictNominalWidthX)
+
+	var charset []byte
+	charset = append(charset, 0) // format 0
+	for i := range names {
+		charset = binary.BigEndian.AppendUint16(charset, uint16(firstCustomSID+i))
+	}
+
+	header := []byte{1, 0, 4, 4} // version 1.0, header size, absolute offsets size
+	nameIndex := appendIndex(nil, [][]byte{[]byte(psName)})
+	stringIndex := appendIndex(nil, names)
+	globalSubrs := appendIndex(nil, nil)
+	charstringsIndex := appendIndex(nil, charstrings)
+
+	// the offsets are encoded with a fixed size, so that
+	// the size of the Top DICT does not depend on them
+	topDict := func(charsetOffset, charstringsOffset, privateOffset int) []byte {
+		var out []byte
+		if upem := face.Upem(); upem != 1000 {
+			scale := 1 / float64(upem)
+			for _, v := range [6]float64{scale, 0, 0, scale, 0, 0} {
+				out = appendDictReal(out, v)
+			}
+			out = append(out, dictEscape, dictFontMatrix)
+		}
+		var box bbox
+		for _, b := range glyphs.bounds {
+			box = unionBox(box, b)
+		}
+		for _, v := range [4]int16{box.xMin, box.yMin, box.xMax, box.yMax} {
+			out = appendDictInt(out, int32(v))
+		}
+		out = append(out, dictFontBBox)
+		out = appendDictInt32(out, int32(charsetOffset))
+		out = append(out, dictCharset)
+		out = appendDictInt32(out, int32(charstringsOffset))
+		out = append(out, dictCharStrings)
+		out = appendDictInt32(out, int32(len(private)))
+		out = appendDictInt32(out, int32(privateOffset))
+		out = append(out, dictPrivate)
+		return out
+	}
+
+	topDictIndexSize := len(appendIndex(nil, [][]byte{topDict(0, 0, 0)}))
+	charsetOffset := len(header) + len(nameIndex) + topDictIndexSize + len(stringIndex) + len(globalSubrs)
+	charstringsOffset := charsetOffset + len(charset)
+	privateOffset := charstringsOffset + len(charstringsIndex)
+	topDictIndex := appendIndex(nil, [][]byte{topDict(charsetOffset, charstringsOffset, privateOffset)})
+
+	out := make([]byte, 0, privateOffset+len(private))
+	out = append(out, header...)
+	out = append(out, nameIndex...)
+	out = append(out, topDictIndex...)
+	out = append(out, stringIndex...)
+	out = append(out, globalSubrs...)
+	out = append(out, charset...)
+	out = append(out, charstringsIndex...)
+	out = append(out, private...)
+	return out
+}
+
+// type2Charstring encodes [segments] as a Type 2 charstring,
+// starting with the glyph width.
+func type2Charstring(segments []ot.Segment, advance uint16) []byte {
+	out := appendCharstringNumber(nil, int32(advance)<<16)
+	var current [2]int32 // in 16.16 fixed point
+	toFixed := func(p ot.SegmentPoint) [2]int32 {
+		return [2]int32{int32(math.Round(float64(p.X) * 65536)), int32(math.Round(float64(p.Y) * 65536))}
+	}
+	appendPoints := func(points ...[2]int32) {
+		for _, p := range points {
+			out = appendCharstringNumber(out, p[0]-current[0])
+			out = appendCharstringNumber(out, p[1]-current[1])
+			current = p
+		}
+	}
+	for _, seg := range segments {
+		switch seg.Op {
+		case ot.SegmentOpMoveTo:
+			appendPoints(toFixed(seg.Args[0]))
+			out = append(out, csRMoveTo)
+		case ot.SegmentOpLineTo:
+			appendPoints(toFixed(seg.Args[0]))
+			out = append(out, csRLineTo)
+		case ot.SegmentOpQuadTo:
+			// degree elevation : the control points are at 2/3 of the way
+			// from the end points to the quadratic control point
+			p0, c, p1 := current, toFixed(seg.Args[0]), toFixed(seg.Args[1])
+			twoThird := func(from, to int32) int32 { return from + int32((int64(to)-int64(from))*2/3) }
+			c1 := [2]int32{twoThird(p0[0], c[0]), twoThird(p0[1], c[1])}
+			c2 := [2]int32{twoThird(p1[0], c[0]), twoThird(p1[1], c[1])}
+			appendPoints(c1, c2, p1)
+			out = append(out, csRRCurveTo)
+		case ot.SegmentOpCubeTo:
+			appendPoints(toFixed(seg.Args[0]), toFixed(seg.Args[1]), toFixed(seg.Args[2]))
+			out = append(out, csRRCurveTo)
+		}
+	}
+	return append(out, csEndChar)
+}
+
+// appendCharstringNumber encodes the 16.16 fixed point number [v],
+// using the shortest integer form when possible
+func appendCharstringNumber(dst []byte, v int32) []byte {
+	if v&0xFFFF == 0 {
+		switch i := v >> 16; {
+		case -107 <= i && i <= 107:
+			return append(dst, byte(i+139))
+		case 108 <= i && i <= 1131:
+			i -= 108
+			return append(dst, byte(i>>8+247), byte(i))
+		case -1131 <= i && i <= -108:
+			i = -i - 108
+			return append(dst, byte(i>>8+251), byte(i))
+		default:
+			return append(dst, 28, byte(i>>8), byte(i))
+		}
+	}
+	return binary.BigEndian.AppendUint32(append(dst, 255), uint32(v))
+}
+
+// appendDictInt uses the shortest encoding for [v]
+func appendDictInt(dst []byte, v int32) []byte {
+	switch {
+	case -107 <= v && v <= 107:
+		return append(dst, byte(v+139))
+	case 108 <= v && v <= 1131:
+		v -= 108
+		return append(dst, byte(v>>8+247), byte(v))
+	case -1131 <= v && v <= -108:
+		v = -v - 108
+		return append(dst, byte(v>>8+251), byte(v))
+	case math.MinInt16 <= v && v <= math.MaxInt16:
+		return append(dst, 28, byte(v>>8), byte(v))
+	default:
+		return appendDictInt32(dst, v)
+	}
+}
+
+// appendDictInt32 always uses 5 bytes
+func appendDictInt32(dst []byte, v int32) []byte {
+	return binary.BigEndian.AppendUint32(append(dst, 29), uint32(v))
+}
+
+// appendDictReal uses the BCD encoding for real numbers
+func appendDictReal(dst []byte, v float64) []byte {
+	s := strings.ToUpper(strconv.FormatFloat(v, 'g', -1, 64))
+	var nibbles []byte
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case '0' <= c && c <= '9':
+			nibbles = append(nibbles, c-'0')
+		case c == '.':
+			nibbles = append(nibbles, 0xa)
+		case c == 'E':
+			if i+1 < len(s) && s[i+1] == '-' {
+				nibbles = append(nibbles, 0xc)
+				i++
+			} else {
+				nibbles = append(nibbles, 0xb)
+				if i+1 < len(s) && s[i+1] == '+' {
+					i++
+				}
+			}
+		case c == '-':
+			nibbles = append(nibbles, 0xe)
+		}
+	}
+	nibbles = append(nibbles, 0xf)
+	if len(nibbles)%2 == 1 {
+		nibbles = append(nibbles, 0xf)
+	}
+	dst = append(dst, 30)
+	for i := 0; i < len(nibbles); i += 2 {
+		dst = append(dst, nibbles[i]<<4|nibbles[i+1])
+	}
+	return dst
+}
+
+// appendIndex encodes a CFF INDEX
+func appendIndex(dst []byte, items [][]byte) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(items)))
+	if len(items) == 0 {
+		return dst
+	}
+	size := 1
+	for _, item := range items {
+		size += len(item)
+	}
+	offSize := 1
+	for ; offSize < 4 && size >= 1<<(8*offSize); offSize++ {
+	}
+	dst = append(dst, byte(offSize))
+	appendOffset := func(offset int) {
+		for i := offSize - 1; i >= 0; i-- {
+			dst = append(dst, byte(offset>>(8*i)))
+		}
+	}
+	offset := 1
+	appendOffset(offset)
+	for _, item := range items {
+		offset += len(item)
+		appendOffset(offset)
+	}
+	for _, item := range items {
+		dst = append(dst, item...)
+	}
+	return dst
+}
+
+// postscriptName builds a PostScript name from the font metadata,
+// removing the forbidden characters
+func postscriptName(desc font.Description) string {
+	name := desc.Family + "-" + subfamilyName(desc.Aspect)
+	name = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' || strings.ContainsRune("[](){}<>/%", r) {
+			return -1
+		}
+		return r
+	}, name)
+	if len(name) > 63 {
+		name = name[:63]
+	}
+	return name
+}
+
+// writeMaxpCFF returns a version 0.5 'maxp' table
+func writeMaxpCFF(numGlyphs int) []byte {
+	return binary.BigEndian.AppendUint16([]byte{0, 0, 0x50, 0}, uint16(numGlyphs))
+}
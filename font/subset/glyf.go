@@ -0,0 +1,252 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package subset
+
+import (
+	"encoding/binary"
+
+	ot "github.com/go-text/typesetting/font/opentype"
+)
+
+// simple glyph flags
+const (
+	flagOnCurve      = 0x01
+	flagXShort       = 0x02
+	flagYShort       = 0x04
+	flagRepeat       = 0x08
+	flagXSameOrPlus  = 0x10
+	flagYSameOrPlus  = 0x20
+	maxRepeatedFlags = 255
+)
+
+type glyfPoint struct {
+	x, y    int16
+	onCurve bool
+}
+
+// glyfContours converts the segments of an outline with quadratic curves
+// to TrueType contours.
+// The redundant on-curve points (the implicit middle points between
+// two off-curve points, and the closing points) are removed.
+func glyfContours(segments []ot.Segment) [][]glyfPoint {
+	var (
+		out     [][]glyfPoint
+		current []glyfPoint
+	)
+	point := func(p ot.SegmentPoint, onCurve bool) glyfPoint {
+		return glyfPoint{toInt16(p.X), toInt16(p.Y), onCurve}
+	}
+	closeContour := func() {
+		if len(current) == 0 {
+			return
+		}
+		if last := current[len(current)-1]; len(current) > 1 && last == current[0] {
+			current = current[:len(current)-1]
+		}
+		out = append(out, simplifyContour(current))
+		current = nil
+	}
+	for _, seg := range segments {
+		switch seg.Op {
+		case ot.SegmentOpMoveTo:
+			closeContour()
+			current = append(current, point(seg.Args[0], true))
+		case ot.SegmentOpLineTo:
+			current = append(current, point(seg.Args[0], true))
+		case ot.SegmentOpQuadTo:
+			current = append(current, point(seg.Args[0], false), point(seg.Args[1], true))
+		}
+	}
+	closeContour()
+	return out
+}
+
+// simplifyContour removes the on-curve points which are exactly
+// in the middle of their (off-curve) neighbors
+func simplifyContour(contour []glyfPoint) []glyfPoint {
+	n := len(contour)
+	if n < 3 {
+		return contour
+	}
+	out := make([]glyfPoint, 0, n)
+	for i, p := range contour {
+		prev, next := contour[(i+n-1)%n], contour[(i+1)%n]
+		if p.onCurve && !prev.onCurve && !next.onCurve &&
+			2*int(p.x) == int(prev.x)+int(next.x) && 2*int(p.y) == int(prev.y)+int(next.y) {
+			continue
+		}
+		out = append(out, p)
+	}
+	if len(out) == 0 || !hasOnCurve(out) {
+		// keep at least one on-curve point
+		return contour
+	}
+	return out
+}
+
+func hasOnCurve(contour []glyfPoint) bool {
+	for _, p := range contour {
+		if p.onCurve {
+			return true
+		}
+	}
+	return false
+}
+
+// writeGlyf returns the 'glyf', 'loca' and 'maxp' tables for the given outlines,
+// as well as the glyph bounding boxes and the 'loca' format
+func writeGlyf(outlines [][]ot.Segment) (glyf, loca, maxp []byte, bounds []bbox, locaLong bool) {
+	var (
+		offsets                = make([]int, len(outlines)+1)
+		maxPoints, maxContours int
+	)
+	bounds = make([]bbox, len(outlines))
+	for i, segments := range outlines {
+		offsets[i] = len(glyf)
+		contours := glyfContours(segments)
+		if len(contours) == 0 {
+			continue
+		}
+
+		var box bbox
+		nbPoints := 0
+		for _, contour := range contours {
+			nbPoints += len(contour)
+		}
+		for j, contour := range contours {
+			for k, p := range contour {
+				if j == 0 && k == 0 {
+					box = bbox{p.x, p.y, p.x, p.y}
+					continue
+				}
+				box.xMin, box.yMin = minInt16(box.xMin, p.x), minInt16(box.yMin, p.y)
+				box.xMax, box.yMax = maxInt16(box.xMax, p.x), maxInt16(box.yMax, p.y)
+			}
+		}
+		bounds[i] = box
+		if nbPoints > maxPoints {
+			maxPoints = nbPoints
+		}
+		if len(contours) > maxContours {
+			maxContours = len(contours)
+		}
+
+		glyf = appendSimpleGlyph(glyf, contours, box)
+		for len(glyf)%4 != 0 {
+			glyf = append(glyf, 0)
+		}
+	}
+	offsets[len(outlines)] = len(glyf)
+
+	locaLong = len(glyf)/2 > 0xFFFF
+	for _, offset := range offsets {
+		if locaLong {
+			loca = binary.BigEndian.AppendUint32(loca, uint32(offset))
+		} else {
+			loca = binary.BigEndian.AppendUint16(loca, uint16(offset/2))
+		}
+	}
+
+	maxp = make([]byte, 32)
+	binary.BigEndian.PutUint32(maxp, 0x00010000)
+	binary.BigEndian.PutUint16(maxp[4:], uint16(len(outlines)))
+	binary.BigEndian.PutUint16(maxp[6:], uint16(maxPoints))
+	binary.BigEndian.PutUint16(maxp[8:], uint16(maxContours))
+	binary.BigEndian.PutUint16(maxp[14:], 2) // maxZones
+	return glyf, loca, maxp, bounds, locaLong
+}
+
+func appendSimpleGlyph(dst []byte, contours [][]glyfPoint, box bbox) []byte {
+	dst = binary.BigEndian.AppendUint16(dst, uint16(len(contours)))
+	dst = binary.BigEndian.AppendUint16(dst, uint16(box.xMin))
+	dst = binary.BigEndian.AppendUint16(dst, uint16(box.yMin))
+	dst = binary.BigEndian.AppendUint16(dst, uint16(box.xMax))
+	dst = binary.BigEndian.AppendUint16(dst, uint16(box.yMax))
+
+	endPoint := -1
+	for _, contour := range contours {
+		endPoint += len(contour)
+		dst = binary.BigEndian.AppendUint16(dst, uint16(endPoint))
+	}
+	dst = binary.BigEndian.AppendUint16(dst, 0) // no instructions
+
+	var (
+		flags                []byte
+		xCoords, yCoords     []byte
+		previousX, previousY int16
+	)
+	for _, contour := range contours {
+		for _, p := range contour {
+			var flag byte
+			if p.onCurve {
+				flag |= flagOnCurve
+			}
+			var shortFlag, sameFlag byte
+			shortFlag, sameFlag, xCoords = appendCoordinate(xCoords, int(p.x)-int(previousX))
+			if shortFlag != 0 {
+				flag |= flagXShort
+			}
+			if sameFlag != 0 {
+				flag |= flagXSameOrPlus
+			}
+			shortFlag, sameFlag, yCoords = appendCoordinate(yCoords, int(p.y)-int(previousY))
+			if shortFlag != 0 {
+				flag |= flagYShort
+			}
+			if sameFlag != 0 {
+				flag |= flagYSameOrPlus
+			}
+			previousX, previousY = p.x, p.y
+			flags = append(flags, flag)
+		}
+	}
+
+	// compress the flags
+	for i := 0; i < len(flags); {
+		flag := flags[i]
+		repeat := 0
+		for i+1+repeat < len(flags) && flags[i+1+repeat] == flag && repeat < maxRepeatedFlags {
+			repeat++
+		}
+		if repeat > 1 {
+			dst = append(dst, flag|flagRepeat, byte(repeat))
+		} else {
+			repeat = 0
+			dst = append(dst, flag)
+		}
+		i += 1 + repeat
+	}
+
+	dst = append(dst, xCoords...)
+	dst = append(dst, yCoords...)
+	return dst
+}
+
+// appendCoordinate encodes the relative coordinate [delta],
+// returning non zero values for the short vector and "same or positive" flags
+func appendCoordinate(dst []byte, delta int) (shortFlag, sameFlag byte, _ []byte) {
+	switch {
+	case delta == 0:
+		return 0, 1, dst
+	case -255 <= delta && delta < 0:
+		return 1, 0, append(dst, byte(-delta))
+	case 0 < delta && delta <= 255:
+		return 1, 1, append(dst, byte(delta))
+	default:
+		return 0, 0, binary.BigEndian.AppendUint16(dst, uint16(int16(delta)))
+	}
+}
+
+func minInt16(a, b int16) int16 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt16(a, b int16) int16 {
+	if a > b {
+		return a
+	}
+	return b
+}
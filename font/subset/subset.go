@@ -0,0 +1,222 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+// Package subset builds reduced font files, restricted to a set of glyphs,
+// typically to embed fonts in documents (like PDF files).
+package subset
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+)
+
+// SubsetOptions controls the content of the subset font.
+type SubsetOptions struct {
+	// Kerning retains the pair kerning between the kept glyphs,
+	// as given by [font.Face.KernPair], in a 'kern' table.
+	Kerning bool
+}
+
+// Subset returns a font file containing only the glyphs [gids] of [face],
+// and the '.notdef' glyph, which is always kept as the first glyph.
+//
+// In the subset font, the glyphs are renumbered following the increasing
+// order of their original indices, duplicates being removed.
+// The 'cmap' table is restricted to the runes mapped to the kept glyphs.
+//
+// The outlines are read from the 'glyf' table or the CFF tables and written with the
+// same kind of curves, that is in a 'glyf' table for TrueType fonts, and in a 'CFF ' table
+// otherwise. Composite glyphs are decomposed, so that their components need not
+// be retained, and the hinting instructions are dropped.
+// For variable fonts, the outlines and metrics are resolved at the variation
+// coordinates of [face], and the subset font is a static font.
+//
+// The shaping tables (GSUB, GPOS, GDEF and AAT tables) are not retained, since
+// the subset is meant to draw already shaped glyphs : restricting them to the kept
+// glyphs would require to rewrite every lookup, remapping its coverage and class
+// definition tables, which is not supported. See [SubsetOptions.Kerning] for
+// a way to keep the simple kerning information, including the GPOS pair kerning.
+//
+// An error is returned if one of the glyphs is not in the font, or
+// if the font has no outlines.
+func Subset(face *font.Face, gids []font.GID, opts SubsetOptions) ([]byte, error) {
+	return subset(face, gids, opts, formatAuto)
+}
+
+type outlineFormat uint8
+
+const (
+	formatAuto outlineFormat = iota // use the format of the input font
+	formatGlyf
+	formatCFF
+)
+
+func subset(face *font.Face, gids []font.GID, opts SubsetOptions, format outlineFormat) ([]byte, error) {
+	glyphs, err := newGlyphSet(face, gids)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == formatAuto {
+		format = formatGlyf
+		if glyphs.hasCubics {
+			format = formatCFF
+		}
+	}
+
+	var (
+		out      []ot.Table
+		locaLong bool
+	)
+	if format == formatCFF {
+		glyphs.bounds = inkBounds(glyphs.outlines)
+		out = append(out,
+			ot.Table{Tag: ot.MustNewTag("CFF "), Content: writeCFF(face, glyphs)},
+			ot.Table{Tag: ot.MustNewTag("maxp"), Content: writeMaxpCFF(len(glyphs.gids))},
+		)
+	} else {
+		var glyf, loca, maxp []byte
+		glyf, loca, maxp, glyphs.bounds, locaLong = writeGlyf(glyphs.outlines)
+		out = append(out,
+			ot.Table{Tag: ot.MustNewTag("glyf"), Content: glyf},
+			ot.Table{Tag: ot.MustNewTag("loca"), Content: loca},
+			ot.Table{Tag: ot.MustNewTag("maxp"), Content: maxp},
+		)
+	}
+
+	runes := glyphs.cmap(face)
+	out = append(out,
+		ot.Table{Tag: ot.MustNewTag("cmap"), Content: writeCmap(runes)},
+		ot.Table{Tag: ot.MustNewTag("head"), Content: writeHead(face, glyphs, locaLong)},
+		ot.Table{Tag: ot.MustNewTag("hhea"), Content: writeHhea(face, glyphs)},
+		ot.Table{Tag: ot.MustNewTag("hmtx"), Content: writeHmtx(glyphs)},
+		ot.Table{Tag: ot.MustNewTag("name"), Content: writeName(face)},
+		ot.Table{Tag: ot.MustNewTag("OS/2"), Content: writeOS2(face, glyphs, runes)},
+		ot.Table{Tag: ot.MustNewTag("post"), Content: writePost(face)},
+	)
+	if opts.Kerning {
+		if kern := writeKern(face, glyphs); kern != nil {
+			out = append(out, ot.Table{Tag: ot.MustNewTag("kern"), Content: kern})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Tag < out[j].Tag })
+	if format == formatCFF {
+		return ot.WriteOTF(out), nil
+	}
+	return ot.WriteTTF(out), nil
+}
+
+// glyphSet stores the kept glyphs, in the subset order
+type glyphSet struct {
+	gids     []font.GID // original glyph indices, indexed by new glyph index
+	outlines [][]ot.Segment
+	advances []uint16
+	bounds   []bbox // computed when writing the outlines
+
+	hasCubics bool
+}
+
+// glyph bounding box, in font units
+type bbox struct {
+	xMin, yMin, xMax, yMax int16
+}
+
+func (b bbox) isEmpty() bool { return b == bbox{} }
+
+func newGlyphSet(face *font.Face, gids []font.GID) (glyphSet, error) {
+	sorted := append([]font.GID{0}, gids...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	// remove duplicates
+	unique := sorted[:1]
+	for _, gid := range sorted[1:] {
+		if gid != unique[len(unique)-1] {
+			unique = append(unique, gid)
+		}
+	}
+	// the glyph count, including .notdef, is stored on 16 bits
+	if len(unique) > math.MaxUint16 {
+		return glyphSet{}, errors.New("too many glyphs for a subset font")
+	}
+
+	var out glyphSet
+	hasOutlines := false
+	for _, gid := range unique {
+		data := face.GlyphData(gid)
+		if data == nil {
+			return glyphSet{}, fmt.Errorf("glyph %d not found", gid)
+		}
+		var segments []ot.Segment
+		if outline, ok := data.(font.GlyphOutline); ok {
+			hasOutlines = true
+			segments = outline.Segments
+			for _, seg := range segments {
+				if seg.Op == ot.SegmentOpCubeTo {
+					out.hasCubics = true
+				}
+			}
+		}
+		out.gids = append(out.gids, gid)
+		out.outlines = append(out.outlines, segments)
+		out.advances = append(out.advances, uint16(toInt16(face.HorizontalAdvance(gid))))
+	}
+	if !hasOutlines {
+		return glyphSet{}, errors.New("font has no outlines")
+	}
+	return out, nil
+}
+
+// runeMapping is a 'cmap' entry
+type runeMapping struct {
+	r   rune
+	gid uint16 // new glyph index
+}
+
+// cmap returns the entries of the font 'cmap' mapped to kept glyphs,
+// sorted by rune
+func (gs glyphSet) cmap(face *font.Face) []runeMapping {
+	newGIDs := make(map[font.GID]uint16, len(gs.gids))
+	for i, gid := range gs.gids {
+		newGIDs[gid] = uint16(i)
+	}
+	var out []runeMapping
+	for iter := face.Cmap.Iter(); iter.Next(); {
+		r, gid := iter.Char()
+		if newGID, ok := newGIDs[gid]; ok && gid != 0 {
+			out = append(out, runeMapping{r, newGID})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].r < out[j].r })
+	return out
+}
+
+// inkBounds returns the exact bounds of the outlines,
+// rounded outward
+func inkBounds(outlines [][]ot.Segment) []bbox {
+	out := make([]bbox, len(outlines))
+	for i, segments := range outlines {
+		ext := font.GlyphOutline{Segments: segments}.InkExtents()
+		out[i] = bbox{
+			xMin: toInt16(float32(math.Floor(float64(ext.XBearing)))),
+			yMin: toInt16(float32(math.Floor(float64(ext.YBearing + ext.Height)))),
+			xMax: toInt16(float32(math.Ceil(float64(ext.XBearing + ext.Width)))),
+			yMax: toInt16(float32(math.Ceil(float64(ext.YBearing)))),
+		}
+	}
+	return out
+}
+
+// toInt16 rounds and clamps [v]
+func toInt16(v float32) int16 {
+	v = float32(math.Round(float64(v)))
+	if v > math.MaxInt16 {
+		return math.MaxInt16
+	} else if v < math.MinInt16 {
+		return math.MinInt16
+	}
+	return int16(v)
+}
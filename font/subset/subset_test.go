@@ -0,0 +1,192 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package subset
+
+import (
+	"bytes"
+	"math"
+	"os"
+	"strconv"
+	"testing"
+
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+)
+
+func loadFace(t *testing.T, filename string) *font.Face {
+	t.Helper()
+	f, err := os.Open(filename)
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	face, err := font.ParseTTF(f)
+	tu.AssertNoErr(t, err)
+	return face
+}
+
+func glyphsFor(t *testing.T, face *font.Face, text string) []font.GID {
+	t.Helper()
+	var out []font.GID
+	for _, r := range text {
+		gid, ok := face.NominalGlyph(r)
+		tu.AssertC(t, ok, string(r))
+		out = append(out, gid)
+	}
+	return out
+}
+
+func inkExtents(face *font.Face, gid font.GID) font.GlyphExtents {
+	outline, _ := face.GlyphDataOutline(gid)
+	return outline.InkExtents()
+}
+
+func closeExtents(e1, e2 font.GlyphExtents) bool {
+	const tol = 0.01
+	return math.Abs(float64(e1.XBearing-e2.XBearing)) < tol && math.Abs(float64(e1.YBearing-e2.YBearing)) < tol &&
+		math.Abs(float64(e1.Width-e2.Width)) < tol && math.Abs(float64(e1.Height-e2.Height)) < tol
+}
+
+func testRoundTrip(t *testing.T, face *font.Face, text string, format outlineFormat) *font.Face {
+	t.Helper()
+	gids := glyphsFor(t, face, text)
+	data, err := subset(face, gids, SubsetOptions{}, format)
+	tu.AssertNoErr(t, err)
+
+	sub, err := font.ParseTTF(bytes.NewReader(data))
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, sub.Upem() == face.Upem())
+	tu.Assert(t, sub.Describe().Family == face.Describe().Family)
+
+	// the glyph set is exactly .notdef and the requested (distinct) glyphs
+	distinct := map[font.GID]bool{0: true}
+	for _, gid := range gids {
+		distinct[gid] = true
+	}
+	for gid := font.GID(0); int(gid) < len(distinct); gid++ {
+		tu.Assert(t, sub.GlyphData(gid) != nil)
+	}
+	tu.Assert(t, sub.GlyphData(font.GID(len(distinct))) == nil)
+
+	for _, r := range text {
+		gid, _ := face.NominalGlyph(r)
+		newGID, ok := sub.NominalGlyph(r)
+		tu.AssertC(t, ok, string(r))
+		tu.Assert(t, newGID != 0)
+		tu.Assert(t, sub.HorizontalAdvance(newGID) == face.HorizontalAdvance(gid))
+		tu.AssertC(t, closeExtents(inkExtents(sub, newGID), inkExtents(face, gid)), string(r))
+	}
+	// runes not in the subset are not mapped
+	_, ok := sub.NominalGlyph('z')
+	tu.Assert(t, !ok)
+	return sub
+}
+
+func TestSubsetGlyf(t *testing.T) {
+	face := loadFace(t, "../testdata/Roboto-Regular.ttf")
+	sub := testRoundTrip(t, face, "Hello, wörld !", formatGlyf)
+	_, hasGlyf := sub.GlyphDataOutline(1)
+	tu.Assert(t, hasGlyf)
+
+	// composite glyphs are decomposed
+	testRoundTrip(t, face, "éÀ", formatGlyf)
+
+	face = loadFace(t, "../testdata/Amiri-Regular.ttf")
+	testRoundTrip(t, face, "سماء", formatAuto)
+}
+
+func TestSubsetCFF(t *testing.T) {
+	face := loadFace(t, "../testdata/Roboto-Regular.ttf")
+	sub := testRoundTrip(t, face, "Hello, wörld !", formatCFF)
+	// Roboto has no glyph names : they are generated from the original indices
+	gid, _ := face.NominalGlyph('H')
+	newGID, _ := sub.NominalGlyph('H')
+	tu.Assert(t, sub.GlyphName(newGID) == "gid"+strconv.Itoa(int(gid)))
+	tu.Assert(t, sub.GlyphName(0) == ".notdef")
+}
+
+func TestBareCFF(t *testing.T) {
+	face := loadFace(t, "../testdata/UbuntuMono-R.ttf")
+	text := "Hello, wörld’ «ß»"
+	data, err := subset(face, glyphsFor(t, face, text), SubsetOptions{}, formatCFF)
+	tu.AssertNoErr(t, err)
+	ld, err := ot.NewLoader(bytes.NewReader(data))
+	tu.AssertNoErr(t, err)
+	cff, err := ld.RawTable(ot.MustNewTag("CFF "))
+	tu.AssertNoErr(t, err)
+
+	// the bare CFF font is wrapped, with a 'cmap' table built from the glyph names
+	bare, err := font.ParseTTF(bytes.NewReader(cff))
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, bare.Upem() == face.Upem())
+	tu.Assert(t, bare.Describe().Family != "")
+	for _, r := range text {
+		gid, _ := face.NominalGlyph(r)
+		newGID, ok := bare.NominalGlyph(r)
+		tu.AssertC(t, ok, string(r))
+		tu.Assert(t, bare.GlyphName(newGID) == face.GlyphName(gid))
+		tu.Assert(t, bare.HorizontalAdvance(newGID) == face.HorizontalAdvance(gid))
+		tu.AssertC(t, closeExtents(inkExtents(bare, newGID), inkExtents(face, gid)), string(r))
+	}
+	_, ok := bare.NominalGlyph('z')
+	tu.Assert(t, !ok)
+	// only the standard names are known
+	gids := glyphsFor(t, face, "π")
+	tu.Assert(t, face.GlyphName(gids[0]) == "pi")
+	data, err = subset(face, gids, SubsetOptions{}, formatCFF)
+	tu.AssertNoErr(t, err)
+	ld, err = ot.NewLoader(bytes.NewReader(data))
+	tu.AssertNoErr(t, err)
+	cff, err = ld.RawTable(ot.MustNewTag("CFF "))
+	tu.AssertNoErr(t, err)
+	bare, err = font.ParseTTF(bytes.NewReader(cff))
+	tu.AssertNoErr(t, err)
+	_, ok = bare.NominalGlyph('π')
+	tu.Assert(t, !ok)
+
+	faces, err := font.ParseTTC(bytes.NewReader(cff))
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, len(faces) == 1)
+}
+
+func TestSubsetKerning(t *testing.T) {
+	face := loadFace(t, "../testdata/Roboto-Regular.ttf")
+	gids := glyphsFor(t, face, "AVo")
+	size := fixed.I(int(face.Upem()))
+	kern := face.KernPair(gids[0], gids[1], size)
+	tu.Assert(t, kern != 0)
+
+	for _, kerning := range []bool{false, true} {
+		data, err := Subset(face, gids, SubsetOptions{Kerning: kerning})
+		tu.AssertNoErr(t, err)
+		sub, err := font.ParseTTF(bytes.NewReader(data))
+		tu.AssertNoErr(t, err)
+		a, _ := sub.NominalGlyph('A')
+		v, _ := sub.NominalGlyph('V')
+		if kerning {
+			tu.Assert(t, sub.KernPair(a, v, size) == kern)
+		} else {
+			tu.Assert(t, sub.KernPair(a, v, size) == 0)
+		}
+	}
+}
+
+func TestSubsetInvalid(t *testing.T) {
+	face := loadFace(t, "../testdata/Roboto-Regular.ttf")
+	_, err := Subset(face, []font.GID{0xFFFF}, SubsetOptions{})
+	tu.Assert(t, err != nil)
+}
+
+func TestSubsetDuplicates(t *testing.T) {
+	face := loadFace(t, "../testdata/Roboto-Regular.ttf")
+	// the limit on the glyph count applies after removing duplicates
+	gids := make([]font.GID, math.MaxUint16+10)
+	for i := range gids {
+		gids[i] = font.GID(1 + i%2)
+	}
+	data, err := Subset(face, gids, SubsetOptions{})
+	tu.AssertNoErr(t, err)
+	sub, err := font.ParseTTF(bytes.NewReader(data))
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, sub.GlyphData(2) != nil && sub.GlyphData(3) == nil)
+}
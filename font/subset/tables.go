@@ -0,0 +1,436 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package subset
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"unicode/utf16"
+
+	"github.com/go-text/typesetting/font"
+	"golang.org/x/image/math/fixed"
+)
+
+// head flags : baseline at y=0, left sidebearing point at x=0,
+// integer scaling
+const headFlags = 1<<0 | 1<<1 | 1<<3
+
+// macStyle and fsSelection bits
+const (
+	macStyleBold   = 1 << 0
+	macStyleItalic = 1 << 1
+
+	fsSelectionItalic        = 1 << 0
+	fsSelectionBold          = 1 << 5
+	fsSelectionRegular       = 1 << 6
+	fsSelectionUseTypoMetric = 1 << 7
+)
+
+func unionBox(b1, b2 bbox) bbox {
+	if b1.isEmpty() {
+		return b2
+	} else if b2.isEmpty() {
+		return b1
+	}
+	return bbox{
+		minInt16(b1.xMin, b2.xMin), minInt16(b1.yMin, b2.yMin),
+		maxInt16(b1.xMax, b2.xMax), maxInt16(b1.yMax, b2.yMax),
+	}
+}
+
+// subfamilyName returns the style name used in the 'name' table
+func subfamilyName(aspect font.Aspect) string {
+	bold, italic := aspect.Weight >= font.WeightBold, aspect.Style == font.StyleItalic
+	switch {
+	case bold && italic:
+		return "Bold Italic"
+	case bold:
+		return "Bold"
+	case italic:
+		return "Italic"
+	default:
+		return "Regular"
+	}
+}
+
+func writeHead(face *font.Face, glyphs glyphSet, locaLong bool) []byte {
+	var box bbox
+	for _, b := range glyphs.bounds {
+		box = unionBox(box, b)
+	}
+	aspect := face.Describe().Aspect
+	var macStyle uint16
+	if aspect.Weight >= font.WeightBold {
+		macStyle |= macStyleBold
+	}
+	if aspect.Style == font.StyleItalic {
+		macStyle |= macStyleItalic
+	}
+
+	out := make([]byte, 54)
+	binary.BigEndian.PutUint32(out, 0x00010000)     // version
+	binary.BigEndian.PutUint32(out[4:], 0x00010000) // fontRevision
+	binary.BigEndian.PutUint32(out[12:], 0x5F0F3CF5)
+	binary.BigEndian.PutUint16(out[16:], headFlags)
+	binary.BigEndian.PutUint16(out[18:], face.Upem())
+	// created and modified dates are left to zero
+	binary.BigEndian.PutUint16(out[36:], uint16(box.xMin))
+	binary.BigEndian.PutUint16(out[38:], uint16(box.yMin))
+	binary.BigEndian.PutUint16(out[40:], uint16(box.xMax))
+	binary.BigEndian.PutUint16(out[42:], uint16(box.yMax))
+	binary.BigEndian.PutUint16(out[44:], macStyle)
+	binary.BigEndian.PutUint16(out[46:], 8) // lowestRecPPEM
+	binary.BigEndian.PutUint16(out[48:], 2) // fontDirectionHint
+	if locaLong {
+		binary.BigEndian.PutUint16(out[50:], 1)
+	}
+	return out
+}
+
+func writeHhea(face *font.Face, glyphs glyphSet) []byte {
+	extents, _ := face.FontHExtents()
+	var (
+		advanceMax                uint16
+		minLsb, minRsb, maxExtent int16
+		started                   bool
+	)
+	for i, b := range glyphs.bounds {
+		if advance := glyphs.advances[i]; advance > advanceMax {
+			advanceMax = advance
+		}
+		if b.isEmpty() {
+			continue
+		}
+		rsb := toInt16(float32(glyphs.advances[i]) - float32(b.xMax))
+		if !started {
+			minLsb, minRsb, maxExtent = b.xMin, rsb, b.xMax
+			started = true
+			continue
+		}
+		minLsb, minRsb, maxExtent = minInt16(minLsb, b.xMin), minInt16(minRsb, rsb), maxInt16(maxExtent, b.xMax)
+	}
+
+	out := make([]byte, 36)
+	binary.BigEndian.PutUint32(out, 0x00010000)
+	binary.BigEndian.PutUint16(out[4:], uint16(toInt16(extents.Ascender)))
+	binary.BigEndian.PutUint16(out[6:], uint16(toInt16(extents.Descender)))
+	binary.BigEndian.PutUint16(out[8:], uint16(toInt16(extents.LineGap)))
+	binary.BigEndian.PutUint16(out[10:], advanceMax)
+	binary.BigEndian.PutUint16(out[12:], uint16(minLsb))
+	binary.BigEndian.PutUint16(out[14:], uint16(minRsb))
+	binary.BigEndian.PutUint16(out[16:], uint16(maxExtent))
+	binary.BigEndian.PutUint16(out[18:], 1) // caretSlopeRise
+	binary.BigEndian.PutUint16(out[34:], uint16(len(glyphs.advances)))
+	return out
+}
+
+func writeHmtx(glyphs glyphSet) []byte {
+	out := make([]byte, 0, 4*len(glyphs.advances))
+	for i, advance := range glyphs.advances {
+		out = binary.BigEndian.AppendUint16(out, advance)
+		out = binary.BigEndian.AppendUint16(out, uint16(glyphs.bounds[i].xMin))
+	}
+	return out
+}
+
+// writeName writes the family, subfamily, full name and PostScript name,
+// for the Windows platform
+func writeName(face *font.Face) []byte {
+	desc := face.Describe()
+	subfamily := subfamilyName(desc.Aspect)
+	records := [...]struct {
+		nameID uint16
+		value  string
+	}{
+		{1, desc.Family},
+		{2, subfamily},
+		{4, desc.Family + " " + subfamily},
+		{6, postscriptName(desc)},
+	}
+
+	const headerSize, recordSize = 6, 12
+	out := make([]byte, headerSize+len(records)*recordSize)
+	binary.BigEndian.PutUint16(out[2:], uint16(len(records)))
+	binary.BigEndian.PutUint16(out[4:], uint16(len(out)))
+	var storage []byte
+	for i, record := range records {
+		start := len(storage)
+		for _, u := range utf16.Encode([]rune(record.value)) {
+			storage = binary.BigEndian.AppendUint16(storage, u)
+		}
+		slice := out[headerSize+i*recordSize:]
+		binary.BigEndian.PutUint16(slice, 3)          // platformID : Windows
+		binary.BigEndian.PutUint16(slice[2:], 1)      // encodingID : Unicode BMP
+		binary.BigEndian.PutUint16(slice[4:], 0x0409) // languageID : English (US)
+		binary.BigEndian.PutUint16(slice[6:], record.nameID)
+		binary.BigEndian.PutUint16(slice[8:], uint16(len(storage)-start))
+		binary.BigEndian.PutUint16(slice[10:], uint16(start))
+	}
+	return append(out, storage...)
+}
+
+// writeOS2 returns a version 4 'OS/2' table
+func writeOS2(face *font.Face, glyphs glyphSet, runes []runeMapping) []byte {
+	desc := face.Describe()
+	extents, _ := face.FontHExtents()
+	metric := func(m font.LineMetric) uint16 { return uint16(toInt16(face.LineMetric(m))) }
+
+	var box bbox
+	var sum, count int
+	for i, b := range glyphs.bounds {
+		box = unionBox(box, b)
+		if advance := glyphs.advances[i]; advance != 0 {
+			sum += int(advance)
+			count++
+		}
+	}
+	var avgWidth int
+	if count != 0 {
+		avgWidth = sum / count
+	}
+
+	var fsSelection uint16 = fsSelectionUseTypoMetric
+	if desc.Aspect.Style == font.StyleItalic {
+		fsSelection |= fsSelectionItalic
+	}
+	if desc.Aspect.Weight >= font.WeightBold {
+		fsSelection |= fsSelectionBold
+	}
+	if fsSelection&(fsSelectionItalic|fsSelectionBold) == 0 {
+		fsSelection |= fsSelectionRegular
+	}
+
+	var firstChar, lastChar uint16
+	if len(runes) != 0 {
+		firstChar, lastChar = 0xFFFF, 0xFFFF
+		if r := runes[0].r; r < 0xFFFF {
+			firstChar = uint16(r)
+		}
+		if r := runes[len(runes)-1].r; r < 0xFFFF {
+			lastChar = uint16(r)
+		}
+	}
+
+	// width class, from 1 (ultra condensed) to 9 (ultra expanded)
+	widthClass := uint16(5)
+	for i, stretch := range [...]font.Stretch{
+		font.StretchUltraCondensed, font.StretchExtraCondensed, font.StretchCondensed, font.StretchSemiCondensed,
+		font.StretchNormal, font.StretchSemiExpanded, font.StretchExpanded, font.StretchExtraExpanded,
+	} {
+		if desc.Aspect.Stretch <= stretch {
+			widthClass = uint16(i + 1)
+			break
+		}
+		widthClass = 9
+	}
+
+	ascender, descender := toInt16(extents.Ascender), toInt16(extents.Descender)
+	winAscent, winDescent := maxInt16(ascender, box.yMax), maxInt16(-descender, -box.yMin)
+
+	out := make([]byte, 96)
+	binary.BigEndian.PutUint16(out, 4) // version
+	binary.BigEndian.PutUint16(out[2:], uint16(avgWidth))
+	binary.BigEndian.PutUint16(out[4:], uint16(math.Round(float64(desc.Aspect.Weight))))
+	binary.BigEndian.PutUint16(out[6:], widthClass)
+	binary.BigEndian.PutUint16(out[8:], uint16(face.EmbedPermission()))
+	binary.BigEndian.PutUint16(out[10:], metric(font.SubscriptEmYSize)) // ySubscriptXSize
+	binary.BigEndian.PutUint16(out[12:], metric(font.SubscriptEmYSize))
+	binary.BigEndian.PutUint16(out[14:], metric(font.SubscriptEmXOffset))
+	binary.BigEndian.PutUint16(out[16:], metric(font.SubscriptEmYOffset))
+	binary.BigEndian.PutUint16(out[18:], metric(font.SuperscriptEmYSize)) // ySuperscriptXSize
+	binary.BigEndian.PutUint16(out[20:], metric(font.SuperscriptEmYSize))
+	binary.BigEndian.PutUint16(out[22:], metric(font.SuperscriptEmXOffset))
+	binary.BigEndian.PutUint16(out[26:], metric(font.StrikethroughThickness))
+	binary.BigEndian.PutUint16(out[28:], metric(font.StrikethroughPosition))
+	// sFamilyClass, panose and ulUnicodeRange are left to zero
+	copy(out[58:], "NONE") // achVendID
+	binary.BigEndian.PutUint16(out[62:], fsSelection)
+	binary.BigEndian.PutUint16(out[64:], firstChar)
+	binary.BigEndian.PutUint16(out[66:], lastChar)
+	binary.BigEndian.PutUint16(out[68:], uint16(ascender))
+	binary.BigEndian.PutUint16(out[70:], uint16(descender))
+	binary.BigEndian.PutUint16(out[72:], uint16(toInt16(extents.LineGap)))
+	binary.BigEndian.PutUint16(out[74:], uint16(winAscent))
+	binary.BigEndian.PutUint16(out[76:], uint16(winDescent))
+	// ulCodePageRange are left to zero
+	binary.BigEndian.PutUint16(out[86:], metric(font.XHeight))
+	binary.BigEndian.PutUint16(out[88:], metric(font.CapHeight))
+	binary.BigEndian.PutUint16(out[92:], ' ') // usBreakChar
+	return out
+}
+
+// writePost returns a version 3 'post' table, without glyph names
+func writePost(face *font.Face) []byte {
+	out := make([]byte, 32)
+	binary.BigEndian.PutUint32(out, 0x00030000)
+	binary.BigEndian.PutUint16(out[8:], uint16(toInt16(face.LineMetric(font.UnderlinePosition))))
+	binary.BigEndian.PutUint16(out[10:], uint16(toInt16(face.LineMetric(font.UnderlineThickness))))
+	if face.IsMonospace() {
+		binary.BigEndian.PutUint32(out[12:], 1)
+	}
+	return out
+}
+
+// writeCmap returns a 'cmap' table with a format 4 subtable for the BMP,
+// and a format 12 subtable if [runes] has supplementary characters.
+func writeCmap(runes []runeMapping) []byte {
+	// groups of consecutive runes mapped to consecutive glyphs
+	type group struct {
+		start, end rune
+		gid        uint16
+	}
+	var groups []group
+	for _, m := range runes {
+		if L := len(groups); L != 0 {
+			if last := &groups[L-1]; last.end+1 == m.r && rune(last.gid)+(m.r-last.start) == rune(m.gid) {
+				last.end = m.r
+				continue
+			}
+		}
+		groups = append(groups, group{m.r, m.r, m.gid})
+	}
+
+	// format 4 : BMP only, split at 0xFFFF
+	var bmp []group
+	for _, g := range groups {
+		if g.start >= 0xFFFF {
+			break
+		}
+		if g.end >= 0xFFFF {
+			g.end = 0xFFFE
+		}
+		bmp = append(bmp, g)
+	}
+	bmp = append(bmp, group{0xFFFF, 0xFFFF, 0}) // required last segment
+
+	var subtables [][]byte
+	segCountX2 := 2 * len(bmp)
+	format4Length := 16 + 4*segCountX2
+	hasFormat4 := format4Length <= math.MaxUint16
+	if hasFormat4 {
+		format4 := make([]byte, 14, format4Length)
+		binary.BigEndian.PutUint16(format4, 4)
+		binary.BigEndian.PutUint16(format4[2:], uint16(format4Length))
+		binary.BigEndian.PutUint16(format4[6:], uint16(segCountX2))
+		searchRange, entrySelector := 2, 0
+		for searchRange*2 <= segCountX2 {
+			searchRange *= 2
+			entrySelector++
+		}
+		binary.BigEndian.PutUint16(format4[8:], uint16(searchRange))
+		binary.BigEndian.PutUint16(format4[10:], uint16(entrySelector))
+		binary.BigEndian.PutUint16(format4[12:], uint16(segCountX2-searchRange))
+		for _, g := range bmp {
+			format4 = binary.BigEndian.AppendUint16(format4, uint16(g.end))
+		}
+		format4 = append(format4, 0, 0) // reservedPad
+		for _, g := range bmp {
+			format4 = binary.BigEndian.AppendUint16(format4, uint16(g.start))
+		}
+		for _, g := range bmp {
+			delta := uint16(g.gid) - uint16(g.start) // modulo 65536
+			if g.start == 0xFFFF {
+				delta = 1
+			}
+			format4 = binary.BigEndian.AppendUint16(format4, delta)
+		}
+		for range bmp {
+			format4 = append(format4, 0, 0) // idRangeOffset
+		}
+		subtables = append(subtables, format4)
+	}
+
+	hasFormat12 := !hasFormat4 || (len(runes) != 0 && runes[len(runes)-1].r > 0xFFFF)
+	if hasFormat12 {
+		format12 := make([]byte, 16, 16+12*len(groups))
+		binary.BigEndian.PutUint16(format12, 12)
+		binary.BigEndian.PutUint32(format12[4:], uint32(16+12*len(groups)))
+		binary.BigEndian.PutUint32(format12[12:], uint32(len(groups)))
+		for _, g := range groups {
+			format12 = binary.BigEndian.AppendUint32(format12, uint32(g.start))
+			format12 = binary.BigEndian.AppendUint32(format12, uint32(g.end))
+			format12 = binary.BigEndian.AppendUint32(format12, uint32(g.gid))
+		}
+		subtables = append(subtables, format12)
+	}
+
+	// encoding records : (3, 1) for format 4 and (3, 10) for format 12
+	out := make([]byte, 4, 4+8*len(subtables))
+	binary.BigEndian.PutUint16(out[2:], uint16(len(subtables)))
+	offset := 4 + 8*len(subtables)
+	for i, subtable := range subtables {
+		encodingID := uint16(1)
+		if i == 1 || !hasFormat4 {
+			encodingID = 10
+		}
+		out = binary.BigEndian.AppendUint16(out, 3)
+		out = binary.BigEndian.AppendUint16(out, encodingID)
+		out = binary.BigEndian.AppendUint32(out, uint32(offset))
+		offset += len(subtable)
+	}
+	for _, subtable := range subtables {
+		out = append(out, subtable...)
+	}
+	return out
+}
+
+// writeKern returns a version 0 'kern' table with the kerning pairs
+// between the kept glyphs, or nil if there are none
+func writeKern(face *font.Face, glyphs glyphSet) []byte {
+	type pair struct {
+		left, right uint16
+		value       int16
+	}
+	size := fixed.Int26_6(face.Upem()) << 6 // so that the values are in font units
+	var pairs []pair
+	for i, left := range glyphs.gids {
+		for j, right := range glyphs.gids {
+			if v := face.KernPair(left, right, size); v != 0 {
+				pairs = append(pairs, pair{uint16(i), uint16(j), toInt16(float32(v) / 64)})
+			}
+		}
+	}
+	if len(pairs) == 0 {
+		return nil
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].left != pairs[j].left {
+			return pairs[i].left < pairs[j].left
+		}
+		return pairs[i].right < pairs[j].right
+	})
+
+	// the subtable length is stored on 16 bits : use
+	// several subtables if needed, whose values are added
+	const maxPairs = (math.MaxUint16 - 14) / 6
+	var chunks [][]pair
+	for len(pairs) > maxPairs {
+		chunks = append(chunks, pairs[:maxPairs])
+		pairs = pairs[maxPairs:]
+	}
+	chunks = append(chunks, pairs)
+
+	out := binary.BigEndian.AppendUint16(nil, 0) // version
+	out = binary.BigEndian.AppendUint16(out, uint16(len(chunks)))
+	for _, chunk := range chunks {
+		searchRange, entrySelector := 1, 0
+		for searchRange*2 <= len(chunk) {
+			searchRange *= 2
+			entrySelector++
+		}
+		out = binary.BigEndian.AppendUint16(out, 0) // version
+		out = binary.BigEndian.AppendUint16(out, uint16(14+6*len(chunk)))
+		out = binary.BigEndian.AppendUint16(out, 0x0001) // format 0, horizontal
+		out = binary.BigEndian.AppendUint16(out, uint16(len(chunk)))
+		out = binary.BigEndian.AppendUint16(out, uint16(searchRange*6))
+		out = binary.BigEndian.AppendUint16(out, uint16(entrySelector))
+		out = binary.BigEndian.AppendUint16(out, uint16((len(chunk)-searchRange)*6))
+		for _, p := range chunk {
+			out = binary.BigEndian.AppendUint16(out, p.left)
+			out = binary.BigEndian.AppendUint16(out, p.right)
+			out = binary.BigEndian.AppendUint16(out, uint16(p.value))
+		}
+	}
+	return out
+}
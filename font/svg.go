@@ -38,6 +38,12 @@ type svgDocument struct {
 
 // rawGlyphData returns the SVG document for [gid], or false.
 func (s svg) rawGlyphData(gid gID) ([]byte, bool) {
+	entry, ok := s.document(gid)
+	return entry.svg, ok
+}
+
+// document returns the entry covering [gid], or false.
+func (s svg) document(gid gID) (svgDocument, bool) {
 	// binary search
 	for i, j := 0, len(s); i < j; {
 		h := i + (j-i)/2
@@ -47,8 +53,8 @@ func (s svg) rawGlyphData(gid gID) ([]byte, bool) {
 		} else if entry.last < gid {
 			i = h + 1
 		} else {
-			return entry.svg, true
+			return entry, true
 		}
 	}
-	return nil, false
+	return svgDocument{}, false
 }
@@ -0,0 +1,118 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package font
+
+import (
+	"fmt"
+
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
+)
+
+// FaceWithTableOverride returns a new [Face], using [data] as the content
+// of the table [tag], instead of the one found in the font file of [base].
+// All the other tables are shared with [base], and the settings of [base]
+// (variation coordinates and ppem) are copied.
+//
+// This is mainly useful to isolate table-specific behavior, for instance when
+// debugging shaping issues, without having to build a whole font file.
+//
+// Only the tables which may be parsed independently are supported, that is
+// 'cmap', 'GSUB', 'GPOS', 'GDEF', 'morx', 'kerx', 'kern', 'ankr', 'trak', 'feat', 'ltag',
+// 'post', 'name', 'STAT' and 'SVG '.
+// An error is returned for other tables, or if [data] is invalid.
+func FaceWithTableOverride(base *Face, tag Tag, data []byte) (*Face, error) {
+	ft := *base.Font // shallow copy : the other tables are shared
+	if err := ft.overrideTable(tag, data); err != nil {
+		return nil, err
+	}
+
+	out := NewFace(&ft)
+	out.coords = append([]tables.Coord(nil), base.coords...)
+	out.xPpem, out.yPpem = base.xPpem, base.yPpem
+	return out, nil
+}
+
+// overrideTable parses [data] and replaces the table [tag]
+func (f *Font) overrideTable(tag Tag, data []byte) error {
+	var err error
+	switch tag {
+	case ot.MustNewTag("cmap"):
+		var cmap tables.Cmap
+		cmap, _, err = tables.ParseCmap(data)
+		if err != nil {
+			break
+		}
+		f.Cmap, f.cmapVar, f.cmapEncoding, err = processCmap(cmap, tables.FPNone)
+	case ot.MustNewTag("GSUB"):
+		var layout tables.Layout
+		layout, _, err = tables.ParseLayout(data)
+		if err != nil {
+			break
+		}
+		f.GSUB, err = newGSUB(layout)
+	case ot.MustNewTag("GPOS"):
+		var layout tables.Layout
+		layout, _, err = tables.ParseLayout(data)
+		if err != nil {
+			break
+		}
+		f.GPOS, err = newGPOS(layout)
+	case ot.MustNewTag("GDEF"):
+		var gdef tables.GDEF
+		gdef, _, err = tables.ParseGDEF(data)
+		if err != nil {
+			break
+		}
+		if err = sanitizeGDEF(gdef, len(f.fvar)); err != nil {
+			break
+		}
+		f.GDEF = gdef
+	case ot.MustNewTag("morx"):
+		var morx tables.Morx
+		morx, _, err = tables.ParseMorx(data, f.nGlyphs)
+		f.Morx = newMorx(morx)
+	case ot.MustNewTag("kerx"):
+		var kerx tables.Kerx
+		kerx, _, err = tables.ParseKerx(data, f.nGlyphs)
+		f.Kerx = newKernxFromKerx(kerx)
+	case ot.MustNewTag("kern"):
+		var kern tables.Kern
+		kern, _, err = tables.ParseKern(data)
+		f.Kern = newKernxFromKern(kern)
+	case ot.MustNewTag("ankr"):
+		f.Ankr, _, err = tables.ParseAnkr(data, f.nGlyphs)
+	case ot.MustNewTag("trak"):
+		f.Trak, _, err = tables.ParseTrak(data)
+	case ot.MustNewTag("feat"):
+		f.Feat, _, err = tables.ParseFeat(data)
+	case ot.MustNewTag("ltag"):
+		f.Ltag, _, err = tables.ParseLtag(data)
+	case ot.MustNewTag("post"):
+		var post tables.Post
+		post, _, err = tables.ParsePost(data)
+		if err != nil {
+			break
+		}
+		f.post, err = newPost(post)
+	case ot.MustNewTag("name"):
+		f.names, _, err = tables.ParseName(data)
+	case ot.MustNewTag("STAT"):
+		var stat STAT
+		stat, _, err = tables.ParseSTAT(data)
+		f.STAT = &stat
+	case ot.MustNewTag("SVG "):
+		var svg tables.SVG
+		svg, _, err = tables.ParseSVG(data)
+		if err != nil {
+			break
+		}
+		f.svg, err = newSvg(svg)
+	default:
+		return fmt.Errorf("overriding table %s is not supported", tag)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid table %s: %s", tag, err)
+	}
+	return nil
+}
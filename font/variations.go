@@ -520,6 +520,59 @@ func (face *Face) SetVariations(variations []Variation) {
 	face.SetCoords(face.NormalizeVariations(designCoords))
 }
 
+// WithVariations returns a new [Face] sharing the [Font] of [face] (and its ppem),
+// with the given variation settings applied as by [Face.SetVariations].
+//
+// The returned face has its own caches, filled when the glyphs are used,
+// so that reusing it (for instance to shape many runs at the same instance)
+// avoids resolving the variations again. Since faces are not safe for concurrent use,
+// this is also the way to use several instances of the same font, or the same
+// instance, in different goroutines : the underlying [Font] is shared.
+func (face *Face) WithVariations(variations []Variation) *Face {
+	out := NewFace(face.Font)
+	out.xPpem, out.yPpem = face.xPpem, face.yPpem
+	out.SetVariations(variations)
+	return out
+}
+
+// Variations returns the current variation settings of the face,
+// with one entry per axis of the 'fvar' table, in design units.
+// The axis default values are used when no coordinates are set.
+// It returns nil for non variable fonts.
+//
+// Since the coordinates are stored in normalized units, the returned
+// values may slightly differ from the ones passed to [Face.SetVariations].
+func (face *Face) Variations() []Variation {
+	fv := face.Font.fvar
+	if len(fv) == 0 {
+		return nil
+	}
+	out := make([]Variation, len(fv))
+	for i, axis := range fv {
+		out[i] = Variation{Tag: axis.Tag, Value: axis.Default}
+		if i >= len(face.coords) {
+			continue
+		}
+		coord := face.coords[i]
+		// revert the 'avar' mapping, if any
+		if i < len(face.Font.avar.AxisSegmentMaps) {
+			coord = invertSegmentMaps(face.Font.avar.AxisSegmentMaps[i]).Map(coord)
+		}
+		out[i].Value = denormalizeCoordinate(axis, coord)
+	}
+	return out
+}
+
+// invertSegmentMaps returns the reverse mapping of [sm],
+// which is expected to be increasing.
+func invertSegmentMaps(sm tables.SegmentMaps) tables.SegmentMaps {
+	inverse := make([]tables.AxisValueMap, len(sm.AxisValueMaps))
+	for i, m := range sm.AxisValueMaps {
+		inverse[i] = tables.AxisValueMap{FromCoordinate: m.ToCoordinate, ToCoordinate: m.FromCoordinate}
+	}
+	return tables.SegmentMaps{AxisValueMaps: inverse}
+}
+
 // getDesignCoordsDefault returns the design coordinates corresponding to the given pairs of axis/value.
 // The default value of the axis is used when not specified in the variations.
 func (fv fvar) getDesignCoordsDefault(variations []Variation) []float32 {
@@ -573,6 +626,18 @@ func (fv fvar) normalizeCoordinates(coords []float32) []VarCoord {
 	return normalized
 }
 
+// denormalizeCoordinate is the inverse of the default normalization,
+// mapping [-1,0,1] to the [min,def,max] values of the axis.
+func denormalizeCoordinate(a tables.VariationAxisRecord, coord VarCoord) float32 {
+	c := float32(coord) / 16384 // 1 << 14
+	if c < 0 {
+		return a.Default + c*(a.Default-a.Minimum)
+	} else if c > 0 {
+		return a.Default + c*(a.Maximum-a.Default)
+	}
+	return a.Default
+}
+
 // NormalizeVariations normalize the given design-space coordinates. The minimum and maximum
 // values for the axis are mapped to the interval [-1,1], with the default
 // axis value mapped to 0.
@@ -587,8 +652,12 @@ func (f *Font) NormalizeVariations(coords []float32) []VarCoord {
 	// Then, if there's an `avar' table, we renormalize this range.
 	normalized := f.fvar.normalizeCoordinates(coords)
 
-	// now applying 'avar'
+	// now applying 'avar', ignoring extra segment maps
+	// found in invalid fonts
 	for i, av := range f.avar.AxisSegmentMaps {
+		if i >= len(normalized) {
+			break
+		}
 		normalized[i] = av.Map(normalized[i])
 	}
 
@@ -169,6 +169,129 @@ func TestAdvanceNoHVar(t *testing.T) {
 	}
 }
 
+func TestAdvanceHVarInstances(t *testing.T) {
+	f, err := os.Open("testdata/Selawik-VF-Subset.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, face.hvar != nil)
+
+	wght := ot.MustNewTag("wght")
+	advances := func(weight float32) (out []float32) {
+		face.SetVariations([]Variation{{wght, weight}})
+		for _, r := range "abcH" {
+			gid, _ := face.NominalGlyph(r)
+			out = append(out, face.HorizontalAdvance(gid))
+		}
+		return out
+	}
+	regular := advances(400)
+	light, bold := advances(100), advances(900)
+	for i := range regular {
+		tu.Assert(t, light[i] < regular[i] && regular[i] < bold[i])
+	}
+	// the default instance uses the 'hmtx' values
+	face.SetVariations(nil)
+	gid, _ := face.NominalGlyph('a')
+	tu.Assert(t, face.HorizontalAdvance(gid) == float32(face.getBaseAdvance(gID(gid), face.hmtx, false)))
+}
+
+func TestAvar(t *testing.T) {
+	f, err := os.Open("testdata/Selawik-VF-Subset.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+	ft := face.Font
+	tu.Assert(t, len(ft.avar.AxisSegmentMaps) == 1)
+
+	// wght axis : 300, 400, 700 with the avar mapping 0.6667 -> 0.44
+	for _, test := range []struct {
+		design           float32
+		linear, withAvar VarCoord
+	}{
+		{300, -16384, -16384},
+		{350, -8192, -8192},
+		{400, 0, 0},
+		{500, 5461, 3604},
+		{600, 10923, 7209},
+		{700, 16384, 16384},
+	} {
+		tu.Assert(t, ft.fvar.normalizeCoordinates([]float32{test.design})[0] == test.linear)
+		tu.Assert(t, ft.NormalizeVariations([]float32{test.design})[0] == test.withAvar)
+
+		face.SetVariations([]Variation{{ot.MustNewTag("wght"), test.design}})
+		tu.Assert(t, face.Coords()[0] == test.withAvar)
+	}
+
+	// invalid avar with too many axis are ignored
+	ft.avar.AxisSegmentMaps = append(ft.avar.AxisSegmentMaps, ft.avar.AxisSegmentMaps[0])
+	tu.Assert(t, ft.NormalizeVariations([]float32{500})[0] == 3604)
+}
+
+func TestFaceVariations(t *testing.T) {
+	f, err := os.Open("testdata/Selawik-VF-Subset.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+
+	wght := ot.MustNewTag("wght")
+	// default values
+	tu.Assert(t, reflect.DeepEqual(face.Variations(), []Variation{{wght, 400}}))
+
+	// round trip, through the avar mapping
+	for _, design := range []float32{300, 350, 400, 500, 600, 700} {
+		face.SetVariations([]Variation{{wght, design}})
+		got := face.Variations()
+		tu.Assert(t, len(got) == 1 && got[0].Tag == wght)
+		tu.AssertC(t, math.Abs(float64(got[0].Value-design)) < 0.1, fmt.Sprintf("%g != %g", got[0].Value, design))
+	}
+
+	// values are clamped
+	face.SetVariations([]Variation{{wght, 900}})
+	tu.Assert(t, reflect.DeepEqual(face.Variations(), []Variation{{wght, 700}}))
+
+	face.SetVariations(nil)
+	tu.Assert(t, reflect.DeepEqual(face.Variations(), []Variation{{wght, 400}}))
+
+	// non variable font
+	f2, err := os.Open("testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f2.Close()
+	face, err = ParseTTF(f2)
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, face.Variations() == nil)
+}
+
+func TestFaceWithVariations(t *testing.T) {
+	f, err := os.Open("testdata/Selawik-VF-Subset.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+
+	face, err := ParseTTF(f)
+	tu.AssertNoErr(t, err)
+	face.SetPpem(12, 12)
+
+	wght := ot.MustNewTag("wght")
+	bold := face.WithVariations([]Variation{{wght, 700}})
+	tu.Assert(t, bold != face && bold.Font == face.Font)
+	tu.Assert(t, reflect.DeepEqual(bold.Variations(), []Variation{{wght, 700}}))
+	x, y := bold.Ppem()
+	tu.Assert(t, x == 12 && y == 12)
+	// the original face is not modified
+	tu.Assert(t, len(face.Coords()) == 0)
+
+	gid, _ := face.NominalGlyph('a')
+	tu.Assert(t, bold.HorizontalAdvance(gid) > face.HorizontalAdvance(gid))
+	face.SetVariations([]Variation{{wght, 700}})
+	tu.Assert(t, bold.HorizontalAdvance(gid) == face.HorizontalAdvance(gid))
+}
+
 func TestInvalidGVAR(t *testing.T) {
 	// this file is build by subsetting the 'glyf' table
 	// but keeping the variations tables
@@ -1,13 +1,20 @@
 package fontscan
 
 import (
+	"context"
+	"encoding/binary"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"math"
 	"path/filepath"
+	"sort"
 	"sync"
+	"unicode"
 
 	"github.com/go-text/typesetting/font"
 	ot "github.com/go-text/typesetting/font/opentype"
+	ucd "github.com/go-text/typesetting/internal/unicodedata"
 	"github.com/go-text/typesetting/language"
 )
 
@@ -36,7 +43,7 @@ func SystemFonts(logger Logger, cacheDir string) ([]Footprint, error) {
 	}
 
 	// safe for concurrent use; subsequent calls are no-ops
-	err := initSystemFonts(logger, cacheDir)
+	err := initSystemFonts(logger, cacheDir, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -50,7 +57,7 @@ func SystemFonts(logger Logger, cacheDir string) ([]Footprint, error) {
 // rules.
 //
 // Note that [FontMap] is NOT safe for concurrent use, but several font maps may coexist
-// in an application.
+// in an application. See also [FontMap.Snapshot] for a concurrency-safe alternative.
 //
 // [FontMap] is mainly designed to work with an index built by scanning the system fonts :
 // see [UseSystemFonts] for more details.
@@ -60,6 +67,9 @@ type FontMap struct {
 	firstFace *font.Face
 	faceCache map[Location]*font.Face
 	metaCache map[*font.Font]cacheEntry
+	// faces of variable fonts configured to an exact weight,
+	// see [Query.PreferVariableInstance]; lazily created
+	weightCache map[weightedLocation]*font.Face
 
 	// the database to query, either loaded from an index
 	// or populated with the [UseSystemFonts], [AddFont], and/or [AddFace] method.
@@ -79,6 +89,38 @@ type FontMap struct {
 
 	query  Query           // current query
 	script language.Script // current script
+
+	// faces used as last resort, see [AddLastResortFace]
+	lastResorts []lastResortFace
+	// face used when no font supports a rune, see [SetLastResortFace]
+	tofuFace *font.Face
+
+	// the face returned by [arbitraryFace], lazily computed and
+	// reset when the database is modified
+	arbitrary      *font.Face
+	arbitraryValid bool
+
+	// preferred (normalized) families for the script coverage step,
+	// see [SetScriptFallback]. The map is replaced, not modified in place.
+	scriptFallbacks map[language.Script][]string
+
+	// faces loaded in the background by [PreloadCandidates],
+	// not yet moved to [faceCache]. A nil value means
+	// the face is being loaded.
+	preloaded   map[Location]*font.Face
+	preloadedMu sync.Mutex
+
+	// the errors reported by the last scan, see [ScanErrors]
+	scanErrors []ScanError
+
+	// the paths of the user provided fonts restored by [Deserialize],
+	// indexed by file ID. The map is replaced, not modified in place.
+	filePaths map[string]string
+}
+
+type lastResortFace struct {
+	face    *font.Face
+	scripts []language.Script // empty for all scripts
 }
 
 // NewFontMap return a new font map, which should be filled with the `UseSystemFonts`
@@ -110,6 +152,8 @@ func (fm *FontMap) SetRuneCacheSize(size int) {
 // UseSystemFonts loads the system fonts and adds them to the font map.
 //
 // The first call of this method trigger a rather long scan.
+// The font files and directories which can't be read are skipped
+// (see [FontMap.ScanErrors]) : an error is only returned if no valid font is found.
 // A per-application on-disk cache is used to speed up subsequent initialisations.
 // Callers can provide an appropriate directory path within which this cache may be
 // stored. If the empty string is provided, the FontMap will attempt to infer a correct,
@@ -121,8 +165,22 @@ func (fm *FontMap) SetRuneCacheSize(size int) {
 // Multiple font maps may call this method concurrently, without duplicating
 // the work of finding the system fonts.
 func (fm *FontMap) UseSystemFonts(cacheDir string) error {
+	return fm.UseSystemFontsWithProgress(cacheDir, nil)
+}
+
+// UseSystemFontsWithProgress is the same as [UseSystemFonts], but also reports the
+// progress of the scan of the system fonts, by calling [progress] with the number of font files
+// already scanned and the total number of font files (including the ones which are up to date in the cache).
+//
+// [progress] is called synchronously, from the goroutine calling this method, at most
+// a few times per second, and always at the start and at the end of the scan.
+// Since the system fonts are only scanned once, [progress] is not called
+// if the scan has already been done (or is in progress) by another call.
+func (fm *FontMap) UseSystemFontsWithProgress(cacheDir string, progress func(scanned, total int)) error {
 	// safe for concurrent use; subsequent calls are no-ops
-	err := initSystemFonts(fm.logger, cacheDir)
+	err := initSystemFonts(fm.logger, cacheDir, progress)
+	// systemScanErrors is read-only once initSystemFonts has returned
+	fm.scanErrors = systemScanErrors
 	if err != nil {
 		return err
 	}
@@ -136,11 +194,76 @@ func (fm *FontMap) UseSystemFonts(cacheDir string) error {
 	return nil
 }
 
+// UseFontsInDirectories scans the fonts found in [dirs] (and their subdirectories)
+// and adds them to the font map, as [UseSystemFonts] does for the system font directories.
+// It may be used instead of (or in addition to) [UseSystemFonts] to only index
+// the fonts of an application.
+//
+// As for system fonts, the faces are lazily loaded from disk, and an index is
+// stored in [userCacheDir] (or the platform cache directory if empty) to speed up
+// subsequent calls. This index is specific to the set of directories, so that
+// it does not collide with the system index.
+//
+// Contrary to [UseSystemFonts], the directories are scanned (incrementally) at each call.
+// An error is returned if no valid font is found.
+func (fm *FontMap) UseFontsInDirectories(userCacheDir string, dirs ...string) error {
+	const cacheFilePattern = "font_index_dirs_%016x_v%d.cache"
+
+	dir, err := cacheDir(userCacheDir)
+	if err != nil {
+		return err
+	}
+	cachePath := filepath.Join(dir, fmt.Sprintf(cacheFilePattern, hashDirectories(dirs), cacheFormatVersion))
+
+	index, scanErrors, err := refreshFontsIndex(fm.logger, cachePath, dirs, nil)
+	fm.scanErrors = scanErrors
+	if err != nil {
+		return err
+	}
+
+	fm.appendFootprints(index.flatten()...)
+
+	fm.built = false
+
+	fm.lru.Clear()
+	return nil
+}
+
+// ScanErrors returns the font files and directories which could not be read
+// by the last call to [UseSystemFonts] (or [UseSystemFontsWithProgress]) or [UseFontsInDirectories].
+// They are skipped, so that the other fonts are still available : this may be used
+// to diagnose why a font is missing.
+//
+// Note that the system fonts are only scanned once, so that the errors
+// are the same for all the font maps using them.
+func (fm *FontMap) ScanErrors() []ScanError { return fm.scanErrors }
+
+// hashDirectories returns a hash identifying the set of [dirs],
+// independent of their order.
+func hashDirectories(dirs []string) uint64 {
+	paths := make([]string, len(dirs))
+	for i, dir := range dirs {
+		if abs, err := filepath.Abs(dir); err == nil {
+			dir = abs
+		}
+		paths[i] = filepath.Clean(dir)
+	}
+	sort.Strings(paths)
+
+	h := fnv.New64a()
+	for _, path := range paths {
+		h.Write([]byte(path))
+		h.Write([]byte{0})
+	}
+	return h.Sum64()
+}
+
 // appendFootprints adds the provided footprints to the database and maps their script
 // coverage.
 func (fm *FontMap) appendFootprints(footprints ...Footprint) {
 	startIdx := len(fm.database)
 	fm.database = append(fm.database, footprints...)
+	fm.arbitrary, fm.arbitraryValid = nil, false
 	// Insert entries into scriptMap for each footprint's covered scripts.
 	for i, fp := range footprints {
 		dbIdx := startIdx + i
@@ -155,6 +278,7 @@ func (fm *FontMap) appendFootprints(footprints ...Footprint) {
 // and `systemFonts` use is then read-only
 var (
 	systemFonts         systemFontsIndex
+	systemScanErrors    []ScanError // the errors reported when scanning the system fonts
 	initSystemFontsOnce sync.Once
 )
 
@@ -170,7 +294,8 @@ func cacheDir(userProvided string) (string, error) {
 // If the returned error is nil, `SystemFonts` is guaranteed to contain
 // at least one valid font.Face.
 // It is protected by sync.Once, and is then safe to use by multiple goroutines.
-func initSystemFonts(logger Logger, userCacheDir string) error {
+// [progress] is only used by the first call, and may be nil.
+func initSystemFonts(logger Logger, userCacheDir string, progress func(scanned, total int)) error {
 	var err error
 
 	initSystemFontsOnce.Do(func() {
@@ -185,42 +310,46 @@ func initSystemFonts(logger Logger, userCacheDir string) error {
 
 		cachePath := filepath.Join(dir, fmt.Sprintf(cacheFilePattern, cacheFormatVersion))
 
-		systemFonts, err = refreshSystemFontsIndex(logger, cachePath)
+		systemFonts, systemScanErrors, err = refreshSystemFontsIndex(logger, cachePath, progress)
 	})
 
 	return err
 }
 
-func refreshSystemFontsIndex(logger Logger, cachePath string) (systemFontsIndex, error) {
+func refreshSystemFontsIndex(logger Logger, cachePath string, progress func(scanned, total int)) (systemFontsIndex, []ScanError, error) {
 	fontDirectories, err := DefaultFontDirectories(logger)
 	if err != nil {
-		return nil, fmt.Errorf("searching font directories: %s", err)
+		return nil, nil, fmt.Errorf("searching font directories: %s", err)
 	}
 	logger.Printf("using system font dirs %q", fontDirectories)
 
+	return refreshFontsIndex(logger, cachePath, fontDirectories, progress)
+}
+
+// refreshFontsIndex scans the fonts in [dirs], using and updating the index stored at [cachePath].
+// The files and directories which can't be read are skipped, and returned
+// in the second value.
+func refreshFontsIndex(logger Logger, cachePath string, dirs []string, progress func(scanned, total int)) (systemFontsIndex, []ScanError, error) {
 	currentIndex, _ := deserializeIndexFile(cachePath)
 	// if an error occured (the cache file does not exists or is invalid), we start from scratch
 
-	updatedIndex, err := scanFontFootprints(logger, currentIndex, fontDirectories...)
-	if err != nil {
-		return nil, fmt.Errorf("scanning system fonts: %s", err)
-	}
+	updatedIndex, scanErrors := scanFontFootprintsWithProgress(logger, currentIndex, progress, dirs...)
 
 	// since ResolveFace must always return a valid face, we make sure
 	// at least one font exists and is valid.
 	// Otherwise, the font map is useless; this is an extreme case anyway.
-	err = updatedIndex.assertValid()
+	err := updatedIndex.assertValid()
 	if err != nil {
-		return nil, fmt.Errorf("loading system fonts: %s", err)
+		return nil, scanErrors, fmt.Errorf("loading fonts: %s", err)
 	}
 
 	// write back the index in the cache file
 	err = updatedIndex.serializeToFile(cachePath)
 	if err != nil {
-		return nil, fmt.Errorf("updating cache: %s", err)
+		return nil, scanErrors, fmt.Errorf("updating cache: %s", err)
 	}
 
-	return updatedIndex, nil
+	return updatedIndex, scanErrors, nil
 }
 
 // [AddFont] loads the faces contained in [fontFile] and add them to
@@ -230,12 +359,16 @@ func refreshSystemFontsIndex(logger Logger, cachePath string) (systemFontsIndex,
 // If `familyName` is not empty, it is used as the family name for `fontFile`
 // instead of the one found in the font file.
 //
+// Variable fonts are added as one face per named instance (or, if the font has
+// no named instances but a weight axis, per standard CSS weight), with the variation
+// coordinates set, and [Location.Instance] identifying the instance.
+//
 // An error is returned if the font resource is not supported.
 //
 // The order of calls to [AddFont] and [AddFace] determines relative priority
 // of manually loaded fonts. See [ResolveFace] for details about when this matters.
 func (fm *FontMap) AddFont(fontFile font.Resource, fileID, familyName string) error {
-	loaders, err := ot.NewLoaders(fontFile)
+	loaders, err := font.NewLoaders(fontFile)
 	if err != nil {
 		return fmt.Errorf("unsupported font resource: %s", err)
 	}
@@ -254,23 +387,33 @@ func (fm *FontMap) AddFont(fontFile font.Resource, fileID, familyName string) er
 
 	var addedFonts []Footprint
 	for i, fontDesc := range loaders {
-		fp, _, err := newFootprintFromLoader(fontDesc, true, scanBuffer{})
+		fps, _, err := newFootprintsFromLoader(fontDesc, true, scanBuffer{})
 		// the font won't be usable, just ignore it
 		if err != nil {
 			continue
 		}
 
-		fp.Location.File = fileID
-		fp.Location.Index = uint16(i)
-		// TODO: for now, we do not handle variable fonts
+		for _, fp := range fps {
+			fp.Location.File = fileID
+			fp.Location.Index = uint16(i)
 
-		if familyName != "" {
-			// give priority to the user provided family
-			fp.Family = font.NormalizeFamily(familyName)
-		}
+			if familyName != "" {
+				// give priority to the user provided family
+				fp.Family = font.NormalizeFamily(familyName)
+				fp.FamilyName = familyName
+			}
 
-		addedFonts = append(addedFonts, fp)
-		fm.cache(fp, faces[i])
+			face := faces[i]
+			if fp.Location.Instance != 0 {
+				face, err = newInstanceFace(face.Font, fontDesc, fp.Location.Instance)
+				if err != nil { // very unlikely, since the instance is built from the same font
+					continue
+				}
+			}
+
+			addedFonts = append(addedFonts, fp)
+			fm.cache(fp, face)
+		}
 	}
 
 	if len(addedFonts) == 0 {
@@ -300,6 +443,113 @@ func (fm *FontMap) AddFace(face *font.Face, location Location, md font.Descripti
 	fm.lru.Clear()
 }
 
+// AddFaces is the same as [AddFace], but inserts several faces at once, typically
+// loaded from a font collection : the i-th face is registered at [baseLocation],
+// with its Index field set to i, and described by md[i].
+//
+// The faces are inserted in order, so that the first one has the highest priority.
+// An error is returned if [faces] is empty or if [md] has not the same length.
+func (fm *FontMap) AddFaces(faces []*font.Face, baseLocation Location, md []font.Description) error {
+	if len(faces) == 0 {
+		return fmt.Errorf("empty font resource %s", baseLocation.File)
+	}
+	if len(md) != len(faces) {
+		return fmt.Errorf("invalid font descriptions: expected %d, got %d", len(faces), len(md))
+	}
+
+	fps := make([]Footprint, len(faces))
+	for i, face := range faces {
+		location := baseLocation
+		location.Index = uint16(i)
+		fps[i] = newFootprintFromFont(face.Font, location, md[i])
+		fm.cache(fps[i], face)
+	}
+
+	fm.appendFootprints(fps...)
+
+	fm.built = false
+	fm.lru.Clear()
+	return nil
+}
+
+// RemoveFont removes the fonts previously added with [AddFont] using [fileID],
+// returning true if at least one font was removed.
+// To replace a font, call [RemoveFont] and then [AddFont] with the new content.
+//
+// System fonts are never removed. See [RemoveFace] for more details.
+func (fm *FontMap) RemoveFont(fileID string) bool {
+	return fm.removeFootprints(func(fp Footprint) bool { return fp.Location.File == fileID })
+}
+
+// RemoveFace removes the font previously added with [AddFont] or [AddFace] at [location],
+// returning true if it was found. System fonts are never removed.
+//
+// The cached faces of the removed fonts are discarded.
+// Note that removing fonts shifts the positions of the remaining ones in the
+// internal database, so callers must not rely on such positions across calls.
+func (fm *FontMap) RemoveFace(location Location) bool {
+	return fm.removeFootprints(func(fp Footprint) bool { return fp.Location == location })
+}
+
+// removeFootprints removes the user provided footprints matching [match],
+// and updates the caches accordingly.
+func (fm *FontMap) removeFootprints(match func(fp Footprint) bool) bool {
+	// do not filter in place, since the database may be shared with a [Snapshot]
+	var kept, removed fontSet
+	for _, fp := range fm.database {
+		if fp.isUserProvided && match(fp) {
+			removed = append(removed, fp)
+		} else {
+			kept = append(kept, fp)
+		}
+	}
+	if len(removed) == 0 {
+		return false
+	}
+
+	removedFirstFace := false
+	fm.preloadedMu.Lock()
+	for _, fp := range removed {
+		delete(fm.preloaded, fp.Location)
+		for key, face := range fm.weightCache {
+			if key.Location == fp.Location {
+				delete(fm.weightCache, key)
+				delete(fm.metaCache, face.Font)
+			}
+		}
+		face, ok := fm.faceCache[fp.Location]
+		if !ok {
+			continue
+		}
+		delete(fm.faceCache, fp.Location)
+		if entry, ok := fm.metaCache[face.Font]; ok && entry.Location == fp.Location {
+			delete(fm.metaCache, face.Font)
+		}
+		removedFirstFace = removedFirstFace || face == fm.firstFace
+	}
+	fm.preloadedMu.Unlock()
+
+	if removedFirstFace {
+		// use the first remaining cached face, if any
+		fm.firstFace = nil
+		for _, fp := range kept {
+			if face, ok := fm.faceCache[fp.Location]; ok {
+				fm.firstFace = face
+				break
+			}
+		}
+	}
+
+	// rebuild the script map, since the indices have changed
+	fm.database = nil
+	fm.scriptMap = make(map[language.Script][]int)
+	fm.appendFootprints(kept...)
+
+	fm.built = false
+	fm.lru.Clear()
+	return true
+}
+
 func (fm *FontMap) cache(fp Footprint, face *font.Face) {
 	if fm.firstFace == nil {
 		fm.firstFace = face
@@ -326,6 +576,54 @@ func (fm *FontMap) FontMetadata(ft *font.Font) (family string, aspect font.Aspec
 	return item.Family, item.Aspect
 }
 
+// SyntheticStyle describes the difference between the aspect requested by
+// the query of a [FontMap] and the aspect of a selected face, which occurs when
+// no font of the family provides the requested aspect.
+// Renderers may use it to apply a synthetic (faux) bold or oblique style.
+type SyntheticStyle struct {
+	// Requested is the aspect of the query, with defaults applied.
+	Requested font.Aspect
+	// Actual is the aspect of the selected face, with defaults applied.
+	Actual font.Aspect
+
+	// NeedsBold is true if a bold weight (at least [font.WeightSemibold])
+	// was requested, but the face is lighter than [font.WeightSemibold].
+	NeedsBold bool
+	// NeedsOblique is true if an italic or oblique style
+	// was requested, but the face is upright.
+	NeedsOblique bool
+}
+
+func newSyntheticStyle(requested, actual font.Aspect) SyntheticStyle {
+	requested.SetDefaults()
+	actual.SetDefaults()
+	return SyntheticStyle{
+		Requested:    requested,
+		Actual:       actual,
+		NeedsBold:    requested.Weight >= font.WeightSemibold && actual.Weight < font.WeightSemibold,
+		NeedsOblique: requested.Style != font.StyleNormal && actual.Style == font.StyleNormal,
+	}
+}
+
+// IsExact returns true if the aspect of the face exactly matches the requested one.
+func (ss SyntheticStyle) IsExact() bool { return ss.Requested == ss.Actual }
+
+// WeightDelta returns the difference between the requested and the actual weights,
+// for instance 300 if [font.WeightBold] was requested but the face is regular.
+func (ss SyntheticStyle) WeightDelta() font.Weight { return ss.Requested.Weight - ss.Actual.Weight }
+
+// SyntheticStyle compares the aspect of the current query with the
+// aspect of the provided font, as returned by [FontMetadata].
+// If the font was not previously returned from this FontMap by a call to ResolveFace,
+// the zero value is returned, meaning no synthetic style should be applied.
+func (fm *FontMap) SyntheticStyle(ft *font.Font) SyntheticStyle {
+	item, ok := fm.metaCache[ft]
+	if !ok {
+		return SyntheticStyle{}
+	}
+	return newSyntheticStyle(fm.query.Aspect, item.Aspect)
+}
+
 // FindSystemFont looks for a system font with the given [family],
 // returning the first match, or false is no one is found.
 //
@@ -362,6 +660,224 @@ func (fm *FontMap) FindSystemFonts(family string) []Location {
 	return locations
 }
 
+// FamilyInfo describes a family known to a [FontMap], as returned by [FontMap.Families].
+type FamilyInfo struct {
+	// Family is the normalized family name, as used in [Query.Families]
+	Family string
+	// Name is the family name as found in the font (or as provided to [FontMap.AddFont]),
+	// suitable for display. It defaults to [Family] for fonts scanned before
+	// this information was recorded.
+	Name string
+	// IsUserProvided is true if at least one font of the
+	// family has been added with [AddFont] or [AddFace]
+	IsUserProvided bool
+	// Aspects is the set of styles available for the family,
+	// sorted by Style, Weight and then Stretch.
+	Aspects []font.Aspect
+}
+
+// Families returns the families known to the font map, both system
+// and user provided, sorted by normalized family name.
+// Fonts sharing the same aspect (for instance the faces of a collection or
+// duplicated files) are only reported once.
+//
+// The precomputed footprints are used, so that no font is loaded.
+func (fm *FontMap) Families() []FamilyInfo {
+	var out []FamilyInfo
+	indices := make(map[string]int) // family -> index in out
+	for _, fp := range fm.database {
+		index, ok := indices[fp.Family]
+		if !ok {
+			index = len(out)
+			indices[fp.Family] = index
+			out = append(out, FamilyInfo{Family: fp.Family})
+		}
+		info := &out[index]
+		if info.Name == "" {
+			info.Name = fp.FamilyName
+		}
+		info.IsUserProvided = info.IsUserProvided || fp.isUserProvided
+
+		isNew := true
+		for _, aspect := range info.Aspects {
+			if aspect == fp.Aspect {
+				isNew = false
+				break
+			}
+		}
+		if isNew {
+			info.Aspects = append(info.Aspects, fp.Aspect)
+		}
+	}
+
+	for i := range out {
+		info := &out[i]
+		if info.Name == "" {
+			info.Name = info.Family
+		}
+		sort.Slice(info.Aspects, func(i, j int) bool {
+			ai, aj := info.Aspects[i], info.Aspects[j]
+			if ai.Style != aj.Style {
+				return ai.Style < aj.Style
+			}
+			if ai.Weight != aj.Weight {
+				return ai.Weight < aj.Weight
+			}
+			return ai.Stretch < aj.Stretch
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Family < out[j].Family })
+
+	return out
+}
+
+// CoverageScore returns the fraction of the runes in [text] supported by
+// the font at [location], between 0 and 1.
+// Control characters and default ignorable runes (like joiners or variation selectors)
+// are not taken into account, and 1 is returned if [text] only contains such runes.
+//
+// The precomputed coverage of the font is used, so that the font is not loaded.
+// If [location] is not known by the [FontMap], 0 is returned.
+func (fm *FontMap) CoverageScore(location Location, text []rune) float32 {
+	index := -1
+	for i, footprint := range fm.database {
+		if footprint.Location == location {
+			index = i
+			break
+		}
+	}
+	if index == -1 {
+		return 0
+	}
+
+	runes := fm.database[index].Runes
+	var total, covered int
+	for _, r := range text {
+		if isIgnorableForCoverage(r) {
+			continue
+		}
+		total++
+		if runes.Contains(r) {
+			covered++
+		}
+	}
+	if total == 0 {
+		return 1
+	}
+	return float32(covered) / float32(total)
+}
+
+// Coverage returns the set of runes supported by the font at [location],
+// which may be enumerated with [RuneSet.Ranges], or false if [location]
+// is not known by the [FontMap].
+//
+// As for [CoverageScore], the precomputed coverage of the font is used, so that the font
+// is not loaded. The returned set is a copy, which may be freely modified.
+func (fm *FontMap) Coverage(location Location) (RuneSet, bool) {
+	for _, footprint := range fm.database {
+		if footprint.Location == location {
+			return append(RuneSet(nil), footprint.Runes...), true
+		}
+	}
+	return nil, false
+}
+
+// isIgnorableForCoverage returns true for control characters and
+// (an approximation of) the runes with the Default_Ignorable_Code_Point property,
+// which are not required to be present in fonts.
+func isIgnorableForCoverage(r rune) bool {
+	return unicode.IsControl(r) ||
+		unicode.In(r, unicode.Cf, unicode.Variation_Selector, unicode.Other_Default_Ignorable_Code_Point)
+}
+
+// EmbeddingPermission returns the embedding licensing rights of the
+// font at [location], as found in its 'OS/2' table, without loading the font.
+//
+// If [location] is not known by the [FontMap], [font.EmbedRestricted]
+// is returned, so that unknown fonts are never embedded.
+func (fm *FontMap) EmbeddingPermission(location Location) EmbedPermission {
+	for _, footprint := range fm.database {
+		if footprint.Location == location {
+			return footprint.EmbedPermission
+		}
+	}
+	return font.EmbedRestricted
+}
+
+// ConfigHash returns a hash of the font set used by the [FontMap], which
+// may be used as a key to invalidate caches built from its content (like layout results).
+//
+// The hash is computed over the footprints of the fonts (location, family, aspect and coverage),
+// in the order they were added. It changes when fonts are added, or when a rescan with [UseSystemFonts]
+// finds a different font set, and is stable across runs for the same font set.
+//
+// The fallback settings are also included : the families registered with [SetScriptFallback],
+// and the faces registered with [AddLastResortFace] and [SetLastResortFace]. These faces
+// are identified by their family, aspect, units per em and variation coordinates.
+//
+// Note that the current query and script (see [SetQuery] and [SetScript]) are not included,
+// and that the family substitutions are static, so that they are not included either.
+func (fm *FontMap) ConfigHash() uint64 {
+	h := fnv.New64a()
+	var buffer []byte
+	for _, fp := range fm.database {
+		buffer = fp.serializeTo(buffer[:0])
+		if fp.isUserProvided {
+			buffer = append(buffer, 1)
+		} else {
+			buffer = append(buffer, 0)
+		}
+		h.Write(buffer)
+	}
+
+	// the map is iterated in a deterministic order
+	scripts := make([]language.Script, 0, len(fm.scriptFallbacks))
+	for script := range fm.scriptFallbacks {
+		scripts = append(scripts, script)
+	}
+	sort.Slice(scripts, func(i, j int) bool { return scripts[i] < scripts[j] })
+	var tmp [4]byte
+	for _, script := range scripts {
+		binary.BigEndian.PutUint32(tmp[:], uint32(script))
+		buffer = append(buffer[:0], tmp[:]...)
+		for _, family := range fm.scriptFallbacks[script] {
+			buffer = append(buffer, serializeString(family)...)
+		}
+		h.Write(buffer)
+	}
+
+	for _, lr := range fm.lastResorts {
+		buffer = serializeFaceKey(lr.face, buffer[:0])
+		binary.BigEndian.PutUint16(tmp[:], uint16(len(lr.scripts)))
+		buffer = append(buffer, tmp[:2]...)
+		for _, script := range lr.scripts {
+			binary.BigEndian.PutUint32(tmp[:], uint32(script))
+			buffer = append(buffer, tmp[:]...)
+		}
+		h.Write(buffer)
+	}
+
+	if fm.tofuFace != nil {
+		h.Write(serializeFaceKey(fm.tofuFace, buffer[:0]))
+	}
+	return h.Sum64()
+}
+
+// serializeFaceKey appends to [dst] the description of [face] used by [FontMap.ConfigHash]
+func serializeFaceKey(face *font.Face, dst []byte) []byte {
+	desc := face.Describe()
+	dst = append(dst, serializeString(desc.Family)...)
+	dst = append(dst, serializeAspect(desc.Aspect)...)
+	var tmp [2]byte
+	binary.BigEndian.PutUint16(tmp[:], face.Upem())
+	dst = append(dst, tmp[:]...)
+	for _, coord := range face.Coords() {
+		binary.BigEndian.PutUint16(tmp[:], uint16(coord))
+		dst = append(dst, tmp[:]...)
+	}
+	return dst
+}
+
 // SetQuery set the families and aspect required, influencing subsequent
 // [ResolveFace] calls. See also [SetScript].
 func (fm *FontMap) SetQuery(query Query) {
@@ -407,50 +923,56 @@ func (fm *FontMap) buildCandidates() {
 	if fm.built {
 		return
 	}
-	fm.candidates.resetWithSize(len(fm.query.Families))
+	fm.candidates.build(fm.database, fm.query, fm.script, fm.cribleBuffer, &fm.footprintsBuffer)
+	fm.built = true
+}
+
+// build selects the footprints in [database] matching [query] and [script]
+func (cd *candidates) build(database fontSet, query Query, script language.Script,
+	cribleBuffer familyCrible, footprintsBuffer *scoredFootprints,
+) {
+	cd.resetWithSize(len(query.Families))
 
 	// first pass for an exact match
 	{
-		for _, family := range fm.query.Families {
-			candidates := fm.database.selectByFamilyExact(family, fm.cribleBuffer, &fm.footprintsBuffer)
+		for _, family := range query.Families {
+			candidates := database.selectByFamilyExact(family, cribleBuffer, footprintsBuffer)
 			if len(candidates) == 0 {
 				continue
 			}
 
 			// select the correct aspects
-			candidates = fm.database.retainsBestMatches(candidates, fm.query.Aspect)
+			candidates = database.retainsBestMatchesVariable(candidates, query.Aspect, query.PreferVariableInstance)
 
 			// with no system fallback, the CSS spec says
 			// that only one font among the candidates must be tried
-			fm.candidates.withoutFallback = append(fm.candidates.withoutFallback, candidates[0])
+			cd.withoutFallback = append(cd.withoutFallback, candidates[0])
 		}
 	}
 
 	// second pass with substitutions
 	{
-		candidates := fm.database.selectByFamilyWithSubs(fm.query.Families, fm.script, fm.cribleBuffer, &fm.footprintsBuffer)
+		candidates := database.selectByFamilyWithSubs(query.Families, script, cribleBuffer, footprintsBuffer)
 
 		// select the correct aspects
-		candidates = fm.database.retainsBestMatches(candidates, fm.query.Aspect)
+		candidates = database.retainsBestMatchesVariable(candidates, query.Aspect, query.PreferVariableInstance)
 
-		// candidates is owned by fm.footprintsBuffer: copy its content
-		S := fm.candidates.withFallback
+		// candidates is owned by footprintsBuffer: copy its content
+		S := cd.withFallback
 		if L := len(candidates); cap(S) < L {
 			S = make([]int, L)
 		} else {
 			S = S[:L]
 		}
 		copy(S, candidates)
-		fm.candidates.withFallback = S
+		cd.withFallback = S
 	}
 
 	// third pass with user provided fonts
 	{
-		fm.candidates.manual = fm.database.filterUserProvided(fm.candidates.manual)
-		fm.candidates.manual = fm.database.retainsBestMatches(fm.candidates.manual, fm.query.Aspect)
+		cd.manual = database.filterUserProvided(cd.manual)
+		cd.manual = database.retainsBestMatchesVariable(cd.manual, query.Aspect, query.PreferVariableInstance)
 	}
-
-	fm.built = true
 }
 
 // returns nil if not candidates supports the rune `r`
@@ -504,70 +1026,406 @@ func (fm *FontMap) resolveForLang(candidates []int, lang LangID) *font.Face {
 //		will be searched, in the order in which they were added.
 //	4 - All fonts matching the current script (set by [FontMap.SetScript]) are tried,
 //		ignoring [Query.Aspect]
+//	5 - The last resort faces registered with [AddLastResortFace] are tried, then
+//		any font supporting the rune
 //
-// If no fonts match after these steps, an arbitrary face will be returned.
-// This face will be nil only if the underlying font database is empty,
-// or if the file system is broken; otherwise the returned [font.Face] is always valid.
+// If no fonts match after these steps, an arbitrary face will be returned
+// (see [FontMap.SetLastResortFace]).
+// This face will be nil only if the underlying font database is empty (and no last resort
+// face is registered), or if the file system is broken; otherwise the returned [font.Face] is always valid.
 func (fm *FontMap) ResolveFace(r rune) (face *font.Face) {
+	face, _ = fm.resolveFaceCached(r)
+	return face
+}
+
+// resolveFaceCached implements [ResolveFace], also returning the matching step.
+func (fm *FontMap) resolveFaceCached(r rune) (face *font.Face, step ResolveStep) {
 	key := fm.lru.KeyFor(fm.query, fm.script, r)
-	face, ok := fm.lru.Get(key, fm.query)
+	face, step, ok := fm.lru.Get(key, fm.query)
 	if ok {
-		return face
+		return face, step
 	}
 	defer func() {
-		fm.lru.Put(key, fm.query, face)
+		fm.lru.Put(key, fm.query, face, step)
 	}()
 
 	// Build the candidates if we missed the cache. If they're already built this is a
 	// no-op.
 	fm.buildCandidates()
 
-	// we first look up for an exact family match, without substitutions
-	if face := fm.resolveForRune(fm.candidates.withoutFallback, r); face != nil {
+	return fm.resolveFace(&fm.candidates, fm.query, fm.script, r)
+}
+
+// ResolveFaceForScript sets the current script, as [SetScript] would do,
+// and selects a face for [r] as [ResolveFace].
+//
+// Moreover, when the face is selected among the fallback fonts and [lang] is known
+// (see [language.NewLangID]), the first fallback font supporting both [lang] and [r]
+// is preferred : for instance, Japanese text uses a Japanese font for Han characters,
+// instead of the first font covering them.
+//
+// This method implements [shaping.FontmapContext], so that the script and the language
+// of each run are used during segmentation.
+func (fm *FontMap) ResolveFaceForScript(r rune, script language.Script, lang language.Language) *font.Face {
+	if script != fm.script {
+		fm.SetScript(script)
+	}
+	face, step := fm.resolveFaceCached(r)
+	if step != ResolveFallback {
+		return face
+	}
+	langID, ok := language.NewLangID(lang)
+	if !ok {
 		return face
 	}
+	// no-op if already built
+	fm.buildCandidates()
+	for _, footprintIndex := range fm.candidates.withFallback {
+		fp := fm.database[footprintIndex]
+		if !fp.Langs.Contains(langID) || !fp.Runes.Contains(r) {
+			continue
+		}
+		langFace, err := fm.loadFont(fp)
+		if err != nil { // very unlikely; try another font
+			fm.logger.Printf("failed loading face: %v", err)
+			continue
+		}
+		return langFace
+	}
+	return face
+}
+
+// FaceRun is a run of text using the same face, as returned by [FontMap.ResolveFaceForString].
+type FaceRun struct {
+	Start, End int // indices into the input text, End excluded
+	Face       *font.Face
+}
+
+// ResolveFaceForString splits [text] into runs of runes sharing the same face,
+// as selected by [ResolveFace] for the current query and script.
+//
+// Adjacent runes resolving to the same face are grouped, and runes which should
+// not trigger a face change (like spaces and control characters) are added to the
+// current run, as done by [shaping.SplitByFace].
+// An empty [text] returns one empty run, with the face selected for a space.
+func (fm *FontMap) ResolveFaceForString(text []rune) []FaceRun {
+	// no-op if already built
+	fm.buildCandidates()
+
+	var (
+		out     []FaceRun
+		current FaceRun
+	)
+	for i, r := range text {
+		// we must force the choice of a face if we still don't have one and we reach
+		// the final rune : otherwise strings like all-whitespace are never assigned a face
+		if ucd.IgnoreFaceChange(r) && (current.Face != nil || i < len(text)-1) {
+			continue
+		}
+
+		face := fm.ResolveFace(r)
+		if current.Face == nil {
+			current.Face = face
+		}
+		if current.Face == face {
+			continue
+		}
+
+		// close the current run and start a new one
+		current.End = i
+		out = append(out, current)
+		current = FaceRun{Start: i, Face: face}
+	}
+
+	if len(text) == 0 {
+		current.Face = fm.ResolveFace(' ')
+	}
+	current.End = len(text)
+	return append(out, current)
+}
+
+// ResolveStep identifies the step of the resolution process described
+// in [FontMap.ResolveFace] which selected a face.
+type ResolveStep uint8
+
+const (
+	// ResolveExact is used for fonts matching exactly one of the [Query.Families].
+	ResolveExact ResolveStep = iota
+	// ResolveFallback is used for fonts with similar families,
+	// or supporting the current script.
+	ResolveFallback
+	// ResolveManual is used for fonts added with [FontMap.AddFont] or [FontMap.AddFace],
+	// without matching family.
+	ResolveManual
+	// ResolveScriptCoverage is used for fonts supporting the current script,
+	// without matching aspect (see also [FontMap.SetScriptFallback]).
+	ResolveScriptCoverage
+	// ResolveLastResort is used for the faces registered with [FontMap.AddLastResortFace].
+	ResolveLastResort
+	// ResolveRuneCoverage is used for any font supporting the rune.
+	ResolveRuneCoverage
+	// ResolveArbitrary is used when no font supports the rune : the
+	// returned face is then the one set by [FontMap.SetLastResortFace],
+	// or the font with the alphabetically-first family.
+	ResolveArbitrary
+)
+
+func (rs ResolveStep) String() string {
+	switch rs {
+	case ResolveExact:
+		return "Exact"
+	case ResolveFallback:
+		return "Fallback"
+	case ResolveManual:
+		return "Manual"
+	case ResolveScriptCoverage:
+		return "ScriptCoverage"
+	case ResolveLastResort:
+		return "LastResort"
+	case ResolveRuneCoverage:
+		return "RuneCoverage"
+	case ResolveArbitrary:
+		return "Arbitrary"
+	default:
+		return "unknown"
+	}
+}
+
+// ResolveInfo describes how a face has been selected by [FontMap.ResolveFaceWithInfo].
+type ResolveInfo struct {
+	// Step is the resolution step which selected the face.
+	Step ResolveStep
+	// Family and Aspect are the metadata of the selected face,
+	// as returned by [FontMap.FontMetadata].
+	// They are empty for last resort faces (see [FontMap.AddLastResortFace]),
+	// which are not part of the font database.
+	Family string
+	Aspect font.Aspect
+	// Synthetic compares [Aspect] with the aspect of the query,
+	// as returned by [FontMap.SyntheticStyle].
+	Synthetic SyntheticStyle
+}
+
+// ResolveFaceWithInfo is the same as [ResolveFace], but also returns
+// information about the selected face, which is useful to debug font fallback,
+// to detect missing glyphs (see [ResolveArbitrary]), or to apply synthetic styles
+// (see [SyntheticStyle]).
+func (fm *FontMap) ResolveFaceWithInfo(r rune) (*font.Face, ResolveInfo) {
+	face, step := fm.resolveFaceCached(r)
+	if face == nil {
+		return nil, ResolveInfo{Step: step}
+	}
+	item := fm.metaCache[face.Font]
+	return face, ResolveInfo{Step: step, Family: item.Family, Aspect: item.Aspect, Synthetic: fm.SyntheticStyle(face.Font)}
+}
+
+// PeekFace returns the face which would be selected by [ResolveFace] for the rune [r],
+// if the query [q] and the script [s] were set, without modifying the current query,
+// nor the internal cache used by [ResolveFace].
+//
+// Note that the faces loaded from disk are still cached, as for [ResolveFace].
+func (fm *FontMap) PeekFace(q Query, s language.Script, r rune) *font.Face {
+	if len(q.Families) == 0 {
+		q.Families = []string{""}
+	}
+	var cd candidates
+	cd.build(fm.database, q, s, make(familyCrible), &scoredFootprints{})
+	face, _ := fm.resolveFace(&cd, q, s, r)
+	return face
+}
+
+// resolveFace implements the resolution steps described in [ResolveFace],
+// using the given (already built) candidates
+func (fm *FontMap) resolveFace(cd *candidates, query Query, script language.Script, r rune) (*font.Face, ResolveStep) {
+	// we first look up for an exact family match, without substitutions
+	if face := fm.resolveForRune(cd.withoutFallback, r); face != nil {
+		return fm.variableInstance(face, query), ResolveExact
+	}
 
 	// if no family has matched so far, try again with system fallback,
 	// including fonts with matching script and user provided ones
-	if face := fm.resolveForRune(fm.candidates.withFallback, r); face != nil {
-		return face
+	if face := fm.resolveForRune(cd.withFallback, r); face != nil {
+		return fm.variableInstance(face, query), ResolveFallback
 	}
 
 	// try manually loaded faces even if the typeface doesn't match, looking for matching aspects
 	// and rune coverage.
 	// Note that, when [SetScript] has been called, this step is actually not needed,
 	// since the fonts supporting the given script are already added in [withFallback] fonts
-	if face := fm.resolveForRune(fm.candidates.manual, r); face != nil {
-		return face
+	if face := fm.resolveForRune(cd.manual, r); face != nil {
+		return fm.variableInstance(face, query), ResolveManual
 	}
 
-	fm.logger.Printf("No font matched for aspect %v, script %s, and rune %U (%c) -> searching by script coverage only", fm.query.Aspect, fm.script, r, r)
-	scriptCandidates := fm.scriptMap[fm.script]
+	fm.logger.Printf("No font matched for aspect %v, script %s, and rune %U (%c) -> searching by script coverage only", query.Aspect, script, r, r)
+	scriptCandidates := fm.scriptFallbackOrder(script)
 	if face := fm.resolveForRune(scriptCandidates, r); face != nil {
-		return face
+		return face, ResolveScriptCoverage
 	}
 
-	fm.logger.Printf("No font matched for script %s and rune %U (%c) -> returning arbitrary face", fm.script, r, r)
-	// return an arbitrary face
-	if fm.firstFace == nil && len(fm.database) > 0 {
-		for _, fp := range fm.database {
-			face, err := fm.loadFont(fp)
-			if err != nil {
-				// very unlikely; warn and keep going
-				fm.logger.Printf("failed loading face: %v", err)
-				continue
-			}
-			return face
+	fm.logger.Printf("No font matched for script %s and rune %U (%c) -> searching last resort fonts", script, r, r)
+	if face := fm.resolveLastResort(script, r); face != nil {
+		return face, ResolveLastResort
+	}
+
+	// try any font supporting the rune
+	for _, fp := range fm.database {
+		if !fp.Runes.Contains(r) {
+			continue
 		}
+		face, err := fm.loadFont(fp)
+		if err != nil {
+			fm.logger.Printf("failed loading face: %v", err)
+			continue
+		}
+		return face, ResolveRuneCoverage
+	}
+
+	fm.logger.Printf("No font supports rune %U (%c) -> returning arbitrary face", r, r)
+	// return an arbitrary face
+	if fm.tofuFace != nil {
+		return fm.tofuFace, ResolveArbitrary
+	}
+	if face := fm.arbitraryFace(); face != nil {
+		return face, ResolveArbitrary
+	}
+	if len(fm.lastResorts) != 0 {
+		return fm.lastResorts[0].face, ResolveArbitrary
 	}
 
-	return fm.firstFace
+	return fm.firstFace, ResolveArbitrary
 	// refreshSystemFontsIndex makes sure at least one face is valid
 	// and AddFont also check for valid font files, meaning that
 	// a valid FontMap should always contain a valid face,
 	// and we should never return a nil face.
 }
 
+// AddLastResortFace registers a broad coverage face (like the Noto fonts),
+// used by [ResolveFace] when no font of the database matches the query and the script.
+// If [scripts] are provided, the face is only used for these scripts, and is tried
+// before the faces registered for all scripts.
+//
+// When resolving a rune, the last resort faces supporting it are tried in
+// registration order, before any other font of the database covering the rune,
+// and finally an arbitrary face.
+func (fm *FontMap) AddLastResortFace(face *font.Face, scripts ...language.Script) {
+	fm.lastResorts = append(fm.lastResorts, lastResortFace{face: face, scripts: scripts})
+	fm.lru.Clear()
+}
+
+// SetScriptFallback pins the preferred fonts used by [ResolveFace] when no font matches
+// the query for a rune of [script], and the fonts supporting [script] are tried
+// (see [ResolveScriptCoverage]) : the fonts of [families] are tried first, in order,
+// before the other ones. Families which are not in the database are ignored.
+//
+// Passing an empty [families] removes the override for [script].
+func (fm *FontMap) SetScriptFallback(script language.Script, families []string) {
+	fallbacks := make(map[language.Script][]string, len(fm.scriptFallbacks)+1)
+	for s, fams := range fm.scriptFallbacks {
+		fallbacks[s] = fams
+	}
+	if len(families) == 0 {
+		delete(fallbacks, script)
+	} else {
+		normalized := make([]string, len(families))
+		for i, family := range families {
+			normalized[i] = font.NormalizeFamily(family)
+		}
+		fallbacks[script] = normalized
+	}
+	fm.scriptFallbacks = fallbacks
+	fm.lru.Clear()
+}
+
+// scriptFallbackOrder returns the fonts supporting [script],
+// sorted according to the override registered with [SetScriptFallback], if any.
+func (fm *FontMap) scriptFallbackOrder(script language.Script) []int {
+	candidates := fm.scriptMap[script]
+	families := fm.scriptFallbacks[script]
+	if len(families) == 0 {
+		return candidates
+	}
+
+	out := make([]int, 0, len(candidates))
+	used := make([]bool, len(candidates))
+	for _, family := range families {
+		for i, index := range candidates {
+			if !used[i] && fm.database[index].Family == family {
+				out = append(out, index)
+				used[i] = true
+			}
+		}
+	}
+	for i, index := range candidates {
+		if !used[i] {
+			out = append(out, index)
+		}
+	}
+	return out
+}
+
+// SetLastResortFace sets the face returned by [ResolveFace] when no font
+// supports the requested rune (see [ResolveArbitrary]), typically a font
+// with a visible .notdef glyph. Passing nil restores the default behavior,
+// which selects the font of the database with the alphabetically-first family.
+func (fm *FontMap) SetLastResortFace(face *font.Face) {
+	fm.tofuFace = face
+	fm.arbitrary, fm.arbitraryValid = nil, false
+	fm.lru.Clear()
+}
+
+// arbitraryFace returns the first font of the database which may be loaded,
+// sorted by family, so that the choice does not depend on the loading order,
+// or nil if the database is empty.
+// The result is cached until the database is modified.
+func (fm *FontMap) arbitraryFace() *font.Face {
+	if !fm.arbitraryValid {
+		fm.arbitrary, fm.arbitraryValid = fm.findArbitraryFace(), true
+	}
+	return fm.arbitrary
+}
+
+func (fm *FontMap) findArbitraryFace() *font.Face {
+	indices := make([]int, len(fm.database))
+	for i := range indices {
+		indices[i] = i
+	}
+	sort.SliceStable(indices, func(i, j int) bool {
+		return fm.database[indices[i]].Family < fm.database[indices[j]].Family
+	})
+	for _, index := range indices {
+		face, err := fm.loadFont(fm.database[index])
+		if err != nil {
+			// very unlikely; warn and keep going
+			fm.logger.Printf("failed loading face: %v", err)
+			continue
+		}
+		return face
+	}
+	return nil
+}
+
+// resolveLastResort returns the first last resort face supporting [r],
+// trying first the ones registered for [script], or nil
+func (fm *FontMap) resolveLastResort(script language.Script, r rune) *font.Face {
+	supports := func(face *font.Face) bool {
+		_, ok := face.NominalGlyph(r)
+		return ok
+	}
+	for _, lr := range fm.lastResorts {
+		for _, s := range lr.scripts {
+			if s == script && supports(lr.face) {
+				return lr.face
+			}
+		}
+	}
+	for _, lr := range fm.lastResorts {
+		if len(lr.scripts) == 0 && supports(lr.face) {
+			return lr.face
+		}
+	}
+	return nil
+}
+
 // ResolveForLang returns the first face supporting the given language
 // (for the actual query), or nil if no one is found.
 //
@@ -595,14 +1453,199 @@ func (fm *FontMap) ResolveFaceForLang(lang LangID) *font.Face {
 	return nil
 }
 
+// ResolveFaceForFamilyLang returns the first face matching [family], supporting the
+// given language and the rune [r], or nil if no one is found.
+//
+// This is useful for families providing one font per language, like pan-CJK fonts.
+// The fonts with exact family match are tried first, then the ones obtained with
+// family substitutions. The current [Query.Aspect] is used to select the best matches,
+// but the current query families and script are ignored.
+func (fm *FontMap) ResolveFaceForFamilyLang(family string, lang LangID, r rune) *font.Face {
+	resolve := func(candidates []int) *font.Face {
+		for _, footprintIndex := range candidates {
+			// check the coverage
+			if fp := fm.database[footprintIndex]; fp.Langs.Contains(lang) && fp.Runes.Contains(r) {
+				// try to use the font
+				face, err := fm.loadFont(fp)
+				if err != nil { // very unlikely; try another font
+					fm.logger.Printf("failed loading face: %v", err)
+					continue
+				}
+
+				return face
+			}
+		}
+		return nil
+	}
+
+	// the buffers may be used since fm.candidates does not share memory with them
+
+	// first pass with exact match, keeping all the fonts with the best aspect
+	candidates := fm.database.selectByFamilyExact(family, fm.cribleBuffer, &fm.footprintsBuffer)
+	candidates = fm.database.retainsBestMatches(candidates, fm.query.Aspect)
+	if face := resolve(candidates); face != nil {
+		return face
+	}
+
+	// second pass with substitutions
+	candidates = fm.database.selectByFamilyWithSubs([]string{family}, 0, fm.cribleBuffer, &fm.footprintsBuffer)
+	candidates = fm.database.retainsBestMatches(candidates, fm.query.Aspect)
+	return resolve(candidates)
+}
+
+// PreloadCandidates loads in the background the faces which may be returned by [ResolveFace]
+// for the current query and script (see [SetQuery] and [SetScript]), so that the subsequent
+// calls to [ResolveFace] do not have to wait for them to be read from disk.
+//
+// The faces already loaded, or currently being preloaded, are skipped, so that
+// it is safe to call PreloadCandidates repeatedly (typically after each [SetQuery]).
+// Canceling [ctx] stops the loading; the returned channel is closed when
+// the background work is done.
+//
+// Note that, as the other methods of [FontMap], PreloadCandidates itself
+// must not be called concurrently with other methods.
+func (fm *FontMap) PreloadCandidates(ctx context.Context) <-chan struct{} {
+	fm.buildCandidates()
+
+	var toLoad []Footprint
+	fm.preloadedMu.Lock()
+	if fm.preloaded == nil {
+		fm.preloaded = make(map[Location]*font.Face)
+	}
+	for _, list := range [3][]int{fm.candidates.withoutFallback, fm.candidates.withFallback, fm.candidates.manual} {
+		for _, index := range list {
+			fp := fm.database[index]
+			if _, has := fm.faceCache[fp.Location]; has {
+				continue
+			}
+			if _, has := fm.preloaded[fp.Location]; has {
+				continue
+			}
+			fm.preloaded[fp.Location] = nil // mark as loading
+			toLoad = append(toLoad, fp)
+		}
+	}
+	fm.preloadedMu.Unlock()
+
+	paths := fm.filePaths // never modified in place
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i, fp := range toLoad {
+			var face *font.Face
+			if ctx.Err() == nil {
+				disk := withFilePath(fp, paths)
+				face, _ = disk.loadFromDisk() // errors are reported when actually loading the face
+			}
+
+			if preloadHook != nil {
+				preloadHook(fp.Location)
+			}
+
+			fm.preloadedMu.Lock()
+			// the mark is removed if the font has been removed in the meantime
+			if current, has := fm.preloaded[fp.Location]; has && current == nil {
+				if face != nil {
+					fm.preloaded[fp.Location] = face
+				} else { // failure or cancellation : remove the mark
+					delete(fm.preloaded, fp.Location)
+				}
+			}
+			fm.preloadedMu.Unlock()
+
+			if ctx.Err() != nil { // clean up the remaining marks
+				fm.preloadedMu.Lock()
+				for _, fp := range toLoad[i+1:] {
+					if current, has := fm.preloaded[fp.Location]; has && current == nil {
+						delete(fm.preloaded, fp.Location)
+					}
+				}
+				fm.preloadedMu.Unlock()
+				return
+			}
+		}
+	}()
+	return done
+}
+
+// preloadHook, if not nil, is called by the goroutine started by
+// [PreloadCandidates] after each face is loaded; it is only used in tests.
+var preloadHook func(Location)
+
+// weightedLocation identifies a variable font face configured to an exact weight
+type weightedLocation struct {
+	Location
+	weight font.Weight
+}
+
+// variableInstance returns [face] unchanged, unless [query] prefers variable instances
+// and [face] is an instance of a variable font whose weight differs from the queried one.
+// In this case, a face using the queried weight for the 'wght' axis is returned,
+// if the axis supports it.
+func (fm *FontMap) variableInstance(face *font.Face, query Query) *font.Face {
+	if !query.PreferVariableInstance {
+		return face
+	}
+	item, ok := fm.metaCache[face.Font]
+	query.Aspect.SetDefaults()
+	weight := query.Aspect.Weight
+	if !ok || item.Location.Instance == 0 || item.Aspect.Weight == weight {
+		return face
+	}
+
+	key := weightedLocation{item.Location, weight}
+	if cached, ok := fm.weightCache[key]; ok {
+		return cached
+	}
+
+	variations := face.Variations()
+	wght := ot.MustNewTag("wght")
+	for i, v := range variations {
+		if v.Tag == wght {
+			variations[i].Value = float32(weight)
+		}
+	}
+	cp := *face.Font // shallow copy, so that the face may be identified in metaCache
+	out := font.NewFace(&cp)
+	out.SetVariations(variations)
+	// check that the weight has not been clamped by the axis range
+	for _, v := range out.Variations() {
+		if v.Tag == wght && math.Abs(float64(v.Value)-float64(weight)) > 1 {
+			return face
+		}
+	}
+
+	if fm.weightCache == nil {
+		fm.weightCache = make(map[weightedLocation]*font.Face)
+	}
+	fm.weightCache[key] = out
+	item.Aspect.Weight = weight
+	fm.metaCache[out.Font] = item
+	return out
+}
+
 func (fm *FontMap) loadFont(fp Footprint) (*font.Face, error) {
 	if face, hasCached := fm.faceCache[fp.Location]; hasCached {
 		return face, nil
 	}
 
+	// use the face loaded by PreloadCandidates, if any
+	fm.preloadedMu.Lock()
+	face := fm.preloaded[fp.Location]
+	if face != nil {
+		delete(fm.preloaded, fp.Location)
+	}
+	fm.preloadedMu.Unlock()
+	if face != nil {
+		fm.cache(fp, face)
+		return face, nil
+	}
+
 	// since user provided fonts are added to `faceCache`
+	// (or have their path registered by [Deserialize])
 	// we may now assume the font is stored on the file system
-	face, err := fp.loadFromDisk()
+	disk := withFilePath(fp, fm.filePaths)
+	face, err := disk.loadFromDisk()
 	if err != nil {
 		return nil, err
 	}
@@ -2,18 +2,22 @@ package fontscan
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io"
 	"log"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/go-text/typesetting/font"
 	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
 	"github.com/go-text/typesetting/language"
 	"github.com/go-text/typesetting/shaping"
 	tu "github.com/go-text/typesetting/testutils"
@@ -67,7 +71,10 @@ func ExampleFontMap_AddFace() {
 	// `fontMap` is now ready for text shaping, using the `ResolveFace` method
 }
 
-var _ shaping.FontmapScript = (*FontMap)(nil)
+var (
+	_ shaping.FontmapScript  = (*FontMap)(nil)
+	_ shaping.FontmapContext = (*FontMap)(nil)
+)
 
 func TestResolveFont(t *testing.T) {
 	var logOutput bytes.Buffer
@@ -150,6 +157,626 @@ func TestResolveFallbackManual(t *testing.T) {
 	tu.Assert(t, face != nil && fm.FontLocation(face.Font).File == "user:Amiri")
 }
 
+func TestPeekFace(t *testing.T) {
+	logger := log.New(os.Stdout, "", 0)
+	fm := NewFontMap(logger)
+
+	file1, err := os.Open("../font/testdata/Amiri-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer file1.Close()
+
+	file2, err := os.Open("../font/testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer file2.Close()
+
+	err = fm.AddFont(file1, "user:Amiri", "")
+	tu.AssertNoErr(t, err)
+	err = fm.AddFont(file2, "user:Roboto", "")
+	tu.AssertNoErr(t, err)
+
+	query := Query{Families: []string{"Amiri"}}
+	fm.SetQuery(query)
+	fm.SetScript(language.Latin)
+	face := fm.ResolveFace('c')
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:Amiri")
+	lruSize := len(fm.lru.m)
+
+	peeked := fm.PeekFace(Query{Families: []string{"Roboto"}}, language.Latin, 'c')
+	tu.Assert(t, fm.FontLocation(peeked.Font).File == "user:Roboto")
+	peeked = fm.PeekFace(Query{}, language.Arabic, 'c')
+	tu.Assert(t, peeked != nil)
+
+	// the state of the font map is preserved
+	tu.Assert(t, fm.built)
+	tu.Assert(t, fm.script == language.Latin)
+	tu.Assert(t, len(fm.query.Families) == 1 && fm.query.Families[0] == "Amiri")
+	tu.Assert(t, len(fm.lru.m) == lruSize)
+	tu.Assert(t, fm.ResolveFace('c') == face)
+}
+
+func TestPreloadCandidates(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+
+	// simulate system fonts, stored on disk
+	for _, file := range []string{"../font/testdata/Roboto-Regular.ttf", "../font/testdata/Amiri-Regular.ttf"} {
+		f, err := os.Open(file)
+		tu.AssertNoErr(t, err)
+		ld, err := ot.NewLoader(f)
+		tu.AssertNoErr(t, err)
+		fp, _, err := newFootprintFromLoader(ld, false, scanBuffer{})
+		tu.AssertNoErr(t, err)
+		f.Close()
+		fp.Location = Location{File: file}
+		fm.appendFootprints(fp)
+	}
+
+	fm.SetQuery(Query{Families: []string{"Roboto"}})
+	fm.SetScript(language.Latin)
+
+	// a canceled preload does not load anything
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	<-fm.PreloadCandidates(ctx)
+	tu.Assert(t, len(fm.preloaded) == 0)
+
+	<-fm.PreloadCandidates(context.Background())
+	roboto := fm.preloaded[Location{File: "../font/testdata/Roboto-Regular.ttf"}]
+	tu.Assert(t, roboto != nil)
+	nbPreloaded := len(fm.preloaded)
+
+	// repeated calls are no-op
+	<-fm.PreloadCandidates(context.Background())
+	tu.Assert(t, len(fm.preloaded) == nbPreloaded)
+	tu.Assert(t, fm.preloaded[Location{File: "../font/testdata/Roboto-Regular.ttf"}] == roboto)
+
+	// the preloaded face is used, and moved to the face cache
+	face := fm.ResolveFace('a')
+	tu.Assert(t, face == roboto)
+	tu.Assert(t, len(fm.preloaded) == nbPreloaded-1)
+	tu.Assert(t, fm.ResolveFace('b') == roboto)
+
+	// faces already in cache are not preloaded again
+	<-fm.PreloadCandidates(context.Background())
+	tu.Assert(t, len(fm.preloaded) == nbPreloaded-1)
+}
+
+func TestPreloadCandidatesRemove(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	for _, file := range []string{"../font/testdata/Roboto-Regular.ttf", "../font/testdata/Amiri-Regular.ttf"} {
+		f, err := os.Open(file)
+		tu.AssertNoErr(t, err)
+		ld, err := ot.NewLoader(f)
+		tu.AssertNoErr(t, err)
+		fp, _, err := newFootprintFromLoader(ld, false, scanBuffer{})
+		tu.AssertNoErr(t, err)
+		f.Close()
+		fp.Location = Location{File: file}
+		fp.isUserProvided = true // lazily loaded, as restored by Deserialize
+		fm.appendFootprints(fp)
+	}
+	fm.SetQuery(Query{Families: []string{"Roboto"}})
+
+	// remove the font while it is being preloaded
+	roboto := Location{File: "../font/testdata/Roboto-Regular.ttf"}
+	loaded, resume := make(chan struct{}), make(chan struct{})
+	preloadHook = func(loc Location) {
+		if loc == roboto {
+			close(loaded)
+			<-resume
+		}
+	}
+	defer func() { preloadHook = nil }()
+
+	done := fm.PreloadCandidates(context.Background())
+	<-loaded
+	tu.Assert(t, fm.RemoveFont(roboto.File))
+	close(resume)
+	<-done
+
+	_, has := fm.preloaded[roboto]
+	tu.Assert(t, !has)
+	tu.Assert(t, fm.FontLocation(fm.ResolveFace('a').Font).File != roboto.File)
+}
+
+func TestResolveFaceForFamilyLang(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	for _, file := range []string{"Amiri-Regular.ttf", "Roboto-Regular.ttf"} {
+		f, err := os.Open("../font/testdata/" + file)
+		tu.AssertNoErr(t, err)
+		err = fm.AddFont(f, "user:"+file, "")
+		tu.AssertNoErr(t, err)
+		f.Close()
+	}
+	fm.SetQuery(Query{Families: []string{"Roboto"}})
+
+	face := fm.ResolveFaceForFamilyLang("Amiri", language.LangAr, 'ب')
+	tu.Assert(t, face != nil && fm.FontLocation(face.Font).File == "user:Amiri-Regular.ttf")
+	face = fm.ResolveFaceForFamilyLang("roboto", language.LangFr, 'é')
+	tu.Assert(t, face != nil && fm.FontLocation(face.Font).File == "user:Roboto-Regular.ttf")
+
+	// the rune must be supported
+	face = fm.ResolveFaceForFamilyLang("Amiri", language.LangAr, '\u4E00')
+	tu.Assert(t, face == nil)
+	// the language must be supported
+	face = fm.ResolveFaceForFamilyLang("Roboto", language.LangJa, 'a')
+	tu.Assert(t, face == nil)
+}
+
+func TestResolveFaceForScript(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	for _, file := range []string{"Amiri-Regular.ttf", "Roboto-Regular.ttf"} {
+		f, err := os.Open("../font/testdata/" + file)
+		tu.AssertNoErr(t, err)
+		err = fm.AddFont(f, "user:"+file, "")
+		tu.AssertNoErr(t, err)
+		f.Close()
+	}
+	fm.SetQuery(Query{}) // no families
+	location := func(face *font.Face) string { return fm.FontLocation(face.Font).File }
+
+	// without language, the first font is used
+	face := fm.ResolveFaceForScript('a', language.Latin, "")
+	tu.Assert(t, location(face) == "user:Amiri-Regular.ttf")
+	tu.Assert(t, fm.script == language.Latin)
+	face = fm.ResolveFaceForScript('a', language.Latin, "xx-unknown")
+	tu.Assert(t, location(face) == "user:Amiri-Regular.ttf")
+
+	// fonts supporting the language are preferred
+	face = fm.ResolveFaceForScript('a', language.Latin, language.NewLanguage("ru"))
+	tu.Assert(t, location(face) == "user:Roboto-Regular.ttf")
+	// .. if they support the rune
+	face = fm.ResolveFaceForScript('ب', language.Arabic, language.NewLanguage("ru"))
+	tu.Assert(t, location(face) == "user:Amiri-Regular.ttf")
+
+	// exact family matches are always preferred
+	fm.SetQuery(Query{Families: []string{"Amiri"}})
+	face = fm.ResolveFaceForScript('a', language.Latin, language.NewLanguage("ru"))
+	tu.Assert(t, location(face) == "user:Amiri-Regular.ttf")
+
+	// the language is used during segmentation
+	fm.SetQuery(Query{})
+	text := []rune("abc")
+	runs := (&shaping.Segmenter{}).Split(shaping.Input{Text: text, RunEnd: len(text), Language: language.NewLanguage("ro")}, fm)
+	tu.Assert(t, len(runs) == 1 && location(runs[0].Face) == "user:Roboto-Regular.ttf")
+}
+
+func TestResolveFaceWithInfo(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	for _, file := range []string{"Amiri-Regular.ttf", "Roboto-Regular.ttf"} {
+		f, err := os.Open("../font/testdata/" + file)
+		tu.AssertNoErr(t, err)
+		err = fm.AddFont(f, "user:"+file, "")
+		tu.AssertNoErr(t, err)
+		f.Close()
+	}
+	fm.SetQuery(Query{Families: []string{"Roboto"}})
+
+	face, info := fm.ResolveFaceWithInfo('a')
+	tu.Assert(t, face == fm.ResolveFace('a'))
+	expFamily, expAspect := fm.FontMetadata(face.Font)
+	tu.Assert(t, info.Family == expFamily && info.Family == font.NormalizeFamily("Roboto"))
+	tu.Assert(t, info.Aspect == expAspect)
+	tu.Assert(t, info.Step == ResolveExact)
+
+	face, info = fm.ResolveFaceWithInfo('ب')
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:Amiri-Regular.ttf")
+	tu.Assert(t, info.Family == font.NormalizeFamily("Amiri"))
+	tu.Assert(t, info.Step == ResolveManual)
+	// the step is also cached
+	_, info = fm.ResolveFaceWithInfo('ب')
+	tu.Assert(t, info.Step == ResolveManual)
+
+	fm.SetQuery(Query{Families: []string{"Amiri"}})
+	_, info = fm.ResolveFaceWithInfo('ب')
+	tu.Assert(t, info.Step == ResolveExact)
+
+	// no font supports the rune
+	face, info = fm.ResolveFaceWithInfo('\u4E00')
+	tu.Assert(t, face != nil)
+	tu.Assert(t, info.Step == ResolveArbitrary)
+}
+
+func TestSyntheticStyle(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	f, err := os.Open("../font/testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer f.Close()
+	tu.AssertNoErr(t, fm.AddFont(f, "user:Roboto", ""))
+
+	fm.SetQuery(Query{Families: []string{"Roboto"}})
+	face, info := fm.ResolveFaceWithInfo('a')
+	tu.Assert(t, info.Synthetic.IsExact() && !info.Synthetic.NeedsBold && !info.Synthetic.NeedsOblique)
+	tu.Assert(t, fm.SyntheticStyle(face.Font) == info.Synthetic)
+
+	// only Regular is available
+	fm.SetQuery(Query{Families: []string{"Roboto"}, Aspect: font.Aspect{Style: font.StyleItalic, Weight: font.WeightBold}})
+	face, info = fm.ResolveFaceWithInfo('a')
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:Roboto")
+	tu.Assert(t, !info.Synthetic.IsExact() && info.Synthetic.NeedsBold && info.Synthetic.NeedsOblique)
+	tu.Assert(t, info.Synthetic.WeightDelta() == 300)
+	tu.Assert(t, info.Synthetic.Actual == info.Aspect)
+	tu.Assert(t, fm.SyntheticStyle(face.Font) == info.Synthetic)
+
+	fm.SetQuery(Query{Families: []string{"Roboto"}, Aspect: font.Aspect{Weight: font.WeightMedium}})
+	ss := fm.SyntheticStyle(face.Font)
+	tu.Assert(t, !ss.IsExact() && !ss.NeedsBold && !ss.NeedsOblique && ss.WeightDelta() == 100)
+
+	// unknown font
+	tu.Assert(t, fm.SyntheticStyle(new(font.Font)) == SyntheticStyle{})
+}
+
+func TestAddVariableFont(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	file, err := os.Open("../font/testdata/Selawik-VF-Subset.ttf")
+	tu.AssertNoErr(t, err)
+	defer file.Close()
+	err = fm.AddFont(file, "user:Selawik", "")
+	tu.AssertNoErr(t, err)
+
+	// one footprint per named instance
+	tu.Assert(t, len(fm.database) == 5)
+	var weights []font.Weight
+	for i, fp := range fm.database {
+		tu.Assert(t, fp.Location.Instance == uint16(i+1))
+		weights = append(weights, fp.Aspect.Weight)
+	}
+	tu.Assert(t, reflect.DeepEqual(weights, []font.Weight{300, 350, 400, 600, 700}))
+
+	fm.SetQuery(Query{Families: []string{"Selawik Variations Test"}, Aspect: font.Aspect{Weight: font.WeightBold}})
+	bold := fm.ResolveFace('a')
+	tu.Assert(t, fm.FontLocation(bold.Font).Instance == 5)
+	_, aspect := fm.FontMetadata(bold.Font)
+	tu.Assert(t, aspect.Weight == font.WeightBold)
+	tu.Assert(t, len(bold.Coords()) == 1 && bold.Coords()[0] != 0)
+
+	fm.SetQuery(Query{Families: []string{"Selawik Variations Test"}, Aspect: font.Aspect{Weight: font.WeightLight}})
+	light := fm.ResolveFace('a')
+	tu.Assert(t, light != bold && light.Font != bold.Font)
+	tu.Assert(t, fm.FontLocation(light.Font).Instance == 1)
+	gid, _ := light.NominalGlyph('a')
+	tu.Assert(t, light.HorizontalAdvance(gid) < bold.HorizontalAdvance(gid))
+}
+
+func TestPreferVariableInstance(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	file, err := os.Open("../font/testdata/Selawik-VF-Subset.ttf")
+	tu.AssertNoErr(t, err)
+	defer file.Close()
+	tu.AssertNoErr(t, fm.AddFont(file, "user:Selawik", ""))
+	// a static face of the same family, with an exact weight match
+	file2, err := os.Open("../font/testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer file2.Close()
+	static, err := font.ParseTTF(file2)
+	tu.AssertNoErr(t, err)
+	fm.AddFace(static, Location{File: "static"}, font.Description{Family: "Selawik Variations Test", Aspect: font.Aspect{Style: font.StyleNormal, Weight: 620, Stretch: font.StretchNormal}})
+
+	query := Query{Families: []string{"Selawik Variations Test"}, Aspect: font.Aspect{Weight: 620}}
+	fm.SetQuery(query)
+	face := fm.ResolveFace('a')
+	tu.Assert(t, face == static)
+
+	query.PreferVariableInstance = true
+	fm.SetQuery(query)
+	face = fm.ResolveFace('a')
+	tu.Assert(t, face != static)
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:Selawik")
+	vars := face.Variations()
+	tu.Assert(t, len(vars) == 1 && math.Abs(float64(vars[0].Value)-620) <= 1)
+	_, aspect := fm.FontMetadata(face.Font)
+	tu.Assert(t, aspect.Weight == 620)
+	tu.Assert(t, fm.SyntheticStyle(face.Font).IsExact())
+	// the configured face is cached
+	fm.SetQuery(query)
+	tu.Assert(t, fm.ResolveFace('b') == face)
+
+	// weights outside the variable range use the regular matching,
+	// without adjusting the closest instance
+	query.Aspect.Weight = font.WeightBlack
+	fm.SetQuery(query)
+	face = fm.ResolveFace('a')
+	tu.Assert(t, fm.FontLocation(face.Font).Instance == 5)
+	_, aspect = fm.FontMetadata(face.Font)
+	tu.Assert(t, aspect.Weight == font.WeightBold)
+
+	// removing the variable font cleans up the cache
+	tu.Assert(t, fm.RemoveFace(Location{File: "user:Selawik", Instance: 4}))
+	tu.Assert(t, len(fm.weightCache) == 0)
+}
+
+func TestFamilies(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	tu.Assert(t, len(fm.Families()) == 0)
+
+	// simulate system fonts, stored on disk and lazily loaded;
+	// Roboto is added twice, as a duplicated file would be
+	for _, file := range []string{"../font/testdata/Roboto-Regular.ttf", "../font/testdata/Amiri-Regular.ttf", "../font/testdata/Roboto-Regular.ttf"} {
+		f, err := os.Open(file)
+		tu.AssertNoErr(t, err)
+		ld, err := ot.NewLoader(f)
+		tu.AssertNoErr(t, err)
+		fp, _, err := newFootprintFromLoader(ld, false, scanBuffer{})
+		tu.AssertNoErr(t, err)
+		f.Close()
+		fp.Location = Location{File: file}
+		fm.appendFootprints(fp)
+	}
+
+	file, err := os.Open("../font/testdata/Selawik-VF-Subset.ttf")
+	tu.AssertNoErr(t, err)
+	defer file.Close()
+	err = fm.AddFont(file, "user:Selawik", "")
+	tu.AssertNoErr(t, err)
+	err = fm.AddFont(file, "user:MyAmiri", "Amiri")
+	tu.AssertNoErr(t, err)
+
+	regular := font.Aspect{Style: font.StyleNormal, Weight: font.WeightNormal, Stretch: font.StretchNormal}
+	var selawikAspects []font.Aspect // the regular instance is shared with Amiri
+	for _, weight := range []font.Weight{300, 350, 400, 600, 700} {
+		selawikAspects = append(selawikAspects, font.Aspect{Style: font.StyleNormal, Weight: weight, Stretch: font.StretchNormal})
+	}
+
+	families := fm.Families()
+	tu.Assert(t, len(families) == 3)
+	tu.Assert(t, reflect.DeepEqual(families[0], FamilyInfo{Family: "amiri", Name: "Amiri", IsUserProvided: true, Aspects: selawikAspects}))
+	tu.Assert(t, reflect.DeepEqual(families[1], FamilyInfo{Family: "roboto", Name: "Roboto", Aspects: []font.Aspect{regular}}))
+	tu.Assert(t, reflect.DeepEqual(families[2], FamilyInfo{Family: "selawikvariationstest", Name: "Selawik Variations test", IsUserProvided: true, Aspects: selawikAspects}))
+
+	// the system fonts have not been loaded
+	for _, fp := range fm.database {
+		if !fp.isUserProvided {
+			_, isLoaded := fm.faceCache[fp.Location]
+			tu.Assert(t, !isLoaded)
+		}
+	}
+}
+
+func TestVariableInstances(t *testing.T) {
+	wght := tables.VariationAxisRecord{Tag: ot.MustNewTag("wght"), Minimum: 250, Default: 400, Maximum: 720}
+	wdth := tables.VariationAxisRecord{Tag: ot.MustNewTag("wdth"), Minimum: 75, Default: 100, Maximum: 100}
+
+	// no named instances : use standard weights
+	fvar := tables.Fvar{FvarRecords: tables.FvarRecords{Axis: []tables.VariationAxisRecord{wdth, wght}}}
+	instances := variableInstances(fvar)
+	tu.Assert(t, reflect.DeepEqual(instances, [][]float32{{100, 300}, {100, 400}, {100, 500}, {100, 600}, {100, 700}}))
+
+	// no weight axis
+	fvar = tables.Fvar{FvarRecords: tables.FvarRecords{Axis: []tables.VariationAxisRecord{wdth}}}
+	tu.Assert(t, variableInstances(fvar) == nil)
+	tu.Assert(t, variableInstances(tables.Fvar{}) == nil)
+
+	aspect := instanceAspect([]tables.VariationAxisRecord{wdth, wght, {Tag: ot.MustNewTag("ital")}}, []float32{75, 600, 1}, font.Aspect{Style: font.StyleNormal, Weight: 400, Stretch: 1})
+	tu.Assert(t, aspect == font.Aspect{Style: font.StyleItalic, Weight: 600, Stretch: font.StretchCondensed})
+}
+
+func TestResolveFaceForString(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	for _, file := range []string{"Roboto-Regular.ttf", "Amiri-Regular.ttf"} {
+		f, err := os.Open("../font/testdata/" + file)
+		tu.AssertNoErr(t, err)
+		err = fm.AddFont(f, "user:"+file, "")
+		tu.AssertNoErr(t, err)
+		f.Close()
+	}
+	fm.SetQuery(Query{Families: []string{"Roboto"}})
+	fm.SetScript(language.Latin)
+	roboto, amiri := fm.ResolveFace('a'), fm.ResolveFace('ب')
+	tu.Assert(t, roboto != amiri)
+
+	text := []rune("hello سلام  world")
+	runs := fm.ResolveFaceForString(text)
+	tu.Assert(t, reflect.DeepEqual(runs, []FaceRun{
+		{0, 6, roboto}, // the space does not trigger a change
+		{6, 12, amiri},
+		{12, 17, roboto},
+	}))
+
+	// whitespace only and empty strings
+	runs = fm.ResolveFaceForString([]rune("  "))
+	tu.Assert(t, len(runs) == 1 && runs[0] == FaceRun{0, 2, fm.ResolveFace(' ')})
+	runs = fm.ResolveFaceForString(nil)
+	tu.Assert(t, len(runs) == 1 && runs[0] == FaceRun{0, 0, fm.ResolveFace(' ')})
+
+	// the query is honored
+	fm.SetQuery(Query{Families: []string{"Amiri"}})
+	runs = fm.ResolveFaceForString([]rune("abc"))
+	tu.Assert(t, len(runs) == 1 && runs[0].Face == amiri)
+}
+
+func TestCoverageScore(t *testing.T) {
+	logger := log.New(os.Stdout, "", 0)
+	fm := NewFontMap(logger)
+
+	file1, err := os.Open("../font/testdata/Amiri-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer file1.Close()
+
+	file2, err := os.Open("../font/testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer file2.Close()
+
+	err = fm.AddFont(file1, "user:Amiri", "")
+	tu.AssertNoErr(t, err)
+	err = fm.AddFont(file2, "user:Roboto", "")
+	tu.AssertNoErr(t, err)
+
+	amiri, roboto := Location{File: "user:Amiri"}, Location{File: "user:Roboto"}
+	latin, arabic := []rune("Hello"), []rune("مرحبا")
+	mixed := []rune("Helloمرحبا\n\u200d")
+
+	tu.Assert(t, fm.CoverageScore(roboto, latin) == 1)
+	tu.Assert(t, fm.CoverageScore(roboto, arabic) == 0)
+	tu.Assert(t, fm.CoverageScore(roboto, mixed) == 0.5)
+	tu.Assert(t, fm.CoverageScore(amiri, arabic) == 1)
+	tu.Assert(t, fm.CoverageScore(amiri, nil) == 1)
+	tu.Assert(t, fm.CoverageScore(Location{File: "unknown"}, latin) == 0)
+
+	coverage, ok := fm.Coverage(roboto)
+	tu.Assert(t, ok && coverage.Contains('H') && !coverage.Contains('م'))
+	tu.Assert(t, len(coverage.Ranges()) != 0)
+	// the returned set is a copy
+	coverage.Add('م')
+	tu.Assert(t, fm.CoverageScore(roboto, arabic) == 0)
+	_, ok = fm.Coverage(Location{File: "unknown"})
+	tu.Assert(t, !ok)
+}
+
+func TestEmbeddingPermission(t *testing.T) {
+	logger := log.New(os.Stdout, "", 0)
+	fm := NewFontMap(logger)
+
+	file1, err := os.Open("../font/testdata/Selawik-VF-Subset.ttf")
+	tu.AssertNoErr(t, err)
+	defer file1.Close()
+
+	file2, err := os.Open("../font/testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer file2.Close()
+
+	err = fm.AddFont(file1, "user:Selawik", "")
+	tu.AssertNoErr(t, err)
+	err = fm.AddFont(file2, "user:Roboto", "")
+	tu.AssertNoErr(t, err)
+
+	// variable font : one footprint per named instance
+	tu.Assert(t, fm.EmbeddingPermission(Location{File: "user:Selawik", Instance: 1}) == font.EmbedEditable)
+	tu.Assert(t, fm.EmbeddingPermission(Location{File: "user:Roboto"}) == font.EmbedInstallable)
+	tu.Assert(t, fm.EmbeddingPermission(Location{File: "unknown"}) == font.EmbedRestricted)
+}
+
+func TestConfigHash(t *testing.T) {
+	logger := log.New(os.Stdout, "", 0)
+	fm1, fm2 := NewFontMap(logger), NewFontMap(logger)
+	tu.Assert(t, fm1.ConfigHash() == fm2.ConfigHash())
+
+	addFont := func(fm *FontMap, filename, fileID string) {
+		file, err := os.Open(filename)
+		tu.AssertNoErr(t, err)
+		defer file.Close()
+		err = fm.AddFont(file, fileID, "")
+		tu.AssertNoErr(t, err)
+	}
+
+	addFont(fm1, "../font/testdata/Amiri-Regular.ttf", "user:Amiri")
+	tu.Assert(t, fm1.ConfigHash() != fm2.ConfigHash())
+	addFont(fm2, "../font/testdata/Amiri-Regular.ttf", "user:Amiri")
+	tu.Assert(t, fm1.ConfigHash() == fm2.ConfigHash())
+
+	// the query does not change the hash
+	hash := fm1.ConfigHash()
+	fm1.SetQuery(Query{Families: []string{"serif"}})
+	fm1.SetScript(language.Arabic)
+	tu.Assert(t, fm1.ConfigHash() == hash)
+
+	// a different location changes the hash
+	addFont(fm1, "../font/testdata/Roboto-Regular.ttf", "user:Roboto")
+	addFont(fm2, "../font/testdata/Roboto-Regular.ttf", "user:Roboto2")
+	tu.Assert(t, fm1.ConfigHash() != fm2.ConfigHash())
+	tu.Assert(t, fm1.ConfigHash() != hash)
+
+	// fallback settings change the hash
+	hash = fm1.ConfigHash()
+	fm1.SetScriptFallback(language.Arabic, []string{"Amiri"})
+	tu.Assert(t, fm1.ConfigHash() != hash)
+	fm1.SetScriptFallback(language.Arabic, nil)
+	tu.Assert(t, fm1.ConfigHash() == hash)
+
+	face, err := fm1.loadFont(fm1.database[0])
+	tu.AssertNoErr(t, err)
+	fm1.AddLastResortFace(face)
+	withLastResort := fm1.ConfigHash()
+	tu.Assert(t, withLastResort != hash)
+	fm1.lastResorts = nil
+	fm1.AddLastResortFace(face, language.Arabic)
+	tu.Assert(t, fm1.ConfigHash() != withLastResort && fm1.ConfigHash() != hash)
+	fm1.lastResorts = nil
+
+	fm1.SetLastResortFace(face)
+	tu.Assert(t, fm1.ConfigHash() != hash)
+	fm1.SetLastResortFace(nil)
+	tu.Assert(t, fm1.ConfigHash() == hash)
+}
+
+func TestResolveLastResort(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	fm := NewFontMap(logger)
+
+	file1, err := os.Open("../font/testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer file1.Close()
+	err = fm.AddFont(file1, "user:Roboto", "")
+	tu.AssertNoErr(t, err)
+
+	file2, err := os.Open("../font/testdata/Amiri-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer file2.Close()
+	amiri, err := font.ParseTTF(file2)
+	tu.AssertNoErr(t, err)
+	file3, err := os.Open("../font/testdata/UbuntuMono-R.ttf")
+	tu.AssertNoErr(t, err)
+	defer file3.Close()
+	mono, err := font.ParseTTF(file3)
+	tu.AssertNoErr(t, err)
+
+	fm.SetQuery(Query{Families: []string{"Roboto"}})
+	fm.SetScript(language.Arabic)
+	roboto := fm.ResolveFace('a')
+	tu.Assert(t, fm.FontLocation(roboto.Font).File == "user:Roboto")
+	// no coverage : arbitrary face
+	tu.Assert(t, fm.ResolveFace('ب') == roboto)
+
+	fm.AddLastResortFace(mono)
+	fm.AddLastResortFace(amiri, language.Arabic)
+	tu.Assert(t, fm.ResolveFace('ب') == amiri)
+	tu.Assert(t, fm.ResolveFace('a') == roboto)
+
+	// Amiri is only registered for Arabic, and mono does not support the rune
+	fm.SetScript(language.Latin)
+	tu.Assert(t, fm.ResolveFace('ب') == roboto)
+	fm.AddLastResortFace(amiri)
+	tu.Assert(t, fm.ResolveFace('ب') == amiri)
+}
+
+func TestSetLastResortFace(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	for _, file := range []string{"../font/testdata/Roboto-Regular.ttf", "../font/testdata/Amiri-Regular.ttf"} {
+		f, err := os.Open(file)
+		tu.AssertNoErr(t, err)
+		defer f.Close()
+		tu.AssertNoErr(t, fm.AddFont(f, "user:"+filepath.Base(file), ""))
+	}
+	file, err := os.Open("../font/testdata/UbuntuMono-R.ttf")
+	tu.AssertNoErr(t, err)
+	defer file.Close()
+	mono, err := font.ParseTTF(file)
+	tu.AssertNoErr(t, err)
+
+	// the arbitrary face does not depend on the loading order
+	fm.SetQuery(Query{Families: []string{"Roboto"}})
+	face, info := fm.ResolveFaceWithInfo('\u4E00')
+	tu.Assert(t, info.Step == ResolveArbitrary)
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:Amiri-Regular.ttf")
+
+	fm.SetLastResortFace(mono)
+	face, info = fm.ResolveFaceWithInfo('\u4E00')
+	tu.Assert(t, info.Step == ResolveArbitrary && face == mono)
+	tu.Assert(t, fm.Snapshot().ResolveFace('\u4E00').Font == mono.Font)
+	// supported runes are not affected
+	tu.Assert(t, fm.ResolveFace('a') != mono)
+
+	fm.SetLastResortFace(nil)
+	face = fm.ResolveFace('\u4E00')
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:Amiri-Regular.ttf")
+
+	// the arbitrary face is updated when the database changes
+	fm.AddFace(font.NewFace(mono.Font), Location{File: "user:mono"}, font.Description{Family: "Abc"})
+	face = fm.ResolveFace('\u4E00')
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:mono")
+	tu.Assert(t, fm.RemoveFont("user:mono"))
+	face = fm.ResolveFace('\u4E00')
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:Amiri-Regular.ttf")
+}
+
 func TestResolveLang(t *testing.T) {
 	logger := log.New(os.Stdout, "", 0)
 	fm := NewFontMap(logger)
@@ -238,19 +865,60 @@ func Test_refreshSystemFontsIndex(t *testing.T) {
 	cachePath := filepath.Join(dir, "fonts.cache")
 
 	logger := log.New(io.Discard, "", 0)
-	_, err := refreshSystemFontsIndex(logger, cachePath)
+	_, _, err := refreshSystemFontsIndex(logger, cachePath, nil)
 	tu.AssertNoErr(t, err)
 
 	ti := time.Now()
-	_, err = refreshSystemFontsIndex(logger, cachePath)
+	_, _, err = refreshSystemFontsIndex(logger, cachePath, nil)
 	tu.AssertNoErr(t, err)
 
 	fmt.Printf("cache refresh in %s\n", time.Since(ti))
 }
 
+func TestUseFontsInDirectories(t *testing.T) {
+	fontDir, cacheDir := t.TempDir(), t.TempDir()
+	tu.AssertNoErr(t, os.Mkdir(filepath.Join(fontDir, "arabic"), 0o700))
+	copyFile(t, "../font/testdata/Roboto-Regular.ttf", filepath.Join(fontDir, "roboto.ttf"))
+	copyFile(t, "../font/testdata/Amiri-Regular.ttf", filepath.Join(fontDir, "arabic", "amiri.ttf"))
+
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	err := fm.UseFontsInDirectories(cacheDir, fontDir)
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, len(fm.database) == 2 && len(fm.faceCache) == 0) // lazy loading
+	tu.Assert(t, len(fm.ScanErrors()) == 0)
+	tu.Assert(t, len(fm.scriptMap[language.Arabic]) == 1)
+
+	// the index is cached, with a name depending on the directories
+	entries, err := os.ReadDir(cacheDir)
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, len(entries) == 1)
+	tu.Assert(t, hashDirectories([]string{fontDir, "../font"}) == hashDirectories([]string{"../font/", fontDir}))
+	tu.Assert(t, hashDirectories([]string{fontDir}) != hashDirectories([]string{fontDir, "../font"}))
+
+	fm.SetQuery(Query{Families: []string{"Amiri"}})
+	face := fm.ResolveFace('ب')
+	tu.Assert(t, fm.FontLocation(face.Font).File == filepath.Join(fontDir, "arabic", "amiri.ttf"))
+
+	// using the cache
+	fm2 := NewFontMap(log.New(io.Discard, "", 0))
+	err = fm2.UseFontsInDirectories(cacheDir, fontDir)
+	tu.AssertNoErr(t, err)
+	tu.AssertNoErr(t, assertFontsetEquals(fm.database, fm2.database))
+
+	// unreadable directories are reported but do not fail the scan
+	missing := filepath.Join(fontDir, "missing")
+	err = fm2.UseFontsInDirectories(cacheDir, fontDir, missing)
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, len(fm2.ScanErrors()) == 1 && fm2.ScanErrors()[0].Path == missing)
+
+	// no valid fonts
+	err = NewFontMap(log.New(io.Discard, "", 0)).UseFontsInDirectories(cacheDir, t.TempDir())
+	tu.Assert(t, err != nil)
+}
+
 func TestInitSystemFonts(t *testing.T) {
 	logger := log.New(io.Discard, "", 0)
-	err := initSystemFonts(logger, t.TempDir())
+	err := initSystemFonts(logger, t.TempDir(), nil)
 	tu.AssertNoErr(t, err)
 
 	tu.AssertC(t, len(systemFonts.flatten()) != 0, "systemFonts should not be empty")
@@ -302,6 +970,141 @@ func TestFontMap_AddFont_FaceLocation(t *testing.T) {
 	tu.Assert(t, fm.FontLocation(face.Font).File == "Roboto2")
 }
 
+func TestAddFaces(t *testing.T) {
+	var faces []*font.Face
+	for _, file := range []string{"../font/testdata/Amiri-Regular.ttf", "../font/testdata/Roboto-Regular.ttf"} {
+		f, err := os.Open(file)
+		tu.AssertNoErr(t, err)
+		defer f.Close()
+		face, err := font.ParseTTF(f)
+		tu.AssertNoErr(t, err)
+		faces = append(faces, face)
+	}
+	mds := []font.Description{faces[0].Describe(), faces[1].Describe()}
+
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	tu.Assert(t, fm.AddFaces(nil, Location{File: "user:collection"}, nil) != nil)
+	tu.Assert(t, fm.AddFaces(faces, Location{File: "user:collection"}, mds[:1]) != nil)
+	tu.Assert(t, len(fm.database) == 0)
+
+	tu.AssertNoErr(t, fm.AddFaces(faces, Location{File: "user:collection"}, mds))
+	tu.Assert(t, len(fm.database) == 2)
+	tu.Assert(t, fm.firstFace == faces[0])
+	for i, face := range faces {
+		tu.Assert(t, fm.FontLocation(face.Font) == Location{File: "user:collection", Index: uint16(i)})
+	}
+
+	fm.SetQuery(Query{Families: []string{"Roboto"}})
+	tu.Assert(t, fm.ResolveFace('a') == faces[1])
+	tu.Assert(t, fm.RemoveFace(Location{File: "user:collection", Index: 1}))
+	tu.Assert(t, fm.ResolveFace('a') == faces[0])
+}
+
+func TestSetScriptFallback(t *testing.T) {
+	amiri, err := os.Open("../font/testdata/Amiri-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer amiri.Close()
+	roboto, err := os.Open("../font/testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer roboto.Close()
+	bold, err := font.ParseTTF(roboto)
+	tu.AssertNoErr(t, err)
+
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	fm.AddFace(bold, Location{File: "bold"}, font.Description{Family: "Bold Latin", Aspect: font.Aspect{Style: font.StyleNormal, Weight: font.WeightBold, Stretch: font.StretchNormal}})
+	tu.AssertNoErr(t, fm.AddFont(amiri, "user:Amiri1", "Arabic One"))
+	tu.AssertNoErr(t, fm.AddFont(amiri, "user:Amiri2", "Arabic Two"))
+
+	// the regular Arabic fonts are discarded by the aspect matching,
+	// so that the script coverage step is used
+	fm.SetQuery(Query{Families: []string{"Bold Latin"}, Aspect: font.Aspect{Weight: font.WeightBold}})
+	fm.SetScript(language.Arabic)
+	face, info := fm.ResolveFaceWithInfo('ب')
+	tu.Assert(t, info.Step == ResolveScriptCoverage)
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:Amiri1")
+
+	fm.SetScriptFallback(language.Arabic, []string{"Unknown", "arabic two"})
+	face, info = fm.ResolveFaceWithInfo('ب')
+	tu.Assert(t, info.Step == ResolveScriptCoverage)
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:Amiri2")
+
+	// the snapshot shares the overrides
+	tu.Assert(t, fm.Snapshot().ResolveFace('ب').Font == face.Font)
+
+	// other scripts are not affected
+	fm.SetScriptFallback(language.Hebrew, []string{"Arabic One"})
+	tu.Assert(t, fm.ResolveFace('ب') == face)
+
+	// unknown families are ignored
+	fm.SetScriptFallback(language.Arabic, []string{"Unknown"})
+	face = fm.ResolveFace('ب')
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:Amiri1")
+
+	fm.SetScriptFallback(language.Arabic, []string{"Arabic Two"})
+	fm.SetScriptFallback(language.Arabic, nil)
+	face = fm.ResolveFace('ب')
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:Amiri1")
+}
+
+func TestRemoveFont(t *testing.T) {
+	amiri, err := os.Open("../font/testdata/Amiri-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer amiri.Close()
+	roboto, err := os.Open("../font/testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer roboto.Close()
+	selawik, err := os.Open("../font/testdata/Selawik-VF-Subset.ttf")
+	tu.AssertNoErr(t, err)
+	defer selawik.Close()
+
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	// a system font, which can't be removed
+	fm.appendFootprints(Footprint{Family: "nimbus", Location: Location{File: "nimbus.ttf"}})
+
+	tu.AssertNoErr(t, fm.AddFont(amiri, "user:Amiri", ""))
+	tu.AssertNoErr(t, fm.AddFont(roboto, "user:MyFont", "My Font"))
+	tu.AssertNoErr(t, fm.AddFont(selawik, "user:Selawik", ""))
+	firstFace := fm.faceCache[Location{File: "user:Amiri"}]
+	tu.Assert(t, fm.firstFace == firstFace)
+
+	fm.SetQuery(Query{Families: []string{"My Font"}})
+	face := fm.ResolveFace('a')
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:MyFont")
+
+	tu.Assert(t, !fm.RemoveFont("user:Unknown"))
+	tu.Assert(t, !fm.RemoveFont("nimbus.ttf"))
+	tu.Assert(t, !fm.RemoveFace(Location{File: "nimbus.ttf"}))
+
+	// replace the font
+	tu.Assert(t, fm.RemoveFont("user:MyFont"))
+	tu.Assert(t, fm.FontLocation(face.Font) == Location{})
+	tu.AssertNoErr(t, fm.AddFont(amiri, "user:MyFont", "My Font"))
+	face = fm.ResolveFace('a')
+	tu.Assert(t, fm.FontLocation(face.Font).File == "user:MyFont")
+	_, hasArabic := face.NominalGlyph('ب')
+	tu.Assert(t, hasArabic)
+
+	// remove one instance of a variable font
+	tu.Assert(t, len(fm.database) == 1+1+5+1)
+	tu.Assert(t, fm.RemoveFace(Location{File: "user:Selawik", Instance: 2}))
+	tu.Assert(t, len(fm.database) == 1+1+4+1)
+	tu.Assert(t, fm.RemoveFont("user:Selawik"))
+	tu.Assert(t, len(fm.database) == 1+1+1)
+
+	// the script map is consistent with the database
+	for script, indices := range fm.scriptMap {
+		for _, index := range indices {
+			tu.Assert(t, fm.database[index].Scripts.contains(script))
+		}
+	}
+	tu.Assert(t, len(fm.scriptMap[language.Arabic]) == 2)
+
+	// the first face is updated
+	tu.Assert(t, fm.RemoveFont("user:Amiri"))
+	tu.Assert(t, fm.firstFace != firstFace && fm.firstFace == fm.faceCache[Location{File: "user:MyFont"}])
+	tu.Assert(t, len(fm.faceCache) == 1 && len(fm.metaCache) == 1)
+}
+
 func TestQueryHelveticaLinux(t *testing.T) {
 	// This is a regression test which asserts that
 	// our behavior is similar than fontconfig
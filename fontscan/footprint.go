@@ -14,6 +14,9 @@ import (
 // Location identifies where a font.Face is stored.
 type Location = font.FontID
 
+// EmbedPermission stores the embedding licensing rights of a font.
+type EmbedPermission = font.EmbedPermission
+
 // Footprint is a condensed summary of the main information
 // about a font, serving as a lightweight surrogate
 // for the original font file.
@@ -27,6 +30,11 @@ type Footprint struct {
 	// normalized version of the family name.
 	Family string
 
+	// FamilyName is the family name as found in the font
+	// (or as provided to [FontMap.AddFont]), before normalization.
+	// It is suited for display, for instance in a font picker.
+	FamilyName string
+
 	// Runes is the set of runes supported by the font.
 	Runes RuneSet
 
@@ -40,6 +48,10 @@ type Footprint struct {
 	// of the font among a family, like "Bold Italic"
 	Aspect font.Aspect
 
+	// EmbedPermission is the embedding licensing rights
+	// of the font, read from the 'OS/2' table.
+	EmbedPermission EmbedPermission
+
 	// isUserProvided is set to true for fonts add manually to
 	// a FontMap
 	// User fonts will always be tried if no other fonts match,
@@ -54,12 +66,18 @@ func newFootprintFromFont(f *font.Font, location Location, md font.Description)
 	out.Runes, out.Scripts, _ = newCoveragesFromCmap(f.Cmap, nil)
 	out.Langs = newLangsetFromCoverage(out.Runes)
 	out.Family = font.NormalizeFamily(md.Family)
+	out.FamilyName = md.Family
 	out.Aspect = md.Aspect
+	out.EmbedPermission = f.EmbedPermission()
 	out.Location = location
 	out.isUserProvided = true
 	return out
 }
 
+// newFootprintFromLoader is the fast path used when scanning font files :
+// only the 'OS/2', 'cmap', 'name' and 'head' tables are read (using the table directory
+// of [ld]), and the layout and glyph tables are never parsed.
+// The font itself is loaded only when it is selected by [FontMap.ResolveFace].
 func newFootprintFromLoader(ld *ot.Loader, isUserProvided bool, buffer scanBuffer) (out Footprint, _ scanBuffer, err error) {
 	raw := buffer.tableBuffer
 
@@ -67,8 +85,10 @@ func newFootprintFromLoader(ld *ot.Loader, isUserProvided bool, buffer scanBuffe
 
 	raw, _ = ld.RawTableTo(ot.MustNewTag("OS/2"), raw)
 	fp := tables.FPNone
-	if os2, _, err := tables.ParseOs2(raw); err != nil {
+	if os2, _, err := tables.ParseOs2(raw); err == nil {
+		// the font page is required to select the same cmap encoding as [font.NewFont]
 		fp = os2.FontPage()
+		out.EmbedPermission = EmbedPermission(os2.FsType())
 	}
 
 	// we can use the buffer since ProcessCmap do not keep any reference on
@@ -92,6 +112,7 @@ func newFootprintFromLoader(ld *ot.Loader, isUserProvided bool, buffer scanBuffe
 
 	desc, raw := font.Describe(ld, raw)
 	out.Family = font.NormalizeFamily(desc.Family)
+	out.FamilyName = desc.Family
 	out.Aspect = desc.Aspect
 	out.isUserProvided = isUserProvided
 
@@ -100,6 +121,150 @@ func newFootprintFromLoader(ld *ot.Loader, isUserProvided bool, buffer scanBuffe
 	return out, buffer, nil
 }
 
+// newFootprintsFromLoader returns the footprints of the font in [ld] : one for each
+// instance of a variable font (see [variableInstances]), with their [Location.Instance]
+// and [Footprint.Aspect] set accordingly, or only one for other fonts.
+func newFootprintsFromLoader(ld *ot.Loader, isUserProvided bool, buffer scanBuffer) ([]Footprint, scanBuffer, error) {
+	fp, buffer, err := newFootprintFromLoader(ld, isUserProvided, buffer)
+	if err != nil {
+		return nil, buffer, err
+	}
+
+	var fvar tables.Fvar
+	fvar, buffer.tableBuffer = loadFvar(ld, buffer.tableBuffer)
+	instances := variableInstances(fvar)
+	if len(instances) == 0 {
+		return []Footprint{fp}, buffer, nil
+	}
+
+	// the coverage is shared between instances
+	out := make([]Footprint, len(instances))
+	for i, coords := range instances {
+		out[i] = fp
+		out[i].Location.Instance = uint16(i + 1)
+		out[i].Aspect = instanceAspect(fvar.Axis, coords, fp.Aspect)
+	}
+	return out, buffer, nil
+}
+
+// loadFvar returns the 'fvar' table of the font, or the zero value
+// for non variable fonts.
+func loadFvar(ld *ot.Loader, buffer []byte) (tables.Fvar, []byte) {
+	raw, err := ld.RawTableTo(ot.MustNewTag("fvar"), buffer)
+	if err != nil {
+		return tables.Fvar{}, raw
+	}
+	fvar, _, err := tables.ParseFvar(raw)
+	if err != nil {
+		return tables.Fvar{}, raw
+	}
+	return fvar, raw
+}
+
+// standardWeights are the CSS weights used to synthesize the
+// instances of variable fonts without named instances.
+var standardWeights = [...]font.Weight{
+	font.WeightThin, font.WeightExtraLight, font.WeightLight, font.WeightNormal, font.WeightMedium,
+	font.WeightSemibold, font.WeightBold, font.WeightExtraBold, font.WeightBlack,
+}
+
+// variableInstances returns the design coordinates of the instances exposed
+// by a variable font, that is the named instances of the 'fvar' table, or,
+// if there is none, instances synthesized at the standard weights supported by the 'wght' axis.
+// It returns nil for non variable fonts (or fonts with no named instances and no weight axis).
+//
+// [Location.Instance] is 1 + the index into the returned slice.
+func variableInstances(fvar tables.Fvar) [][]float32 {
+	if len(fvar.Axis) == 0 {
+		return nil
+	}
+
+	if len(fvar.Instances) != 0 {
+		out := make([][]float32, 0, len(fvar.Instances))
+		for _, instance := range fvar.Instances {
+			out = append(out, instance.Coordinates)
+		}
+		return out
+	}
+
+	wghtIndex := -1
+	for i, axis := range fvar.Axis {
+		if axis.Tag == ot.MustNewTag("wght") && axis.Minimum < axis.Maximum {
+			wghtIndex = i
+			break
+		}
+	}
+	if wghtIndex == -1 {
+		return nil
+	}
+
+	wght := fvar.Axis[wghtIndex]
+	var out [][]float32
+	for _, weight := range standardWeights {
+		if w := float32(weight); w < wght.Minimum || w > wght.Maximum {
+			continue
+		}
+		coords := make([]float32, len(fvar.Axis))
+		for i, axis := range fvar.Axis {
+			coords[i] = axis.Default
+		}
+		coords[wghtIndex] = float32(weight)
+		out = append(out, coords)
+	}
+	return out
+}
+
+// instanceAspect returns [base] updated with the values of the registered
+// axes found in [coords].
+func instanceAspect(axes []tables.VariationAxisRecord, coords []float32, base font.Aspect) font.Aspect {
+	for i, axis := range axes {
+		if i >= len(coords) {
+			break
+		}
+		v := coords[i]
+		switch axis.Tag {
+		case ot.MustNewTag("wght"):
+			base.Weight = font.Weight(v)
+		case ot.MustNewTag("wdth"): // in percent
+			base.Stretch = font.Stretch(v / 100)
+		case ot.MustNewTag("ital"):
+			if v >= 0.5 {
+				base.Style = font.StyleItalic
+			} else {
+				base.Style = font.StyleNormal
+			}
+		case ot.MustNewTag("slnt"):
+			if v != 0 {
+				base.Style = font.StyleItalic
+			}
+		}
+	}
+	return base
+}
+
+// newInstanceFace returns a face for the variable font [ft], with the variations of
+// [instance] applied (see [Location.Instance]).
+// [ld] is the loader [ft] has been built from.
+//
+// Each face uses its own copy of [ft], so that the instances may be distinguished by
+// [FontMap.FontLocation] and [FontMap.FontMetadata].
+func newInstanceFace(ft *font.Font, ld *ot.Loader, instance uint16) (*font.Face, error) {
+	fvar, _ := loadFvar(ld, nil)
+	instances := variableInstances(fvar)
+	if instance == 0 || int(instance) > len(instances) {
+		return nil, fmt.Errorf("invalid variable font instance: %d", instance)
+	}
+	coords := instances[instance-1]
+	if len(coords) != len(fvar.Axis) {
+		return nil, fmt.Errorf("invalid variable font instance: %d", instance)
+	}
+
+	cp := *ft // shallow copy
+	face := font.NewFace(&cp)
+	face.SetCoords(cp.NormalizeVariations(coords))
+	return face, nil
+}
+
 // returns true for .ttf and .ttc font files
 func (fp *Footprint) isTruetypeHint() bool {
 	switch strings.ToLower(filepath.Ext(fp.Location.File)) {
@@ -126,7 +291,7 @@ func (fp *Footprint) loadFromDisk() (*font.Face, error) {
 	}
 	defer file.Close()
 
-	loaders, err := ot.NewLoaders(file)
+	loaders, err := font.NewLoaders(file)
 	if err != nil {
 		return nil, err
 	}
@@ -137,10 +302,23 @@ func (fp *Footprint) loadFromDisk() (*font.Face, error) {
 		return nil, fmt.Errorf("invalid font index in collection: %d >= %d", index, len(loaders))
 	}
 
-	ft, err := font.NewFont(loaders[location.Index])
+	ld := loaders[location.Index]
+	ft, err := font.NewFont(ld)
 	if err != nil {
 		return nil, fmt.Errorf("reading font at %s: %s", location.File, err)
 	}
 
+	if location.Instance != 0 {
+		return newInstanceFace(ft, ld, location.Instance)
+	}
 	return font.NewFace(ft), nil
 }
+
+// withFilePath returns [fp], with its location file replaced
+// by the path registered in [paths], if any.
+func withFilePath(fp Footprint, paths map[string]string) Footprint {
+	if path, ok := paths[fp.Location.File]; ok {
+		fp.Location.File = path
+	}
+	return fp
+}
@@ -13,6 +13,7 @@ type runeLRUEntry struct {
 	key        runeLRUKey
 	families   []string
 	v          *font.Face
+	step       ResolveStep
 }
 
 type runeLRUKey struct {
@@ -20,6 +21,7 @@ type runeLRUKey struct {
 	s            language.Script
 	aspect       font.Aspect
 	r            rune
+	variable     bool // Query.PreferVariableInstance
 }
 
 // runeLRU is a least-recently-used cache for font faces supporting a given rune.
@@ -60,25 +62,26 @@ func (l *runeLRU) KeyFor(q Query, s language.Script, r rune) runeLRUKey {
 		s:            s,
 		aspect:       q.Aspect,
 		r:            r,
+		variable:     q.PreferVariableInstance,
 	}
 }
 
 // Get fetches the value associated with the given key, if any.
-func (l *runeLRU) Get(k runeLRUKey, q Query) (*font.Face, bool) {
+func (l *runeLRU) Get(k runeLRUKey, q Query) (*font.Face, ResolveStep, bool) {
 	if lt, ok := l.m[k]; ok {
 		if len(lt.families) != len(q.Families) {
-			return nil, false
+			return nil, 0, false
 		}
 		for i := range lt.families {
 			if lt.families[i] != q.Families[i] {
-				return nil, false
+				return nil, 0, false
 			}
 		}
 		l.remove(lt)
 		l.insert(lt)
-		return lt.v, true
+		return lt.v, lt.step, true
 	}
-	return nil, false
+	return nil, 0, false
 }
 
 func copyStrSlice(s []string) []string {
@@ -89,9 +92,9 @@ func copyStrSlice(s []string) []string {
 
 // Put inserts the given value with the given key, evicting old
 // cache entries if necessary.
-func (l *runeLRU) Put(k runeLRUKey, q Query, v *font.Face) {
+func (l *runeLRU) Put(k runeLRUKey, q Query, v *font.Face, step ResolveStep) {
 	l.init()
-	val := &runeLRUEntry{key: k, v: v, families: copyStrSlice(q.Families)}
+	val := &runeLRUEntry{key: k, v: v, step: step, families: copyStrSlice(q.Families)}
 	l.m[k] = val
 	l.insert(val)
 	for len(l.m) > l.maxSize {
@@ -19,6 +19,13 @@ type Query struct {
 	// Aspect selects which particular face to use among
 	// the font matching the family criteria.
 	Aspect font.Aspect
+
+	// PreferVariableInstance favors variable fonts over static ones :
+	// when the instances of a variable font span the requested weight,
+	// they are selected even if a static face has an exact match, and
+	// the resolved face is configured to the exact requested weight
+	// (using its 'wght' axis), instead of the closest instance.
+	PreferVariableInstance bool
 }
 
 // fontSet stores the list of fonts available for text shaping.
@@ -410,10 +417,63 @@ func (fs fontSet) filterByWeight(candidates []int, weight font.Weight) []int {
 	return candidates
 }
 
+// filterVariableSpanning selects the variable fonts whose instances span [weight],
+// retaining for each of them its instance closest to [weight].
+// If at least one variable font is selected, `candidates` is updated in place and
+// the number of selected footprints is returned; otherwise, `candidates` is not modified.
+func (fs fontSet) filterVariableSpanning(candidates []int, weight font.Weight) int {
+	type span struct {
+		min, max font.Weight
+		closest  int // index into fs
+	}
+	var (
+		spans = map[Location]*span{} // keyed by the font location, ignoring the instance
+		order []Location
+	)
+	for _, index := range candidates {
+		fp := fs[index]
+		if fp.Location.Instance == 0 {
+			continue
+		}
+		key := fp.Location
+		key.Instance = 0
+		w := fp.Aspect.Weight
+		sp, ok := spans[key]
+		if !ok {
+			spans[key] = &span{min: w, max: w, closest: index}
+			order = append(order, key)
+			continue
+		}
+		if w < sp.min {
+			sp.min = w
+		}
+		if w > sp.max {
+			sp.max = w
+		}
+		if math.Abs(float64(w-weight)) < math.Abs(float64(fs[sp.closest].Aspect.Weight-weight)) {
+			sp.closest = index
+		}
+	}
+
+	var selected []int
+	for _, key := range order {
+		if sp := spans[key]; sp.min <= weight && weight <= sp.max {
+			selected = append(selected, sp.closest)
+		}
+	}
+	return copy(candidates, selected)
+}
+
 // retainsBestMatches narrows `candidates` to the closest footprints to `query`, according to the CSS font rules
 // `candidates` is a slice of indexes into `fs`, which is mutated and returned
 // if `candidates` is not empty, the returned slice is guaranteed not to be empty
 func (fs fontSet) retainsBestMatches(candidates []int, query font.Aspect) []int {
+	return fs.retainsBestMatchesVariable(candidates, query, false)
+}
+
+// retainsBestMatchesVariable is the same as [retainsBestMatches], but, if [preferVariable] is true,
+// favors the variable fonts whose instances span the queried weight, as described in [Query.PreferVariableInstance].
+func (fs fontSet) retainsBestMatchesVariable(candidates []int, query font.Aspect, preferVariable bool) []int {
 	// this follows CSS Fonts Level 3 § 5.2 [1].
 	// https://drafts.csswg.org/css-fonts-3/#font-style-matching
 
@@ -428,6 +488,11 @@ func (fs fontSet) retainsBestMatches(candidates []int, query font.Aspect) []int
 	candidates = fs.filterByStyle(candidates, matchingStyle)
 
 	// Third step : font-weight
+	if preferVariable {
+		if n := fs.filterVariableSpanning(candidates, query.Weight); n != 0 {
+			return candidates[:n]
+		}
+	}
 	matchingWeight := fs.matchWeight(candidates, query.Weight)
 	candidates = fs.filterByWeight(candidates, matchingWeight)
 
@@ -444,3 +509,178 @@ func (fs fontSet) filterUserProvided(candidates []int) []int {
 	}
 	return candidates
 }
+
+// NoMatchScore is returned by [FontMap.ScoreFootprint] for
+// footprints whose family does not match the query.
+const NoMatchScore = math.MaxInt
+
+const (
+	// the aspect distance is split so that the CSS priority
+	// is respected : stretch, then style, then weight
+	styleFactor   = 2000              // greater than any weight distance
+	stretchFactor = 3 * styleFactor   // greater than any style and weight distance
+	familyFactor  = 1 << 22           // greater than any aspect distance
+	weakPenalty   = 512               // weak substitutions come after strong ones
+	stretchUnit   = font.Stretch(.01) // precision of stretch distances
+)
+
+// stretchDistance follows the order used by [fontSet.matchStretch]
+func stretchDistance(query, stretch font.Stretch) int {
+	d := int(math.Round(float64((stretch - query) / stretchUnit)))
+	if query <= font.StretchNormal { // narrow first
+		if d > 0 {
+			return d + 200
+		}
+		return -d
+	} else { // wide first
+		if d < 0 {
+			return -d + 200
+		}
+		return d
+	}
+}
+
+// styleDistance follows the order used by [fontSet.matchStyle] :
+// 0 for an exact match, 1 between italic and oblique, 2 for the opposite style
+func styleDistance(query, style font.Style) int {
+	if query == style {
+		return 0
+	}
+	if query != font.StyleNormal && style != font.StyleNormal { // italic <-> oblique
+		return 1
+	}
+	return 2
+}
+
+// weightDistance follows the order used by [fontSet.matchWeight]
+func weightDistance(query, weight font.Weight) int {
+	d := int(weight - query)
+	if 400 <= query && query <= 500 { // fatter until 500, then thinner then fatter
+		if d < 0 {
+			return -d + 500
+		} else if weight > 500 {
+			return d + 1000
+		}
+		return d
+	} else if query < 400 { // thinner then fatter
+		if d > 0 {
+			return d + 1000
+		}
+		return -d
+	} else { // fatter then thinner
+		if d < 0 {
+			return -d + 1000
+		}
+		return d
+	}
+}
+
+// aspectDistance returns a distance, lower being better, between the [candidate]
+// and the [query], which must have been sanitized by [font.Aspect.SetDefaults]
+func aspectDistance(query, candidate font.Aspect) int {
+	candidate.SetDefaults()
+	return stretchDistance(query.Stretch, candidate.Stretch)*stretchFactor +
+		styleDistance(query.Style, candidate.Style)*styleFactor +
+		weightDistance(query.Weight, candidate.Weight)
+}
+
+// ScoreFootprint returns the score of [fp] for the query [q], combining
+// the family distance (after applying family substitutions, using the current script
+// set by [SetScript]), and the aspect distance.
+//
+// Lower is better. The aspect distance follows the
+// priorities used when selecting candidates, so that sorting footprints by score
+// gives the same order as the one used by [ResolveFace], within one family.
+//
+// [NoMatchScore] is returned if the family of [fp] does not match the query.
+func (fm *FontMap) ScoreFootprint(fp Footprint, q Query) int {
+	q = fm.prepareScoring(q)
+	return fm.scoreFootprint(fp, q.Aspect)
+}
+
+// prepareScoring fills [fm.cribleBuffer] with the families of [q], expanded
+// with substitutions, and returns the sanitized query
+func (fm *FontMap) prepareScoring(q Query) Query {
+	families := q.Families
+	if len(families) == 0 {
+		families = []string{""}
+	}
+	fm.cribleBuffer.reset()
+	fm.cribleBuffer.fillWithSubstitutionsList(families, language.ScriptToLang[fm.script])
+	q.Aspect.SetDefaults()
+	return q
+}
+
+// scoreFootprint implements [ScoreFootprint], once [prepareScoring] has been called
+func (fm *FontMap) scoreFootprint(fp Footprint, aspect font.Aspect) int {
+	score, ok := fm.cribleBuffer[fp.Family]
+	if !ok {
+		return NoMatchScore
+	}
+	familyRank := score.score
+	if !score.strong {
+		familyRank += weakPenalty
+	}
+
+	return familyRank*familyFactor + aspectDistance(aspect, fp.Aspect)
+}
+
+// ScoredMatch is a font matching a [Query], as returned by [FontMap.MatchFamily].
+type ScoredMatch struct {
+	Location Location
+	// Family is the normalized family name, as used in [Query.Families],
+	// and Name is the family name suitable for display (see [Footprint.FamilyName]).
+	Family, Name string
+	Aspect       font.Aspect
+	// Score is the value returned by [FontMap.ScoreFootprint] : lower is better.
+	Score int
+}
+
+// MatchFamily returns the fonts whose family matches [q], either exactly or
+// after applying the family substitutions, sorted from the best to the worst match,
+// according to [ScoreFootprint]. Fonts with the same score are sorted as in [ResolveFace] :
+// user provided fonts first, then "regular" over "mono", then TTF over CFF.
+//
+// Only the family and the aspect are taken into account : contrary to [ResolveFace],
+// the rune coverage is ignored, and fonts are not added because they support the current script
+// (which is still used to select the language based substitutions).
+// This is useful to implement a font chooser suggesting the closest matches.
+func (fm *FontMap) MatchFamily(q Query) []ScoredMatch {
+	q = fm.prepareScoring(q)
+	var (
+		out     []ScoredMatch
+		indices []int
+	)
+	for i, fp := range fm.database {
+		score := fm.scoreFootprint(fp, q.Aspect)
+		if score == NoMatchScore {
+			continue
+		}
+		name := fp.FamilyName
+		if name == "" {
+			name = fp.Family
+		}
+		out = append(out, ScoredMatch{Location: fp.Location, Family: fp.Family, Name: name, Aspect: fp.Aspect, Score: score})
+		indices = append(indices, i)
+	}
+	sort.Stable(scoredMatches{out, indices, fm.database})
+	return out
+}
+
+// scoredMatches sorts matches and their indices into [database]
+type scoredMatches struct {
+	matches  []ScoredMatch
+	indices  []int
+	database fontSet
+}
+
+func (sm scoredMatches) Len() int { return len(sm.matches) }
+
+func (sm scoredMatches) Less(i, j int) bool {
+	return less(sm.matches[i].Score, sm.matches[j].Score, &sm.database[sm.indices[i]], &sm.database[sm.indices[j]])
+}
+
+func (sm scoredMatches) Swap(i, j int) {
+	sm.matches[i], sm.matches[j] = sm.matches[j], sm.matches[i]
+	sm.indices[i], sm.indices[j] = sm.indices[j], sm.indices[i]
+}
@@ -1,11 +1,13 @@
 package fontscan
 
 import (
+	"fmt"
 	"reflect"
 	"testing"
 
 	"github.com/go-text/typesetting/font"
 	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
 )
 
 func allIndices(fs fontSet) []int {
@@ -248,3 +250,113 @@ func TestFontSet_retainsBestMatches(t *testing.T) {
 		})
 	}
 }
+
+func TestScoreFootprint(t *testing.T) {
+	fm := NewFontMap(nil)
+	regular := font.Aspect{Style: font.StyleNormal, Weight: font.WeightNormal, Stretch: font.StretchNormal}
+	bold := font.Aspect{Style: font.StyleNormal, Weight: font.WeightBold, Stretch: font.StretchNormal}
+	italic := font.Aspect{Style: font.StyleItalic, Weight: font.WeightNormal, Stretch: font.StretchNormal}
+	condensed := font.Aspect{Style: font.StyleNormal, Weight: font.WeightNormal, Stretch: font.StretchCondensed}
+
+	arial := Footprint{Family: font.NormalizeFamily("Arial"), Aspect: regular}
+	arialBold := Footprint{Family: font.NormalizeFamily("Arial"), Aspect: bold}
+	arialItalic := Footprint{Family: font.NormalizeFamily("Arial"), Aspect: italic}
+	arialCondensed := Footprint{Family: font.NormalizeFamily("Arial"), Aspect: condensed}
+	times := Footprint{Family: font.NormalizeFamily("Times"), Aspect: regular}
+	unknown := Footprint{Family: "xxx", Aspect: regular}
+
+	q := Query{Families: []string{"Arial", "Times"}}
+	tu.Assert(t, fm.ScoreFootprint(unknown, q) == NoMatchScore)
+
+	// family priority comes first
+	tu.Assert(t, fm.ScoreFootprint(arial, q) < fm.ScoreFootprint(times, q))
+	tu.Assert(t, fm.ScoreFootprint(arialCondensed, q) < fm.ScoreFootprint(times, q))
+
+	// aspect priority : stretch, then style, then weight
+	tu.Assert(t, fm.ScoreFootprint(arial, q) < fm.ScoreFootprint(arialBold, q))
+	tu.Assert(t, fm.ScoreFootprint(arialBold, q) < fm.ScoreFootprint(arialItalic, q))
+	tu.Assert(t, fm.ScoreFootprint(arialItalic, q) < fm.ScoreFootprint(arialCondensed, q))
+
+	q.Aspect = bold
+	tu.Assert(t, fm.ScoreFootprint(arialBold, q) < fm.ScoreFootprint(arial, q))
+
+	tu.Assert(t, styleDistance(font.StyleItalic, font.StyleItalic) == 0)
+	tu.Assert(t, styleDistance(font.StyleItalic, styleOblique) == 0) // oblique is merged with italic
+	tu.Assert(t, styleDistance(font.StyleNormal, font.StyleItalic) == 2)
+	tu.Assert(t, styleDistance(font.StyleItalic, font.StyleNormal) == 2)
+
+	// the worst aspect distance is still smaller than one family rank
+	worst := aspectDistance(font.Aspect{Style: font.StyleNormal, Weight: 450, Stretch: font.StretchUltraCondensed},
+		font.Aspect{Style: font.StyleItalic, Weight: 1000, Stretch: font.StretchUltraExpanded})
+	tu.Assert(t, worst < familyFactor)
+
+	// the order is consistent with retainsBestMatches
+	fs := fontSet{arial, arialBold, arialItalic, arialCondensed}
+	for _, aspect := range []font.Aspect{regular, bold, italic, condensed, {Weight: 300}, {Weight: 450}, {Stretch: font.StretchExpanded}} {
+		q.Aspect = aspect
+		best := fs.retainsBestMatches(allIndices(fs), aspect)
+		tu.Assert(t, len(best) == 1)
+		for i, fp := range fs {
+			if i == best[0] {
+				continue
+			}
+			tu.Assert(t, fm.ScoreFootprint(fs[best[0]], q) < fm.ScoreFootprint(fp, q))
+		}
+	}
+}
+
+func TestMatchFamily(t *testing.T) {
+	regular := font.Aspect{Style: font.StyleNormal, Weight: font.WeightNormal, Stretch: font.StretchNormal}
+	bold := font.Aspect{Style: font.StyleNormal, Weight: font.WeightBold, Stretch: font.StretchNormal}
+
+	fm := NewFontMap(nil)
+	fm.database = fontSet{
+		{Location: Location{File: "timesbd.ttf"}, Family: font.NormalizeFamily("Times"), Aspect: bold},
+		{Location: Location{File: "arialbd.ttf"}, Family: font.NormalizeFamily("Arial"), FamilyName: "Arial", Aspect: bold},
+		{Location: Location{File: "arial.ttf"}, Family: font.NormalizeFamily("Arial"), FamilyName: "Arial", Aspect: regular},
+		{Location: Location{File: "user-arial.ttf"}, Family: font.NormalizeFamily("Arial"), Aspect: regular, isUserProvided: true},
+		{Location: Location{File: "unknown.ttf"}, Family: "xxx", Aspect: regular},
+	}
+
+	files := func(matches []ScoredMatch) (out []string) {
+		for _, m := range matches {
+			out = append(out, m.Location.File)
+		}
+		return out
+	}
+
+	matches := fm.MatchFamily(Query{Families: []string{"Arial"}})
+	tu.Assert(t, len(matches) >= 3)
+	// user provided fonts come first among equal scores
+	tu.AssertC(t, reflect.DeepEqual(files(matches[:3]), []string{"user-arial.ttf", "arial.ttf", "arialbd.ttf"}), fmt.Sprint(files(matches)))
+	tu.Assert(t, matches[1].Name == "Arial" && matches[1].Family == "arial" && matches[1].Aspect == regular)
+	tu.Assert(t, matches[0].Name == "arial") // defaults to the normalized family
+	for i, m := range matches {
+		tu.Assert(t, m.Location.File != "unknown.ttf")
+		tu.Assert(t, m.Score == fm.ScoreFootprint(fm.database[indexOf(fm.database, m.Location)], Query{Families: []string{"Arial"}}))
+		if i > 0 {
+			tu.Assert(t, matches[i-1].Score <= m.Score)
+		}
+	}
+
+	// the aspect is taken into account
+	matches = fm.MatchFamily(Query{Families: []string{"Arial"}, Aspect: bold})
+	tu.Assert(t, matches[0].Location.File == "arialbd.ttf")
+
+	// substitutions are applied
+	matches = fm.MatchFamily(Query{Families: []string{"Helvetica"}})
+	tu.Assert(t, len(matches) >= 3 && matches[0].Family == "arial")
+
+	matches = fm.MatchFamily(Query{Families: []string{"xxx"}})
+	tu.Assert(t, len(matches) >= 1 && matches[0].Location.File == "unknown.ttf")
+	tu.Assert(t, len(NewFontMap(nil).MatchFamily(Query{Families: []string{"Arial"}})) == 0)
+}
+
+func indexOf(fs fontSet, location Location) int {
+	for i, fp := range fs {
+		if fp.Location == location {
+			return i
+		}
+	}
+	return -1
+}
@@ -276,6 +276,36 @@ func (a RuneSet) Len() int {
 	return count
 }
 
+// Ranges returns the runes of the set, as a sorted list of
+// inclusive ranges [start, end], where consecutive runes are merged.
+// This is the same format as [font.CmapRuneRanger.RuneRanges].
+func (rs RuneSet) Ranges() [][2]rune {
+	var out [][2]rune
+	for _, page := range rs {
+		base := rune(page.ref) << 8
+		for i, word := range page.set {
+			for word != 0 {
+				// the next run of ones in word
+				start := bits.TrailingZeros32(word)
+				length := bits.TrailingZeros32(^(word >> start))
+				if length == 32 { // only possible for start == 0
+					word = 0
+				} else {
+					word &= ^((uint32(1)<<length - 1) << start)
+				}
+				first := base + rune(i*32+start)
+				last := first + rune(length) - 1
+				if L := len(out); L != 0 && out[L-1][1]+1 == first {
+					out[L-1][1] = last
+				} else {
+					out = append(out, [2]rune{first, last})
+				}
+			}
+		}
+	}
+	return out
+}
+
 const runePageSize = 2 + 8*4 // uint16 + 8 * uint32
 
 // serialize serializes the rune coverage in binary format
@@ -269,6 +269,27 @@ func TestRuneRanges(t *testing.T) {
 	}
 }
 
+func TestRuneSetRanges(t *testing.T) {
+	tu.Assert(t, RuneSet(nil).Ranges() == nil)
+
+	rs := newRuneSet(0, 1, 2, 31, 32, 33, 255, 256, 0x1F600)
+	exp := [][2]rune{{0, 2}, {31, 33}, {255, 256}, {0x1F600, 0x1F600}}
+	tu.Assert(t, reflect.DeepEqual(rs.Ranges(), exp))
+
+	full, _, _ := newCoveragesFromCmapRange(runeRange{{0, 0x2FF}}, nil)
+	tu.Assert(t, reflect.DeepEqual(full.Ranges(), [][2]rune{{0, 0x2FF}}))
+
+	for range [50]int{} {
+		rs := newRuneSet(runesFromRanges(randomRanges())...)
+		ranges := rs.Ranges()
+		tu.Assert(t, reflect.DeepEqual(runesFromRanges(ranges), rs.runes()))
+		// the ranges are maximal
+		for i := 1; i < len(ranges); i++ {
+			tu.Assert(t, ranges[i][0] > ranges[i-1][1]+1)
+		}
+	}
+}
+
 func TestScriptSet(t *testing.T) {
 	type testcase struct {
 		name     string
@@ -10,8 +10,9 @@ import (
 	"runtime"
 	"sort"
 	"strings"
+	"time"
 
-	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font"
 )
 
 // DefaultFontDirectories return the OS-dependent usual directories for
@@ -208,10 +209,39 @@ type footprintScanner struct {
 
 	dst systemFontsIndex // accumulated footprints
 
+	errors []ScanError // the files and directories which could not be scanned
+
+	pending []pendingFile // the files found, to be scanned
+
 	// used to reduce allocations
 	scanBuffer
 }
 
+// ScanError describes a font file or a font directory
+// which could not be read when scanning font directories.
+// Such errors do not stop the scan : the other files are still indexed.
+type ScanError struct {
+	Path string // the file or directory path
+	Err  error
+}
+
+func (se ScanError) Error() string { return fmt.Sprintf("scanning %q: %s", se.Path, se.Err) }
+
+func (se ScanError) Unwrap() error { return se.Err }
+
+// addError records a scan failure for [path], logging it with [logger]
+func (fa *footprintScanner) addError(logger Logger, path string, err error) {
+	se := ScanError{Path: path, Err: err}
+	logger.Printf("%s", se)
+	fa.errors = append(fa.errors, se)
+}
+
+// pendingFile is a font file found when walking the font directories
+type pendingFile struct {
+	path string
+	info os.FileInfo
+}
+
 type scanBuffer struct {
 	tableBuffer []byte
 	cmapBuffer  [][2]rune
@@ -226,6 +256,7 @@ func newFootprintAccumulator(currentIndex systemFontsIndex) footprintScanner {
 	return out
 }
 
+// consume scans the font file at [path], returning an error if it can't be opened
 func (fa *footprintScanner) consume(path string, info os.FileInfo) error {
 	modTime := newTimeStamp(info)
 
@@ -251,21 +282,21 @@ func (fa *footprintScanner) consume(path string, info os.FileInfo) error {
 
 	// fetch the loaders for the given font file, or nil if is not
 	// an Opentype font.
-	loaders, _ := ot.NewLoaders(file)
+	loaders, _ := font.NewLoaders(file)
 
 	for i, ld := range loaders {
-		var fp Footprint
-		fp, fa.scanBuffer, err = newFootprintFromLoader(ld, false, fa.scanBuffer)
+		var fps []Footprint
+		fps, fa.scanBuffer, err = newFootprintsFromLoader(ld, false, fa.scanBuffer)
 		// the font won't be usable, just ignore it
 		if err != nil {
 			continue
 		}
 
-		fp.Location.File = path
-		fp.Location.Index = uint16(i)
-		// TODO: for now, we do not handle variable fonts
-
-		ff.footprints = append(ff.footprints, fp)
+		for _, fp := range fps {
+			fp.Location.File = path
+			fp.Location.Index = uint16(i)
+			ff.footprints = append(ff.footprints, fp)
+		}
 	}
 
 	// newFootprintFromLoader still uses file, do not close earlier
@@ -281,22 +312,48 @@ func (fa *footprintScanner) consume(path string, info os.FileInfo) error {
 
 // scanFontFootprints walk through the given directories
 // and scan each font file to extract its footprint.
-// An error is returned if the directory traversal fails, not for invalid font files,
-// which are simply ignored.
+// Invalid font files are simply ignored, and the files or directories
+// which can't be read are skipped and returned as [ScanError]s.
 // `currentIndex` may be passed to avoid scanning font files that are
 // already present in `currentIndex` and up to date, and directly duplicating
 // the footprint in `currentIndex`
-func scanFontFootprints(logger Logger, currentIndex systemFontsIndex, dirs ...string) (systemFontsIndex, error) {
+func scanFontFootprints(logger Logger, currentIndex systemFontsIndex, dirs ...string) (systemFontsIndex, []ScanError) {
+	return scanFontFootprintsWithProgress(logger, currentIndex, nil, dirs...)
+}
+
+// progressInterval is the minimum delay between two
+// calls to the progress callback of [scanFontFootprintsWithProgress]
+const progressInterval = 100 * time.Millisecond
+
+// scanFontFootprintsWithProgress is the same as [scanFontFootprints], but also
+// reports the number of font files scanned so far, and the total number of font files,
+// by calling [progress] (if not nil), at most once per [progressInterval].
+// [progress] is always called with (0, total) before scanning, and (total, total) at the end.
+func scanFontFootprintsWithProgress(logger Logger, currentIndex systemFontsIndex, progress func(scanned, total int), dirs ...string) (systemFontsIndex, []ScanError) {
 	// keep track of visited dirs to avoid double inclusions,
 	// for instance with symbolic links
 	visited := make(map[string]bool)
 
+	// first list the font files ...
 	accu := newFootprintAccumulator(currentIndex)
 	for _, dir := range dirs {
-		err := accu.scanDirectory(logger, dir, visited)
-		if err != nil {
-			return nil, err
+		accu.scanDirectory(logger, dir, visited)
+	}
+
+	// ... then scan them, so that the total is known
+	total := len(accu.pending)
+	if progress != nil {
+		progress(0, total)
+	}
+	lastReport := time.Now()
+	for i, file := range accu.pending {
+		if err := accu.consume(file.path, file.info); err != nil {
+			accu.addError(logger, file.path, err)
+		}
+		if progress != nil && (i+1 == total || time.Since(lastReport) >= progressInterval) {
+			progress(i+1, total)
+			lastReport = time.Now()
 		}
 	}
-	return accu.dst, nil
+	return accu.dst, accu.errors
 }
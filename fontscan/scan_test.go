@@ -1,14 +1,21 @@
 package fontscan
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
 	tu "github.com/go-text/typesetting/testutils"
 )
 
@@ -26,8 +33,8 @@ func TestScanFontFootprints(t *testing.T) {
 	directories, err := DefaultFontDirectories(logger)
 	tu.AssertNoErr(t, err)
 
-	fontset, err := scanFontFootprints(logger, nil, directories...)
-	tu.AssertNoErr(t, err)
+	fontset, scanErrors := scanFontFootprints(logger, nil, directories...)
+	tu.Assert(t, len(scanErrors) == 0)
 
 	// Show some basic stats
 	families := map[string]bool{}
@@ -60,13 +67,13 @@ func TestScanIncrementalNoOp(t *testing.T) {
 	tu.AssertNoErr(t, err)
 
 	// first scan
-	fontset, err := scanFontFootprints(logger, nil, directories...)
-	tu.AssertNoErr(t, err)
+	fontset, scanErrors := scanFontFootprints(logger, nil, directories...)
+	tu.Assert(t, len(scanErrors) == 0)
 	fmt.Printf("Initial scan time: %s\n", time.Since(ti))
 
 	ti = time.Now()
-	incremental, err := scanFontFootprints(logger, fontset, directories...)
-	tu.AssertNoErr(t, err)
+	incremental, scanErrors := scanFontFootprints(logger, fontset, directories...)
+	tu.Assert(t, len(scanErrors) == 0)
 	fmt.Printf("Second scan time: %s\n", time.Since(ti))
 
 	if err = assertFontsetEquals(fontset.flatten(), incremental.flatten()); err != nil {
@@ -101,8 +108,8 @@ func TestScanIncrementalUpdate(t *testing.T) {
 
 	// first scan
 	logger := log.New(io.Discard, "", 0)
-	fontset, err := scanFontFootprints(logger, nil, dir)
-	tu.AssertNoErr(t, err)
+	fontset, scanErrors := scanFontFootprints(logger, nil, dir)
+	tu.Assert(t, len(scanErrors) == 0)
 	if len(fontset) != 1 {
 		t.Fatalf("unexpected font set: %v", fontset)
 	}
@@ -112,8 +119,8 @@ func TestScanIncrementalUpdate(t *testing.T) {
 	// test adding a new file
 	copyFile(t, filepath.Join("..", "font", "testdata", "Roboto-Regular.ttf"), filepath.Join(dir, "font2.ttf"))
 
-	fontset2, err := scanFontFootprints(logger, fontset, dir)
-	tu.AssertNoErr(t, err)
+	fontset2, scanErrors := scanFontFootprints(logger, fontset, dir)
+	tu.Assert(t, len(scanErrors) == 0)
 	if len(fontset2) != 2 {
 		t.Fatalf("unexpected font set: %v", fontset)
 	}
@@ -123,8 +130,8 @@ func TestScanIncrementalUpdate(t *testing.T) {
 	// test updating an existing file
 	copyFile(t, filepath.Join("..", "font", "testdata", "Roboto-Regular.ttf"), filepath.Join(dir, "font1.ttf"))
 
-	fontset3, err := scanFontFootprints(logger, nil, dir)
-	tu.AssertNoErr(t, err)
+	fontset3, scanErrors := scanFontFootprints(logger, nil, dir)
+	tu.Assert(t, len(scanErrors) == 0)
 	if len(fontset3) != 2 {
 		t.Fatalf("unexpected font set: %v", fontset)
 	}
@@ -134,19 +141,127 @@ func TestScanIncrementalUpdate(t *testing.T) {
 
 	time.Sleep(time.Millisecond * 10)
 
-	incremental, err := scanFontFootprints(logger, fontset2, dir)
-	tu.AssertNoErr(t, err)
-	if err = assertFontsetEquals(fontset3.flatten(), incremental.flatten()); err != nil {
+	incremental, scanErrors := scanFontFootprints(logger, fontset2, dir)
+	tu.Assert(t, len(scanErrors) == 0)
+	if err := assertFontsetEquals(fontset3.flatten(), incremental.flatten()); err != nil {
 		t.Fatalf("incremental scan not consistent with initial scan: %s", err)
 	}
 
 	// test removing a file
-	if err = os.Remove(filepath.Join(dir, "font1.ttf")); err != nil {
+	if err := os.Remove(filepath.Join(dir, "font1.ttf")); err != nil {
 		t.Fatal(err)
 	}
-	fontset4, err := scanFontFootprints(logger, fontset3, dir)
-	tu.AssertNoErr(t, err)
+	fontset4, scanErrors := scanFontFootprints(logger, fontset3, dir)
+	tu.Assert(t, len(scanErrors) == 0)
 	if len(fontset4) != 1 {
 		t.Fatalf("unexpected font set: %v", fontset)
 	}
 }
+
+func TestScanProgress(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	var calls [][2]int
+	fontset, scanErrors := scanFontFootprintsWithProgress(logger, nil, func(scanned, total int) {
+		calls = append(calls, [2]int{scanned, total})
+	}, "../font/testdata")
+	tu.Assert(t, len(scanErrors) == 0)
+
+	// the start and the end of the scan are always reported
+	total := len(fontset)
+	tu.Assert(t, total > 0)
+	tu.Assert(t, len(calls) >= 2 && len(calls) <= total+1)
+	tu.Assert(t, calls[0] == [2]int{0, total} && calls[len(calls)-1] == [2]int{total, total})
+	for i := 1; i < len(calls); i++ {
+		tu.Assert(t, calls[i][0] > calls[i-1][0])
+	}
+
+	// the progress is also reported with an up to date index
+	calls = nil
+	_, scanErrors = scanFontFootprintsWithProgress(logger, fontset, func(scanned, total int) {
+		calls = append(calls, [2]int{scanned, total})
+	}, "../font/testdata")
+	tu.Assert(t, len(scanErrors) == 0)
+	tu.Assert(t, len(calls) >= 2 && calls[len(calls)-1] == [2]int{total, total})
+
+	// empty directory
+	calls = nil
+	_, scanErrors = scanFontFootprintsWithProgress(logger, nil, func(scanned, total int) {
+		calls = append(calls, [2]int{scanned, total})
+	}, t.TempDir())
+	tu.Assert(t, len(scanErrors) == 0)
+	tu.Assert(t, len(calls) == 1 && calls[0] == [2]int{0, 0})
+}
+
+func TestScanErrors(t *testing.T) {
+	dir := t.TempDir()
+	copyFile(t, filepath.Join("..", "font", "testdata", "Amiri-Regular.ttf"), filepath.Join(dir, "font1.ttf"))
+	missing := filepath.Join(dir, "missing")
+
+	logger := log.New(io.Discard, "", 0)
+	fontset, scanErrors := scanFontFootprints(logger, nil, missing, dir)
+	// the other directories are still scanned
+	tu.Assert(t, len(fontset) == 1)
+	tu.Assert(t, len(scanErrors) == 1)
+	tu.Assert(t, scanErrors[0].Path == missing && errors.Is(scanErrors[0], os.ErrNotExist))
+	tu.Assert(t, strings.Contains(scanErrors[0].Error(), missing))
+}
+
+func TestScanMatchesFullParse(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	fontset, scanErrors := scanFontFootprints(logger, nil, "../font/testdata")
+	tu.Assert(t, len(scanErrors) == 0)
+
+	footprints := fontset.flatten()
+	tu.Assert(t, len(footprints) == 4+5) // Selawik has 5 named instances, Amiri is also stored as WOFF2
+	for _, fp := range footprints {
+		face, err := fp.loadFromDisk()
+		tu.AssertNoErr(t, err)
+
+		runes, scripts, _ := newCoveragesFromCmap(face.Cmap, nil)
+		tu.AssertC(t, reflect.DeepEqual(fp.Runes, runes), fp.Location.File)
+		tu.AssertC(t, reflect.DeepEqual(fp.Scripts, scripts), fp.Location.File)
+		desc := face.Describe()
+		tu.AssertC(t, fp.Family == font.NormalizeFamily(desc.Family), fp.Location.File)
+		if fp.Location.Instance == 0 { // variable instances have their own aspect
+			tu.AssertC(t, fp.Aspect == desc.Aspect, fp.Location.File)
+		} else {
+			tu.AssertC(t, len(face.Coords()) != 0, fp.Location.File)
+		}
+		tu.AssertC(t, fp.EmbedPermission == face.EmbedPermission(), fp.Location.File)
+	}
+}
+
+func TestScanEmbedPermission(t *testing.T) {
+	data, err := os.ReadFile("../font/testdata/Roboto-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	// set the fsType field of the OS/2 table
+	numTables := int(binary.BigEndian.Uint16(data[4:]))
+	for i := 0; i < numTables; i++ {
+		record := data[12+16*i:]
+		if string(record[:4]) == "OS/2" {
+			offset := binary.BigEndian.Uint32(record[8:])
+			binary.BigEndian.PutUint16(data[offset+8:], uint16(font.EmbedRestricted|font.EmbedNoSubsetting))
+		}
+	}
+
+	ld, err := ot.NewLoader(bytes.NewReader(data))
+	tu.AssertNoErr(t, err)
+	fp, _, err := newFootprintFromLoader(ld, false, scanBuffer{})
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, fp.EmbedPermission == font.EmbedRestricted|font.EmbedNoSubsetting)
+	tu.Assert(t, !fp.EmbedPermission.CanEmbed())
+}
+
+func TestScanVariableFont(t *testing.T) {
+	logger := log.New(io.Discard, "", 0)
+	fontset, scanErrors := scanFontFootprints(logger, nil, "../font/testdata")
+	tu.Assert(t, len(scanErrors) == 0)
+
+	fm := NewFontMap(logger)
+	fm.appendFootprints(fontset.flatten()...)
+	locations := fm.FindSystemFonts("Selawik Variations Test")
+	tu.Assert(t, len(locations) == 5)
+	for i, loc := range locations {
+		tu.Assert(t, loc.Instance == uint16(i+1))
+	}
+}
@@ -2,18 +2,22 @@ package fontscan
 
 import (
 	"errors"
-	"fmt"
 	"io/fs"
 	"os"
 	"path/filepath"
 )
 
-// recursively walk through the given directory, scanning font files and calling dst.consume
-// for each valid file found.
-func (dst *footprintScanner) scanDirectory(logger Logger, dir string, visited map[string]bool) error {
+// recursively walk through the given directory, adding the
+// font files to scan to dst.pending.
+// The directories and files which can't be read are skipped
+// and recorded in dst.errors.
+func (dst *footprintScanner) scanDirectory(logger Logger, dir string, visited map[string]bool) {
 	walkFn := func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			logger.Printf("error walking font directory %q: %v", path, err)
+			dst.addError(logger, path, err)
+			if d != nil && !d.IsDir() {
+				return nil // skip the file only
+			}
 			return filepath.SkipDir
 		}
 
@@ -33,7 +37,8 @@ func (dst *footprintScanner) scanDirectory(logger Logger, dir string, visited ma
 				logger.Printf("skipping dead link or missing file: %q", path)
 				return nil
 			}
-			return fmt.Errorf("failed to stat %q: %w", path, err)
+			dst.addError(logger, path, err)
+			return nil
 		}
 
 		// always ignore files which should never be font files
@@ -41,14 +46,13 @@ func (dst *footprintScanner) scanDirectory(logger Logger, dir string, visited ma
 			return nil
 		}
 
-		err = dst.consume(path, info)
+		dst.pending = append(dst.pending, pendingFile{path: path, info: info})
 
-		return err
+		return nil
 	}
 
-	err := filepath.WalkDir(dir, walkFn)
-
-	return err
+	// walkFn never returns an error
+	_ = filepath.WalkDir(dir, walkFn)
 }
 
 type dirEntry = fs.DirEntry
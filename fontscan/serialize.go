@@ -10,6 +10,7 @@ import (
 	"math"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"github.com/go-text/typesetting/font"
 )
@@ -84,10 +85,13 @@ func (fp Footprint) serializeTo(dst []byte) []byte {
 	dst = append(dst, buffer[:]...)
 
 	dst = append(dst, serializeString(fp.Family)...)
+	dst = append(dst, serializeString(fp.FamilyName)...)
 	dst = append(dst, fp.Runes.serialize()...)
 	dst = append(dst, fp.Scripts.serialize()...)
 	dst = append(dst, fp.Langs.serialize()...)
 	dst = append(dst, serializeAspect(fp.Aspect)...)
+	binary.BigEndian.PutUint16(buffer[:], uint16(fp.EmbedPermission))
+	dst = append(dst, buffer[:2]...)
 
 	return dst
 }
@@ -111,6 +115,11 @@ func (fp *Footprint) deserializeFrom(data []byte) (int, error) {
 		return 0, err
 	}
 	n += read
+	read, err = deserializeString(&fp.FamilyName, data[n:])
+	if err != nil {
+		return 0, err
+	}
+	n += read
 	read, err = fp.Runes.deserializeFrom(data[n:])
 	if err != nil {
 		return 0, err
@@ -131,6 +140,11 @@ func (fp *Footprint) deserializeFrom(data []byte) (int, error) {
 		return 0, err
 	}
 	n += read
+	if len(data) < n+2 {
+		return 0, errors.New("invalid EmbedPermission (EOF)")
+	}
+	fp.EmbedPermission = EmbedPermission(binary.BigEndian.Uint16(data[n:]))
+	n += 2
 
 	return n, nil
 }
@@ -185,7 +199,7 @@ func (ff *fileFootprints) deserializeFrom(src []byte) error {
 	return nil
 }
 
-const cacheFormatVersion = 6
+const cacheFormatVersion = 10
 
 func max(i, j int) int {
 	if i > j {
@@ -301,3 +315,107 @@ func (index systemFontsIndex) serializeToFile(cachePath string) error {
 	err = f.Close()
 	return err
 }
+
+// Serialize writes the fonts added with [AddFont] or [AddFace] in a compact binary format,
+// so that they may be restored with [Deserialize] without scanning the font files again.
+// System fonts are not included, since they are already cached by [UseSystemFonts].
+//
+// Only the font footprints are written, not the faces themselves : they are
+// lazily loaded from disk when needed, using the file IDs of their [Location].
+// When these IDs are not file paths, [paths] must map them to the path of the
+// font files (it may be nil otherwise).
+func (fm *FontMap) Serialize(w io.Writer, paths map[string]string) error {
+	var userFonts fontSet
+	for _, fp := range fm.database {
+		if fp.isUserProvided {
+			userFonts = append(userFonts, fp)
+		}
+	}
+
+	// version as uint16 + number of paths as uint32
+	buffer := make([]byte, 6)
+	binary.BigEndian.PutUint16(buffer[:], cacheFormatVersion)
+	binary.BigEndian.PutUint32(buffer[2:], uint32(len(paths)))
+	fileIDs := make([]string, 0, len(paths))
+	for fileID := range paths {
+		fileIDs = append(fileIDs, fileID)
+	}
+	sort.Strings(fileIDs) // deterministic output
+	for _, fileID := range fileIDs {
+		buffer = append(buffer, serializeString(fileID)...)
+		buffer = append(buffer, serializeString(paths[fileID])...)
+	}
+	// end by the variable length footprint list
+	buffer = serializeFootprintsTo(userFonts, buffer)
+
+	wr := gzip.NewWriter(w)
+	_, err := wr.Write(buffer)
+	if err != nil {
+		return fmt.Errorf("serializing font footprints: %s", err)
+	}
+	err = wr.Close()
+	if err != nil {
+		return fmt.Errorf("compressing serialized font footprints: %s", err)
+	}
+	return nil
+}
+
+// Deserialize reads the fonts written by [Serialize], and adds them to the font map,
+// as [AddFont] would do, with the same priority order.
+// An error is returned if the data is invalid or has been written
+// by an other version of this package : in this case, the fonts should be added again.
+//
+// The font files are not read : the faces are loaded from disk when needed.
+func (fm *FontMap) Deserialize(r io.Reader) error {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("invalid compressed font map: %s", err)
+	}
+	defer gr.Close()
+	src, err := io.ReadAll(gr)
+	if err != nil {
+		return fmt.Errorf("invalid compressed font map: %s", err)
+	}
+
+	if len(src) < 6 {
+		return errors.New("invalid font map format (EOF)")
+	}
+	version := binary.BigEndian.Uint16(src)
+	if version != cacheFormatVersion {
+		return fmt.Errorf("different font map version format: found %d", version)
+	}
+	L := binary.BigEndian.Uint32(src[2:])
+	n := 6
+	paths := make(map[string]string, len(fm.filePaths))
+	for fileID, path := range fm.filePaths {
+		paths[fileID] = path
+	}
+	for i := uint32(0); i < L; i++ {
+		var fileID, path string
+		read, err := deserializeString(&fileID, src[n:])
+		if err != nil {
+			return fmt.Errorf("invalid font map: %s", err)
+		}
+		n += read
+		read, err = deserializeString(&path, src[n:])
+		if err != nil {
+			return fmt.Errorf("invalid font map: %s", err)
+		}
+		n += read
+		paths[fileID] = path
+	}
+	footprints, err := deserializeFootprints(src[n:])
+	if err != nil {
+		return fmt.Errorf("invalid font map: %s", err)
+	}
+	for i := range footprints {
+		footprints[i].isUserProvided = true
+	}
+
+	fm.filePaths = paths
+	fm.appendFootprints(footprints...)
+
+	fm.built = false
+	fm.lru.Clear()
+	return nil
+}
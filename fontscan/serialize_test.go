@@ -2,17 +2,20 @@ package fontscan
 
 import (
 	"bytes"
+	"compress/gzip"
 	"encoding/binary"
 	"fmt"
 	"io"
 	"log"
 	"math/rand"
+	"os"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/go-text/typesetting/font"
 	"github.com/go-text/typesetting/language"
+	tu "github.com/go-text/typesetting/testutils"
 )
 
 func Test_serializeFootprints(t *testing.T) {
@@ -22,6 +25,8 @@ func Test_serializeFootprints(t *testing.T) {
 			Runes:   newRuneSet(1, 0, 2, 0x789, 0xfffee),
 			Scripts: ScriptSet{0, 1, 5, 0xffffff, language.Nabataean, language.Unknown},
 			Aspect:  font.Aspect{Style: 1, Weight: 200, Stretch: 0.45},
+
+			EmbedPermission: font.EmbedPreviewAndPrint | font.EmbedNoSubsetting,
 		},
 		{
 			Runes:   RuneSet{},
@@ -130,9 +135,9 @@ func TestSerializeSystemFonts(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	fontset, err := scanFontFootprints(logger, nil, directories...)
-	if err != nil {
-		t.Fatal(err)
+	fontset, scanErrors := scanFontFootprints(logger, nil, directories...)
+	if len(scanErrors) != 0 {
+		t.Fatal(scanErrors)
 	}
 
 	ti := time.Now()
@@ -151,3 +156,51 @@ func TestSerializeSystemFonts(t *testing.T) {
 		t.Fatalf("inconsistent serialization %s", err)
 	}
 }
+
+func TestSerializeFontMap(t *testing.T) {
+	amiri, err := os.Open("../font/testdata/Amiri-Regular.ttf")
+	tu.AssertNoErr(t, err)
+	defer amiri.Close()
+	selawik, err := os.Open("../font/testdata/Selawik-VF-Subset.ttf")
+	tu.AssertNoErr(t, err)
+	defer selawik.Close()
+
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	fm.appendFootprints(Footprint{Family: "nimbus", Location: Location{File: "nimbus.ttf"}}) // a system font
+	tu.AssertNoErr(t, fm.AddFont(amiri, "asset:amiri", "My Font"))
+	tu.AssertNoErr(t, fm.AddFont(selawik, "../font/testdata/Selawik-VF-Subset.ttf", ""))
+
+	var b bytes.Buffer
+	err = fm.Serialize(&b, map[string]string{"asset:amiri": "../font/testdata/Amiri-Regular.ttf"})
+	tu.AssertNoErr(t, err)
+	data := b.Bytes()
+
+	fm2 := NewFontMap(log.New(io.Discard, "", 0))
+	err = fm2.Deserialize(bytes.NewReader(data))
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, len(fm2.database) == len(fm.database)-1)
+	tu.AssertNoErr(t, assertFontsetEquals(fm.database[1:], fm2.database))
+	tu.Assert(t, reflect.DeepEqual(fm.scriptMap[language.Arabic], []int{1}) && reflect.DeepEqual(fm2.scriptMap[language.Arabic], []int{0}))
+	tu.Assert(t, len(fm2.faceCache) == 0)
+
+	// the faces are lazily loaded, using the provided paths
+	fm2.SetQuery(Query{Families: []string{"My Font"}})
+	face := fm2.ResolveFace('ب')
+	tu.Assert(t, face != nil && fm2.FontLocation(face.Font) == Location{File: "asset:amiri"})
+	fm2.SetQuery(Query{Families: []string{"Selawik Variations Test"}, Aspect: font.Aspect{Weight: font.WeightBold}})
+	face = fm2.ResolveFace('a')
+	tu.Assert(t, fm2.FontLocation(face.Font) == Location{File: "../font/testdata/Selawik-VF-Subset.ttf", Instance: 5})
+
+	// stale or invalid data is rejected
+	gr, err := gzip.NewReader(bytes.NewReader(data))
+	tu.AssertNoErr(t, err)
+	raw, err := io.ReadAll(gr)
+	tu.AssertNoErr(t, err)
+	binary.BigEndian.PutUint16(raw, cacheFormatVersion-1)
+	var stale bytes.Buffer
+	wr := gzip.NewWriter(&stale)
+	wr.Write(raw)
+	wr.Close()
+	tu.Assert(t, NewFontMap(nil).Deserialize(&stale) != nil)
+	tu.Assert(t, NewFontMap(nil).Deserialize(bytes.NewReader(randomBytes())) != nil)
+}
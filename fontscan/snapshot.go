@@ -0,0 +1,171 @@
+package fontscan
+
+import (
+	"sync"
+
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"github.com/go-text/typesetting/language"
+)
+
+// Snapshot is a frozen view of a [FontMap], returned by [FontMap.Snapshot].
+// Contrary to [FontMap], it is safe for concurrent use : its [ResolveFace] method
+// may be called from several goroutines, for instance to segment several documents
+// in parallel. It implements [shaping.Fontmap].
+//
+// The query and script are fixed : use [WithQuery] and [WithScript] to obtain
+// a snapshot with other settings. Such snapshots share the font database and
+// the loaded fonts, but have their own cache.
+//
+// The returned faces are owned by the snapshot : they are not shared with the original
+// [FontMap] nor with the other snapshots. However, since a [font.Face] is not safe for concurrent use,
+// goroutines shaping text concurrently should each use their own snapshot, obtained with [Clone].
+type Snapshot struct {
+	shared *snapshotFonts
+
+	query  Query
+	script language.Script
+
+	// the candidates are lazily built
+	buildOnce  sync.Once
+	candidates candidates
+
+	// lruMu protects lru and faces
+	lruMu sync.Mutex
+	lru   runeLRU
+	// the copies of the faces resolved by [fm], returned by [ResolveFace]
+	faces faceCopies
+}
+
+// snapshotFonts stores the frozen database of a [Snapshot],
+// shared between the snapshots derived from it.
+type snapshotFonts struct {
+	// mu protects fm, which lazily loads faces (and updates its face caches).
+	// The faces of fm are never returned to the users of the snapshots.
+	mu sync.Mutex
+	fm *FontMap
+}
+
+// faceCopies maps faces to copies using the same font and settings,
+// but their own caches.
+type faceCopies map[*font.Face]*font.Face
+
+// get returns the copy of [face], creating it if needed.
+func (fc *faceCopies) get(face *font.Face) *font.Face {
+	if face == nil {
+		return nil
+	}
+	if copied, ok := (*fc)[face]; ok {
+		return copied
+	}
+	if *fc == nil {
+		*fc = make(faceCopies)
+	}
+	copied := font.NewFace(face.Font)
+	copied.SetCoords(append([]tables.Coord(nil), face.Coords()...))
+	copied.SetPpem(face.Ppem())
+	(*fc)[face] = copied
+	return copied
+}
+
+// Snapshot returns a frozen view of the font map, including its current query and script,
+// which may be used concurrently. See [Snapshot] for more details.
+//
+// The snapshot shares the (read-only) font footprints and the already loaded fonts
+// with [fm], and is not affected by subsequent modifications of [fm], like [AddFont].
+func (fm *FontMap) Snapshot() *Snapshot {
+	// the faces of fm may be used concurrently with the snapshot : copy them
+	var copies faceCopies
+	frozen := &FontMap{
+		logger:    fm.logger,
+		firstFace: copies.get(fm.firstFace),
+		faceCache: make(map[Location]*font.Face, len(fm.faceCache)),
+		metaCache: make(map[*font.Font]cacheEntry, len(fm.metaCache)),
+		// restrict the capacity so that appending to fm does not modify the snapshot
+		database:    fm.database[:len(fm.database):len(fm.database)],
+		scriptMap:   make(map[language.Script][]int, len(fm.scriptMap)),
+		lastResorts: make([]lastResortFace, len(fm.lastResorts)),
+		tofuFace:    copies.get(fm.tofuFace),
+		query:       fm.query,
+		script:      fm.script,
+		filePaths:   fm.filePaths,
+
+		scriptFallbacks: fm.scriptFallbacks,
+	}
+	for loc, face := range fm.faceCache {
+		frozen.faceCache[loc] = copies.get(face)
+	}
+	for i, lr := range fm.lastResorts {
+		frozen.lastResorts[i] = lastResortFace{face: copies.get(lr.face), scripts: lr.scripts}
+	}
+	for ft, entry := range fm.metaCache {
+		frozen.metaCache[ft] = entry
+	}
+	for script, indices := range fm.scriptMap {
+		frozen.scriptMap[script] = indices[:len(indices):len(indices)]
+	}
+
+	query := fm.query
+	if len(query.Families) == 0 { // fm.SetQuery has not been called
+		query.Families = []string{""}
+	}
+	return newSnapshot(&snapshotFonts{fm: frozen}, query, fm.script, fm.lru.maxSize)
+}
+
+func newSnapshot(shared *snapshotFonts, query Query, script language.Script, cacheSize int) *Snapshot {
+	out := &Snapshot{shared: shared, query: query, script: script}
+	out.lru.maxSize = cacheSize
+	return out
+}
+
+// Clone returns a new snapshot with the same query and script, with its own
+// cache and faces, to be used by another goroutine.
+// It is cheap, since the font database is shared.
+func (sn *Snapshot) Clone() *Snapshot {
+	return newSnapshot(sn.shared, sn.query, sn.script, sn.lru.maxSize)
+}
+
+// WithQuery returns a new snapshot using [query] instead of the
+// current query, as [FontMap.SetQuery] would do.
+// It is cheap, since the font database is shared.
+func (sn *Snapshot) WithQuery(query Query) *Snapshot {
+	if len(query.Families) == 0 {
+		query.Families = []string{""}
+	}
+	return newSnapshot(sn.shared, query, sn.script, sn.lru.maxSize)
+}
+
+// WithScript returns a new snapshot using [script] instead of the
+// current script, as [FontMap.SetScript] would do.
+// It is cheap, since the font database is shared.
+func (sn *Snapshot) WithScript(script language.Script) *Snapshot {
+	return newSnapshot(sn.shared, sn.query, script, sn.lru.maxSize)
+}
+
+// ResolveFace selects a face supporting [r], as [FontMap.ResolveFace] does
+// for the query and script of the snapshot.
+// It is safe for concurrent use, but the returned face is not : see [Snapshot].
+func (sn *Snapshot) ResolveFace(r rune) *font.Face {
+	sn.buildOnce.Do(func() {
+		sn.candidates.build(sn.shared.fm.database, sn.query, sn.script, make(familyCrible), &scoredFootprints{})
+	})
+
+	sn.lruMu.Lock()
+	key := sn.lru.KeyFor(sn.query, sn.script, r)
+	face, _, ok := sn.lru.Get(key, sn.query)
+	sn.lruMu.Unlock()
+	if ok {
+		return face
+	}
+
+	sn.shared.mu.Lock()
+	face, step := sn.shared.fm.resolveFace(&sn.candidates, sn.query, sn.script, r)
+	sn.shared.mu.Unlock()
+
+	sn.lruMu.Lock()
+	face = sn.faces.get(face)
+	sn.lru.Put(key, sn.query, face, step)
+	sn.lruMu.Unlock()
+
+	return face
+}
@@ -0,0 +1,122 @@
+package fontscan
+
+import (
+	"io"
+	"log"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/language"
+	"github.com/go-text/typesetting/shaping"
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+var _ shaping.Fontmap = (*Snapshot)(nil)
+
+func TestSnapshot(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+
+	// simulate system fonts, stored on disk and lazily loaded
+	for _, file := range []string{"../font/testdata/Roboto-Regular.ttf", "../font/testdata/Amiri-Regular.ttf"} {
+		f, err := os.Open(file)
+		tu.AssertNoErr(t, err)
+		ld, err := ot.NewLoader(f)
+		tu.AssertNoErr(t, err)
+		fp, _, err := newFootprintFromLoader(ld, false, scanBuffer{})
+		tu.AssertNoErr(t, err)
+		f.Close()
+		fp.Location = Location{File: file}
+		fm.appendFootprints(fp)
+	}
+	fm.SetQuery(Query{Families: []string{"Roboto"}})
+
+	sn := fm.Snapshot()
+	text := []rune("Hello world, مرحبا بالعالم")
+
+	var wg sync.WaitGroup
+	results := make([][]*font.Face, 8)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for _, r := range text {
+				results[i] = append(results[i], sn.ResolveFace(r))
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// the results match the ones of the font map
+	for j, r := range text {
+		exp := fm.FontLocation(fm.ResolveFace(r).Font).File
+		for i := range results {
+			tu.Assert(t, sn.shared.fm.FontLocation(results[i][j].Font).File == exp)
+		}
+	}
+
+	// the snapshot is not affected by further modifications of the font map
+	fm.SetQuery(Query{Families: []string{"Amiri"}})
+	fm.appendFootprints(fm.database[1])
+	tu.Assert(t, len(sn.shared.fm.database) == 2)
+	tu.Assert(t, sn.shared.fm.FontLocation(sn.ResolveFace('a').Font).File == "../font/testdata/Roboto-Regular.ttf")
+
+	// derived snapshots
+	amiri := sn.WithQuery(Query{Families: []string{"Amiri"}})
+	tu.Assert(t, amiri.shared == sn.shared)
+	tu.Assert(t, sn.shared.fm.FontLocation(amiri.ResolveFace('a').Font).File == "../font/testdata/Amiri-Regular.ttf")
+	tu.Assert(t, sn.shared.fm.FontLocation(sn.ResolveFace('a').Font).File == "../font/testdata/Roboto-Regular.ttf")
+
+	arabic := sn.WithScript(language.Arabic)
+	tu.Assert(t, arabic.script == language.Arabic && sn.script == 0)
+	tu.Assert(t, arabic.ResolveFace('ب').Font == sn.ResolveFace('ب').Font)
+
+	// each snapshot has its own faces
+	tu.Assert(t, sn.ResolveFace('a') == sn.ResolveFace('a'))
+	tu.Assert(t, sn.Clone().ResolveFace('a') != sn.ResolveFace('a'))
+	tu.Assert(t, sn.ResolveFace('a') != fm.ResolveFace('a'))
+}
+
+// TestSnapshotShaping should be run with the -race flag
+func TestSnapshotShaping(t *testing.T) {
+	fm := NewFontMap(log.New(io.Discard, "", 0))
+	for _, file := range []string{"../font/testdata/Roboto-Regular.ttf", "../font/testdata/Amiri-Regular.ttf"} {
+		f, err := os.Open(file)
+		tu.AssertNoErr(t, err)
+		err = fm.AddFont(f, file, "")
+		tu.AssertNoErr(t, err)
+		f.Close()
+	}
+	fm.SetQuery(Query{Families: []string{"Roboto"}})
+	fm.SetLastResortFace(fm.ResolveFace('ب'))
+
+	text := []rune("Hello world, مرحبا بالعالم 123 \u4E00")
+	sn := fm.Snapshot()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(sn *Snapshot) {
+			defer wg.Done()
+			var (
+				seg    shaping.Segmenter
+				shaper shaping.HarfbuzzShaper
+			)
+			for j := 0; j < 10; j++ {
+				runs := seg.Split(shaping.Input{Text: text, RunEnd: len(text), Size: 12 * 64}, sn)
+				for _, run := range runs {
+					out := shaper.Shape(run)
+					tu.Assert(t, len(out.Glyphs) != 0)
+				}
+			}
+		}(sn.Clone())
+	}
+	// the font map is still usable
+	for _, r := range text {
+		face := fm.ResolveFace(r)
+		face.NominalGlyph(r)
+	}
+	wg.Wait()
+}
@@ -3,6 +3,7 @@ module github.com/go-text/typesetting
 go 1.19
 
 require (
+	github.com/andybalholm/brotli v1.1.1
 	github.com/go-text/typesetting-utils v0.0.0-20260327125527-fbf04b32d9ad
 	golang.org/x/image v0.23.0
 	golang.org/x/text v0.21.0
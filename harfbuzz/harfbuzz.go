@@ -50,17 +50,11 @@ const (
 // horizontally or vertically will return `Invalid`.
 // Unknown scripts will return `LeftToRight`.
 func getHorizontalDirection(script language.Script) Direction {
-	/* https://docs.google.com/spreadsheets/d/1Y90M0Ie3MUJ6UVCRDOypOtijlMDLNNyyLk36T6iMu0o */
-	switch script {
-	case language.Arabic, language.Hebrew, language.Syriac, language.Thaana,
-		language.Cypriot, language.Kharoshthi, language.Phoenician, language.Nko, language.Lydian,
-		language.Avestan, language.Imperial_Aramaic, language.Inscriptional_Pahlavi, language.Inscriptional_Parthian, language.Old_South_Arabian, language.Old_Turkic,
-		language.Samaritan, language.Mandaic, language.Meroitic_Cursive, language.Meroitic_Hieroglyphs, language.Manichaean, language.Mende_Kikakui,
-		language.Nabataean, language.Old_North_Arabian, language.Palmyrene, language.Psalter_Pahlavi, language.Hatran, language.Adlam, language.Hanifi_Rohingya,
-		language.Old_Sogdian, language.Sogdian, language.Elymaic, language.Chorasmian, language.Yezidi:
-
+	if script.IsRTL() {
 		return RightToLeft
+	}
 
+	switch script {
 	/* https://github.com/harfbuzz/harfbuzz/issues/1000 */
 	case language.Old_Hungarian, language.Old_Italic, language.Runic, language.Tifinagh:
 		return 0
@@ -2,6 +2,7 @@ package harfbuzz
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/go-text/typesetting/font/opentype/tables"
 )
@@ -31,6 +32,35 @@ func (b *Buffer) Shape(font *Font, features []Feature) {
 	shapePlan.execute(font, b, features)
 }
 
+// PlannedFeatures returns the sorted tags of the OpenType features whose
+// 'GSUB' or 'GPOS' lookups are selected by the shaping plan
+// built for `font`, `features` and the current `Props` of the buffer.
+//
+// The returned list includes the features automatically enabled for the
+// script (like 'init' or 'rlig' for Arabic) and the ones requested in `features`,
+// restricted to the ones implemented by the font.
+// Note that a selected feature may still leave a given text unchanged.
+// Features applied through AAT tables are not reported.
+func (b *Buffer) PlannedFeatures(font *Font, features []Feature) []tables.Tag {
+	plan := &b.newShapePlanCached(font, b.Props, features, font.varCoords()).shaper.plan
+
+	var out []tables.Tag
+	seen := map[tables.Tag]bool{}
+	for table, lookups := range plan.otMap.lookups {
+		if table == 0 && plan.applyMorx || table == 1 && !plan.applyGpos {
+			continue
+		}
+		for _, lookup := range lookups {
+			if !seen[lookup.featureTag] {
+				seen[lookup.featureTag] = true
+				out = append(out, lookup.featureTag)
+			}
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
 // Shape plans are an internal mechanism. Each plan contains state
 // describing how HarfBuzz will shape a particular text segment, based on
 // the combination of segment properties and the capabilities in the
@@ -222,50 +222,7 @@ func uniModifiedCombiningClass(u rune) uint8 {
 // IsDefaultIgnorable returns `true` for
 // codepoints with the Default_Ignorable property
 // (as defined in unicode data DerivedCoreProperties.txt)
-func IsDefaultIgnorable(ch rune) bool {
-	// Note: While U+115F, U+1160, U+3164 and U+FFA0 are Default_Ignorable,
-	// we do NOT want to hide them, as the way Uniscribe has implemented them
-	// is with regular spacing glyphs, and that's the way fonts are made to work.
-	// As such, we make exceptions for those four.
-	// Also ignoring U+1BCA0..1BCA3. https://github.com/harfbuzz/harfbuzz/issues/503
-	plane := ch >> 16
-	if plane == 0 {
-		/* BMP */
-		page := ch >> 8
-		switch page {
-		case 0x00:
-			return (ch == 0x00AD)
-		case 0x03:
-			return (ch == 0x034F)
-		case 0x06:
-			return (ch == 0x061C)
-		case 0x17:
-			return 0x17B4 <= ch && ch <= 0x17B5
-		case 0x18:
-			return 0x180B <= ch && ch <= 0x180E
-		case 0x20:
-			return 0x200B <= ch && ch <= 0x200F ||
-				0x202A <= ch && ch <= 0x202E ||
-				0x2060 <= ch && ch <= 0x206F
-		case 0xFE:
-			return 0xFE00 <= ch && ch <= 0xFE0F || ch == 0xFEFF
-		case 0xFF:
-			return 0xFFF0 <= ch && ch <= 0xFFF8
-		default:
-			return false
-		}
-	} else {
-		/* Other planes */
-		switch plane {
-		case 0x01:
-			return 0x1D173 <= ch && ch <= 0x1D17A
-		case 0x0E:
-			return 0xE0000 <= ch && ch <= 0xE0FFF
-		default:
-			return false
-		}
-	}
-}
+func IsDefaultIgnorable(ch rune) bool { return ucd.IsDefaultIgnorable(ch) }
 
 /* Space estimates based on:
  * https://unicode.org/charts/PDF/U2000.pdf
@@ -0,0 +1,91 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+// Package bidi implements the resolution of the embedding levels
+// defined by the Unicode Bidirectional Algorithm (UAX #9), which is
+// not exposed by the golang.org/x/text/unicode/bidi package.
+//
+// The files core.go and bracket.go are adapted from golang.org/x/text/unicode/bidi,
+// version v0.21.0, and are distributed under the license found in the LICENSE file.
+package bidi
+
+import (
+	"github.com/go-text/typesetting/internal/unicodedata"
+	"golang.org/x/text/unicode/bidi"
+)
+
+type Class = bidi.Class
+
+const (
+	L   = bidi.L
+	R   = bidi.R
+	EN  = bidi.EN
+	ES  = bidi.ES
+	ET  = bidi.ET
+	AN  = bidi.AN
+	CS  = bidi.CS
+	B   = bidi.B
+	S   = bidi.S
+	WS  = bidi.WS
+	ON  = bidi.ON
+	BN  = bidi.BN
+	NSM = bidi.NSM
+	AL  = bidi.AL
+	LRO = bidi.LRO
+	RLO = bidi.RLO
+	LRE = bidi.LRE
+	RLE = bidi.RLE
+	PDF = bidi.PDF
+	LRI = bidi.LRI
+	RLI = bidi.RLI
+	FSI = bidi.FSI
+	PDI = bidi.PDI
+
+	unknownClass = ^Class(0)
+)
+
+// ImplicitLevel may be passed to [Levels] to resolve the paragraph
+// level from the text, following the rules P2 and P3.
+const ImplicitLevel int8 = -1
+
+// Levels returns the embedding levels of the runes of a paragraph,
+// after the rule L1 has been applied to [text] as a single line.
+// [paragraphLevel] is either 0, 1 or [ImplicitLevel], and the resolved paragraph
+// level is returned as well.
+//
+// [text] must not contain a paragraph separator, except as its last rune.
+func Levels(text []rune, paragraphLevel int8) ([]int8, int8) {
+	levels, _, level := Resolve(text, paragraphLevel)
+	return levels, level
+}
+
+// Resolve is the same as [Levels], but also returns the classes of the runes
+// resolved by the weak and neutral rules (W1 to W7, N0 to N2), which are
+// the Unicode database classes for the runes removed by the rule X9.
+func Resolve(text []rune, paragraphLevel int8) ([]int8, []Class, int8) {
+	if len(text) == 0 {
+		return nil, nil, paragraphLevel
+	}
+	types := make([]Class, len(text))
+	pairTypes := make([]bracketType, len(text))
+	pairValues := make([]rune, len(text))
+	for i, r := range text {
+		props, _ := bidi.LookupRune(r)
+		types[i] = props.Class()
+		if props.IsOpeningBracket() {
+			pairTypes[i] = bpOpen
+			pairValues[i] = r
+		} else if props.IsBracket() {
+			// pair the closing bracket with its opening one
+			pairTypes[i] = bpClose
+			pairValues[i] = unicodedata.LookupMirrorChar(r)
+		}
+	}
+
+	p := newParagraph(types, pairTypes, pairValues, level(paragraphLevel))
+	levels := p.getLevels([]int{len(text)})
+	out := make([]int8, len(levels))
+	for i, l := range levels {
+		out[i] = int8(l)
+	}
+	return out, p.resultTypes, int8(p.embeddingLevel)
+}
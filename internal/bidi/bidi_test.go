@@ -0,0 +1,36 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package bidi
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLevels(t *testing.T) {
+	for _, test := range []struct {
+		text           string
+		paragraphLevel int8
+		expected       []int8
+		expectedLevel  int8
+	}{
+		{"", 0, nil, 0},
+		{"abc def", ImplicitLevel, []int8{0, 0, 0, 0, 0, 0, 0}, 0},
+		{"אב c", ImplicitLevel, []int8{1, 1, 1, 2}, 1},
+		// numbers in a right-to-left span
+		{"abc אב 123 גד def", 0, []int8{0, 0, 0, 0, 1, 1, 1, 2, 2, 2, 1, 1, 1, 0, 0, 0, 0}, 0},
+		// explicit embedding
+		{"אב\u202Bcd\u202Cגד", 0, []int8{1, 1, 1, 2, 2, 2, 1, 1}, 0},
+		// isolate
+		{"a\u2067ב c\u2069d", 0, []int8{0, 0, 1, 1, 2, 0, 0}, 0},
+		// paired brackets (rule N0)
+		{"א(ב)c", 0, []int8{1, 1, 1, 1, 0}, 0},
+		// rule L1 : trailing whitespace and paragraph separator
+		{"אב \n", 0, []int8{1, 1, 0, 0}, 0},
+	} {
+		levels, paragraphLevel := Levels([]rune(test.text), test.paragraphLevel)
+		if !reflect.DeepEqual(levels, test.expected) || paragraphLevel != test.expectedLevel {
+			t.Fatalf("for %q, expected %v (%d), got %v (%d)", test.text, test.expected, test.expectedLevel, levels, paragraphLevel)
+		}
+	}
+}
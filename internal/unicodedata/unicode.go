@@ -104,11 +104,90 @@ func LookupIndicConjunctBreak(r rune) IndicConjunctBreak {
 // Unicode standard and has a mirrored equivalent, it is returned.
 // Otherwise the input character itself is returned
 func LookupMirrorChar(ch rune) rune {
-	return ch + rune(mirLookup(ch))
+	m, _ := language.Mirror(ch)
+	return m
 }
 
 func IsExtendedPictographic(ch rune) bool { return emojiLookup(ch) == 1 }
 
+// IsDefaultIgnorable returns `true` for
+// codepoints with the Default_Ignorable property
+// (as defined in unicode data DerivedCoreProperties.txt)
+func IsDefaultIgnorable(ch rune) bool {
+	// Note: While U+115F, U+1160, U+3164 and U+FFA0 are Default_Ignorable,
+	// we do NOT want to hide them, as the way Uniscribe has implemented them
+	// is with regular spacing glyphs, and that's the way fonts are made to work.
+	// As such, we make exceptions for those four.
+	// Also ignoring U+1BCA0..1BCA3. https://github.com/harfbuzz/harfbuzz/issues/503
+	plane := ch >> 16
+	if plane == 0 {
+		/* BMP */
+		page := ch >> 8
+		switch page {
+		case 0x00:
+			return (ch == 0x00AD)
+		case 0x03:
+			return (ch == 0x034F)
+		case 0x06:
+			return (ch == 0x061C)
+		case 0x17:
+			return 0x17B4 <= ch && ch <= 0x17B5
+		case 0x18:
+			return 0x180B <= ch && ch <= 0x180E
+		case 0x20:
+			return 0x200B <= ch && ch <= 0x200F ||
+				0x202A <= ch && ch <= 0x202E ||
+				0x2060 <= ch && ch <= 0x206F
+		case 0xFE:
+			return 0xFE00 <= ch && ch <= 0xFE0F || ch == 0xFEFF
+		case 0xFF:
+			return 0xFFF0 <= ch && ch <= 0xFFF8
+		default:
+			return false
+		}
+	} else {
+		/* Other planes */
+		switch plane {
+		case 0x01:
+			return 0x1D173 <= ch && ch <= 0x1D17A
+		case 0x0E:
+			return 0xE0000 <= ch && ch <= 0xE0FFF
+		default:
+			return false
+		}
+	}
+}
+
+// IgnoreFaceChange returns `true` is the given rune should not trigger
+// a change of font during segmentation. It is shared by the shaping and
+// fontscan packages.
+//
+// We don't want space characters to affect font selection; in general,
+// it's always wrong to select a font just to render a space.
+// We assume that all fonts have the ASCII space, and for other space
+// characters if they don't, HarfBuzz will compatibility-decompose them
+// to ASCII space...
+//
+// We don't want to change fonts for line or paragraph separators.
+//
+// Finaly, we also don't change fonts for what Harfbuzz consider
+// as ignorable (however, some Control Format runes like 06DD are not ignored).
+//
+// The rationale is taken from pango : see bugs
+// https://bugzilla.gnome.org/show_bug.cgi?id=355987
+// https://bugzilla.gnome.org/show_bug.cgi?id=701652
+// https://bugzilla.gnome.org/show_bug.cgi?id=781123
+// for more details.
+func IgnoreFaceChange(r rune) bool {
+	g := LookupGeneralCategory(r)
+	return g == Cc || // control
+		g == Cs || // surrogate
+		g == Zl || // line separator
+		g == Zp || // paragraph separator
+		(g == Zs && r != '\u1680') || // space separator != OGHAM SPACE MARK
+		IsDefaultIgnorable(r)
+}
+
 // IsLargeEastAsian matches runes with East_Asian_Width property of
 // F, W or H, and is used for UAX14, rule LB30.
 func IsLargeEastAsian(ch rune) bool { return eastAsianWidthLookup(ch) == 1 }
@@ -318,3 +318,42 @@ var ScriptToLang = map[Script]LangID{
 
 	Nko: LangNqo,
 }
+
+// scriptDefaultLanguages completes [ScriptToLang] for [DefaultLanguageForScript],
+// with the likely language associated to some scripts by CLDR.
+var scriptDefaultLanguages = map[Script]Language{
+	Han:         "zh",
+	Bopomofo:    "zh",
+	Yi:          "ii",
+	Tifinagh:    "zgh",
+	Limbu:       "lif",
+	Tai_Le:      "tdd",
+	New_Tai_Lue: "khb",
+	Osmanya:     "so",
+	Gothic:      "got",
+	Glagolitic:  "cu",
+	Runic:       "non",
+	Ogham:       "sga",
+	Old_Italic:  "ett",
+	Linear_B:    "grc",
+	Cypriot:     "grc",
+	Deseret:     "en",
+	Shavian:     "en",
+}
+
+// DefaultLanguageForScript returns a reasonable default language for
+// text written in [s], when no language is specified, for instance
+// "ar" for Arabic, "he" for Hebrew, "zh" for Han and "ja" for Hiragana and Katakana.
+//
+// It is a heuristic, based on [ScriptToLang] and on the likely languages defined by CLDR :
+// contrary to [ScriptToLang], it returns a language for scripts shared by several languages
+// (like "zh" for Han), which should only be used as a hint, for instance to select
+// the OpenType language system when shaping.
+// The empty language is returned for Common, Inherited, Unknown and
+// the scripts with no associated language.
+func DefaultLanguageForScript(s Script) Language {
+	if l := ScriptToLang[s]; l != 0 {
+		return l.Language()
+	}
+	return scriptDefaultLanguages[s]
+}
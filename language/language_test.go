@@ -247,3 +247,66 @@ func TestLangID_UseScript(t *testing.T) {
 		}
 	}
 }
+
+func TestDefaultLanguageForScript(t *testing.T) {
+	for _, test := range []struct {
+		script Script
+		want   Language
+	}{
+		{Arabic, "ar"},
+		{Hebrew, "he"},
+		{Han, "zh"},
+		{Hiragana, "ja"},
+		{Katakana, "ja"},
+		{Hangul, "ko"},
+		{Latin, "en"},
+		{Cyrillic, "ru"},
+		{Yi, "ii"},
+		{Common, ""},
+		{Inherited, ""},
+		{Unknown, ""},
+		{Braille, ""},
+	} {
+		tu.AssertC(t, DefaultLanguageForScript(test.script) == test.want, test.script.String())
+	}
+
+	// ScriptToLang is used when possible
+	for script, lang := range ScriptToLang {
+		if lang != 0 {
+			tu.AssertC(t, DefaultLanguageForScript(script) == lang.Language(), script.String())
+		}
+	}
+}
+
+func TestMirror(t *testing.T) {
+	for _, test := range []struct {
+		r        rune
+		expected rune
+		ok       bool
+	}{
+		{'(', ')', true},
+		{')', '(', true},
+		{'[', ']', true},
+		{'<', '>', true},
+		{'«', '»', true},
+		{'≤', '≥', true},
+		{'\u2039', '\u203A', true},
+		{'\uFF08', '\uFF09', true},
+		{'a', 'a', false},
+		{'1', '1', false},
+		{'∑', '∑', false}, // Bidi_Mirrored, without mirroring glyph
+		{0x10FFFF, 0x10FFFF, false},
+		{-1, -1, false},
+	} {
+		got, ok := Mirror(test.r)
+		tu.AssertC(t, got == test.expected && ok == test.ok, fmt.Sprintf("%U", test.r))
+	}
+
+	// the mapping is an involution
+	for r := rune(0); r < 0x20000; r++ {
+		if m, ok := Mirror(r); ok {
+			back, ok := Mirror(m)
+			tu.AssertC(t, ok && back == r, fmt.Sprintf("%U", r))
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package language
+
+// The mirroring table (mirLookup, in mirroring_table.go) is generated from BidiMirroring.txt by
+// typesetting-utils/generators/unicodedata, like the other Unicode tables of this package.
+// It lives here rather than in internal/unicodedata, which imports this package : the generator
+// output for the mirroring table must be written to language/mirroring_table.go (package language)
+// when updating the Unicode version.
+
+// Mirror returns the mirrored equivalent of [r], as defined by the
+// Bidi_Mirroring_Glyph property in the file BidiMirroring.txt of the Unicode Character Database,
+// or false if [r] has no mirrored equivalent (in which case [r] itself is returned).
+// For instance, '(' is mirrored to ')' and '≤' to '≥'.
+//
+// Mirrored runes should be displayed with the glyph of their equivalent in right-to-left runs
+// (rule L4 of the Unicode Bidirectional Algorithm).
+// Note that the harfbuzz shaper already applies this mirroring to right-to-left runs.
+//
+// Fonts may also provide their own mirrored glyphs with the 'rtlm' feature, which the shaper
+// only enables for the runes whose equivalent is not supported by the font,
+// or which have no equivalent (like '∛'). Mirroring the text before shaping
+// would defeat this mechanism : this function is only needed when the text is
+// rendered without shaping, or to inspect the mirrored text.
+func Mirror(r rune) (rune, bool) {
+	m := r + rune(mirLookup(r))
+	return m, m != r
+}
@@ -1,6 +1,6 @@
 // SPDX-License-Identifier: Unlicense OR BSD-3-Clause
 
-package unicodedata
+package language
 
 // Code generated by typesettings-utils/generators/unicodedata/cmd/main.go DO NOT EDIT.
 
@@ -3,6 +3,10 @@ package language
 import (
 	"encoding/binary"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Script identifies different writing systems.
@@ -12,21 +16,64 @@ import (
 type Script uint32
 
 // ParseScript converts a 4 bytes string into its binary encoding,
-// enforcing the conventional capitalized case.
-// If [script] is longer, only its 4 first bytes are used.
+// enforcing the conventional capitalized case (as returned by [Script.String]),
+// so that "arab", "ARAB" and "Arab" are all parsed as [Arabic].
+//
+// The 3 digits numeric ISO 15924 codes are also supported : "215" is parsed as [Latin]
+// (see also [ScriptFromNumericCode]).
+// An error is returned for other lengths, or for unknown numeric codes.
 func ParseScript(script string) (Script, error) {
-	if len(script) < 4 {
-		return 0, fmt.Errorf("invalid script string: %s", script)
+	if len(script) == 4 {
+		s := binary.BigEndian.Uint32([]byte(script))
+		// ensure capitalized case : make first letter upper, others lower
+		const mask uint32 = 0x20000000
+		return Script(s & ^mask | 0x00202020), nil
 	}
-	s := binary.BigEndian.Uint32([]byte(script))
-	// ensure capitalized case : make first letter upper, others lower
-	const mask uint32 = 0x20000000
-	return Script(s & ^mask | 0x00202020), nil
+	if len(script) == 3 {
+		code := 0
+		for i := 0; i < 3; i++ {
+			c := script[i]
+			if c < '0' || c > '9' {
+				return 0, fmt.Errorf("invalid numeric script code: %s", script)
+			}
+			code = 10*code + int(c-'0')
+		}
+		if s, ok := ScriptFromNumericCode(code); ok {
+			return s, nil
+		}
+		return 0, fmt.Errorf("unknown numeric script code: %s", script)
+	}
+	return 0, fmt.Errorf("invalid script string (expected 4 letters or 3 digits): %s", script)
 }
 
+// latin1Scripts caches the scripts of the first 256 runes,
+// which are very common in practice.
+var latin1Scripts = func() (out [256]Script) {
+	for r := range out {
+		out[r] = lookupScriptRanges(rune(r))
+	}
+	return out
+}()
+
 // LookupScript looks up the script for a particular character (as defined by
 // Unicode Standard Annex #24), and returns Unknown if not found.
+//
+// The ranges added by [RegisterScriptRange] have priority over
+// the generated [ScriptRanges] table.
 func LookupScript(r rune) Script {
+	if extra := extraScriptRanges.Load(); extra != nil {
+		if s, ok := lookupExtraScriptRanges(*extra, r); ok {
+			return s
+		}
+	}
+	// fast path for ASCII and Latin-1
+	if 0 <= r && r < rune(len(latin1Scripts)) {
+		return latin1Scripts[r]
+	}
+	return lookupScriptRanges(r)
+}
+
+func lookupScriptRanges(r rune) Script {
 	// binary search
 	for i, j := 0, len(ScriptRanges); i < j; {
 		h := i + (j-i)/2
@@ -42,6 +89,183 @@ func LookupScript(r rune) Script {
 	return Unknown
 }
 
+// LookupScriptExtensions returns the scripts [r] is used with, as defined by the
+// Script_Extensions property (see Unicode Standard Annex #24).
+// For most runes, it is the script returned by [LookupScript], but some Common
+// or Inherited runes (like the Arabic comma or the Devanagari danda) are shared by
+// a limited set of scripts, which helps to resolve their script from the context.
+//
+// The returned slice is never empty, is shared, and must not be modified.
+// The ranges added by [RegisterScriptRange] are only taken into account for
+// runes with no specific extensions.
+func LookupScriptExtensions(r rune) []Script {
+	// binary search
+	for i, j := 0, len(scriptExtensionRanges); i < j; {
+		h := i + (j-i)/2
+		entry := scriptExtensionRanges[h]
+		if r < entry.Start {
+			j = h
+		} else if entry.End < r {
+			i = h + 1
+		} else {
+			return scriptExtensionSets[entry.set]
+		}
+	}
+
+	switch s := LookupScript(r); s {
+	case Common:
+		return commonExtensions
+	case Inherited:
+		return inheritedExtensions
+	default:
+		return []Script{s}
+	}
+}
+
+// avoid allocations for the most frequent cases
+var (
+	commonExtensions    = []Script{Common}
+	inheritedExtensions = []Script{Inherited}
+)
+
+var (
+	// extraScriptRanges is a sorted list of non overlapping ranges,
+	// which is never mutated once stored : [RegisterScriptRange]
+	// replaces it by an updated copy
+	extraScriptRanges   atomic.Pointer[[]ScriptRange]
+	extraScriptRangesMu sync.Mutex // serializes the calls to RegisterScriptRange
+)
+
+// RegisterScriptRange registers the script [s] for the runes in the
+// inclusive range [start, end], overriding the generated [ScriptRanges] table
+// in [LookupScript].
+// This is useful to support codepoints assigned in Unicode versions
+// newer than the one used to generate [ScriptRanges].
+//
+// When several registered ranges overlap, the last registration wins.
+// Note that the generated [ScriptRanges] table itself is not modified.
+//
+// RegisterScriptRange is safe for concurrent use, but is typically
+// called once, during initialization.
+// It panics if start > end.
+func RegisterScriptRange(start, end rune, s Script) {
+	if start > end {
+		panic(fmt.Sprintf("invalid script range [%d, %d]", start, end))
+	}
+
+	extraScriptRangesMu.Lock()
+	defer extraScriptRangesMu.Unlock()
+
+	var current []ScriptRange
+	if ptr := extraScriptRanges.Load(); ptr != nil {
+		current = *ptr
+	}
+
+	updated := make([]ScriptRange, 0, len(current)+2)
+	inserted := false
+	for _, item := range current {
+		if item.End < start { // before the new range
+			updated = append(updated, item)
+			continue
+		}
+		if !inserted {
+			updated = append(updated, ScriptRange{Start: start, End: end, Script: s})
+			inserted = true
+		}
+		if item.Start > end { // after the new range
+			updated = append(updated, item)
+			continue
+		}
+		// overlapping : only keep the parts outside [start, end],
+		// in sorted order
+		if item.Start < start {
+			updated = append(updated[:len(updated)-1], ScriptRange{Start: item.Start, End: start - 1, Script: item.Script}, updated[len(updated)-1])
+		}
+		if item.End > end {
+			updated = append(updated, ScriptRange{Start: end + 1, End: item.End, Script: item.Script})
+		}
+	}
+	if !inserted {
+		updated = append(updated, ScriptRange{Start: start, End: end, Script: s})
+	}
+
+	extraScriptRanges.Store(&updated)
+}
+
+func lookupExtraScriptRanges(ranges []ScriptRange, r rune) (Script, bool) {
+	// binary search
+	for i, j := 0, len(ranges); i < j; {
+		h := i + (j-i)/2
+		entry := ranges[h]
+		if r < entry.Start {
+			j = h
+		} else if entry.End < r {
+			i = h + 1
+		} else {
+			return entry.Script, true
+		}
+	}
+	return 0, false
+}
+
+// ScriptExtent returns the runes whose script is [s], as reported by [LookupScript],
+// that is, from the generated [ScriptRanges] table and the ranges
+// added by [RegisterScriptRange].
+// The returned ranges are sorted, and adjacent ranges are merged. Their Script field is [s].
+//
+// Since the unassigned runes are not listed in the tables, the extent
+// of Unknown is empty (unless registered with [RegisterScriptRange]).
+func ScriptExtent(s Script) []ScriptRange {
+	var out []ScriptRange
+	for _, rg := range ScriptRanges {
+		if rg.Script == s {
+			out = append(out, rg)
+		}
+	}
+
+	if extra := extraScriptRanges.Load(); extra != nil {
+		for _, rg := range *extra {
+			if rg.Script == s {
+				out = append(out, rg)
+			} else {
+				out = removeRange(out, rg.Start, rg.End)
+			}
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Start < out[j].Start })
+	// merge overlapping and adjacent ranges
+	merged := out[:0]
+	for _, rg := range out {
+		if L := len(merged); L != 0 && rg.Start <= merged[L-1].End+1 {
+			if rg.End > merged[L-1].End {
+				merged[L-1].End = rg.End
+			}
+			continue
+		}
+		merged = append(merged, rg)
+	}
+	return merged
+}
+
+// removeRange removes the runes in [start, end] from [ranges]
+func removeRange(ranges []ScriptRange, start, end rune) []ScriptRange {
+	out := ranges[:0:0]
+	for _, rg := range ranges {
+		if rg.End < start || rg.Start > end { // no overlap
+			out = append(out, rg)
+			continue
+		}
+		if rg.Start < start {
+			out = append(out, ScriptRange{Start: rg.Start, End: start - 1, Script: rg.Script})
+		}
+		if rg.End > end {
+			out = append(out, ScriptRange{Start: end + 1, End: rg.End, Script: rg.Script})
+		}
+	}
+	return out
+}
+
 // String returns the ISO 4 lower letters code of the script
 func (s Script) String() string {
 	var buf [4]byte
@@ -53,3 +277,266 @@ func (s Script) String() string {
 func (s Script) Strong() bool {
 	return s != Common && s != Inherited
 }
+
+// Name returns the human readable name of the script, as defined by Unicode,
+// with spaces instead of underscores (for instance "Latin" or "Old Italic").
+// The tag returned by [Script.String] is used for scripts not known by this package.
+func (s Script) Name() string {
+	scriptNamesOnce.Do(func() {
+		scriptNames = make(map[Script]string, len(scriptToTag))
+		for name, script := range scriptToTag {
+			scriptNames[script] = strings.ReplaceAll(name, "_", " ")
+		}
+	})
+	if name, ok := scriptNames[s]; ok {
+		return name
+	}
+	return s.String()
+}
+
+var (
+	scriptNamesOnce sync.Once
+	scriptNames     map[Script]string // lazily built from [scriptToTag]
+)
+
+// IsRTL returns true if the script is written from right to left
+// when set horizontally, like Arabic or Hebrew.
+// It may be used to choose a default direction when no bidi information is available.
+// False is returned for Common, Inherited and Unknown, and for the scripts which
+// may be written in both directions, like Old Italic or Runic.
+func (s Script) IsRTL() bool {
+	// https://docs.google.com/spreadsheets/d/1Y90M0Ie3MUJ6UVCRDOypOtijlMDLNNyyLk36T6iMu0o
+	switch s {
+	case Arabic, Hebrew, Syriac, Thaana,
+		Cypriot, Kharoshthi, Phoenician, Nko, Lydian,
+		Avestan, Imperial_Aramaic, Inscriptional_Pahlavi, Inscriptional_Parthian, Old_South_Arabian, Old_Turkic,
+		Samaritan, Mandaic, Meroitic_Cursive, Meroitic_Hieroglyphs, Manichaean, Mende_Kikakui,
+		Nabataean, Old_North_Arabian, Palmyrene, Psalter_Pahlavi, Hatran, Adlam, Hanifi_Rohingya,
+		Old_Sogdian, Sogdian, Elymaic, Chorasmian, Yezidi:
+		return true
+	}
+	return false
+}
+
+// NumericCode returns the numeric ISO 15924 code of the script
+// (for instance 215 for Latin), or 0 if the script is not known.
+func (s Script) NumericCode() int { return int(scriptNumericCodes[s]) }
+
+// ScriptFromNumericCode returns the script with the given numeric ISO 15924 code,
+// or false if [code] does not match a known script.
+func ScriptFromNumericCode(code int) (Script, bool) {
+	if code <= 0 {
+		return 0, false
+	}
+	for s, c := range scriptNumericCodes {
+		if int(c) == code {
+			return s, true
+		}
+	}
+	return 0, false
+}
+
+// scriptNumericCodes maps the scripts to their numeric ISO 15924 code.
+//
+// See https://www.unicode.org/iso15924/iso15924-codes.html
+var scriptNumericCodes = map[Script]uint16{
+	Adlam:                        166,
+	Afaka:                        439,
+	Ahom:                         338,
+	Anatolian_Hieroglyphs:        80,
+	Arabic:                       160,
+	Armenian:                     230,
+	Avestan:                      134,
+	Balinese:                     360,
+	Bamum:                        435,
+	Bassa_Vah:                    259,
+	Batak:                        365,
+	Bengali:                      325,
+	Beria_Erfe:                   258,
+	Bhaiksuki:                    334,
+	Blissymbols:                  550,
+	Book_Pahlavi:                 133,
+	Bopomofo:                     285,
+	Brahmi:                       300,
+	Braille:                      570,
+	Buginese:                     367,
+	Buhid:                        372,
+	Canadian_Aboriginal:          440,
+	Carian:                       201,
+	Caucasian_Albanian:           239,
+	Chakma:                       349,
+	Cham:                         358,
+	Cherokee:                     445,
+	Chisoi:                       298,
+	Chorasmian:                   109,
+	Cirth:                        291,
+	Code_for_unwritten_documents: 997,
+	Common:                       998,
+	Coptic:                       204,
+	Cuneiform:                    20,
+	Cypriot:                      403,
+	Cypro_Minoan:                 402,
+	Cyrillic:                     220,
+	Deseret:                      250,
+	Devanagari:                   315,
+	Dives_Akuru:                  342,
+	Dogra:                        328,
+	Duployan:                     755,
+	Egyptian_Hieroglyphs:         50,
+	Egyptian_demotic:             70,
+	Egyptian_hieratic:            60,
+	Elbasan:                      226,
+	Elymaic:                      128,
+	Ethiopic:                     430,
+	Garay:                        164,
+	Georgian:                     240,
+	Glagolitic:                   225,
+	Gothic:                       206,
+	Grantha:                      343,
+	Greek:                        200,
+	Gujarati:                     320,
+	Gunjala_Gondi:                312,
+	Gurmukhi:                     310,
+	Gurung_Khema:                 397,
+	Han:                          500,
+	Hangul:                       286,
+	Hanifi_Rohingya:              167,
+	Hanunoo:                      371,
+	Hatran:                       127,
+	Hebrew:                       125,
+	Hiragana:                     410,
+	Imperial_Aramaic:             124,
+	Inherited:                    994,
+	Inscriptional_Pahlavi:        131,
+	Inscriptional_Parthian:       130,
+	Javanese:                     361,
+	Jurchen:                      510,
+	Kaithi:                       317,
+	Kannada:                      345,
+	Katakana:                     411,
+	Katakana_Or_Hiragana:         412,
+	Kawi:                         368,
+	Kayah_Li:                     357,
+	Kharoshthi:                   305,
+	Khitan_Small_Script:          288,
+	Khitan_large_script:          505,
+	Khmer:                        355,
+	Khojki:                       322,
+	Khudawadi:                    318,
+	Kirat_Rai:                    396,
+	Kpelle:                       436,
+	Lao:                          356,
+	Latin:                        215,
+	Leke:                         364,
+	Lepcha:                       335,
+	Limbu:                        336,
+	Linear_A:                     400,
+	Linear_B:                     401,
+	Lisu:                         399,
+	Loma:                         437,
+	Lycian:                       202,
+	Lydian:                       116,
+	Mahajani:                     314,
+	Makasar:                      366,
+	Malayalam:                    347,
+	Mandaic:                      140,
+	Manichaean:                   139,
+	Marchen:                      332,
+	Masaram_Gondi:                313,
+	Mathematical_notation:        995,
+	Mayan_hieroglyphs:            90,
+	Medefaidrin:                  265,
+	Meetei_Mayek:                 337,
+	Mende_Kikakui:                438,
+	Meroitic_Cursive:             101,
+	Meroitic_Hieroglyphs:         100,
+	Miao:                         282,
+	Modi:                         324,
+	Mongolian:                    145,
+	Mro:                          264,
+	Multani:                      323,
+	Myanmar:                      350,
+	Nabataean:                    159,
+	Nag_Mundari:                  295,
+	Nandinagari:                  311,
+	New_Tai_Lue:                  354,
+	Newa:                         333,
+	Nko:                          165,
+	Nushu:                        499,
+	Nyiakeng_Puachue_Hmong:       451,
+	Ogham:                        212,
+	Ol_Chiki:                     261,
+	Ol_Onal:                      296,
+	Old_Hungarian:                176,
+	Old_Italic:                   210,
+	Old_North_Arabian:            106,
+	Old_Permic:                   227,
+	Old_Persian:                  30,
+	Old_Sogdian:                  142,
+	Old_South_Arabian:            105,
+	Old_Turkic:                   175,
+	Old_Uyghur:                   143,
+	Oriya:                        327,
+	Osage:                        219,
+	Osmanya:                      260,
+	Pahawh_Hmong:                 450,
+	Palmyrene:                    126,
+	Pau_Cin_Hau:                  263,
+	Phags_Pa:                     331,
+	Phoenician:                   115,
+	Psalter_Pahlavi:              132,
+	Ranjana:                      303,
+	Rejang:                       363,
+	Rongorongo:                   620,
+	Runic:                        211,
+	Samaritan:                    123,
+	Sarati:                       292,
+	Saurashtra:                   344,
+	Sharada:                      319,
+	Shavian:                      281,
+	Shuishu:                      530,
+	Siddham:                      302,
+	Sidetic:                      180,
+	SignWriting:                  95,
+	Sinhala:                      348,
+	Sogdian:                      141,
+	Sora_Sompeng:                 398,
+	Soyombo:                      329,
+	Sundanese:                    362,
+	Sunuwar:                      274,
+	Syloti_Nagri:                 316,
+	Symbols:                      996,
+	Syriac:                       135,
+	Tagalog:                      370,
+	Tagbanwa:                     373,
+	Tai_Le:                       353,
+	Tai_Tham:                     351,
+	Tai_Viet:                     359,
+	Tai_Yo:                       380,
+	Takri:                        321,
+	Tamil:                        346,
+	Tangsa:                       275,
+	Tangut:                       520,
+	Telugu:                       340,
+	Tengwar:                      290,
+	Thaana:                       170,
+	Thai:                         352,
+	Tibetan:                      330,
+	Tifinagh:                     120,
+	Tirhuta:                      326,
+	Todhri:                       229,
+	Tolong_Siki:                  299,
+	Toto:                         294,
+	Tulu_Tigalari:                341,
+	Ugaritic:                     40,
+	Unknown:                      999,
+	Vai:                          470,
+	Visible_Speech:               280,
+	Vithkuqi:                     228,
+	Wancho:                       283,
+	Warang_Citi:                  262,
+	Woleai:                       480,
+	Yezidi:                       192,
+	Yi:                           460,
+	Zanabazar_Square:             339,
+}
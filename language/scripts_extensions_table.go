@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package language
+
+// Code generated from the Script_Extensions property of the Unicode Character Database
+// (Unicode version 15.0.0). DO NOT EDIT.
+
+// scriptExtensionSets stores the distinct sets of scripts used by [scriptExtensionRanges].
+var scriptExtensionSets = [...][]Script{
+	{Greek},
+	{Latin},
+	{Cyrillic, Old_Permic},
+	{Cyrillic, Glagolitic},
+	{Cyrillic, Latin},
+	{Arabic, Syriac, Thaana, Nko, Hanifi_Rohingya, Yezidi},
+	{Arabic, Syriac, Thaana},
+	{Arabic, Syriac, Thaana, Nko, Adlam, Hanifi_Rohingya, Yezidi},
+	{Arabic, Syriac, Mandaic, Manichaean, Psalter_Pahlavi, Adlam, Hanifi_Rohingya, Sogdian, Old_Uyghur},
+	{Arabic, Syriac},
+	{Arabic, Thaana, Yezidi},
+	{Arabic, Hanifi_Rohingya},
+	{Bengali, Devanagari, Gujarati, Gurmukhi, Kannada, Latin, Malayalam, Oriya, Tamil, Telugu, Grantha, Sharada, Tirhuta},
+	{Bengali, Devanagari, Gujarati, Gurmukhi, Kannada, Latin, Malayalam, Oriya, Tamil, Telugu, Grantha, Tirhuta},
+	{Bengali, Devanagari, Gujarati, Gurmukhi, Kannada, Malayalam, Oriya, Sinhala, Tamil, Telugu, Syloti_Nagri, Grantha, Khudawadi, Takri, Tirhuta, Mahajani, Masaram_Gondi, Dogra, Gunjala_Gondi, Nandinagari},
+	{Bengali, Devanagari, Gujarati, Gurmukhi, Kannada, Malayalam, Oriya, Sinhala, Tamil, Telugu, Limbu, Syloti_Nagri, Grantha, Khudawadi, Takri, Tirhuta, Mahajani, Masaram_Gondi, Dogra, Gunjala_Gondi, Nandinagari},
+	{Devanagari, Kaithi, Mahajani, Dogra},
+	{Bengali, Syloti_Nagri, Chakma},
+	{Gurmukhi, Multani},
+	{Gujarati, Khojki},
+	{Tamil, Grantha},
+	{Kannada, Nandinagari},
+	{Myanmar, Tai_Le, Chakma},
+	{Georgian, Latin},
+	{Tagalog, Hanunoo, Buhid, Tagbanwa},
+	{Mongolian, Phags_Pa},
+	{Bengali, Devanagari, Kannada, Grantha},
+	{Devanagari},
+	{Devanagari, Grantha},
+	{Bengali, Devanagari},
+	{Devanagari, Sharada},
+	{Devanagari, Kannada, Malayalam, Oriya, Tamil, Telugu},
+	{Devanagari, Nandinagari},
+	{Bengali, Devanagari, Kannada, Oriya, Telugu, Grantha, Tirhuta, Nandinagari},
+	{Devanagari, Kannada, Grantha},
+	{Bengali},
+	{Nandinagari},
+	{Cyrillic, Syriac},
+	{Syriac},
+	{Latin, Mongolian},
+	{Devanagari, Latin, Grantha},
+	{Bopomofo, Han, Hangul, Hiragana, Katakana, Yi},
+	{Bopomofo, Han, Hangul, Hiragana, Katakana},
+	{Han},
+	{Bopomofo, Han},
+	{Hiragana, Katakana},
+	{Han, Hiragana, Katakana},
+	{Han, Latin},
+	{Devanagari, Gujarati, Gurmukhi, Kannada, Malayalam, Kaithi, Khudawadi, Takri, Khojki, Tirhuta, Mahajani, Modi, Dogra, Nandinagari},
+	{Devanagari, Gujarati, Gurmukhi, Kannada, Kaithi, Khudawadi, Takri, Khojki, Tirhuta, Mahajani, Modi, Dogra, Nandinagari},
+	{Devanagari, Gujarati, Gurmukhi, Kaithi, Khudawadi, Takri, Khojki, Tirhuta, Mahajani, Modi, Dogra},
+	{Devanagari, Tamil},
+	{Latin, Myanmar, Kayah_Li},
+	{Buginese, Javanese},
+	{Arabic, Nko},
+	{Arabic, Thaana},
+	{Cypriot, Linear_B, Cypro_Minoan},
+	{Cypriot, Linear_B},
+	{Cypriot, Linear_B, Linear_A},
+	{Arabic, Coptic},
+	{Manichaean, Old_Uyghur},
+	{Duployan},
+}
+
+// scriptExtensionRanges is a sorted list of the (inclusive) rune ranges whose
+// Script_Extensions property is not the script returned by [LookupScript] :
+// set is an index into [scriptExtensionSets].
+var scriptExtensionRanges = [...]struct {
+	Start, End rune
+	set        uint8
+}{
+	{0x342, 0x342, 0},
+	{0x345, 0x345, 0},
+	{0x363, 0x36f, 1},
+	{0x483, 0x483, 2},
+	{0x484, 0x484, 3},
+	{0x485, 0x486, 4},
+	{0x487, 0x487, 3},
+	{0x60c, 0x60c, 5},
+	{0x61b, 0x61b, 5},
+	{0x61c, 0x61c, 6},
+	{0x61f, 0x61f, 7},
+	{0x640, 0x640, 8},
+	{0x64b, 0x655, 9},
+	{0x660, 0x669, 10},
+	{0x670, 0x670, 9},
+	{0x6d4, 0x6d4, 11},
+	{0x951, 0x951, 12},
+	{0x952, 0x952, 13},
+	{0x964, 0x964, 14},
+	{0x965, 0x965, 15},
+	{0x966, 0x96f, 16},
+	{0x9e6, 0x9ef, 17},
+	{0xa66, 0xa6f, 18},
+	{0xae6, 0xaef, 19},
+	{0xbe6, 0xbf3, 20},
+	{0xce6, 0xcef, 21},
+	{0x1040, 0x1049, 22},
+	{0x10fb, 0x10fb, 23},
+	{0x1735, 0x1736, 24},
+	{0x1802, 0x1803, 25},
+	{0x1805, 0x1805, 25},
+	{0x1cd0, 0x1cd0, 26},
+	{0x1cd1, 0x1cd1, 27},
+	{0x1cd2, 0x1cd2, 26},
+	{0x1cd3, 0x1cd3, 28},
+	{0x1cd4, 0x1cd4, 27},
+	{0x1cd5, 0x1cd6, 29},
+	{0x1cd7, 0x1cd7, 30},
+	{0x1cd8, 0x1cd8, 29},
+	{0x1cd9, 0x1cd9, 30},
+	{0x1cda, 0x1cda, 31},
+	{0x1cdb, 0x1cdb, 27},
+	{0x1cdc, 0x1cdd, 30},
+	{0x1cde, 0x1cdf, 27},
+	{0x1ce0, 0x1ce0, 30},
+	{0x1ce1, 0x1ce1, 29},
+	{0x1ce2, 0x1ce8, 27},
+	{0x1ce9, 0x1ce9, 32},
+	{0x1cea, 0x1cea, 29},
+	{0x1ceb, 0x1cec, 27},
+	{0x1ced, 0x1ced, 29},
+	{0x1cee, 0x1cf1, 27},
+	{0x1cf2, 0x1cf2, 33},
+	{0x1cf3, 0x1cf3, 28},
+	{0x1cf4, 0x1cf4, 34},
+	{0x1cf5, 0x1cf6, 29},
+	{0x1cf7, 0x1cf7, 35},
+	{0x1cf8, 0x1cf9, 28},
+	{0x1cfa, 0x1cfa, 36},
+	{0x1dc0, 0x1dc1, 0},
+	{0x1df8, 0x1df8, 37},
+	{0x1dfa, 0x1dfa, 38},
+	{0x202f, 0x202f, 39},
+	{0x20f0, 0x20f0, 40},
+	{0x2e43, 0x2e43, 3},
+	{0x3001, 0x3002, 41},
+	{0x3003, 0x3003, 42},
+	{0x3006, 0x3006, 43},
+	{0x3008, 0x3011, 41},
+	{0x3013, 0x3013, 42},
+	{0x3014, 0x301b, 41},
+	{0x301c, 0x301f, 42},
+	{0x302a, 0x302d, 44},
+	{0x3030, 0x3030, 42},
+	{0x3031, 0x3035, 45},
+	{0x3037, 0x3037, 42},
+	{0x303c, 0x303d, 46},
+	{0x303e, 0x303f, 43},
+	{0x3099, 0x309c, 45},
+	{0x30a0, 0x30a0, 45},
+	{0x30fb, 0x30fb, 41},
+	{0x30fc, 0x30fc, 45},
+	{0x3190, 0x319f, 43},
+	{0x31c0, 0x31e3, 43},
+	{0x3220, 0x3247, 43},
+	{0x3280, 0x32b0, 43},
+	{0x32c0, 0x32cb, 43},
+	{0x32ff, 0x32ff, 43},
+	{0x3358, 0x3370, 43},
+	{0x337b, 0x337f, 43},
+	{0x33e0, 0x33fe, 43},
+	{0xa66f, 0xa66f, 3},
+	{0xa700, 0xa707, 47},
+	{0xa830, 0xa832, 48},
+	{0xa833, 0xa835, 49},
+	{0xa836, 0xa839, 50},
+	{0xa8f1, 0xa8f1, 29},
+	{0xa8f3, 0xa8f3, 51},
+	{0xa92e, 0xa92e, 52},
+	{0xa9cf, 0xa9cf, 53},
+	{0xfd3e, 0xfd3f, 54},
+	{0xfdf2, 0xfdf2, 55},
+	{0xfdfd, 0xfdfd, 55},
+	{0xfe45, 0xfe46, 42},
+	{0xff61, 0xff65, 41},
+	{0xff70, 0xff70, 45},
+	{0xff9e, 0xff9f, 45},
+	{0x10100, 0x10101, 56},
+	{0x10102, 0x10102, 57},
+	{0x10107, 0x10133, 58},
+	{0x10137, 0x1013f, 57},
+	{0x102e0, 0x102fb, 59},
+	{0x10af2, 0x10af2, 60},
+	{0x11301, 0x11301, 20},
+	{0x11303, 0x11303, 20},
+	{0x1133b, 0x1133c, 20},
+	{0x11fd0, 0x11fd1, 20},
+	{0x11fd3, 0x11fd3, 20},
+	{0x1bca0, 0x1bca3, 61},
+	{0x1d360, 0x1d371, 43},
+	{0x1f250, 0x1f251, 43},
+}
@@ -1,6 +1,8 @@
 package language
 
 import (
+	"reflect"
+	"strings"
 	"testing"
 	"unicode"
 
@@ -14,14 +16,23 @@ func TestParseScript(t *testing.T) {
 		wantErr bool
 	}{
 		{"xxx", 0, true},
+		{"", 0, true},
 		{"bamu", Bamum, false},
-		{"bamu_to_long", Bamum, false},
+		{"bamu_to_long", 0, true},
 		{"cyrl", Cyrillic, false},
 		{"samr", Samaritan, false},
 		{"ARAB", Arabic, false},
 		{"arab", Arabic, false},
 		{"Arab", Arabic, false},
 		{"Samr", Samaritan, false},
+		{"lATN", Latin, false},
+		// numeric codes
+		{"215", Latin, false},
+		{"160", Arabic, false},
+		{"080", Anatolian_Hieroglyphs, false},
+		{"998", Common, false},
+		{"001", 0, true},
+		{"21a", 0, true},
 	}
 	for _, tt := range tests {
 		got, err := ParseScript(tt.args)
@@ -307,6 +318,14 @@ func TestFastLookup(t *testing.T) {
 	}
 }
 
+func TestLatin1Lookup(t *testing.T) {
+	for r := rune(0); r < 256; r++ {
+		tu.Assert(t, LookupScript(r) == lookupScriptRanges(r))
+		tu.Assert(t, LookupScript(r) == lookupScriptNaive(r))
+	}
+	tu.Assert(t, LookupScript(-1) == Unknown)
+}
+
 func BenchmarkLookupScript(b *testing.B) {
 	b.Run("naive", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
@@ -432,3 +451,141 @@ const scriptsSample = `
 	我能吞下玻璃而不傷身體。 
 	Saya boleh makan kaca dan ia tidak mencederakan saya. 
 `
+
+func TestRegisterScriptRange(t *testing.T) {
+	defer extraScriptRanges.Store(nil) // do not pollute the other tests
+
+	const unassigned = 0xEFFFF
+	tu.Assert(t, LookupScript(unassigned) == Unknown)
+
+	RegisterScriptRange(unassigned-10, unassigned, Han)
+	tu.Assert(t, LookupScript(unassigned) == Han)
+	tu.Assert(t, LookupScript(unassigned-10) == Han)
+	tu.Assert(t, LookupScript(unassigned+1) == Unknown)
+
+	// overrides the generated table, including the Latin-1 fast path
+	RegisterScriptRange('a', 'c', Greek)
+	tu.Assert(t, LookupScript('b') == Greek)
+	tu.Assert(t, LookupScript('d') == Latin)
+
+	// the last registration wins
+	RegisterScriptRange(unassigned-5, unassigned-2, Arabic)
+	tu.Assert(t, LookupScript(unassigned-10) == Han)
+	tu.Assert(t, LookupScript(unassigned-5) == Arabic)
+	tu.Assert(t, LookupScript(unassigned-2) == Arabic)
+	tu.Assert(t, LookupScript(unassigned-1) == Han)
+	RegisterScriptRange('b', 'z', Cyrillic)
+	tu.Assert(t, LookupScript('a') == Greek)
+	tu.Assert(t, LookupScript('c') == Cyrillic)
+	tu.Assert(t, LookupScript('z') == Cyrillic)
+
+	// the overlay stays sorted and non overlapping
+	ranges := *extraScriptRanges.Load()
+	tu.Assert(t, len(ranges) == 5)
+	for i := 1; i < len(ranges); i++ {
+		tu.Assert(t, ranges[i-1].End < ranges[i].Start)
+	}
+}
+
+func TestScriptNumericCode(t *testing.T) {
+	tu.Assert(t, Latin.NumericCode() == 215)
+	tu.Assert(t, Arabic.NumericCode() == 160)
+	tu.Assert(t, Unknown.NumericCode() == 999)
+	tu.Assert(t, Script(0).NumericCode() == 0)
+
+	for name, s := range scriptToTag {
+		code := s.NumericCode()
+		tu.AssertC(t, code > 0, name)
+		back, ok := ScriptFromNumericCode(code)
+		tu.AssertC(t, ok && back == s, name)
+	}
+
+	s, ok := ScriptFromNumericCode(220)
+	tu.Assert(t, ok && s == Cyrillic)
+	for _, code := range []int{-1, 0, 1, 1000} {
+		_, ok = ScriptFromNumericCode(code)
+		tu.Assert(t, !ok)
+	}
+}
+
+func TestScriptName(t *testing.T) {
+	tu.Assert(t, Latin.Name() == "Latin")
+	tu.Assert(t, Arabic.Name() == "Arabic")
+	tu.Assert(t, Old_Italic.Name() == "Old Italic")
+	tu.Assert(t, Unknown.Name() == "Unknown")
+	tu.Assert(t, Common.Name() == "Common")
+	tu.Assert(t, Script(0x58787878).Name() == "Xxxx")
+
+	for name, s := range scriptToTag {
+		tu.AssertC(t, s.Name() == strings.ReplaceAll(name, "_", " "), name)
+	}
+}
+
+func TestScriptIsRTL(t *testing.T) {
+	for _, s := range []Script{Arabic, Hebrew, Syriac, Thaana, Nko, Adlam} {
+		tu.AssertC(t, s.IsRTL(), s.Name())
+	}
+	for _, s := range []Script{Latin, Han, Cyrillic, Common, Inherited, Unknown, Old_Italic} {
+		tu.AssertC(t, !s.IsRTL(), s.Name())
+	}
+}
+
+func TestScriptExtent(t *testing.T) {
+	for _, script := range []Script{Latin, Arabic, Han, Common, Inherited, Hebrew} {
+		ranges := ScriptExtent(script)
+		tu.Assert(t, len(ranges) != 0)
+		for i, rg := range ranges {
+			tu.Assert(t, rg.Script == script && rg.Start <= rg.End)
+			// sorted and merged
+			if i > 0 {
+				tu.Assert(t, ranges[i-1].End+1 < rg.Start)
+			}
+			tu.Assert(t, LookupScript(rg.Start) == script && LookupScript(rg.End) == script)
+			tu.Assert(t, LookupScript(rg.Start-1) != script && LookupScript(rg.End+1) != script)
+		}
+	}
+	tu.Assert(t, len(ScriptExtent(Unknown)) == 0)
+
+	latin := ScriptExtent(Latin)
+	tu.Assert(t, latin[0] == ScriptRange{'A', 'Z', Latin})
+	tu.Assert(t, latin[1] == ScriptRange{'a', 'z', Latin})
+
+	// registered ranges are taken into account
+	defer extraScriptRanges.Store(nil) // do not pollute the other tests
+	RegisterScriptRange('b', 'c', Greek)
+	RegisterScriptRange('0', '1', Latin)
+	latin = ScriptExtent(Latin)
+	tu.Assert(t, latin[0] == ScriptRange{'0', '1', Latin})
+	tu.Assert(t, latin[1] == ScriptRange{'A', 'Z', Latin})
+	tu.Assert(t, latin[2] == ScriptRange{'a', 'a', Latin})
+	tu.Assert(t, latin[3] == ScriptRange{'d', 'z', Latin})
+}
+
+func TestLookupScriptExtensions(t *testing.T) {
+	for _, test := range []struct {
+		r       rune
+		scripts []Script
+	}{
+		{'a', []Script{Latin}},
+		{' ', []Script{Common}},
+		{0x0301, []Script{Inherited}},
+		{'،', []Script{Arabic, Syriac, Thaana, Nko, Hanifi_Rohingya, Yezidi}},
+		{'٣', []Script{Arabic, Thaana, Yezidi}},
+		{0x064B, []Script{Arabic, Syriac}},
+		{'、', []Script{Bopomofo, Han, Hangul, Hiragana, Katakana, Yi}},
+		{'ー', []Script{Hiragana, Katakana}},
+	} {
+		tu.AssertC(t, reflect.DeepEqual(LookupScriptExtensions(test.r), test.scripts), string(test.r))
+	}
+
+	// the ranges are sorted and the extensions include several scripts,
+	// or a script different from the Script property
+	for i, rg := range scriptExtensionRanges {
+		tu.Assert(t, rg.Start <= rg.End)
+		if i > 0 {
+			tu.Assert(t, scriptExtensionRanges[i-1].End < rg.Start)
+		}
+		set := scriptExtensionSets[rg.set]
+		tu.Assert(t, len(set) > 1 || set[0] != LookupScript(rg.Start))
+	}
+}
@@ -0,0 +1,214 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package segmenter
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"sync"
+
+	ucd "github.com/go-text/typesetting/internal/unicodedata"
+)
+
+// Dictionary is a word list used to find word and line boundaries
+// in scripts which do not use spaces between words, like Thai, Lao or Khmer.
+//
+// Such scripts are identified by the South East Asian (SA) Line Break class,
+// for which the rule based algorithms of UAX #14 and UAX #29 do not
+// provide meaningful boundaries.
+//
+// A Dictionary is safe for concurrent use once built.
+type Dictionary struct {
+	root dictNode
+}
+
+// dictNode is a node in the trie storing the dictionary words
+type dictNode struct {
+	children map[rune]*dictNode
+	isWord   bool // true if the path from the root to this node is a word
+}
+
+// NewDictionary builds a dictionary from the given words.
+// Empty strings are ignored.
+func NewDictionary(words []string) *Dictionary {
+	var dict Dictionary
+	for _, word := range words {
+		dict.add(word)
+	}
+	return &dict
+}
+
+// LoadDictionary reads a dictionary from [src], which must
+// contain one word per line. Surrounding white spaces are trimmed,
+// and empty lines or lines starting with '#' are ignored.
+func LoadDictionary(src io.Reader) (*Dictionary, error) {
+	var dict Dictionary
+	sc := bufio.NewScanner(src)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		dict.add(line)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return &dict, nil
+}
+
+var (
+	defaultDictionary     *Dictionary
+	defaultDictionaryOnce sync.Once
+)
+
+// DefaultDictionary returns a minimal bundled dictionary,
+// containing common Thai, Lao and Khmer words.
+//
+// It is intended as a reasonable fallback : applications requiring
+// accurate segmentation should provide a complete word list
+// with [LoadDictionary] or [NewDictionary].
+func DefaultDictionary() *Dictionary {
+	defaultDictionaryOnce.Do(func() {
+		defaultDictionary = NewDictionary(defaultWords)
+	})
+	return defaultDictionary
+}
+
+// Contains returns true if [word] is in the dictionary.
+func (dict *Dictionary) Contains(word string) bool {
+	node := &dict.root
+	for _, r := range word {
+		node = node.children[r]
+		if node == nil {
+			return false
+		}
+	}
+	return node != &dict.root && node.isWord
+}
+
+func (dict *Dictionary) add(word string) {
+	if word == "" {
+		return
+	}
+	node := &dict.root
+	for _, r := range word {
+		child := node.children[r]
+		if child == nil {
+			if node.children == nil {
+				node.children = make(map[rune]*dictNode)
+			}
+			child = new(dictNode)
+			node.children[r] = child
+		}
+		node = child
+	}
+	node.isWord = true
+}
+
+// matches calls [fn] with the length of each word of the dictionary
+// which is a prefix of [text], in increasing order.
+func (dict *Dictionary) matches(text []rune, fn func(length int)) {
+	node := &dict.root
+	for i, r := range text {
+		node = node.children[r]
+		if node == nil {
+			return
+		}
+		if node.isWord {
+			fn(i + 1)
+		}
+	}
+}
+
+// dictSegmentation stores the best segmentation found
+// for a prefix of a run of text
+type dictSegmentation struct {
+	unknown  int // number of runes not covered by a dictionary word
+	segments int // number of segments (words and unknown sequences)
+	prev     int // start of the last segment
+}
+
+func (s dictSegmentation) isBetter(other dictSegmentation) bool {
+	if s.unknown != other.unknown {
+		return s.unknown < other.unknown
+	}
+	return s.segments < other.segments
+}
+
+// applyDictionary updates the line and word boundaries inside
+// each sequence of runes with the SA Line Break class, using
+// the segmentation provided by [dict].
+func applyDictionary(text []rune, attributes []breakAttr, dict *Dictionary) {
+	for start := 0; start < len(text); {
+		if ucd.LookupLineBreak(text[start]) != ucd.LB_SA {
+			start++
+			continue
+		}
+		end := start + 1
+		for end < len(text) && ucd.LookupLineBreak(text[end]) == ucd.LB_SA {
+			end++
+		}
+		dict.segmentRun(text[start:end], attributes[start:end+1])
+		start = end
+	}
+}
+
+// segmentRun finds the segmentation of [run] minimizing the number of runes
+// not covered by the dictionary, and then the number of words.
+// Unknown runes are grouped in segments spanning up to the next position
+// where a dictionary word starts.
+// [attributes] has length len(run)+1 : its first and last elements are not modified.
+func (dict *Dictionary) segmentRun(run []rune, attributes []breakAttr) {
+	const none = -1
+	best := make([]dictSegmentation, len(run)+1)
+	for i := range best {
+		best[i].prev = none
+	}
+	best[0] = dictSegmentation{prev: 0}
+
+	// startsWord[i] is true if a dictionary word starts at i
+	startsWord := make([]bool, len(run))
+	for i := range run {
+		dict.matches(run[i:], func(length int) { startsWord[i] = true })
+	}
+
+	update := func(from, to, unknown int) {
+		// only break at grapheme boundaries
+		if to != len(run) && attributes[to]&graphemeBoundary == 0 {
+			return
+		}
+		candidate := dictSegmentation{
+			unknown:  best[from].unknown + unknown,
+			segments: best[from].segments + 1,
+			prev:     from,
+		}
+		if best[to].prev == none || candidate.isBetter(best[to]) {
+			best[to] = candidate
+		}
+	}
+
+	for i := range run {
+		if best[i].prev == none { // unreachable position
+			continue
+		}
+		dict.matches(run[i:], func(length int) { update(i, i+length, 0) })
+
+		// unknown sequence, up to the next word start
+		j := i + 1
+		for j < len(run) && !(startsWord[j] && attributes[j]&graphemeBoundary != 0) {
+			j++
+		}
+		update(i, j, j-i)
+	}
+
+	// clear the boundaries inside the run ...
+	for i := 1; i < len(run); i++ {
+		attributes[i] &^= lineBoundary | wordBoundary
+	}
+	// ... and set the ones given by the segmentation
+	for i := best[len(run)].prev; i > 0; i = best[i].prev {
+		attributes[i] |= lineBoundary | wordBoundary
+	}
+}
@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package segmenter
+
+// defaultWords is a minimal list of common words,
+// used by [DefaultDictionary].
+var defaultWords = []string{
+	// Thai
+	"การ", "กับ", "กิน", "ข้าว", "ของ", "ความ", "ครับ", "คน", "ค่ะ", "คุณ",
+	"จะ", "ฉัน", "ดี", "ได้", "ที่", "น้ำ", "นี้", "บ้าน", "ประเทศ", "ผม",
+	"พูด", "ภาษา", "มา", "มาก", "มี", "เมือง", "ไม่", "รัก", "เรา", "เรียน",
+	"โรงเรียน", "โลก", "วัน", "ว่า", "สวัสดี", "หนังสือ", "ให้", "ใน", "ไทย", "ไป",
+	"เป็น", "แล้ว", "และ", "อ่าน", "เขา", "เขียน",
+
+	// Lao
+	"ກິນ", "ຂອງ", "ຂ້ອຍ", "ຄົນ", "ຈະ", "ເຈົ້າ", "ດີ", "ໄດ້", "ນ້ຳ", "ບໍ່",
+	"ປະເທດ", "ພາສາ", "ມາ", "ມີ", "ລາວ", "ສະບາຍດີ", "ຫຼາຍ", "ເຂົ້າ", "ເປັນ", "ເຮົາ",
+	"ແລະ", "ໃນ", "ໄປ",
+
+	// Khmer
+	"កម្ពុជា", "ក្នុង", "ខ្ញុំ", "ខ្មែរ", "ជា", "ញ៉ាំ", "ណាស់", "ទឹក", "ទៅ", "នឹង",
+	"និង", "បាន", "បាយ", "ប្រទេស", "ភាសា", "មក", "មនុស្ស", "មាន", "មិន", "យើង",
+	"របស់", "ល្អ", "សួស្តី", "អ្នក",
+}
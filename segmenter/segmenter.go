@@ -228,8 +228,19 @@ type Segmenter struct {
 	// 	text : 			[b, 		u, 	l, 	l]
 	// 	attributes :	[<start> b, b u, u l, l l, l <end>]
 	attributes []breakAttr
+
+	// optional, used to segment South East Asian scripts
+	dictionary *Dictionary
 }
 
+// SetDictionary sets the dictionary used to find line and word boundaries
+// in text using scripts without spaces between words, like Thai, Lao or Khmer.
+// It must be called before [Init] (or its variants) to be taken into account.
+// Passing nil disables dictionary based breaking, which is the default.
+//
+// See [DefaultDictionary] for a minimal bundled word list.
+func (seg *Segmenter) SetDictionary(dict *Dictionary) { seg.dictionary = dict }
+
 // Init resets the segmenter storage with the given input,
 // and computes the attributes required to segment the text.
 //
@@ -302,6 +313,9 @@ func (seg *Segmenter) InitWithBytes(paragraph []byte) (err error) {
 func (seg *Segmenter) initAttributes() {
 	seg.attributes = append(seg.attributes[:0], make([]breakAttr, len(seg.text)+1)...)
 	computeBreakAttributes(seg.text, seg.attributes)
+	if seg.dictionary != nil {
+		applyDictionary(seg.text, seg.attributes, seg.dictionary)
+	}
 }
 
 // attributeIterator is an helper type used to
@@ -460,7 +474,9 @@ func (gr *WordIterator) Next() bool {
 	}
 
 	if gr.inWord { // we are have reached the END of a word
-		gr.inWord = false
+		// words may be adjacent, without separator (for instance
+		// when using a dictionary): check if a new word starts
+		gr.inWord = gr.pos < len(gr.src.text) && ucd.IsWord(gr.src.text[gr.pos])
 		return true
 	}
 
@@ -492,3 +508,89 @@ func (sg *Segmenter) WordIterator() *WordIterator {
 	}
 	return &WordIterator{attributeIterator: attributeIterator{src: sg, flag: wordBoundary}, inWord: inWord}
 }
+
+// Break is a line break opportunity, as returned by [LineBreaks].
+type Break struct {
+	// Index is the position of the break in the input rune slice :
+	// the break occurs right before the rune at Index.
+	Index int
+	// IsMandatory is true if breaking is mandatory (for instance after
+	// a newline), and false if breaking is only allowed.
+	IsMandatory bool
+}
+
+// LineBreaks returns the line break opportunities of [text], in increasing order,
+// as defined by the Unicode Line Breaking Algorithm.
+//
+// The start of the text is never a break opportunity, whereas the end of a non
+// empty text is always a mandatory one.
+//
+// LineBreaks is a convenience function, which does not use any [Dictionary].
+// Use a [Segmenter] and its [LineIterator] to process several paragraphs
+// without allocating.
+//
+// See also https://unicode.org/reports/tr14
+func LineBreaks(text []rune) []Break {
+	attributes := make([]breakAttr, len(text)+1)
+	computeBreakAttributes(text, attributes)
+	var out []Break
+	for i := 1; i <= len(text); i++ {
+		if attr := attributes[i]; attr&lineBoundary != 0 {
+			out = append(out, Break{Index: i, IsMandatory: attr&mandatoryLineBoundary != 0})
+		}
+	}
+	return out
+}
+
+// GraphemeClusters returns the boundaries of the extended grapheme clusters of [text],
+// in increasing order, as defined by the Unicode Text Segmentation algorithm.
+//
+// The start and the end of a non empty text are always boundaries,
+// so that the clusters are given by text[b[i]:b[i+1]].
+// This is typically used to move a cursor or to delete a whole user-perceived
+// character (like an emoji ZWJ sequence).
+//
+// Use a [Segmenter] and its [GraphemeIterator] to process several paragraphs
+// without allocating.
+//
+// See also https://unicode.org/reports/tr29/#Grapheme_Cluster_Boundaries
+func GraphemeClusters(text []rune) []int {
+	if len(text) == 0 {
+		return nil
+	}
+	attributes := make([]breakAttr, len(text)+1)
+	computeBreakAttributes(text, attributes)
+	out := []int{0}
+	for i := 1; i <= len(text); i++ {
+		if attributes[i]&graphemeBoundary != 0 {
+			out = append(out, i)
+		}
+	}
+	return out
+}
+
+// WordBoundaries returns the word boundaries of [text], in increasing order,
+// as defined by the Unicode Text Segmentation algorithm.
+//
+// As for [GraphemeClusters], the start and the end of a non empty text
+// are always boundaries. Note that the segments delimited by the boundaries
+// include spaces and punctuation : see [WordIterator] to only select words.
+// Ideographs are not grouped, since no [Dictionary] is used.
+//
+// This is typically used to select a word on a double click.
+//
+// See also https://unicode.org/reports/tr29/#Word_Boundaries
+func WordBoundaries(text []rune) []int {
+	if len(text) == 0 {
+		return nil
+	}
+	attributes := make([]breakAttr, len(text)+1)
+	computeBreakAttributes(text, attributes)
+	out := []int{0}
+	for i := 1; i <= len(text); i++ {
+		if attributes[i]&wordBoundary != 0 {
+			out = append(out, i)
+		}
+	}
+	return out
+}
@@ -142,6 +142,40 @@ func parseUCDTestLine(t *testing.T, line string) (string, []int) {
 	return string(runes), breaks
 }
 
+func TestLineBreaks(t *testing.T) {
+	b, err := os.ReadFile("test/LineBreakTest.txt")
+	tu.AssertNoErr(t, err)
+	for i, line := range strings.Split(string(b), "\n") {
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, expected := parseUCDTestLine(t, line)
+		var got []int
+		for _, br := range LineBreaks([]rune(s)) {
+			got = append(got, br.Index)
+		}
+		if !reflect.DeepEqual(expected, got) {
+			t.Fatalf("line %d [%s]: expected breaks %v, got %v", i+1, hex([]rune(s)), expected, got)
+		}
+	}
+
+	for _, test := range []struct {
+		text     string
+		expected []Break
+	}{
+		{"", nil},
+		{"a b", []Break{{2, false}, {3, true}}},
+		{"a\nb", []Break{{2, true}, {3, true}}},
+		{"a\u00A0b c", []Break{{4, false}, {5, true}}},         // no break at NBSP
+		{"well-known", []Break{{5, false}, {10, true}}},        // break after hyphen
+		{"\u6F22\u5B57\u3002", []Break{{1, false}, {3, true}}}, // between ideographs, not before full stop
+		{"$ 12.50 (2)", []Break{{2, false}, {8, false}, {11, true}}},
+		{"-12,5%", []Break{{6, true}}}, // numeric sequence
+	} {
+		tu.AssertC(t, reflect.DeepEqual(LineBreaks([]rune(test.text)), test.expected), fmt.Sprint(test.text, LineBreaks([]rune(test.text))))
+	}
+}
+
 func TestGraphemeBreakUnicodeReference(t *testing.T) {
 	file := "test/GraphemeBreakTest.txt"
 	b, err := os.ReadFile(file)
@@ -178,6 +212,38 @@ func TestGraphemeBreakUnicodeReference(t *testing.T) {
 	}
 }
 
+func TestGraphemeClusters(t *testing.T) {
+	b, err := os.ReadFile("test/GraphemeBreakTest.txt")
+	tu.AssertNoErr(t, err)
+	for i, line := range strings.Split(string(b), "\n") {
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, expected := parseUCDTestLine(t, line)
+		got := GraphemeClusters([]rune(s))
+		if !reflect.DeepEqual(append([]int{0}, expected...), got) {
+			t.Fatalf("line %d [%s]: expected %v, got %v", i+1, hex([]rune(s)), expected, got)
+		}
+	}
+
+	for _, test := range []struct {
+		text     string
+		expected []int
+	}{
+		{"", nil},
+		{"abc", []int{0, 1, 2, 3}},
+		{"\U0001F1EB\U0001F1F7\U0001F1E9\U0001F1EA", []int{0, 2, 4}},    // flags FR, DE
+		{"\U0001F1EB\U0001F1F7\U0001F1E9", []int{0, 2, 3}},              // unpaired regional indicator
+		{"\U0001F44D\U0001F3FD!", []int{0, 2, 3}},                       // skin tone modifier
+		{"\U0001F468\u200D\U0001F469\u200D\U0001F467x", []int{0, 5, 6}}, // family ZWJ sequence
+		{"e\u0301\u0915\u093F", []int{0, 2, 4}},                         // combining and spacing marks
+		{"\u0D4E\u0D15", []int{0, 2}},                                   // prepend
+		{"\r\n\n", []int{0, 2, 3}},
+	} {
+		tu.AssertC(t, reflect.DeepEqual(GraphemeClusters([]rune(test.text)), test.expected), fmt.Sprint(test.text, GraphemeClusters([]rune(test.text))))
+	}
+}
+
 func TestWordBreakUnicodeReference(t *testing.T) {
 	file := "test/WordBreakTest.txt"
 	b, err := os.ReadFile(file)
@@ -214,6 +280,37 @@ func TestWordBreakUnicodeReference(t *testing.T) {
 	}
 }
 
+func TestWordBoundaries(t *testing.T) {
+	b, err := os.ReadFile("test/WordBreakTest.txt")
+	tu.AssertNoErr(t, err)
+	for i, line := range strings.Split(string(b), "\n") {
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		s, expected := parseUCDTestLine(t, line)
+		got := WordBoundaries([]rune(s))
+		if !reflect.DeepEqual(append([]int{0}, expected...), got) {
+			t.Fatalf("line %d [%s]: expected %v, got %v", i+1, hex([]rune(s)), expected, got)
+		}
+	}
+
+	for _, test := range []struct {
+		text     string
+		expected []int
+	}{
+		{"", nil},
+		{"hello world", []int{0, 5, 6, 11}},
+		{"don't", []int{0, 5}},           // MidNumLet
+		{"3.14 a.b", []int{0, 4, 5, 8}},  // MidNum, MidNumLet
+		{"a: b", []int{0, 1, 2, 3, 4}},   // no MidLetter without letter after
+		{"\u6F22\u5B57", []int{0, 1, 2}}, // ideographs are single words
+		{"\u30AB\u30BF", []int{0, 2}},    // Katakana
+		{"e\u0301x", []int{0, 3}},        // combining mark
+	} {
+		tu.AssertC(t, reflect.DeepEqual(WordBoundaries([]rune(test.text)), test.expected), fmt.Sprint(test.text, WordBoundaries([]rune(test.text))))
+	}
+}
+
 func TestWordSegmenter(t *testing.T) {
 	var seg Segmenter
 	for mode := initMode(0); mode < initModeMax; mode++ {
@@ -352,3 +449,50 @@ func BenchmarkSegmentUnicodeReference(b *testing.B) {
 		}
 	}
 }
+
+func collectLines(s *Segmenter) []string {
+	iter := s.LineIterator()
+	var out []string
+	for iter.Next() {
+		out = append(out, string(iter.Line().Text))
+	}
+	return out
+}
+
+func TestDictionary(t *testing.T) {
+	dict, err := LoadDictionary(strings.NewReader("# comment\nabc\n\n  ภาษา \nไทย\n"))
+	tu.AssertNoErr(t, err)
+	tu.Assert(t, dict.Contains("abc") && dict.Contains("ภาษา") && dict.Contains("ไทย"))
+	tu.Assert(t, !dict.Contains("ab") && !dict.Contains("") && !dict.Contains("# comment"))
+
+	var seg Segmenter
+	// without dictionary, a Thai sentence is not broken
+	seg.Init([]rune("สวัสดีครับ"))
+	tu.Assert(t, reflect.DeepEqual(collectLines(&seg), []string{"สวัสดีครับ"}))
+
+	seg.SetDictionary(DefaultDictionary())
+	for _, test := range []struct {
+		input string
+		lines []string
+		words []string
+	}{
+		{"สวัสดีครับ", []string{"สวัสดี", "ครับ"}, []string{"สวัสดี", "ครับ"}},
+		{"ผมรักภาษาไทย", []string{"ผม", "รัก", "ภาษา", "ไทย"}, []string{"ผม", "รัก", "ภาษา", "ไทย"}},
+		{"ฉันไปโรงเรียน", []string{"ฉัน", "ไป", "โรงเรียน"}, []string{"ฉัน", "ไป", "โรงเรียน"}},
+		// unknown sequences are kept together
+		{"กขคภาษา", []string{"กขค", "ภาษา"}, []string{"กขค", "ภาษา"}},
+		// mixed with Latin text
+		{"Hi สวัสดีครับ!", []string{"Hi ", "สวัสดี", "ครับ!"}, []string{"Hi", "สวัสดี", "ครับ"}},
+		{"ພາສາລາວ", []string{"ພາສາ", "ລາວ"}, []string{"ພາສາ", "ລາວ"}},
+		{"ភាសាខ្មែរ", []string{"ភាសា", "ខ្មែរ"}, []string{"ភាសា", "ខ្មែរ"}},
+	} {
+		seg.Init([]rune(test.input))
+		tu.AssertC(t, reflect.DeepEqual(collectLines(&seg), test.lines), test.input)
+		tu.AssertC(t, reflect.DeepEqual(collectWords(&seg), test.words), test.input)
+	}
+
+	// the dictionary is not used for other scripts
+	seg.SetDictionary(dict)
+	seg.Init([]rune("abcabc"))
+	tu.Assert(t, reflect.DeepEqual(collectLines(&seg), []string{"abcabc"}))
+}
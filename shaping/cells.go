@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"unicode"
+
+	"github.com/go-text/typesetting/font"
+	"golang.org/x/text/width"
+)
+
+// CellWidth returns the number of terminal cells (columns) occupied by [r],
+// that is 0 for combining marks, zero width and control characters, 2 for
+// wide characters (like CJK ideographs), and 1 otherwise.
+//
+// The width is first deduced from the Unicode East Asian Width property :
+// Wide and Fullwidth characters always use 2 cells.
+// If [face] is not nil, it is used to refine the width of the other characters :
+// a glyph whose advance is at least 1.5 times the advance of the digit zero
+// (typically an Ambiguous character rendered wide by a CJK font) uses 2 cells.
+func CellWidth(r rune, face *font.Face) int {
+	if isZeroWidth(r) {
+		return 0
+	}
+
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return 2
+	}
+
+	if face == nil {
+		return 1
+	}
+	gid, ok := face.NominalGlyph(r)
+	if !ok {
+		return 1
+	}
+	ref, ok := face.NominalGlyph('0')
+	if !ok {
+		return 1
+	}
+	if cell := face.HorizontalAdvance(ref); cell > 0 && face.HorizontalAdvance(gid) >= 1.5*cell {
+		return 2
+	}
+	return 1
+}
+
+// isZeroWidth returns true for runes which do not occupy
+// any cell : control characters, combining marks,
+// Hangul medial vowels and final consonants, and format characters
+// (except the soft hyphen)
+func isZeroWidth(r rune) bool {
+	switch {
+	case r == 0x00AD: // soft hyphen is usually displayed
+		return false
+	case 0x1160 <= r && r <= 0x11FF: // Hangul Jamo combine with the previous leading consonant
+		return true
+	}
+	return unicode.In(r, unicode.Cc, unicode.Mn, unicode.Me, unicode.Cf)
+}
@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"testing"
+
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+func TestCellWidth(t *testing.T) {
+	mono := loadOpentypeFont(t, "../font/testdata/UbuntuMono-R.ttf")
+	amiri := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+
+	for _, test := range []struct {
+		r        rune
+		expected int
+	}{
+		{'a', 1},
+		{' ', 1},
+		{'é', 1},
+		{'­', 1}, // soft hyphen
+		{'\n', 0},
+		{'́', 0}, // combining acute accent
+		{'‍', 0}, // ZWJ
+		{'ᅡ', 0}, // Hangul medial vowel
+		{'中', 2},
+		{'あ', 2},
+		{'한', 2},
+		{'Ａ', 2}, // fullwidth A
+		{'ｱ', 1}, // halfwidth katakana
+		{'😀', 2},
+	} {
+		tu.AssertC(t, CellWidth(test.r, nil) == test.expected, string(test.r))
+		tu.AssertC(t, CellWidth(test.r, mono) == test.expected, string(test.r))
+	}
+
+	// use the face advance for non wide characters
+	tu.Assert(t, CellWidth('﷽', nil) == 1)
+	tu.Assert(t, CellWidth('﷽', amiri) == 2)
+	// em dash is Ambiguous : narrow in a monospace font, but wide in Amiri
+	tu.Assert(t, CellWidth('—', mono) == 1)
+	tu.Assert(t, CellWidth('—', amiri) == 2)
+}
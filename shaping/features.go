@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	ot "github.com/go-text/typesetting/font/opentype"
+)
+
+// Tags of commonly used OpenType features, which may be used
+// to build [FontFeature] values.
+//
+// See https://learn.microsoft.com/en-us/typography/opentype/spec/featurelist
+const (
+	LigaturesTag              ot.Tag = 0x6c696761 // liga
+	ContextualLigaturesTag    ot.Tag = 0x636c6967 // clig
+	DiscretionaryLigaturesTag ot.Tag = 0x646c6967 // dlig
+	HistoricalLigaturesTag    ot.Tag = 0x686c6967 // hlig
+	ContextualAlternatesTag   ot.Tag = 0x63616c74 // calt
+	StylisticAlternatesTag    ot.Tag = 0x73616c74 // salt
+	SwashTag                  ot.Tag = 0x73777368 // swsh
+	KerningTag                ot.Tag = 0x6b65726e // kern
+	SmallCapsTag              ot.Tag = 0x736d6370 // smcp
+	CapitalsToSmallCapsTag    ot.Tag = 0x63327363 // c2sc
+	PetiteCapsTag             ot.Tag = 0x70636170 // pcap
+	FractionsTag              ot.Tag = 0x66726163 // frac
+	AlternativeFractionsTag   ot.Tag = 0x61667263 // afrc
+	OldstyleFiguresTag        ot.Tag = 0x6f6e756d // onum
+	LiningFiguresTag          ot.Tag = 0x6c6e756d // lnum
+	TabularFiguresTag         ot.Tag = 0x746e756d // tnum
+	ProportionalFiguresTag    ot.Tag = 0x706e756d // pnum
+	SlashedZeroTag            ot.Tag = 0x7a65726f // zero
+	SuperscriptTag            ot.Tag = 0x73757073 // sups
+	SubscriptTag              ot.Tag = 0x73756273 // subs
+	OrdinalsTag               ot.Tag = 0x6f72646e // ordn
+)
+
+// ParseFontFeatures parses the value of a CSS font-feature-settings property,
+// like `"liga" 1, "smcp", "frac" off`, and returns the corresponding features, in order.
+//
+// Each setting is a quoted tag (with single or double quotes), optionally followed by
+// a non negative integer value or by the keywords 'on' (1) or 'off' (0) :
+// the default value is 1. The keyword 'normal' (or an empty string) returns no features.
+//
+// Contrary to CSS, which requires 4 characters, tags with 1 to 4 printable ASCII characters
+// are accepted, and padded with spaces.
+// An error reporting the offending setting is returned for invalid input.
+//
+// See https://developer.mozilla.org/en-US/docs/Web/CSS/font-feature-settings
+func ParseFontFeatures(s string) ([]FontFeature, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "normal") {
+		return nil, nil
+	}
+
+	var out []FontFeature
+	for _, setting := range strings.Split(s, ",") {
+		feature, err := parseFontFeature(strings.TrimSpace(setting))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, feature)
+	}
+	return out, nil
+}
+
+// parseFontFeature parses one setting of a font-feature-settings property
+func parseFontFeature(setting string) (FontFeature, error) {
+	tag, value, err := parseSettingTag(setting, "font feature")
+	if err != nil {
+		return FontFeature{}, err
+	}
+
+	out := FontFeature{Tag: tag, Value: 1}
+	switch value {
+	case "", "on":
+	case "off":
+		out.Value = 0
+	default:
+		v, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			return FontFeature{}, fmt.Errorf("invalid font feature setting %q: invalid value %q", setting, value)
+		}
+		out.Value = uint32(v)
+	}
+	return out, nil
+}
+
+// ParseVariations parses the value of a CSS font-variation-settings property,
+// like `"wght" 650, "wdth" 80.5`, and returns the corresponding variations, in order.
+//
+// Each setting is a quoted tag (with single or double quotes), followed by
+// a number, which is required. The keyword 'normal' (or an empty string) returns no variations.
+// Tags are accepted as in [ParseFontFeatures].
+// An error reporting the offending setting is returned for invalid input.
+//
+// See https://developer.mozilla.org/en-US/docs/Web/CSS/font-variation-settings
+func ParseVariations(s string) ([]FontVariation, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.EqualFold(s, "normal") {
+		return nil, nil
+	}
+
+	var out []FontVariation
+	for _, setting := range strings.Split(s, ",") {
+		setting = strings.TrimSpace(setting)
+		tag, value, err := parseSettingTag(setting, "font variation")
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseFloat(value, 32)
+		if err != nil || math.IsInf(v, 0) || math.IsNaN(v) {
+			return nil, fmt.Errorf("invalid font variation setting %q: invalid value %q", setting, value)
+		}
+		out = append(out, FontVariation{Tag: tag, Value: float32(v)})
+	}
+	return out, nil
+}
+
+// parseSettingTag parses the quoted tag starting [setting], returning
+// the tag and the (trimmed) remaining value. [kind] is used in error messages.
+func parseSettingTag(setting, kind string) (ot.Tag, string, error) {
+	if len(setting) < 2 || (setting[0] != '"' && setting[0] != '\'') {
+		return 0, "", fmt.Errorf("invalid %s setting %q: expected a quoted tag", kind, setting)
+	}
+	end := strings.IndexByte(setting[1:], setting[0])
+	if end == -1 {
+		return 0, "", fmt.Errorf("invalid %s setting %q: missing closing quote", kind, setting)
+	}
+	tagString, value := setting[1:end+1], strings.TrimSpace(setting[end+2:])
+
+	if len(tagString) == 0 || len(tagString) > 4 {
+		return 0, "", fmt.Errorf("invalid %s tag %q: expected 1 to 4 characters", kind, tagString)
+	}
+	var tag [4]byte
+	for i := range tag {
+		if i >= len(tagString) {
+			tag[i] = ' '
+			continue
+		}
+		if c := tagString[i]; c < 0x20 || c > 0x7E {
+			return 0, "", fmt.Errorf("invalid %s tag %q: expected printable ASCII characters", kind, tagString)
+		}
+		tag[i] = tagString[i]
+	}
+	return ot.NewTag(tag[0], tag[1], tag[2], tag[3]), value, nil
+}
+
+// PlannedFeatures returns the sorted list of the OpenType features selected
+// by the shaper for [in] : the ones enabled by default for its script and direction,
+// merged with the settings of [in.FontFeatures], restricted to the features
+// implemented by the font.
+//
+// A feature globally disabled by [in.FontFeatures] (that is, with zero Start and End)
+// is not reported, whereas a feature disabled on a part of the text still is.
+// See [AppliedFeatures] to only report the features actually changing the shaped glyphs.
+//
+// Features applied through AAT tables are not reported.
+func PlannedFeatures(in Input) []ot.Tag {
+	if in.Face == nil {
+		return nil
+	}
+
+	var shaper HarfbuzzShaper
+	shaper.shapeFull(in)
+	return shaper.buf.PlannedFeatures(shaper.font(in), shaper.features)
+}
+
+// AppliedFeatures returns the sorted list of the OpenType features which
+// actually affected the shaping of [in], including the ones automatically
+// enabled for the script (like 'init', 'medi', 'fina' for Arabic, or 'ccmp')
+// and the ones requested in [in.FontFeatures].
+//
+// A feature is reported if it is selected by the shaping plan and if disabling
+// it changes the shaped glyphs or their positions. Thus, this function shapes
+// the input several times and is intended for debugging and introspection,
+// not for performance sensitive code.
+//
+// Features applied through AAT tables are not reported.
+func AppliedFeatures(in Input) []ot.Tag {
+	if in.Face == nil {
+		return nil
+	}
+
+	var shaper HarfbuzzShaper
+	ref := shaper.shapeFull(in)
+	candidates := shaper.buf.PlannedFeatures(shaper.font(in), shaper.features)
+
+	userFeatures := in.FontFeatures[:len(in.FontFeatures):len(in.FontFeatures)] // force copy on append
+	var out []ot.Tag
+	for _, tag := range candidates {
+		in.FontFeatures = append(userFeatures, FontFeature{Tag: tag, Value: 0})
+		if !sameGlyphs(ref.Glyphs, shaper.shapeFull(in).Glyphs) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+// sameGlyphs compares the glyphs and their positions, ignoring
+// the masks, which depend on the features
+func sameGlyphs(g1, g2 []Glyph) bool {
+	if len(g1) != len(g2) {
+		return false
+	}
+	for i, g := range g1 {
+		o := g2[i]
+		if g.GlyphID != o.GlyphID || g.ClusterIndex != o.ClusterIndex ||
+			g.XAdvance != o.XAdvance || g.YAdvance != o.YAdvance ||
+			g.XOffset != o.XOffset || g.YOffset != o.YOffset {
+			return false
+		}
+	}
+	return true
+}
@@ -3,10 +3,12 @@
 package shaping
 
 import (
+	"unicode/utf8"
+
 	"github.com/go-text/typesetting/di"
 	"github.com/go-text/typesetting/font"
 	ot "github.com/go-text/typesetting/font/opentype"
-	"github.com/go-text/typesetting/harfbuzz"
+	ubidi "github.com/go-text/typesetting/internal/bidi"
 	ucd "github.com/go-text/typesetting/internal/unicodedata"
 	"github.com/go-text/typesetting/language"
 	"golang.org/x/image/math/fixed"
@@ -27,9 +29,28 @@ type Input struct {
 
 	// FontFeatures activates or deactivates optional features
 	// provided by the font.
-	// The settings are applied to the whole [Text].
+	// The settings are applied to the whole [Text], unless restricted
+	// by [FontFeature.Start] and [FontFeature.End]. When several settings
+	// for the same tag overlap, the last one wins.
+	//
+	// The settings are merged with the features enabled by default by the shaper
+	// (like 'ccmp', 'liga' or 'kern', and the script specific ones, like 'init'
+	// or 'rlig' for Arabic), so that a zero [FontFeature.Value] disables a
+	// default feature. Only the required feature of the font language system
+	// (rarely used) cannot be disabled. See [PlannedFeatures] to inspect the result.
+	//
+	// Note that the order of the settings does not change the order in which
+	// the features are applied, which is defined by the font lookups.
 	FontFeatures []FontFeature
 
+	// Variations selects an instance of a variable font, by setting
+	// the value of some of its axes, which take precedence over the
+	// coordinates of [Face]. Values are clamped to the range of the axis
+	// defined in the 'fvar' table, and tags not defined by the font are ignored.
+	// The variations are only used for shaping : to render the glyphs with
+	// the same outlines, the caller should apply them to the face with [font.Face.SetVariations].
+	Variations []FontVariation
+
 	// Size is the requested size of the font.
 	// More generally, it is a scale factor applied to the resulting metrics.
 	// For instance, given a device resolution (in dpi) and a point size (like 14), the `Size` to
@@ -41,6 +62,13 @@ type Input struct {
 
 	// Language is an identifier for the language of the text.
 	Language language.Language
+
+	// LetterSpacing, if not zero, is added between each cluster of the shaped
+	// run, so that ligatures and marks attached to their base are kept together.
+	// Half of the spacing is also added on the run boundaries,
+	// unless the run starts (resp. ends) [Text].
+	// See [Output.AddLetterSpacing] for more details.
+	LetterSpacing fixed.Int26_6
 }
 
 // FontFeature sets one font feature.
@@ -53,13 +81,36 @@ type Input struct {
 // An exemple of font feature is the replacement of fractions (like 1/2, 3/4)
 // by specialized glyphs, which would be activated by using
 //
-//	FontFeature{Tag: ot.MustNewTag("frac"), Value: 1}
+//	FontFeature{Tag: FractionsTag, Value: 1}
 //
+// Features may also be parsed from a CSS font-feature-settings value with [ParseFontFeatures].
 // See also https://learn.microsoft.com/en-us/typography/opentype/spec/featurelist
 // and https://developer.mozilla.org/en-US/docs/Web/CSS/CSS_fonts/OpenType_fonts_guide
+//
+// A feature may be restricted to a part of the text, for instance to enable 'smcp'
+// for only one word :
+//
+//	FontFeature{Tag: SmallCapsTag, Value: 1, Start: 4, End: 9}
 type FontFeature struct {
 	Tag   ot.Tag
 	Value uint32
+
+	// Start and End optionally restrict the feature to the runes Text[Start:End]
+	// of the [Input], using the same indices as [Input.RunStart] and [Input.RunEnd],
+	// so that the range is preserved when the input is split by a [Segmenter].
+	// A zero End applies the feature up to the end of the run : the zero values
+	// apply the feature to the whole run.
+	Start, End int
+}
+
+// FontVariation sets the value of one axis of a variable font,
+// identified by a 4 bytes [Tag], like 'wght' or 'wdth'.
+// The [Value] is expressed in design units, as in the CSS font-variation-settings property.
+//
+// Variations may also be parsed from a CSS value with [ParseVariations].
+type FontVariation struct {
+	Tag   ot.Tag
+	Value float32
 }
 
 // Fontmap provides a general mechanism to select
@@ -85,6 +136,32 @@ type FontmapScript interface {
 	SetScript(language.Script)
 }
 
+// FontmapContext is an optional interface for [Fontmap]s using the script and
+// the language of the run to select a face, for instance to prefer a Japanese
+// font for Han characters in Japanese text.
+//
+// When implemented, [SplitByFace] and [Segmenter.Split] call [ResolveFaceForScript]
+// instead of [Fontmap.ResolveFace], with the script and language of the run
+// (the ones resolved by the segmentation for [Segmenter.Split]).
+// [FontmapScript.SetScript] is still called if implemented.
+type FontmapContext interface {
+	Fontmap
+
+	// ResolveFaceForScript is the same as [Fontmap.ResolveFace], for a rune
+	// belonging to a run with the given script and language.
+	// [lang] may be empty if the language is not known.
+	ResolveFaceForScript(r rune, script language.Script, lang language.Language) *font.Face
+}
+
+// resolveFace calls [FontmapContext.ResolveFaceForScript] if supported by [faces],
+// or [Fontmap.ResolveFace] otherwise.
+func resolveFace(faces Fontmap, r rune, script language.Script, lang language.Language) *font.Face {
+	if withContext, ok := faces.(FontmapContext); ok {
+		return withContext.ResolveFaceForScript(r, script, lang)
+	}
+	return faces.ResolveFace(r)
+}
+
 var _ Fontmap = fixedFontmap(nil)
 
 type fixedFontmap []*font.Face
@@ -116,13 +193,99 @@ func SplitByFontGlyphs(input Input, availableFaces []*font.Face) []Input {
 // characteristics as 'input', expected for the `Face` which is set to
 // the return value of the [Fontmap.ResolveFace] call.
 // The 'Face' field of 'input' is ignored: only 'availableFaces' is used to select the face.
+//
+// Grapheme clusters are not split : combining marks and the pictographs joined
+// by a ZWJ in emoji sequences use the face of the preceding rune, whatever their coverage.
 func SplitByFace(input Input, availableFaces Fontmap) []Input {
-	return splitByFace(input, availableFaces, nil, true)
+	return splitByFace(input, availableFaces, ucd.IgnoreFaceChange, nil, true)
+}
+
+// SplitByFaceWithCoverage is the same as [SplitByFace], but also reports the runes
+// which are not supported by the face selected for their run, which happens when
+// no face supports them and [Fontmap.ResolveFace] returns a fallback face.
+// Layout engines may use it to draw explicit .notdef boxes, or to log missing glyphs.
+//
+// uncovered[i] contains the indices (into input.Text) of the runes of runs[i]
+// with no glyph in runs[i].Face, in increasing order; it is nil if the face covers the whole run.
+// The runes ignored during face selection (like spaces and default ignorables) are never reported.
+// The returned runs are the same as the ones returned by [SplitByFace].
+func SplitByFaceWithCoverage(input Input, availableFaces Fontmap) (runs []Input, uncovered [][]int) {
+	runs = splitByFace(input, availableFaces, ucd.IgnoreFaceChange, nil, true)
+	uncovered = make([][]int, len(runs))
+	for i, run := range runs {
+		for j := run.RunStart; j < run.RunEnd; j++ {
+			r := run.Text[j]
+			if ucd.IgnoreFaceChange(r) {
+				continue
+			}
+			if run.Face != nil {
+				if _, ok := run.Face.NominalGlyph(r); ok {
+					continue
+				}
+			}
+			uncovered[i] = append(uncovered[i], j)
+		}
+	}
+	return runs, uncovered
+}
+
+// BaseDirectionMode selects how the base (paragraph) direction
+// is resolved during bidi segmentation.
+type BaseDirectionMode uint8
+
+const (
+	// BaseDirectionDefault uses the direction of the input as a hint :
+	// for right-to-left input, the paragraph is right-to-left; otherwise
+	// it is resolved as for [FirstStrongIsolate].
+	BaseDirectionDefault BaseDirectionMode = iota
+	// FirstStrong uses the direction of the first strong character,
+	// including the ones inside isolates.
+	// If no strong character is found, the direction of the input is used.
+	FirstStrong
+	// FirstStrongIsolate uses the direction of the first strong character,
+	// skipping the characters between an isolate initiator and its matching PDI,
+	// as described in the rules P2 and P3 of the Unicode Bidirectional Algorithm.
+	// If no strong character is found, the direction of the input is used.
+	FirstStrongIsolate
+	// ForceLTR always uses a left-to-right paragraph direction.
+	ForceLTR
+	// ForceRTL always uses a right-to-left paragraph direction.
+	ForceRTL
+)
+
+// SegmenterOptions configures the behavior of a [Segmenter].
+// The zero value is a valid default.
+type SegmenterOptions struct {
+	// BaseDirectionMode selects how the paragraph direction is resolved.
+	BaseDirectionMode BaseDirectionMode
+
+	// ReuseFacesPerScript, if true, makes [Segmenter.Split] remember the first face
+	// selected for each script, and reuse it for the following runes of the same script,
+	// as long as it supports them, instead of resolving them independently.
+	// This avoids using different fallback faces for the same script
+	// in a paragraph.
+	ReuseFacesPerScript bool
+
+	// IgnoreFaceChange, if not nil, replaces [IgnoreFaceChange] to select the runes
+	// which do not trigger a change of face : they are added to the current run,
+	// without calling [Fontmap.ResolveFace].
+	//
+	// For instance, to select the face of emoji sequences using the emoji presentation
+	// selector U+FE0F (VS16), which is ignored by default, use
+	//
+	//	func(r rune) bool { return r != 0xFE0F && shaping.IgnoreFaceChange(r) }
+	//
+	// so that the [Fontmap] may return a color emoji face for it.
+	IgnoreFaceChange func(r rune) bool
 }
 
 // Segmenter holds a state used to split input
 // according to three caracteristics : text direction (bidi),
 // script, and face.
+//
+// The runes of right-to-left runs are not mirrored (see [language.Mirror]) :
+// [HarfbuzzShaper] already does it, and mirroring them beforehand would bypass
+// the 'rtlm' feature of the fonts providing their own mirrored glyphs.
 type Segmenter struct {
 	// pools of inputs, used to reduce allocations,
 	// which are alternatively swapped between each step of the segmentation
@@ -131,10 +294,40 @@ type Segmenter struct {
 	// used to handle Common script
 	delimStack []delimEntry
 
-	// buffer used for bidi segmentation
-	bidiParagraph bidi.Paragraph
+	// the embedding levels and the bidi classes resolved by the
+	// last call to [splitByBidi], for each rune of its input
+	bidiLevels  []int8
+	bidiClasses []bidi.Class
+
+	options SegmenterOptions
+
+	// used when [SegmenterOptions.ReuseFacesPerScript] is true
+	scriptFaces scriptFontmap
+
+	// cache of the faces resolved during the last call to [Split]
+	cachedFaces cachedFontmap
+
+	// the runes split by the last call to [Split]
+	text []rune
+
+	// the language of the input of the last call to [Split],
+	// and the buffer used by [ReSplit]
+	language language.Language
+	kept     []Input
+
+	// buffers used by [SplitUTF8]
+	utf8Runes   []rune
+	byteOffsets []int
+	byteRanges  []Range
+
+	// buffers used by [SplitVisual]
+	visual       []Input
+	visualLevels []int8
 }
 
+// SetOptions configures the [Segmenter] for the subsequent calls to [Split].
+func (seg *Segmenter) SetOptions(opts SegmenterOptions) { seg.options = opts }
+
 type delimEntry struct {
 	index  int             // in the [pairedDelims] list
 	script language.Script // resolved from the context
@@ -156,21 +349,143 @@ type delimEntry struct {
 //   - Language
 //   - Face
 //
+// Face is always resolved, even for empty or whitespace-only input, so that
+// the returned runs are valid shaping inputs.
+//
 // [text.Direction] is used during bidi ordering, and should refer to the general
 // context [text] is used in (typically the user system preference for GUI apps.)
+// How it is combined with the content of [text] to resolve the paragraph direction
+// is controlled by [SegmenterOptions.BaseDirectionMode].
+//
+// Since the bidi algorithm is defined per paragraph, the text is first split after each
+// paragraph separator (as defined by the rule P1 of the Unicode Bidirectional Algorithm,
+// like U+2029 or a newline), and the direction of each paragraph is resolved independently,
+// as defined by [SegmenterOptions.BaseDirectionMode]. The returned runs never cross
+// a paragraph boundary.
+//
+// The explicit bidi formatting characters are supported : embeddings (U+202A LRE, U+202B RLE),
+// overrides (U+202D LRO, U+202E RLO), isolates (U+2066 LRI, U+2067 RLI, U+2068 FSI)
+// and their terminators (U+202C PDF, U+2069 PDI), as well as the marks U+200E LRM, U+200F RLM
+// and U+061C ALM. Note that an embedding only changes the direction of the
+// neutral runes it contains : use an override to force the direction of a span.
+// These characters are kept in the returned runs, so that RunStart and RunEnd
+// still refer to [text] : they do not trigger a change of face, and are
+// removed by the shaper (as default ignorable runes), so that they are not rendered.
+//
+// Runes with Common script (like spaces and punctuation) are attached to the
+// surrounding run. However, a rune whose Script_Extensions property does not include
+// the script of the current run (like the Devanagari danda after Latin text) starts the run of the
+// following text, if it is written in one of its scripts (see [language.LookupScriptExtensions]).
 //
 // For vertical text, if its orientation is set, is copied as it is; otherwise, the
 // orientation is resolved using the Unicode recommendations (see https://www.unicode.org/reports/tr50/).
 //
 // When possible, the language are resolved to match the current script. For instance,
 // (language: 'fr', script: 'arabic') is resolved to language: 'arabic'.
+// A language consistent with the script of a run is preserved (for instance 'sr' for
+// Cyrillic runs), and an empty language is resolved from the script of each run.
+//
+// Each returned run has its own copy of [text.FontFeatures], which may be safely modified.
+//
+// Interactive editors should use [ReSplit] to update the segmentation after an edit,
+// which only segments again the region affected by the edit.
 //
 // The returned sliced is owned by the [Segmenter] and is only valid until
 // the next call to [Split].
 func (seg *Segmenter) Split(text Input, faces Fontmap) []Input {
 	seg.reset()
+	if text.RunStart < text.RunEnd {
+		seg.text = text.Text[text.RunStart:text.RunEnd]
+	}
+	seg.language = text.Language
 	seg.splitByBidi(text) // fills output
 
+	seg.splitAfterBidi(text, faces)
+
+	return seg.output
+}
+
+// SplitUTF8 is the same as [Split], for a paragraph stored as UTF-8 [text],
+// with direction [dir]. Invalid UTF-8 bytes are decoded as [utf8.RuneError].
+//
+// The returned runs are the ones returned by [Split] for the decoded text :
+// their Text field holds the decoded runes, and RunStart and RunEnd are indices into it,
+// so that they may be directly shaped. Their location in [text] is returned
+// in [byteRanges], as byte offsets : the run runs[i] spans
+// text[byteRanges[i].Offset:byteRanges[i].Offset+byteRanges[i].Count].
+// These offsets are computed in one pass over [text].
+//
+// Only the fields set by [Split] are filled : the other ones, like Size,
+// should be set by the caller.
+//
+// The returned slices are owned by the [Segmenter] and are only valid until
+// the next call to [Split] or [SplitUTF8].
+func (seg *Segmenter) SplitUTF8(text []byte, faces Fontmap, dir di.Direction) (runs []Input, byteRanges []Range) {
+	// decode the text, recording the byte offset of each rune
+	seg.utf8Runes, seg.byteOffsets = seg.utf8Runes[:0], seg.byteOffsets[:0]
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRune(text[i:])
+		seg.utf8Runes = append(seg.utf8Runes, r)
+		seg.byteOffsets = append(seg.byteOffsets, i)
+		i += size
+	}
+	seg.byteOffsets = append(seg.byteOffsets, len(text))
+
+	runs = seg.Split(Input{Text: seg.utf8Runes, RunEnd: len(seg.utf8Runes), Direction: dir}, faces)
+
+	seg.byteRanges = seg.byteRanges[:0]
+	for _, run := range runs {
+		start, end := seg.byteOffsets[run.RunStart], seg.byteOffsets[run.RunEnd]
+		seg.byteRanges = append(seg.byteRanges, Range{Offset: start, Count: end - start})
+	}
+	return runs, seg.byteRanges
+}
+
+// SplitStream segments a (possibly very large) [text], with direction [dir],
+// calling [yield] for each run, in logical order, instead of returning them.
+// It stops as soon as [yield] returns false.
+//
+// The text is split into paragraphs (after each paragraph separator, as defined
+// by the rule P1 of the Unicode Bidirectional Algorithm, a CRLF sequence being
+// kept in one paragraph), which are segmented one at a time by [Split] : the memory used by
+// the [Segmenter] is thus bounded by the size of the largest paragraph, and not by the
+// size of [text]. As for [Split], the runs never cross paragraph boundaries, and the
+// direction of each paragraph is resolved independently.
+//
+// The runs passed to [yield] reference [text] : their Text field is [text] and
+// RunStart and RunEnd are indices into it. As for [Split], only the fields set by
+// the segmentation are filled : the other ones, like Size, should be set by the caller.
+func (seg *Segmenter) SplitStream(text []rune, faces Fontmap, dir di.Direction, yield func(Input) bool) {
+	for start := 0; start < len(text); {
+		end := paragraphEnd(text, start)
+		runs := seg.Split(Input{Text: text, RunStart: start, RunEnd: end, Direction: dir}, faces)
+		for _, run := range runs {
+			if !yield(run) {
+				return
+			}
+		}
+		start = end
+	}
+}
+
+// paragraphEnd returns the end of the paragraph starting at [start],
+// including its separator.
+func paragraphEnd(text []rune, start int) int {
+	for i := start; i < len(text); i++ {
+		if props, _ := bidi.LookupRune(text[i]); props.Class() != bidi.B {
+			continue
+		}
+		if text[i] == '\r' && i+1 < len(text) && text[i+1] == '\n' {
+			i++
+		}
+		return i + 1
+	}
+	return len(text)
+}
+
+// splitAfterBidi performs the segmentation steps following [splitByBidi],
+// whose result is expected in [seg.output].
+func (seg *Segmenter) splitAfterBidi(text Input, faces Fontmap) {
 	seg.input, seg.output = seg.output, seg.input // output is empty
 	seg.splitByScript()
 
@@ -187,10 +502,42 @@ func (seg *Segmenter) Split(text Input, faces Fontmap) []Input {
 	seg.output = seg.output[:0]
 	seg.splitByFace(faces)
 
-	return seg.output
+	seg.copyFeatures(text.FontFeatures)
 }
 
+// copyFeatures gives each output run its own copy of [features],
+// so that modifying the features of one run does not affect the others.
+// One allocation is used for all the runs : the capacity of each slice
+// is restricted so that appending to one run also preserves the others.
+func (seg *Segmenter) copyFeatures(features []FontFeature) {
+	if len(features) == 0 {
+		return
+	}
+	L := len(features)
+	buffer := make([]FontFeature, L*len(seg.output))
+	for i := range seg.output {
+		dst := buffer[i*L : (i+1)*L : (i+1)*L]
+		copy(dst, features)
+		seg.output[i].FontFeatures = dst
+	}
+}
+
+// BidiClasses returns the bidi class of each rune split by the
+// last call to [Split], that is, the element i is the class of text.Text[text.RunStart+i].
+// It may be used to understand the direction resolved for numbers or neutral runes
+// (like currency symbols), which depends on their class and their context.
+//
+// The classes are the ones resolved by the weak and neutral rules of
+// the Unicode Bidirectional Algorithm, so that numbers are either [bidi.EN] or [bidi.AN],
+// and neutral runes are either [bidi.L] or [bidi.R]. The explicit formatting
+// characters keep the class defined in the Unicode database.
+//
+// The returned slice is owned by the [Segmenter] and is only valid until
+// the next call to [Split].
+func (seg *Segmenter) BidiClasses() []bidi.Class { return seg.bidiClasses }
+
 func (seg *Segmenter) reset() {
+	seg.text = nil
 	// zero the slices to avoid 'memory leak' on pointer slice fields
 	for i := range seg.input {
 		seg.input[i].Text = nil
@@ -200,50 +547,169 @@ func (seg *Segmenter) reset() {
 		seg.output[i].Text = nil
 		seg.output[i].FontFeatures = nil
 	}
+	for i := range seg.visual {
+		seg.visual[i].Text = nil
+		seg.visual[i].FontFeatures = nil
+	}
 	seg.input = seg.input[:0]
 	seg.output = seg.output[:0]
+	seg.visual = seg.visual[:0]
 
-	// bidiParagraph is reset when using SetString
+	seg.bidiLevels = seg.bidiLevels[:0]
+	seg.bidiClasses = seg.bidiClasses[:0]
 
 	seg.delimStack = seg.delimStack[:0]
+	seg.cachedFaces.reset(nil)
 }
 
+// splitByParagraph splits [text] into paragraphs, as defined by the rule P1 of the
+// Unicode Bidirectional Algorithm (a CRLF sequence is kept in one paragraph).
+// Each paragraph includes its separator.
+func (seg *Segmenter) splitByParagraph(text Input) {
+	if text.RunStart >= text.RunEnd {
+		seg.output = append(seg.output, text)
+		return
+	}
+	for start := text.RunStart; start < text.RunEnd; {
+		paragraph := text
+		paragraph.RunStart = start
+		paragraph.RunEnd = paragraphEnd(text.Text[:text.RunEnd], start)
+		seg.output = append(seg.output, paragraph)
+		start = paragraph.RunEnd
+	}
+}
+
+// splitByBidi splits [text] into paragraphs, and then each paragraph into
+// runs of the same direction, the paragraph direction being resolved independently.
 func (seg *Segmenter) splitByBidi(text Input) {
+	seg.bidiLevels = seg.bidiLevels[:0]
+	seg.bidiClasses = seg.bidiClasses[:0]
 	// split vertical text like horizontal one
 	if text.RunStart >= text.RunEnd {
 		seg.output = append(seg.output, text)
 		return
 	}
-	def := bidi.LeftToRight
-	if text.Direction.Progression() == di.TowardTopLeft {
-		def = bidi.RightToLeft
+
+	seg.splitByParagraph(text)
+	seg.input, seg.output = seg.output, seg.input
+	seg.output = seg.output[:0]
+
+	for _, paragraph := range seg.input {
+		runes := paragraph.Text[paragraph.RunStart:paragraph.RunEnd]
+		isRTL := seg.baseDirectionIsRTL(runes, paragraph.Direction)
+
+		// A right-to-left direction forces the paragraph level to 1. Otherwise, the level
+		// is resolved from the text (rules P2 and P3) for BaseDirectionDefault only,
+		// and forced to 0 for the other modes, which already resolved [isRTL].
+		seg.splitByBidiWith(paragraph, isRTL, seg.options.BaseDirectionMode != BaseDirectionDefault)
 	}
-	seg.bidiParagraph.SetString(string(text.Text[text.RunStart:text.RunEnd]), bidi.DefaultDirection(def))
-	out, err := seg.bidiParagraph.Order()
-	if err != nil || out.NumRuns() == 0 {
+	seg.input = seg.input[:0]
+}
+
+// baseDirectionIsRTL applies [SegmenterOptions.BaseDirectionMode].
+// For [BaseDirectionDefault] and a left-to-right [dir], the direction
+// is actually resolved later by the bidi algorithm.
+func (seg *Segmenter) baseDirectionIsRTL(runes []rune, dir di.Direction) bool {
+	isInputRTL := dir.Progression() == di.TowardTopLeft
+	switch seg.options.BaseDirectionMode {
+	case FirstStrong:
+		return firstStrongIsRTL(runes, false, isInputRTL)
+	case FirstStrongIsolate:
+		return firstStrongIsRTL(runes, true, isInputRTL)
+	case ForceLTR:
+		return false
+	case ForceRTL:
+		return true
+	default:
+		return isInputRTL
+	}
+}
+
+// splitByBidiWith splits [text], which must not be empty, with the given
+// paragraph direction. If [isRTL] is false, [forceLTR] disables the
+// P2 and P3 rules.
+// The embedding levels and the resolved classes of the runes are appended
+// to [seg.bidiLevels] and [seg.bidiClasses].
+func (seg *Segmenter) splitByBidiWith(text Input, isRTL, forceLTR bool) {
+	runes := text.Text[text.RunStart:text.RunEnd]
+	paragraphLevel := ubidi.ImplicitLevel
+	if isRTL {
+		paragraphLevel = 1
+	} else if forceLTR {
+		paragraphLevel = 0
+	}
+	levels, classes, _ := ubidi.Resolve(runes, paragraphLevel)
+	seg.bidiLevels = append(seg.bidiLevels, levels...)
+	seg.bidiClasses = append(seg.bidiClasses, classes...)
+
+	// the paragraph separator is kept in the last run
+	end := len(levels)
+	for end > 0 {
+		if props, _ := bidi.LookupRune(runes[end-1]); props.Class() != bidi.B {
+			break
+		}
+		end--
+	}
+	if end == 0 {
 		seg.output = append(seg.output, text)
 		return
 	}
 
-	input := text // start a rune 0 of the run
-	for i := 0; i < out.NumRuns(); i++ {
-		currentInput := input
-		run := out.Run(i)
-		dir := run.Direction()
-		_, endRune := run.Pos()
-		endRune += text.RunStart // shift by the input run position
-		currentInput.RunEnd = endRune + 1
+	// split at direction changes
+	for start := 0; start < end; {
+		isOdd := levels[start]&1 == 1
+		runEnd := start + 1
+		for runEnd < end && (levels[runEnd]&1 == 1) == isOdd {
+			runEnd++
+		}
+		if runEnd == end {
+			runEnd = len(levels)
+		}
 
+		currentInput := text
+		currentInput.RunStart, currentInput.RunEnd = text.RunStart+start, text.RunStart+runEnd
 		// override the direction
-		if dir == bidi.RightToLeft {
+		if isOdd {
 			currentInput.Direction.SetProgression(di.TowardTopLeft)
 		} else {
 			currentInput.Direction.SetProgression(di.FromTopLeft)
 		}
-
 		seg.output = append(seg.output, currentInput)
-		input.RunStart = currentInput.RunEnd
+		start = runEnd
+	}
+}
+
+// firstStrongIsRTL returns true if the first strong character of [text]
+// is right-to-left, following the rules P2 and P3 of the Unicode Bidirectional Algorithm.
+// If [skipIsolates] is false, the characters inside isolates are also considered.
+// Only the first paragraph is inspected, and [def] is returned if no strong
+// character is found.
+func firstStrongIsRTL(text []rune, skipIsolates bool, def bool) bool {
+	isolateDepth := 0
+	for _, r := range text {
+		props, _ := bidi.LookupRune(r)
+		switch props.Class() {
+		case bidi.L:
+			if isolateDepth == 0 {
+				return false
+			}
+		case bidi.R, bidi.AL:
+			if isolateDepth == 0 {
+				return true
+			}
+		case bidi.LRI, bidi.RLI, bidi.FSI:
+			if skipIsolates {
+				isolateDepth++
+			}
+		case bidi.PDI:
+			if isolateDepth > 0 {
+				isolateDepth--
+			}
+		case bidi.B:
+			return def
+		}
 	}
+	return def
 }
 
 // lookupDelimIndex binary searches in the list of the paired delimiters,
@@ -308,6 +774,14 @@ func (seg *Segmenter) splitByScript() {
 				}
 			}
 
+			// a Common rune used with a limited set of scripts, not including
+			// the current one, starts the run of the following text if possible
+			if rScript == language.Common && delimIndex < 0 && currentInput.Script != language.Common {
+				if next := extensionScript(r, currentInput.Script, input.Text[i+1:input.RunEnd]); next != 0 {
+					rScript = next
+				}
+			}
+
 			// check if we have a 'real' change of script, or not
 			if !rScript.Strong() || rScript == currentInput.Script {
 				// no change
@@ -337,6 +811,37 @@ func (seg *Segmenter) splitByScript() {
 	}
 }
 
+// extensionScript returns the script of the first strong rune of [next], if the
+// Script_Extensions property of [r] includes it, but not [current].
+// Otherwise, it returns 0.
+func extensionScript(r rune, current language.Script, next []rune) language.Script {
+	extensions := language.LookupScriptExtensions(r)
+	if len(extensions) == 1 && !extensions[0].Strong() {
+		return 0 // no specific extensions
+	}
+	if containsScript(extensions, current) {
+		return 0
+	}
+	for _, n := range next {
+		if s := language.LookupScript(n); s.Strong() {
+			if containsScript(extensions, s) {
+				return s
+			}
+			return 0
+		}
+	}
+	return 0
+}
+
+func containsScript(scripts []language.Script, s language.Script) bool {
+	for _, script := range scripts {
+		if script == s {
+			return true
+		}
+	}
+	return false
+}
+
 // assume splitByScript has been called and enforce sane languages
 func (seg *Segmenter) enforceLanguages() {
 	initialLang := seg.output[0].Language
@@ -392,17 +897,111 @@ func (seg *Segmenter) splitByVertOrientation() {
 	}
 }
 
+// scriptFontmap wraps a [Fontmap], reusing the first face
+// selected for each script
+type scriptFontmap struct {
+	Fontmap
+	script language.Script
+	lang   language.Language
+	faces  map[language.Script]*font.Face
+}
+
+func (sf *scriptFontmap) reset(faces Fontmap) {
+	sf.Fontmap = faces
+	if sf.faces == nil {
+		sf.faces = make(map[language.Script]*font.Face)
+	}
+	for script := range sf.faces {
+		delete(sf.faces, script)
+	}
+}
+
+func (sf *scriptFontmap) ResolveFace(r rune) *font.Face {
+	if face := sf.faces[sf.script]; face != nil {
+		if _, ok := face.NominalGlyph(r); ok {
+			return face
+		}
+	}
+	face := resolveFace(sf.Fontmap, r, sf.script, sf.lang)
+	if _, has := sf.faces[sf.script]; !has {
+		// only remember faces actually supporting the rune
+		if _, ok := face.NominalGlyph(r); ok {
+			sf.faces[sf.script] = face
+		}
+	}
+	return face
+}
+
+// maxCachedFaces is the maximum number of entries of [cachedFontmap] :
+// the cache is cleared when it is full
+const maxCachedFaces = 4096
+
+type cachedFaceKey struct {
+	r      rune
+	script language.Script
+	lang   language.Language
+}
+
+// cachedFontmap wraps a [Fontmap], caching the face resolved for
+// each rune, script and language, so that the runes repeated in the text
+// only trigger one call to the wrapped [Fontmap].
+//
+// The cache is only valid for one [Fontmap] : it must be
+// cleared with [reset] before using another one.
+type cachedFontmap struct {
+	Fontmap
+	script language.Script
+	lang   language.Language
+	faces  map[cachedFaceKey]*font.Face
+}
+
+func (cf *cachedFontmap) reset(faces Fontmap) {
+	cf.Fontmap = faces
+	for key := range cf.faces {
+		delete(cf.faces, key)
+	}
+}
+
+func (cf *cachedFontmap) ResolveFace(r rune) *font.Face {
+	key := cachedFaceKey{r, cf.script, cf.lang}
+	if face, ok := cf.faces[key]; ok {
+		return face
+	}
+	face := resolveFace(cf.Fontmap, r, cf.script, cf.lang)
+	if cf.faces == nil {
+		cf.faces = make(map[cachedFaceKey]*font.Face)
+	} else if len(cf.faces) >= maxCachedFaces {
+		cf.reset(cf.Fontmap)
+	}
+	cf.faces[key] = face
+	return face
+}
+
 // assume [splitByScript] has been called
 func (seg *Segmenter) splitByFace(faces Fontmap) {
 	withScript, hasScriptSupport := faces.(FontmapScript)
+	// the cache is cleared in [reset], so that it only stores
+	// the faces resolved by the current [Fontmap]
+	seg.cachedFaces.Fontmap = faces
+	faces = &seg.cachedFaces
+	if seg.options.ReuseFacesPerScript {
+		seg.scriptFaces.reset(faces)
+		faces = &seg.scriptFaces
+	}
+	ignore := seg.options.IgnoreFaceChange
+	if ignore == nil {
+		ignore = ucd.IgnoreFaceChange
+	}
 	lastRunWithoutFace := -1
 	for i, input := range seg.input {
 		if hasScriptSupport {
 			withScript.SetScript(input.Script)
 		}
+		seg.scriptFaces.script, seg.scriptFaces.lang = input.Script, input.Language
+		seg.cachedFaces.script, seg.cachedFaces.lang = input.Script, input.Language
 		isLast := i == len(seg.input)-1
 		L := len(seg.output)
-		seg.output = splitByFace(input, faces, seg.output, isLast)
+		seg.output = splitByFace(input, faces, ignore, seg.output, isLast)
 		if face := seg.output[L].Face; face != nil {
 			if lastRunWithoutFace != -1 {
 				// apply it back
@@ -421,20 +1020,26 @@ func (seg *Segmenter) splitByFace(faces Fontmap) {
 	}
 }
 
-func splitByFace(input Input, availableFaces Fontmap, buffer []Input, isLast bool) []Input {
+func splitByFace(input Input, availableFaces Fontmap, ignore func(r rune) bool, buffer []Input, isLast bool) []Input {
 	currentInput := input
 	for i := input.RunStart; i < input.RunEnd; i++ {
 		r := input.Text[i]
 		// We can safely ignore characters if we have a face or if there is more text,
 		// but we must force the choice of a face if we still don't have one and we reach
 		// the final rune. Otherwise strings like all-whitespace are never assigned a face.
-		if ignoreFaceChange(r) && (currentInput.Face != nil || !isLast || i < input.RunEnd-1) {
+		if ignore(r) && (currentInput.Face != nil || !isLast || i < input.RunEnd-1) {
 			// add the rune to the current input
 			continue
 		}
 
+		// do not split grapheme clusters, which would break mark positioning :
+		// combining marks use the face of their base
+		if i > input.RunStart && currentInput.Face != nil && continuesGrapheme(input.Text, i) {
+			continue
+		}
+
 		// select the first font supporting r
-		selectedFace := availableFaces.ResolveFace(r)
+		selectedFace := resolveFace(availableFaces, r, input.Script, input.Language)
 
 		// now that we have a font, apply it back,
 		// but do NOT create a new run
@@ -462,41 +1067,49 @@ func splitByFace(input Input, availableFaces Fontmap, buffer []Input, isLast boo
 		currentInput.Face = selectedFace
 	}
 
+	// empty inputs have no rune to trigger the choice of a face :
+	// since shaping requires a valid face, use the one selected for a space
+	if currentInput.Face == nil && isLast && input.RunStart >= input.RunEnd {
+		currentInput.Face = resolveFace(availableFaces, ' ', input.Script, input.Language)
+	}
+
 	// close and add the last input
 	currentInput.RunEnd = input.RunEnd
 	buffer = append(buffer, currentInput)
 	return buffer
 }
 
-// ignoreFaceChange returns `true` is the given rune should not trigger
-// a change of font.
-//
-// We don't want space characters to affect font selection; in general,
-// it's always wrong to select a font just to render a space.
-// We assume that all fonts have the ASCII space, and for other space
-// characters if they don't, HarfBuzz will compatibility-decompose them
-// to ASCII space...
-//
-// We don't want to change fonts for line or paragraph separators.
-//
-// Finaly, we also don't change fonts for what Harfbuzz consider
-// as ignorable (however, some Control Format runes like 06DD are not ignored).
-//
-// The rationale is taken from pango : see bugs
-// https://bugzilla.gnome.org/show_bug.cgi?id=355987
-// https://bugzilla.gnome.org/show_bug.cgi?id=701652
-// https://bugzilla.gnome.org/show_bug.cgi?id=781123
-// for more details.
-func ignoreFaceChange(r rune) bool {
-	g := ucd.LookupGeneralCategory(r)
-	return g == ucd.Cc || // control
-		g == ucd.Cs || // surrogate
-		g == ucd.Zl || // line separator
-		g == ucd.Zp || // paragraph separator
-		(g == ucd.Zs && r != '\u1680') || // space separator != OGHAM SPACE MARK
-		harfbuzz.IsDefaultIgnorable(r)
+// continuesGrapheme returns true if there is no grapheme boundary between text[i-1]
+// and text[i], for the cases relevant to face selection : text[i] is a combining mark
+// (Grapheme_Cluster_Break Extend or SpacingMark, which includes the Mn, Mc and Me
+// categories) or is the pictographic continuation of an emoji ZWJ sequence (rule GB11).
+// Default ignorable runes, like variation selectors or ZWJ itself, are
+// left to [IgnoreFaceChange] (or its replacement).
+func continuesGrapheme(text []rune, i int) bool {
+	r := text[i]
+	if text[i-1] == '\u200D' && ucd.IsExtendedPictographic(r) {
+		// GB11 : Extended_Pictographic Extend* ZWJ × Extended_Pictographic
+		for j := i - 2; j >= 0; j-- {
+			if ucd.IsExtendedPictographic(text[j]) {
+				return true
+			}
+			if ucd.LookupGraphemeBreak(text[j])&ucd.GB_Extend == 0 {
+				break
+			}
+		}
+	}
+	if ucd.IsDefaultIgnorable(r) {
+		return false
+	}
+	return ucd.LookupGraphemeBreak(r)&(ucd.GB_Extend|ucd.GB_SpacingMark) != 0
 }
 
+// IgnoreFaceChange returns true if the given rune should not trigger
+// a change of face during segmentation. It is used by [SplitByFace] and [Segmenter.Split],
+// unless [SegmenterOptions.IgnoreFaceChange] is set.
+// See [SegmenterOptions.IgnoreFaceChange] for an example of customization.
+func IgnoreFaceChange(r rune) bool { return ucd.IgnoreFaceChange(r) }
+
 // enforceLang makes sure the returned language is compatible with
 // the given script, so that it maybe be usefull for Opentype shaping.
 //
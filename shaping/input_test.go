@@ -1,18 +1,24 @@
 package shaping
 
 import (
+	"fmt"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/go-text/typesetting/di"
 	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
 	"github.com/go-text/typesetting/language"
 	tu "github.com/go-text/typesetting/testutils"
+	"golang.org/x/image/math/fixed"
+	"golang.org/x/text/unicode/bidi"
 )
 
-func Test_ignoreFaceChange(t *testing.T) {
+func TestIgnoreFaceChange(t *testing.T) {
 	tests := []struct {
 		args rune
 		want bool
@@ -29,8 +35,8 @@ func Test_ignoreFaceChange(t *testing.T) {
 		{'\u200f', true},
 	}
 	for _, tt := range tests {
-		if got := ignoreFaceChange(tt.args); got != tt.want {
-			t.Errorf("ignoreFaceChange() = %v, want %v", got, tt.want)
+		if got := IgnoreFaceChange(tt.args); got != tt.want {
+			t.Errorf("IgnoreFaceChange() = %v, want %v", got, tt.want)
 		}
 	}
 }
@@ -271,6 +277,26 @@ func TestSplitByFontGlyphs(t *testing.T) {
 	}
 }
 
+func TestSplitByFaceWithCoverage(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	fm := fixedFontmap{latinFont, arabicFont}
+
+	text := []rune("abc 中文 سماء 漢 def")
+	input := Input{Text: text, RunEnd: len(text)}
+	runs, uncovered := SplitByFaceWithCoverage(input, fm)
+	tu.Assert(t, reflect.DeepEqual(runs, SplitByFace(input, fm)))
+	tu.Assert(t, len(runs) == 3 && len(uncovered) == 3)
+	// the Han runes fall back to the first face
+	tu.Assert(t, runs[0].Face == latinFont && reflect.DeepEqual(uncovered[0], []int{4, 5}))
+	tu.Assert(t, runs[1].Face == arabicFont && uncovered[1] == nil)
+	tu.Assert(t, runs[2].Face == latinFont && reflect.DeepEqual(uncovered[2], []int{12}))
+
+	// no face
+	runs, uncovered = SplitByFaceWithCoverage(input, perScriptFontmap{})
+	tu.Assert(t, len(runs) == 1 && len(uncovered[0]) == len(text)-4) // spaces are ignored
+}
+
 func TestSplitBidi(t *testing.T) {
 	ltrSource := []rune("The quick brown fox jumps over the lazy dog.")
 	rtlSource := []rune("الحب سماء لا تمط غير الأحلام")
@@ -366,6 +392,134 @@ func TestSplitBidi(t *testing.T) {
 	}
 }
 
+func TestSplitBidiBaseDirection(t *testing.T) {
+	type run struct {
+		start, end int
+		dir        di.Direction
+	}
+	for _, test := range []struct {
+		text             string
+		defaultDirection di.Direction
+		mode             BaseDirectionMode
+		expectedRuns     []run
+	}{
+		{"abc.", di.DirectionRTL, BaseDirectionDefault, []run{{0, 3, di.DirectionLTR}, {3, 4, di.DirectionRTL}}},
+		{"abc.", di.DirectionRTL, FirstStrong, []run{{0, 4, di.DirectionLTR}}},
+		{"abc.", di.DirectionRTL, FirstStrongIsolate, []run{{0, 4, di.DirectionLTR}}},
+		{"abc.", di.DirectionLTR, ForceRTL, []run{{0, 3, di.DirectionLTR}, {3, 4, di.DirectionRTL}}},
+		{"abc.", di.DirectionRTL, ForceLTR, []run{{0, 4, di.DirectionLTR}}},
+		{"שלום abc", di.DirectionLTR, BaseDirectionDefault, []run{{0, 5, di.DirectionRTL}, {5, 8, di.DirectionLTR}}},
+		{"שלום abc", di.DirectionLTR, ForceLTR, []run{{0, 4, di.DirectionRTL}, {4, 8, di.DirectionLTR}}},
+		{"שלום abc", di.DirectionRTL, ForceLTR, []run{{0, 4, di.DirectionRTL}, {4, 8, di.DirectionLTR}}},
+		// no strong character : use the input direction
+		{"123 !", di.DirectionRTL, FirstStrong, []run{{0, 3, di.DirectionLTR}, {3, 5, di.DirectionRTL}}},
+		{"123 !", di.DirectionLTR, FirstStrongIsolate, []run{{0, 5, di.DirectionLTR}}},
+		// isolates are only skipped with FirstStrongIsolate
+		{"\u2067שלום\u2069 abc!", di.DirectionLTR, FirstStrongIsolate, []run{{0, 1, di.DirectionLTR}, {1, 5, di.DirectionRTL}, {5, 11, di.DirectionLTR}}},
+		{"\u2067שלום\u2069 abc!", di.DirectionLTR, FirstStrong, []run{{0, 7, di.DirectionRTL}, {7, 10, di.DirectionLTR}, {10, 11, di.DirectionRTL}}},
+	} {
+		var seg Segmenter
+		seg.SetOptions(SegmenterOptions{BaseDirectionMode: test.mode})
+		text := []rune(test.text)
+		seg.splitByBidi(Input{Text: text, RunEnd: len(text), Direction: test.defaultDirection})
+		tu.AssertC(t, len(seg.output) == len(test.expectedRuns), test.text)
+		for i, run := range test.expectedRuns {
+			got := seg.output[i]
+			tu.AssertC(t, got.RunStart == run.start, test.text)
+			tu.AssertC(t, got.RunEnd == run.end, test.text)
+			tu.AssertC(t, got.Direction == run.dir, test.text)
+		}
+	}
+}
+
+func TestSplitParagraphs(t *testing.T) {
+	type run struct {
+		start, end int
+		dir        di.Direction
+	}
+	for _, test := range []struct {
+		text         string
+		mode         BaseDirectionMode
+		expectedRuns []run
+	}{
+		// the direction is resolved for each paragraph
+		{"abc def\u2029שלום abc", BaseDirectionDefault, []run{{0, 8, di.DirectionLTR}, {8, 13, di.DirectionRTL}, {13, 16, di.DirectionLTR}}},
+		{"שלום abc\nabc def", BaseDirectionDefault, []run{{0, 5, di.DirectionRTL}, {5, 9, di.DirectionLTR}, {9, 16, di.DirectionLTR}}},
+		{"abc def\r\nשלום!", FirstStrong, []run{{0, 9, di.DirectionLTR}, {9, 14, di.DirectionRTL}}},
+		{"abc def\u2029שלום abc", ForceLTR, []run{{0, 8, di.DirectionLTR}, {8, 12, di.DirectionRTL}, {12, 16, di.DirectionLTR}}},
+		// without separator, the whole text is one paragraph
+		{"abc def שלום abc", BaseDirectionDefault, []run{{0, 8, di.DirectionLTR}, {8, 12, di.DirectionRTL}, {12, 16, di.DirectionLTR}}},
+	} {
+		var seg Segmenter
+		seg.SetOptions(SegmenterOptions{BaseDirectionMode: test.mode})
+		text := []rune(test.text)
+		seg.splitByBidi(Input{Text: text, RunEnd: len(text), Direction: di.DirectionLTR})
+		tu.AssertC(t, len(seg.output) == len(test.expectedRuns), test.text)
+		for i, run := range test.expectedRuns {
+			got := seg.output[i]
+			tu.AssertC(t, got.RunStart == run.start && got.RunEnd == run.end, test.text)
+			tu.AssertC(t, got.Direction == run.dir, test.text)
+		}
+	}
+
+	// the paragraphs of a sub range
+	var seg Segmenter
+	text := []rune("ab\ncd\u2029ef\r\ngh")
+	seg.splitByParagraph(Input{Text: text, RunStart: 1, RunEnd: 10})
+	tu.Assert(t, len(seg.output) == 3)
+	tu.Assert(t, seg.output[0].RunStart == 1 && seg.output[0].RunEnd == 3)
+	tu.Assert(t, seg.output[1].RunStart == 3 && seg.output[1].RunEnd == 6)
+	tu.Assert(t, seg.output[2].RunStart == 6 && seg.output[2].RunEnd == 10)
+}
+
+func TestSplitExplicitBidi(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	fm := fixedFontmap{latinFont, arabicFont}
+
+	type run struct {
+		start, end int
+		dir        di.Direction
+	}
+	for _, test := range []struct {
+		text         string
+		expectedRuns []run
+	}{
+		// an embedding only changes the direction of neutral runes
+		{"abc \u202bdef ghi\u202c jkl", []run{{0, 17, di.DirectionLTR}}},
+		{"abc \u202b123 !\u202c jkl", []run{{0, 8, di.DirectionLTR}, {8, 11, di.DirectionRTL}, {11, 15, di.DirectionLTR}}},
+		// an override forces the direction
+		{"abc \u202edef\u202c jkl", []run{{0, 5, di.DirectionLTR}, {5, 9, di.DirectionRTL}, {9, 13, di.DirectionLTR}}},
+		{"سماء \u202dabc\u202c", []run{{0, 6, di.DirectionRTL}, {6, 9, di.DirectionLTR}, {9, 10, di.DirectionRTL}}},
+		// the isolate terminator is at the level of the surrounding text
+		{"abc \u2067def\u2069 jkl", []run{{0, 13, di.DirectionLTR}}},
+		{"abc \u2067123 !\u2069 jkl", []run{{0, 8, di.DirectionLTR}, {8, 10, di.DirectionRTL}, {10, 15, di.DirectionLTR}}},
+	} {
+		var seg Segmenter
+		text := []rune(test.text)
+		seg.splitByBidi(Input{Text: text, RunEnd: len(text), Direction: di.DirectionLTR})
+		tu.AssertC(t, len(seg.output) == len(test.expectedRuns), test.text)
+		for i, run := range test.expectedRuns {
+			got := seg.output[i]
+			tu.AssertC(t, got.RunStart == run.start && got.RunEnd == run.end, test.text)
+			tu.AssertC(t, got.Direction == run.dir, test.text)
+		}
+
+		// the formatting characters are not rendered
+		var shaper HarfbuzzShaper
+		runs := seg.Split(Input{Text: text, RunEnd: len(text), Direction: di.DirectionLTR, Size: fixed.I(16)}, fm)
+		for _, run := range runs {
+			out := shaper.Shape(run)
+			for _, g := range out.Glyphs {
+				tu.AssertC(t, g.GlyphID != 0, test.text)
+				if r := text[g.TextIndex()]; r >= 0x202A && r <= 0x202E || r >= 0x2066 && r <= 0x2069 {
+					tu.AssertC(t, g.XAdvance == 0, test.text)
+				}
+			}
+		}
+	}
+}
+
 func TestSplitScript(t *testing.T) {
 	ltrSource := []rune("The quick brown fox jumps over the lazy dog.")
 	rtlSource := []rune("الحب سماء لا تمط غير الأحلام")
@@ -374,7 +528,13 @@ func TestSplitScript(t *testing.T) {
 	commonSource := []rune("()[](][ gamma") // Common at first
 	commonSource2 := []rune("gamma (Γ) est une lettre")
 	commonSource3 := []rune("gamma (Γ [п] Γ) est une lettre") // nested delimiters
+	unmatchedClosing := []rune("Γάμμα) alpha")                // the closing parenthesis inherits the preceding script
+	withDigits := []rune("alpha 123 Γ 45 beta")               // digits join the surrounding run
 	withInherited := []rune("لمّا")
+	// runes with Script_Extensions start the run of a following script they are used with
+	withExtensions := []rune("abc । नमस्ते")
+	withExtensions2 := []rune("abc、かな")
+	withExtensions3 := []rune("abc । def")
 	type run struct {
 		start, end int
 		script     language.Script
@@ -413,9 +573,29 @@ func TestSplitScript(t *testing.T) {
 			{11, 14, language.Greek},
 			{14, 30, language.Latin},
 		}},
+		{unmatchedClosing, []run{
+			{0, 7, language.Greek},
+			{7, 12, language.Latin},
+		}},
+		{withDigits, []run{
+			{0, 10, language.Latin},
+			{10, 15, language.Greek},
+			{15, 19, language.Latin},
+		}},
 		{withInherited, []run{
 			{0, 4, language.Arabic},
 		}},
+		{withExtensions, []run{
+			{0, 4, language.Latin},
+			{4, 12, language.Devanagari},
+		}},
+		{withExtensions2, []run{
+			{0, 3, language.Latin},
+			{3, 6, language.Hiragana},
+		}},
+		{withExtensions3, []run{
+			{0, 9, language.Latin},
+		}},
 	} {
 		var seg Segmenter
 		seg.splitByBidi(Input{Text: test.text, RunEnd: len(test.text), Direction: di.DirectionLTR})
@@ -583,13 +763,33 @@ func TestSplit(t *testing.T) {
 		{
 			"",
 			di.DirectionLTR,
-			[]run{{0, 0, di.DirectionLTR, language.Common, "fr", nil}},
+			[]run{{0, 0, di.DirectionLTR, language.Common, "fr", latinFont}},
 		},
 		{ // make sure we dont crash
 			"\n",
 			di.DirectionLTR,
 			[]run{{0, 1, di.DirectionLTR, language.Common, "fr", latinFont}},
 		},
+		{ // whitespace only runs still get a face
+			"   ",
+			di.DirectionLTR,
+			[]run{{0, 3, di.DirectionLTR, language.Common, "fr", latinFont}},
+		},
+		{ // one run per paragraph
+			"\n\n",
+			di.DirectionLTR,
+			[]run{{0, 1, di.DirectionLTR, language.Common, "fr", latinFont}, {1, 2, di.DirectionLTR, language.Common, "fr", latinFont}},
+		},
+		{
+			" \t ",
+			di.DirectionLTR,
+			[]run{{0, 3, di.DirectionLTR, language.Common, "fr", latinFont}},
+		},
+		{
+			" \t ",
+			di.DirectionRTL,
+			[]run{{0, 3, di.DirectionRTL, language.Common, "fr", latinFont}},
+		},
 		{
 			"The quick brown fox jumps over the lazy dog.",
 			di.DirectionLTR,
@@ -736,6 +936,379 @@ func TestSplit(t *testing.T) {
 	tu.Assert(t, inputs[0].Language == "ar")
 }
 
+// perScriptFontmap selects a face according to the script of the rune,
+// defaulting to the face for [language.Common]
+type perScriptFontmap map[language.Script]*font.Face
+
+func (sf perScriptFontmap) ResolveFace(r rune) *font.Face {
+	if face, ok := sf[language.LookupScript(r)]; ok {
+		return face
+	}
+	return sf[language.Common]
+}
+
+func TestSplitVertical(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	cjkFont := font.NewFace(latinFont.Font) // only used as a distinct face
+	fm := perScriptFontmap{language.Common: latinFont, language.Han: cjkFont, language.Arabic: arabicFont}
+
+	sideways, upright := di.DirectionTTB, di.DirectionTTB
+	sideways.SetSideways(true)
+	upright.SetSideways(false)
+
+	type run struct {
+		start, end int
+		dir        di.Direction
+		script     language.Script
+		face       *font.Face
+	}
+	for _, test := range []struct {
+		text         string
+		expectedRuns []run
+	}{
+		{
+			"我们使用Go语言",
+			[]run{
+				{0, 4, upright, language.Han, cjkFont},
+				{4, 6, sideways, language.Latin, latinFont},
+				{6, 8, upright, language.Han, cjkFont},
+			},
+		},
+		{
+			"中文 English",
+			[]run{
+				{0, 3, upright, language.Han, cjkFont},
+				{3, 10, sideways, language.Latin, latinFont},
+			},
+		},
+		{
+			"ᠮᠣᠩᠭᠣᠯ English",
+			[]run{
+				{0, 7, sideways, language.Mongolian, latinFont},
+				{7, 14, sideways, language.Latin, latinFont},
+			},
+		},
+	} {
+		var seg Segmenter
+		text := []rune(test.text)
+		inputs := seg.Split(Input{Text: text, RunEnd: len(text), Direction: di.DirectionTTB, Language: "zh"}, fm)
+		tu.Assert(t, len(inputs) == len(test.expectedRuns))
+		for i, run := range test.expectedRuns {
+			got := inputs[i]
+			tu.Assert(t, got.RunStart == run.start && got.RunEnd == run.end)
+			tu.Assert(t, got.Direction == run.dir)
+			tu.Assert(t, got.Script == run.script)
+			tu.Assert(t, got.Face == run.face)
+		}
+	}
+}
+
+func TestSplitLanguage(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	fm := fixedFontmap{latinFont, arabicFont}
+
+	var seg Segmenter
+	for _, test := range []struct {
+		text     string
+		lang     language.Language
+		expected []language.Language // per run
+	}{
+		// the requested language is kept when consistent with the script ...
+		{"Српски језик", "sr", []language.Language{"sr"}},
+		{"Русский язык", "ru", []language.Language{"ru"}},
+		{"日本語の文章", "ja", []language.Language{"ja", "ja", "ja"}},
+		{"漢字", "zh", []language.Language{"zh"}},
+		{"سماء", "ar", []language.Language{"ar"}},
+		{"سماء", "fa", []language.Language{"fa"}},
+		// ... and replaced by a default language for the other scripts
+		{"Ελληνικά και English", "el", []language.Language{"el", "en"}},
+		{"French and سماء", "fr", []language.Language{"fr", "ar"}},
+		// empty languages are resolved from the script
+		{"سماء", "", []language.Language{"ar"}},
+		{"Русский", "", []language.Language{"ru"}},
+	} {
+		text := []rune(test.text)
+		runs := seg.Split(Input{Text: text, RunEnd: len(text), Direction: di.DirectionLTR, Language: test.lang}, fm)
+		tu.AssertC(t, len(runs) == len(test.expected), test.text)
+		for i, lang := range test.expected {
+			tu.AssertC(t, runs[i].Language == lang, string(runs[i].Language))
+		}
+	}
+}
+
+func TestSplitUTF8(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	fm := fixedFontmap{latinFont, arabicFont}
+
+	var seg, ref Segmenter
+	for _, text := range []string{
+		"",
+		"The quick brown fox",
+		"The quick سماء שלום لا fox تمط שלום غير the lazy dog.",
+		"Ελληνικά and русский 😀 text",
+		"invalid \xff\xfe bytes سماء",
+	} {
+		runs, byteRanges := seg.SplitUTF8([]byte(text), fm, di.DirectionLTR)
+		runes := []rune(text)
+		exp := ref.Split(Input{Text: runes, RunEnd: len(runes), Direction: di.DirectionLTR}, fm)
+		tu.Assert(t, len(runs) == len(exp) && len(byteRanges) == len(runs))
+
+		for i, run := range runs {
+			tu.Assert(t, string(run.Text) == text || !utf8.ValidString(text))
+			run.Text, exp[i].Text = nil, nil
+			tu.Assert(t, reflect.DeepEqual(run, exp[i]))
+			// the byte range matches the runes of the run
+			br := byteRanges[i]
+			tu.Assert(t, string(runes[run.RunStart:run.RunEnd]) == string([]rune(text[br.Offset:br.Offset+br.Count])))
+			if i > 0 {
+				tu.Assert(t, br.Offset == byteRanges[i-1].Offset+byteRanges[i-1].Count)
+			}
+		}
+		last := byteRanges[len(byteRanges)-1]
+		tu.Assert(t, byteRanges[0].Offset == 0 && last.Offset+last.Count == len(text))
+	}
+}
+
+// alternateFontmap cycles through its faces for each call to ResolveFace
+type alternateFontmap struct {
+	faces []*font.Face
+	calls int
+}
+
+func (af *alternateFontmap) ResolveFace(r rune) *font.Face {
+	face := af.faces[af.calls%len(af.faces)]
+	af.calls++
+	return face
+}
+
+func TestSplitReuseFacesPerScript(t *testing.T) {
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	otherArabicFont := font.NewFace(arabicFont.Font)
+
+	text := []rune("سلام عليكم")
+	input := Input{Text: text, RunEnd: len(text), Direction: di.DirectionRTL, Language: "ar"}
+
+	var seg Segmenter
+	runs := seg.Split(input, &alternateFontmap{faces: []*font.Face{arabicFont, otherArabicFont}})
+	tu.Assert(t, len(runs) > 1)
+
+	seg.SetOptions(SegmenterOptions{ReuseFacesPerScript: true})
+	fm := &alternateFontmap{faces: []*font.Face{arabicFont, otherArabicFont}}
+	runs = seg.Split(input, fm)
+	tu.Assert(t, len(runs) == 1)
+	tu.Assert(t, runs[0].Face == arabicFont)
+	tu.Assert(t, fm.calls == 1)
+}
+
+// languageFontmap selects a face by language, and records the contexts it receives
+type languageFontmap struct {
+	faces    map[language.Language]*font.Face
+	fallback *font.Face
+	scripts  []language.Script
+}
+
+func (lf *languageFontmap) ResolveFace(r rune) *font.Face { return lf.fallback }
+
+func (lf *languageFontmap) ResolveFaceForScript(r rune, script language.Script, lang language.Language) *font.Face {
+	lf.scripts = append(lf.scripts, script)
+	if face := lf.faces[lang]; face != nil {
+		return face
+	}
+	return lf.fallback
+}
+
+func TestSplitFontmapContext(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	frenchFont := font.NewFace(latinFont.Font) // only used as a distinct face
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+
+	text := []rune("Bonjour سلام")
+	input := Input{Text: text, RunEnd: len(text), Direction: di.DirectionLTR, Language: "fr"}
+
+	fm := &languageFontmap{faces: map[language.Language]*font.Face{"fr": frenchFont, "ar": arabicFont}, fallback: latinFont}
+	var seg Segmenter
+	runs := seg.Split(input, fm)
+	tu.Assert(t, len(runs) == 2)
+	// the language is resolved for each script
+	tu.Assert(t, runs[0].Face == frenchFont && runs[0].Language == "fr")
+	tu.Assert(t, runs[1].Face == arabicFont && runs[1].Script == language.Arabic)
+	for _, script := range fm.scripts {
+		tu.Assert(t, script == language.Latin || script == language.Arabic)
+	}
+
+	// also with the cache per script
+	seg.SetOptions(SegmenterOptions{ReuseFacesPerScript: true})
+	runs = seg.Split(input, fm)
+	tu.Assert(t, len(runs) == 2)
+	tu.Assert(t, runs[0].Face == frenchFont && runs[1].Face == arabicFont)
+
+	// and with SplitByFace
+	runs = SplitByFace(Input{Text: text, RunEnd: 7, Script: language.Latin, Language: "fr"}, fm)
+	tu.Assert(t, len(runs) == 1 && runs[0].Face == frenchFont)
+}
+
+func TestSplitCachedFaces(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+
+	text := []rune("abab abab سلام سلام")
+	input := Input{Text: text, RunEnd: len(text), Direction: di.DirectionLTR}
+
+	var seg Segmenter
+	fm := &countingFontmap{Fontmap: fixedFontmap{latinFont, arabicFont}}
+	runs := seg.Split(input, fm)
+	tu.Assert(t, len(runs) == 2)
+	tu.Assert(t, runs[0].Face == latinFont && runs[1].Face == arabicFont)
+	// each rune is resolved once
+	tu.AssertC(t, fm.calls == 6, fmt.Sprint(fm.calls))
+
+	// the cache is cleared between calls, since the Fontmap may change
+	runs = seg.Split(input, fixedFontmap{arabicFont})
+	tu.Assert(t, runs[0].Face == arabicFont)
+	fm.calls = 0
+	seg.Split(input, fm)
+	tu.Assert(t, fm.calls == 6)
+
+	// the cache is bounded
+	var long []rune
+	for r := rune(0x4E00); r < 0x4E00+maxCachedFaces+10; r++ {
+		long = append(long, r)
+	}
+	fm.calls = 0
+	seg.Split(Input{Text: long, RunEnd: len(long), Direction: di.DirectionLTR}, fm)
+	tu.Assert(t, fm.calls == len(long))
+	tu.Assert(t, len(seg.cachedFaces.faces) <= maxCachedFaces)
+}
+
+func BenchmarkSplitRepetitive(b *testing.B) {
+	latinFont := loadOpentypeFont(b, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(b, "../font/testdata/Amiri-Regular.ttf")
+	fm := fixedFontmap{arabicFont, latinFont}
+
+	text := []rune(strings.Repeat("The quick brown fox jumps over the lazy dog. ", 100))
+	input := Input{Text: text, RunEnd: len(text), Direction: di.DirectionLTR}
+
+	var seg Segmenter
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = seg.Split(input, fm)
+	}
+}
+
+func TestSplitIgnoreFaceChange(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	emojiFont := font.NewFace(latinFont.Font) // only used as a distinct face
+	// U+FE0F has the Inherited script
+	fm := perScriptFontmap{language.Common: latinFont, language.Inherited: emojiFont}
+
+	text := []rune("A\uFE0F")
+	input := Input{Text: text, RunEnd: len(text), Direction: di.DirectionLTR}
+
+	// by default, the variation selector is ignored
+	var seg Segmenter
+	runs := seg.Split(input, fm)
+	tu.Assert(t, len(runs) == 1 && runs[0].Face == latinFont)
+
+	seg.SetOptions(SegmenterOptions{IgnoreFaceChange: func(r rune) bool { return r != 0xFE0F && IgnoreFaceChange(r) }})
+	runs = seg.Split(input, fm)
+	tu.Assert(t, len(runs) == 2)
+	tu.Assert(t, runs[0].RunEnd == 1 && runs[0].Face == latinFont)
+	tu.Assert(t, runs[1].RunStart == 1 && runs[1].Face == emojiFont)
+}
+
+func TestSplitByFaceGraphemes(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	otherFont := font.NewFace(latinFont.Font) // only used as a distinct face
+	// combining marks have the Inherited script
+	fm := perScriptFontmap{language.Common: latinFont, language.Inherited: otherFont, language.Han: otherFont}
+
+	type run struct {
+		start, end int
+		face       *font.Face
+	}
+	for _, test := range []struct {
+		text     string
+		expected []run
+	}{
+		{"e\u0301te\u0301", []run{{0, 5, latinFont}}},                                 // combining marks keep the face of their base
+		{"\u0301abc", []run{{0, 1, otherFont}, {1, 4, latinFont}}},                    // standalone mark at the start
+		{"a\u200D中b", []run{{0, 2, latinFont}, {2, 3, otherFont}, {3, 4, latinFont}}}, // ZWJ only joins pictographs
+		{"a 中", []run{{0, 2, latinFont}, {2, 3, otherFont}}},
+	} {
+		text := []rune(test.text)
+		runs := SplitByFace(Input{Text: text, RunEnd: len(text)}, fm)
+		tu.AssertC(t, len(runs) == len(test.expected), test.text)
+		for i, exp := range test.expected {
+			tu.Assert(t, runs[i].RunStart == exp.start && runs[i].RunEnd == exp.end && runs[i].Face == exp.face)
+		}
+	}
+
+	// emoji ZWJ sequences are not split, even if the font map would select another face
+	for _, text := range []string{"👩\u200D💻", "👩\U0001F3FD\u200D💻", "❤\uFE0F\u200D🔥"} {
+		text := []rune(text)
+		runs := SplitByFace(Input{Text: text, RunEnd: len(text)}, &alternateFontmap{faces: []*font.Face{latinFont, otherFont}})
+		tu.AssertC(t, len(runs) == 1, string(text))
+	}
+}
+
+func TestSplitFontFeatures(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+
+	features := []FontFeature{{Tag: ot.MustNewTag("liga"), Value: 0}, {Tag: ot.MustNewTag("kern"), Value: 1}}
+	text := []rune("hello سلام world")
+	input := Input{Text: text, RunEnd: len(text), Direction: di.DirectionLTR, FontFeatures: features}
+
+	var seg Segmenter
+	runs := seg.Split(input, fixedFontmap{latinFont, arabicFont})
+	tu.Assert(t, len(runs) == 3)
+	for _, run := range runs {
+		tu.Assert(t, reflect.DeepEqual(run.FontFeatures, features))
+	}
+
+	// modifying one run does not affect the others nor the input
+	runs[0].FontFeatures[0].Value = 1
+	runs[1].FontFeatures = append(runs[1].FontFeatures, FontFeature{Tag: ot.MustNewTag("smcp"), Value: 1})
+	tu.Assert(t, features[0].Value == 0)
+	tu.Assert(t, reflect.DeepEqual(runs[2].FontFeatures, features))
+	tu.Assert(t, len(runs[1].FontFeatures) == 3 && runs[1].FontFeatures[0].Value == 0)
+}
+
+func TestBidiClasses(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+
+	text := []rune("> a1 ب٣$")
+	var seg Segmenter
+	seg.Split(Input{Text: text, RunStart: 2, RunEnd: len(text), Direction: di.DirectionLTR}, fixedFontmap{latinFont, arabicFont})
+	classes := seg.BidiClasses()
+	// EN after L resolves to L (W7), AL to R (W3), ET and WS to the embedding direction (N2)
+	tu.Assert(t, reflect.DeepEqual(classes, []bidi.Class{bidi.L, bidi.L, bidi.L, bidi.R, bidi.AN, bidi.L}))
+
+	for _, test := range []struct {
+		text     string
+		dir      di.Direction
+		expected []bidi.Class
+	}{
+		// EN after AL resolves to AN (W2)
+		{"ب12", di.DirectionLTR, []bidi.Class{bidi.R, bidi.AN, bidi.AN}},
+		// numbers and spaces between RTL runs take their direction (N1)
+		{"a א 12 ב", di.DirectionLTR, []bidi.Class{bidi.L, bidi.L, bidi.R, bidi.R, bidi.EN, bidi.EN, bidi.R, bidi.R}},
+		// ET adjacent to EN resolves to EN (W5), CS between EN to EN (W4)
+		{"א $1,5", di.DirectionRTL, []bidi.Class{bidi.R, bidi.R, bidi.EN, bidi.EN, bidi.EN, bidi.EN}},
+	} {
+		text := []rune(test.text)
+		seg.Split(Input{Text: text, RunEnd: len(text), Direction: test.dir}, fixedFontmap{latinFont, arabicFont})
+		tu.AssertC(t, reflect.DeepEqual(seg.BidiClasses(), test.expected), fmt.Sprint(test.text, seg.BidiClasses()))
+	}
+
+	seg.Split(Input{Text: text, Direction: di.DirectionLTR}, fixedFontmap{latinFont})
+	tu.Assert(t, len(seg.BidiClasses()) == 0)
+}
+
 func TestIssue127(t *testing.T) {
 	// regression test for https://github.com/go-text/typesetting/issues/127
 	str := []rune("لمّا")
@@ -771,3 +1344,59 @@ func Test_enforceLang(t *testing.T) {
 		}
 	}
 }
+
+func TestSplitStream(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	fm := fixedFontmap{latinFont, arabicFont}
+
+	text := []rune("The quick سماء fox\nتمط غير the lazy dog.\r\nجميل last paragraph")
+	paragraphs := [][2]int{{0, 19}, {19, 42}, {42, 47}, {47, len(text)}}
+
+	var seg, ref Segmenter
+	var got []Input
+	seg.SplitStream(text, fm, di.DirectionLTR, func(run Input) bool {
+		got = append(got, run)
+		return true
+	})
+
+	// each paragraph is segmented independently
+	var exp []Input
+	for _, para := range paragraphs {
+		exp = append(exp, ref.Split(Input{Text: text, RunStart: para[0], RunEnd: para[1], Direction: di.DirectionLTR}, fm)...)
+	}
+	tu.Assert(t, len(got) == len(exp))
+	for i := range exp {
+		tu.Assert(t, reflect.DeepEqual(got[i], exp[i]))
+	}
+	// the runs cover the whole text
+	for i, run := range got {
+		tu.Assert(t, &run.Text[0] == &text[0])
+		if i > 0 {
+			tu.Assert(t, run.RunStart == got[i-1].RunEnd)
+		}
+	}
+	tu.Assert(t, got[0].RunStart == 0 && got[len(got)-1].RunEnd == len(text))
+
+	// stop early
+	var count int
+	seg.SplitStream(text, fm, di.DirectionLTR, func(run Input) bool {
+		count++
+		return run.RunEnd < paragraphs[1][1]
+	})
+	tu.Assert(t, count > 0 && count < len(exp))
+
+	for _, test := range []struct {
+		text string
+		end  int
+	}{
+		{"abc", 3},
+		{"abc\ndef", 4},
+		{"abc\r\ndef", 5},
+		{"abc\rdef", 4},
+		{"abc ", 4},
+		{"\n\n", 1},
+	} {
+		tu.Assert(t, paragraphEnd([]rune(test.text), 0) == test.end)
+	}
+}
@@ -191,6 +191,20 @@ func (o *Output) FromFontUnit(v float32) fixed.Int26_6 {
 	return fixed.Int26_6(v * float32(o.Size) / float32(o.Face.Upem()))
 }
 
+// AdvancesF appends to [dst] the advances of the glyphs, as floating point
+// values expressed in the same unit as [Size] (typically pixels), and returns the extended slice.
+//
+// It is a convenience for layout engines working with floating point values :
+// the precision is the one of the shaped glyphs. To measure text at another
+// size without shaping it again, scale the values by newSize / Size, instead of
+// converting them back to [fixed.Int26_6]. See also [font.Face.HAdvanceF].
+func (o *Output) AdvancesF(dst []float32) []float32 {
+	for _, g := range o.Glyphs {
+		dst = append(dst, float32(g.Advance)/64)
+	}
+	return dst
+}
+
 // RecomputeAdvance updates only the Advance field based on the current
 // contents of the Glyphs field. It is faster than RecalculateAll(),
 // and can be used to speed up line wrapping logic.
@@ -369,3 +383,169 @@ func (l Line) AdjustBaselines() {
 		l[i].moveCrossAxis(-middle)
 	}
 }
+
+// GraphemeGlyphs maps a grapheme cluster to the glyphs rendering it.
+type GraphemeGlyphs struct {
+	// Runes is the location of the grapheme in the input text.
+	Runes Range
+	// Glyphs is the location of the glyphs in [Output.Glyphs].
+	// It may be shared by several graphemes, for instance when a ligature
+	// spans more than one grapheme, and is empty if the grapheme
+	// is not rendered.
+	Glyphs Range
+}
+
+// GraphemeGlyphs returns the glyphs rendering each grapheme cluster of
+// the run, in logical order. This is useful to map a selection of
+// graphemes to the glyphs to highlight.
+//
+// [graphemeStarts] must be the sorted start indices (in runes, relative to the input text) of the
+// grapheme clusters, as returned by [segmenter.Segmenter.GraphemeIterator].
+// Starts outside of [Output.Runes] are ignored, and the start of the run is always
+// considered as a grapheme boundary.
+//
+// Since shaping clusters are rune based, a grapheme may be rendered by glyphs
+// belonging to several clusters (for instance emoji ZWJ sequences), which are
+// merged in the returned [Range].
+func (o *Output) GraphemeGlyphs(graphemeStarts []int) []GraphemeGlyphs {
+	if o.Runes.Count == 0 {
+		return nil
+	}
+	runStart, runEnd := o.Runes.Offset, o.Runes.Offset+o.Runes.Count
+	n := len(o.Glyphs)
+	isReversed := o.Direction.Progression() == di.TowardTopLeft
+	// logical returns the index in Glyphs of the k-th glyph in logical order
+	logical := func(k int) int {
+		if isReversed {
+			return n - 1 - k
+		}
+		return k
+	}
+
+	// clusterEnds[k] is the end of the cluster of the k-th glyph in logical order
+	clusterEnds := make([]int, n)
+	nextStart := runEnd
+	for k := n - 1; k >= 0; k-- {
+		if k+1 < n {
+			if next := o.Glyphs[logical(k+1)].ClusterIndex; next != o.Glyphs[logical(k)].ClusterIndex {
+				nextStart = next
+			}
+		}
+		clusterEnds[k] = nextStart
+	}
+
+	// select the boundaries inside the run
+	starts := []int{runStart}
+	for _, start := range graphemeStarts {
+		if start > starts[len(starts)-1] && start < runEnd {
+			starts = append(starts, start)
+		}
+	}
+
+	out := make([]GraphemeGlyphs, 0, len(starts))
+	k0, k1 := 0, 0 // logical glyph range
+	for i, start := range starts {
+		end := runEnd
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		// skip the clusters before the grapheme ...
+		for k0 < n && clusterEnds[k0] <= start {
+			k0++
+		}
+		// ... and add the ones starting in the grapheme
+		if k1 < k0 {
+			k1 = k0
+		}
+		for k1 < n && o.Glyphs[logical(k1)].ClusterIndex < end {
+			k1++
+		}
+
+		glyphs := Range{Offset: k0, Count: k1 - k0}
+		if isReversed {
+			glyphs.Offset = n - k1
+		}
+		out = append(out, GraphemeGlyphs{
+			Runes:  Range{Offset: start, Count: end - start},
+			Glyphs: glyphs,
+		})
+	}
+	return out
+}
+
+// Cluster is the smallest group of runes and of the glyphs rendering them
+// which may not be split : carets and selection boundaries should only
+// be placed between clusters.
+type Cluster struct {
+	// Runes is the location of the cluster in the input text.
+	// It spans more than one rune for ligatures, or when several runes
+	// are merged during shaping (like base characters and combining marks).
+	Runes Range
+	// Glyphs is the location of the cluster in [Output.Glyphs].
+	// It spans more than one glyph when a rune is decomposed.
+	// Glyphs of a cluster are always contiguous.
+	Glyphs Range
+}
+
+// ClusterForRune returns the cluster containing the rune at [runeIndex],
+// which is an index into the input text (like [Glyph.ClusterIndex]).
+// It returns false if the rune is not in [Output.Runes].
+//
+// For right-to-left runs, where the glyphs are in visual order,
+// the [Cluster.Glyphs] range still refers to indices in [Output.Glyphs].
+func (o *Output) ClusterForRune(runeIndex int) (Cluster, bool) {
+	runStart, runEnd := o.Runes.Offset, o.Runes.Offset+o.Runes.Count
+	if runeIndex < runStart || runeIndex >= runEnd {
+		return Cluster{}, false
+	}
+	// the cluster start is the highest cluster index before the rune
+	start := -1
+	for _, g := range o.Glyphs {
+		if g.ClusterIndex <= runeIndex && g.ClusterIndex > start {
+			start = g.ClusterIndex
+		}
+	}
+	if start == -1 {
+		// the runes before the first cluster are not rendered
+		start = runStart
+	}
+	return o.cluster(start), true
+}
+
+// ClusterForGlyph returns the cluster containing the glyph at [glyphIndex],
+// which is an index into [Output.Glyphs].
+// It returns false if the index is out of range.
+func (o *Output) ClusterForGlyph(glyphIndex int) (Cluster, bool) {
+	if glyphIndex < 0 || glyphIndex >= len(o.Glyphs) {
+		return Cluster{}, false
+	}
+	return o.cluster(o.Glyphs[glyphIndex].ClusterIndex), true
+}
+
+// cluster returns the cluster starting at rune [start]
+func (o *Output) cluster(start int) Cluster {
+	end := o.Runes.Offset + o.Runes.Count
+	glyphStart, glyphEnd := -1, -1
+	for i, g := range o.Glyphs {
+		if g.ClusterIndex == start {
+			if glyphStart == -1 {
+				glyphStart = i
+			}
+			glyphEnd = i + 1
+		} else if g.ClusterIndex > start && g.ClusterIndex < end {
+			end = g.ClusterIndex
+		}
+	}
+	if glyphStart == -1 {
+		// runes before the first cluster, which is (logically) after them
+		glyphStart = 0
+		if o.Direction.Progression() == di.TowardTopLeft {
+			glyphStart = len(o.Glyphs)
+		}
+		glyphEnd = glyphStart
+	}
+	return Cluster{
+		Runes:  Range{Offset: start, Count: end - start},
+		Glyphs: Range{Offset: glyphStart, Count: glyphEnd - glyphStart},
+	}
+}
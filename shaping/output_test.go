@@ -4,6 +4,7 @@ package shaping
 
 import (
 	"bytes"
+	"fmt"
 	"reflect"
 	"testing"
 
@@ -280,6 +281,12 @@ func TestConvertUnit(t *testing.T) {
 	}
 }
 
+func TestAdvancesF(t *testing.T) {
+	o := Output{Glyphs: []Glyph{{Advance: fixed.I(10)}, {Advance: fixed.Int26_6(1<<6 + 16)}, {Advance: -32}}}
+	tu.Assert(t, reflect.DeepEqual(o.AdvancesF(nil), []float32{10, 1.25, -0.5}))
+	tu.Assert(t, reflect.DeepEqual(o.AdvancesF([]float32{4}), []float32{4, 10, 1.25, -0.5}))
+}
+
 func TestLine_AdjustBaseline(t *testing.T) {
 	var sideways di.Direction
 	sideways.SetSideways(true)
@@ -495,3 +502,95 @@ func TestAdvanceSpaceAware(t *testing.T) {
 		})
 	}
 }
+
+func TestGraphemeGlyphs(t *testing.T) {
+	glyphs := func(clusters ...int) []Glyph {
+		out := make([]Glyph, len(clusters))
+		for i, c := range clusters {
+			out[i].ClusterIndex = c
+		}
+		return out
+	}
+	type gg = GraphemeGlyphs
+	for _, test := range []struct {
+		output   Output
+		starts   []int
+		expected []GraphemeGlyphs
+	}{
+		{ // empty run
+			Output{Direction: di.DirectionLTR},
+			nil,
+			nil,
+		},
+		{ // combining mark in the same cluster and a ligature spanning two graphemes
+			Output{Direction: di.DirectionLTR, Runes: Range{0, 4}, Glyphs: glyphs(0, 0, 2)},
+			[]int{0, 2, 3},
+			[]gg{{Range{0, 2}, Range{0, 2}}, {Range{2, 1}, Range{2, 1}}, {Range{3, 1}, Range{2, 1}}},
+		},
+		{ // one grapheme spanning several clusters, like emoji ZWJ sequences
+			Output{Direction: di.DirectionLTR, Runes: Range{0, 3}, Glyphs: glyphs(0, 1, 2)},
+			[]int{0},
+			[]gg{{Range{0, 3}, Range{0, 3}}},
+		},
+		{ // run in the middle of the text : out of range boundaries are ignored
+			Output{Direction: di.DirectionLTR, Runes: Range{5, 3}, Glyphs: glyphs(5, 6, 7)},
+			[]int{0, 2, 6, 8, 9},
+			[]gg{{Range{5, 1}, Range{0, 1}}, {Range{6, 2}, Range{1, 2}}},
+		},
+		{ // RTL : glyphs are in visual order
+			Output{Direction: di.DirectionRTL, Runes: Range{0, 4}, Glyphs: glyphs(3, 2, 0)},
+			[]int{0, 1, 2, 3},
+			[]gg{{Range{0, 1}, Range{2, 1}}, {Range{1, 1}, Range{2, 1}}, {Range{2, 1}, Range{1, 1}}, {Range{3, 1}, Range{0, 1}}},
+		},
+	} {
+		got := test.output.GraphemeGlyphs(test.starts)
+		tu.Assert(t, reflect.DeepEqual(got, test.expected))
+	}
+}
+
+func TestClusters(t *testing.T) {
+	glyphs := func(clusters ...int) []Glyph {
+		out := make([]Glyph, len(clusters))
+		for i, c := range clusters {
+			out[i].ClusterIndex = c
+		}
+		return out
+	}
+	type cl = Cluster
+	for _, test := range []struct {
+		output  Output
+		byRune  []Cluster // for each rune of the run
+		byGlyph []Cluster // for each glyph
+	}{
+		{ // ligature (runes 1 and 2) and decomposition (rune 3)
+			Output{Direction: di.DirectionLTR, Runes: Range{0, 4}, Glyphs: glyphs(0, 1, 3, 3)},
+			[]cl{{Range{0, 1}, Range{0, 1}}, {Range{1, 2}, Range{1, 1}}, {Range{1, 2}, Range{1, 1}}, {Range{3, 1}, Range{2, 2}}},
+			[]cl{{Range{0, 1}, Range{0, 1}}, {Range{1, 2}, Range{1, 1}}, {Range{3, 1}, Range{2, 2}}, {Range{3, 1}, Range{2, 2}}},
+		},
+		{ // RTL : glyphs are in visual order
+			Output{Direction: di.DirectionRTL, Runes: Range{10, 4}, Glyphs: glyphs(13, 13, 11, 10)},
+			[]cl{{Range{10, 1}, Range{3, 1}}, {Range{11, 2}, Range{2, 1}}, {Range{11, 2}, Range{2, 1}}, {Range{13, 1}, Range{0, 2}}},
+			[]cl{{Range{13, 1}, Range{0, 2}}, {Range{13, 1}, Range{0, 2}}, {Range{11, 2}, Range{2, 1}}, {Range{10, 1}, Range{3, 1}}},
+		},
+		{ // runes not rendered at the start of the run
+			Output{Direction: di.DirectionRTL, Runes: Range{0, 3}, Glyphs: glyphs(2, 1)},
+			[]cl{{Range{0, 1}, Range{2, 0}}, {Range{1, 1}, Range{1, 1}}, {Range{2, 1}, Range{0, 1}}},
+			[]cl{{Range{2, 1}, Range{0, 1}}, {Range{1, 1}, Range{1, 1}}},
+		},
+	} {
+		for i, expected := range test.byRune {
+			got, ok := test.output.ClusterForRune(test.output.Runes.Offset + i)
+			tu.Assert(t, ok)
+			tu.AssertC(t, got == expected, fmt.Sprint(got))
+		}
+		for i, expected := range test.byGlyph {
+			got, ok := test.output.ClusterForGlyph(i)
+			tu.Assert(t, ok)
+			tu.AssertC(t, got == expected, fmt.Sprint(got))
+		}
+		_, ok := test.output.ClusterForRune(test.output.Runes.Offset + test.output.Runes.Count)
+		tu.Assert(t, !ok)
+		_, ok = test.output.ClusterForGlyph(len(test.output.Glyphs))
+		tu.Assert(t, !ok)
+	}
+}
@@ -0,0 +1,303 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+import (
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/language"
+	"golang.org/x/text/unicode/bidi"
+)
+
+// ReSplit updates the segmentation [prev] of a paragraph after an edit,
+// and returns the same runs as [Split] would for the edited paragraph,
+// re-running the bidi, script and face segmentation only on the region
+// affected by the edit. It is meant for interactive editing, where
+// inserting or deleting a few runes should not trigger a full segmentation.
+//
+// [prev] must be the (non modified) result of a previous call to [Split] or [ReSplit],
+// and its Text field must still hold the paragraph before the edit : editors modifying
+// their text in place should pass a copy to [Split].
+// [text] is the paragraph after the edit, where the runes
+// text[editStart:editStart+editNewLen] have replaced the runes
+// old[editStart:editStart+editOldLen] of the previous text. The
+// range covered by the runs is updated accordingly.
+// [faces] and [dir] must be the same as the ones used to build [prev].
+// The other fields of the input (like Size and FontFeatures) are
+// taken from [prev]. Since [Split] adjusts the language of each run to its script,
+// the language of the input is the one of the last call to [Split] when [prev] is its
+// output; otherwise, it is inferred from the runs of [prev].
+//
+// Since the bidi algorithm is not local, the re-segmented region is
+// extended on both sides of the edit, up to the first positions which
+// are not influenced by it. Such a position must lie between two
+// runes outside of the edit, which are both
+//   - strongly directional (Bidi_Class L, R or AL) : the resolution of the
+//     weak and neutral types (rules W1-W7, N1 and N2) does not look beyond them,
+//     and their levels only depend on the paragraph direction
+//   - of a real (not Common or Inherited) script : the script resolution
+//     of Common runes does not look beyond them, and the face of a rune only
+//     depends on its script
+//
+// and no paired delimiter (like brackets or quotes) may be pending there, either in the
+// previous or the edited text, since they are matched across runs (rule N0 of the bidi
+// algorithm and the script resolution both use them).
+// In practice, the region usually spans from the word before the edit to the word after it.
+// The runs of [prev] crossing the region boundaries are cut, and merged back with the new runs.
+//
+// In some cases the whole paragraph is segmented again :
+//   - when it contains explicit bidi formatting runes (embeddings, overrides or isolates)
+//     or paragraph separators, or when the removed runes contain some
+//   - when the edit changes the paragraph direction (for instance by adding a strong
+//     right-to-left rune at the start of a left-to-right paragraph)
+//   - when [SegmenterOptions.ReuseFacesPerScript] is true
+//   - when no boundary is found
+//
+// Note that the shaping of the runs surrounding the re-segmented region may still
+// be affected by the edit, since the text around a run is used as context
+// (for instance for Arabic joining) : applications caching shaped runs should shape
+// again the runs adjacent to the modified ones.
+//
+// The returned sliced is owned by the [Segmenter] and is only valid until
+// the next call to [Split] or [ReSplit].
+func (seg *Segmenter) ReSplit(prev []Input, text []rune, editStart, editOldLen, editNewLen int, faces Fontmap, dir di.Direction) []Input {
+	if len(prev) == 0 {
+		return seg.Split(Input{Text: text, RunEnd: len(text), Direction: dir}, faces)
+	}
+
+	delta := editNewLen - editOldLen
+	first, last := prev[0], prev[len(prev)-1]
+	old := first.Text
+	start, oldEnd := first.RunStart, last.RunEnd
+	end := oldEnd + delta
+
+	paragraph := first
+	paragraph.Text = text
+	paragraph.RunStart, paragraph.RunEnd = start, end
+	paragraph.Direction = dir
+	paragraph.Face = nil
+	if len(seg.output) != 0 && &prev[0] == &seg.output[0] {
+		paragraph.Language = seg.language
+	} else {
+		paragraph.Language = inputLanguage(prev)
+	}
+
+	if editStart < start || editOldLen < 0 || editNewLen < 0 || editStart+editOldLen > oldEnd ||
+		oldEnd > len(old) || end > len(text) {
+		// invalid edit : segment the whole text
+		paragraph.RunStart, paragraph.RunEnd = 0, len(text)
+		return seg.Split(paragraph, faces)
+	}
+
+	if seg.options.ReuseFacesPerScript || start >= end ||
+		hasExplicitBidi(text[start:end]) || hasExplicitBidi(old[editStart:editStart+editOldLen]) {
+		return seg.Split(paragraph, faces)
+	}
+
+	isRTL := seg.paragraphIsRTL(text[start:end], dir)
+	if isRTL != seg.paragraphIsRTL(old[start:oldEnd], dir) {
+		return seg.Split(paragraph, faces)
+	}
+
+	// find the last safe boundary before the edit ...
+	var newDelims delimTracker
+	regionStart := start
+	for x := start; x < editStart; x++ {
+		if x > start && len(newDelims) == 0 && isSafeBoundary(text, x) {
+			regionStart = x
+		}
+		newDelims.advance(text[x])
+	}
+
+	// ... and the first one after it
+	oldDelims := append(delimTracker(nil), newDelims...)
+	for _, r := range old[editStart : editStart+editOldLen] {
+		oldDelims.advance(r)
+	}
+	for _, r := range text[editStart : editStart+editNewLen] {
+		newDelims.advance(r)
+	}
+	regionEnd := end
+	for y := editStart + editNewLen; y < end; y++ {
+		if y > editStart+editNewLen && len(oldDelims) == 0 && len(newDelims) == 0 && isSafeBoundary(text, y) {
+			regionEnd = y
+			break
+		}
+		oldDelims.advance(text[y])
+		newDelims.advance(text[y])
+	}
+
+	if regionStart == start && regionEnd == end {
+		return seg.Split(paragraph, faces)
+	}
+
+	// save the runs to keep, since [prev] is usually
+	// the previous output of the segmenter
+	seg.kept = seg.kept[:0]
+	cutLeft, cutRight := false, false
+	for _, run := range prev {
+		if run.RunStart < regionStart {
+			if run.RunEnd > regionStart {
+				run.RunEnd = regionStart
+				cutLeft = true
+			}
+			seg.kept = append(seg.kept, run)
+		}
+	}
+	leftRuns := len(seg.kept)
+	for _, run := range prev {
+		run.RunStart += delta
+		run.RunEnd += delta
+		if run.RunEnd > regionEnd {
+			if run.RunStart < regionEnd {
+				run.RunStart = regionEnd
+				cutRight = true
+			}
+			seg.kept = append(seg.kept, run)
+		}
+	}
+
+	region := paragraph
+	region.RunStart, region.RunEnd = regionStart, regionEnd
+
+	seg.reset()
+	seg.text = text[start:end]
+	seg.language = paragraph.Language
+	seg.splitByBidiWith(region, isRTL, true)
+	seg.splitAfterBidi(region, faces)
+
+	// assemble the kept runs and the new ones, merging
+	// the runs which have been cut
+	seg.input = append(seg.input[:0], seg.kept[:leftRuns]...)
+	seg.input = appendRuns(seg.input, seg.output, cutLeft)
+	seg.input = appendRuns(seg.input, seg.kept[leftRuns:], cutRight)
+	for i := range seg.input {
+		seg.input[i].Text = text
+	}
+	seg.input, seg.output = seg.output, seg.input
+
+	// zero the slice to avoid 'memory leak' on pointer slice fields
+	for i := range seg.kept {
+		seg.kept[i].Text = nil
+		seg.kept[i].FontFeatures = nil
+	}
+
+	return seg.output
+}
+
+// appendRuns appends [runs] to [dst]. If [merge] is true, the first run
+// is merged with the last run of [dst] when they have the same properties.
+func appendRuns(dst, runs []Input, merge bool) []Input {
+	if L := len(dst); merge && L != 0 && len(runs) != 0 {
+		last, run := &dst[L-1], runs[0]
+		if last.Direction == run.Direction && last.Face == run.Face &&
+			last.Script == run.Script && last.Language == run.Language {
+			last.RunEnd = run.RunEnd
+			runs = runs[1:]
+		}
+	}
+	return append(dst, runs...)
+}
+
+// inputLanguage returns the language of the input segmented into [runs] :
+// since [enforceLanguages] may have modified the language of some runs,
+// it looks for a language consistent with the script of all the runs.
+func inputLanguage(runs []Input) language.Language {
+	for _, candidate := range runs {
+		lang, ok := language.NewLangID(candidate.Language)
+		if !ok {
+			continue
+		}
+		isConsistent := true
+		for _, run := range runs {
+			if enforceLang(lang, run.Script).Language() != run.Language {
+				isConsistent = false
+				break
+			}
+		}
+		if isConsistent {
+			return candidate.Language
+		}
+	}
+	return runs[0].Language
+}
+
+// paragraphIsRTL returns the direction of the paragraph [runes],
+// as resolved by [splitByBidi].
+func (seg *Segmenter) paragraphIsRTL(runes []rune, dir di.Direction) bool {
+	isRTL := seg.baseDirectionIsRTL(runes, dir)
+	if !isRTL && seg.options.BaseDirectionMode == BaseDirectionDefault {
+		// rules P2 and P3
+		return firstStrongIsRTL(runes, true, false)
+	}
+	return isRTL
+}
+
+// hasExplicitBidi returns true if [runes] contains explicit
+// bidi formatting characters or paragraph separators.
+func hasExplicitBidi(runes []rune) bool {
+	for _, r := range runes {
+		props, _ := bidi.LookupRune(r)
+		switch props.Class() {
+		case bidi.LRE, bidi.RLE, bidi.LRO, bidi.RLO, bidi.PDF,
+			bidi.LRI, bidi.RLI, bidi.FSI, bidi.PDI, bidi.B:
+			return true
+		}
+	}
+	return false
+}
+
+// isSafeBoundary returns true if text[x-1] and text[x] are both
+// strongly directional runes with a real script.
+func isSafeBoundary(text []rune, x int) bool {
+	for _, r := range text[x-1 : x+1] {
+		props, _ := bidi.LookupRune(r)
+		if c := props.Class(); c != bidi.L && c != bidi.R && c != bidi.AL {
+			return false
+		}
+		if !language.LookupScript(r).Strong() {
+			return false
+		}
+	}
+	return true
+}
+
+// delimTracker stores the opening paired delimiters
+// which are not closed yet.
+type delimTracker []rune
+
+// advance updates the pending delimiters with [r]
+func (dt *delimTracker) advance(r rune) {
+	opening, isOpening, ok := pairedDelimiter(r)
+	if !ok {
+		return
+	}
+	if isOpening {
+		*dt = append(*dt, opening)
+		return
+	}
+	// close the matching delimiter, if any
+	for j := len(*dt) - 1; j >= 0; j-- {
+		if (*dt)[j] == opening {
+			*dt = (*dt)[:j]
+			return
+		}
+	}
+}
+
+// pairedDelimiter returns the opening delimiter matching [r],
+// if [r] is a paired delimiter, as used by the script resolution,
+// or a bracket, as used by the bidi algorithm.
+func pairedDelimiter(r rune) (opening rune, isOpening, ok bool) {
+	if index := lookupDelimIndex(r); index >= 0 {
+		return pairedDelims[index&^1], index%2 == 0, true
+	}
+	if props, _ := bidi.LookupRune(r); props.IsBracket() {
+		// the remaining brackets (Tibetan and Ogham) are
+		// encoded with consecutive code points
+		if props.IsOpeningBracket() {
+			return r, true, true
+		}
+		return r - 1, false, true
+	}
+	return 0, false, false
+}
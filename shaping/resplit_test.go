@@ -0,0 +1,115 @@
+package shaping
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+// countingFontmap records the number of calls to ResolveFace
+type countingFontmap struct {
+	Fontmap
+	calls int
+}
+
+func (cf *countingFontmap) ResolveFace(r rune) *font.Face {
+	cf.calls++
+	return cf.Fontmap.ResolveFace(r)
+}
+
+type edit struct {
+	start, oldLen int
+	inserted      string
+}
+
+func (e edit) apply(text []rune) []rune {
+	out := append([]rune(nil), text[:e.start]...)
+	out = append(out, []rune(e.inserted)...)
+	return append(out, text[e.start+e.oldLen:]...)
+}
+
+func assertSameRuns(t *testing.T, got, exp []Input) {
+	t.Helper()
+	tu.Assert(t, len(got) == len(exp))
+	for i := range exp {
+		tu.AssertC(t, reflect.DeepEqual(got[i], exp[i]), fmt.Sprintf("run %d: %v != %v", i, got[i], exp[i]))
+	}
+}
+
+func TestReSplit(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	faces := &countingFontmap{Fontmap: fixedFontmap{latinFont, arabicFont}}
+
+	const paragraph = "Lorem ipsum dolor sit amet, تحت السماء consectetur (adipiscing) elit, sed do « eiusmod » tempor 123 incididunt ut labore et dolore magna aliqua."
+
+	for _, test := range []struct {
+		dir   di.Direction
+		edit  edit
+		local bool // the whole paragraph is not segmented again
+	}{
+		{di.DirectionLTR, edit{2, 0, "x"}, true},                       // insertion
+		{di.DirectionLTR, edit{50, 3, ""}, true},                       // deletion
+		{di.DirectionLTR, edit{100, 2, "abc"}, true},                   // replacement
+		{di.DirectionLTR, edit{30, 0, "حمل"}, true},                    // new Arabic word
+		{di.DirectionLTR, edit{62, 0, "("}, true},                      // unbalanced parenthesis : the region extends to the end
+		{di.DirectionLTR, edit{0, 0, "سماء "}, false},                  // paragraph direction change
+		{di.DirectionLTR, edit{40, 0, "\u2067"}, false},                // explicit isolate
+		{di.DirectionLTR, edit{100, 0, "\u0301"}, true},                // combining mark
+		{di.DirectionRTL, edit{20, 0, " 45"}, true},                    // numbers in RTL paragraph
+		{di.DirectionLTR, edit{len([]rune(paragraph)), 0, " x"}, true}, // append
+	} {
+		text := []rune(paragraph)
+		var seg Segmenter
+		prev := seg.Split(Input{Text: text, RunEnd: len(text), Direction: test.dir, Size: 12, Language: "fr"}, faces)
+
+		newText := test.edit.apply(text)
+		faces.calls = 0
+		got := seg.ReSplit(prev, newText, test.edit.start, test.edit.oldLen, len([]rune(test.edit.inserted)), faces, test.dir)
+		calls := faces.calls
+
+		var ref Segmenter
+		faces.calls = 0
+		exp := ref.Split(Input{Text: newText, RunEnd: len(newText), Direction: test.dir, Size: 12, Language: "fr"}, faces)
+		assertSameRuns(t, got, exp)
+		tu.AssertC(t, (calls < faces.calls) == test.local, fmt.Sprint(test.edit, calls, faces.calls))
+	}
+}
+
+func TestReSplitRandom(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	faces := fixedFontmap{latinFont, arabicFont}
+
+	alphabet := []rune("abc DEF 12 ,.()«» سماء ١٢ \u0301")
+	rng := rand.New(rand.NewSource(1))
+	randomString := func(n int) string {
+		out := make([]rune, n)
+		for i := range out {
+			out[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return string(out)
+	}
+
+	for _, dir := range []di.Direction{di.DirectionLTR, di.DirectionRTL, di.DirectionTTB} {
+		var seg, ref Segmenter
+		text := []rune(randomString(80))
+		runs := seg.Split(Input{Text: text, RunEnd: len(text), Direction: dir}, faces)
+		for i := 0; i < 200; i++ {
+			start := rng.Intn(len(text) + 1)
+			e := edit{start: start, oldLen: rng.Intn(len(text)-start+1) / 4, inserted: randomString(rng.Intn(4))}
+			newText := e.apply(text)
+
+			runs = seg.ReSplit(runs, newText, e.start, e.oldLen, len([]rune(e.inserted)), faces, dir)
+			exp := ref.Split(Input{Text: newText, RunEnd: len(newText), Direction: dir}, faces)
+			assertSameRuns(t, runs, exp)
+
+			text = newText
+		}
+	}
+}
@@ -3,8 +3,15 @@
 package shaping
 
 import (
+	"sync"
+	"unicode"
+
 	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
 	"github.com/go-text/typesetting/harfbuzz"
+	"github.com/go-text/typesetting/language"
 	"golang.org/x/image/math/fixed"
 )
 
@@ -17,7 +24,19 @@ type HarfbuzzShaper struct {
 
 	fonts fontLRU
 
+	// varFont is used for inputs with [Input.Variations],
+	// so that the coordinates of the cached fonts are not modified
+	varFont *harfbuzz.Font
+
 	features []harfbuzz.Feature
+
+	// simplePlans caches, for the runs verifying [Input.IsSimple],
+	// whether the font has lookups enabled by default
+	simplePlans map[simplePlanKey]simplePlan
+
+	// glyphs is the storage used for the outputs, after a call to Reset
+	glyphs      []Glyph
+	reuseGlyphs bool
 }
 
 // SetFontCacheSize adjusts the size of the font cache within the shaper.
@@ -27,6 +46,40 @@ func (h *HarfbuzzShaper) SetFontCacheSize(size int) {
 	h.fonts.maxSizeOffset = size - defaultFontCacheSize
 }
 
+// Reset allows the shaper to reuse the memory of the [Output.Glyphs] slices
+// returned by the previous calls to [HarfbuzzShaper.Shape] : once Reset has been
+// called, the glyphs of the following outputs are stored in a buffer owned by the shaper,
+// which is recycled by the next call to Reset.
+//
+// This is useful to avoid allocations when shaping many runs, for instance for each frame :
+// call Reset, shape all the runs, use the outputs and start again.
+// As a consequence, the Glyphs of the outputs are only valid until the next call to Reset.
+// Shapers which never call Reset allocate a new slice for each output.
+func (t *HarfbuzzShaper) Reset() {
+	t.reuseGlyphs = true
+	t.glyphs = t.glyphs[:0]
+}
+
+// allocGlyphs returns a zeroed slice of length [n], using the storage
+// of the shaper when [Reset] has been called
+func (t *HarfbuzzShaper) allocGlyphs(n int) []Glyph {
+	if !t.reuseGlyphs {
+		return make([]Glyph, n)
+	}
+	start := len(t.glyphs)
+	if cap(t.glyphs)-start < n {
+		// the previous outputs keep the old storage
+		t.glyphs = make([]Glyph, 0, 2*cap(t.glyphs)+n)
+		start = 0
+	}
+	t.glyphs = t.glyphs[:start+n]
+	out := t.glyphs[start : start+n : start+n] // appending to an output must not override the storage
+	for i := range out {
+		out[i] = Glyph{}
+	}
+	return out
+}
+
 var _ Shaper = (*HarfbuzzShaper)(nil)
 
 // Shaper describes the signature of a font shaping operation.
@@ -55,8 +108,185 @@ func clamp(val, low, high int) int {
 	return val
 }
 
+// IsSimple returns true if the run does not require complex shaping, that is
+// if it only contains printable ASCII characters supported by [Input.Face],
+// is written horizontally from left to right, with a Latin (or Common) script, no font features,
+// and if the face has no AAT layout or legacy kerning tables (kern, kerx, morx, trak).
+// In particular, [RequiresComplexShaping] returns false for simple runs.
+//
+// The 'GSUB' and 'GPOS' tables are not inspected : [HarfbuzzShaper.Shape] uses a faster path,
+// directly using the 'cmap' and the glyph advances, for the simple runs whose shaping plan
+// selects no lookup, that is when the font implements none of the features enabled by default
+// for the script, language and direction of the run (see [PlannedFeatures]).
+// This fast path yields the same output as the full shaping.
+func (input Input) IsSimple() bool {
+	return input.isSimpleRun() && input.hasNominalGlyphs()
+}
+
+// isSimpleRun performs the checks of [Input.IsSimple] which do not need a glyph lookup.
+func (input Input) isSimpleRun() bool {
+	if input.Face == nil || RequiresComplexShaping(input) ||
+		(input.Script != language.Latin && input.Script != language.Common) {
+		return false
+	}
+	ft := input.Face.Font
+	if len(ft.Kern) != 0 || len(ft.Kerx) != 0 || len(ft.Morx) != 0 || !ft.Trak.IsEmpty() {
+		return false
+	}
+	// control characters have been rejected by RequiresComplexShaping
+	for _, r := range input.Text[input.RunStart:input.RunEnd] {
+		if r > 0x7E {
+			return false
+		}
+	}
+	return true
+}
+
+// hasNominalGlyphs returns true if [input.Face] supports every rune of the run.
+func (input Input) hasNominalGlyphs() bool {
+	for _, r := range input.Text[input.RunStart:input.RunEnd] {
+		if _, ok := input.Face.NominalGlyph(r); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// RequiresComplexShaping returns false if the run of [input] only uses features
+// of a simple script, whose shaping does not depend on the context : it is written
+// horizontally from left to right, with a single script among Latin, Cyrillic and Greek
+// (Common runes like digits and punctuation are also accepted), with no combining marks,
+// no format characters (like ZWJ, ZWNJ or bidi controls) and no [Input.FontFeatures].
+// It returns true otherwise, and the heuristic is conservative : a false negative
+// only costs a full shaping.
+//
+// Contrary to [Input.IsSimple], the font is not inspected : default features, like
+// 'liga' or 'kern', may still change the output of [HarfbuzzShaper.Shape] for such runs,
+// so that a mapping of each rune to its nominal glyph is only an approximation.
+// The result only depends on the runes, the direction, the script and the features
+// of the run, so that it may be used as part of a cache key.
+func RequiresComplexShaping(input Input) bool {
+	if input.Direction != di.DirectionLTR || len(input.FontFeatures) != 0 ||
+		!isSimpleScript(input.Script) {
+		return true
+	}
+	if input.RunStart < 0 || input.RunEnd > len(input.Text) || input.RunStart > input.RunEnd {
+		return true
+	}
+	runScript := language.Common
+	for _, r := range input.Text[input.RunStart:input.RunEnd] {
+		var script language.Script
+		if r < 0x80 { // fast path for ASCII
+			if r < 0x20 || r == 0x7F {
+				return true
+			}
+			script = language.Common
+			if ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z') {
+				script = language.Latin
+			}
+		} else {
+			if unicode.In(r, unicode.M, unicode.Cf, unicode.Cc) {
+				return true
+			}
+			script = language.LookupScript(r)
+		}
+		if script == language.Common {
+			continue
+		}
+		if !isSimpleScript(script) || (runScript != language.Common && script != runScript) {
+			return true
+		}
+		runScript = script
+	}
+	return false
+}
+
+// isSimpleScript returns true for the scripts accepted by [RequiresComplexShaping],
+// including the zero value.
+func isSimpleScript(s language.Script) bool {
+	switch s {
+	case 0, language.Common, language.Latin, language.Cyrillic, language.Greek:
+		return true
+	}
+	return false
+}
+
 // Shape turns an input into an output.
+// See [HarfbuzzShaper.Reset] for the lifetime of the returned glyphs.
 func (t *HarfbuzzShaper) Shape(input Input) Output {
+	// the plan is checked first since it is cached
+	if input.isSimpleRun() && !t.hasDefaultLookups(input) && input.hasNominalGlyphs() {
+		return t.shapeSimple(input)
+	}
+	return t.shapeFull(input)
+}
+
+// hasDefaultLookups returns true if the shaping plan of [input] selects
+// 'GSUB' or 'GPOS' lookups, that is if the font implements one of the features
+// enabled by default for the script, language and direction of the run.
+// [input] is assumed to verify [Input.isSimpleRun].
+//
+// The result is cached, since computing a plan is about as expensive as
+// shaping a short run.
+func (t *HarfbuzzShaper) hasDefaultLookups(input Input) bool {
+	font := t.font(input)
+	key := simplePlanKey{font: input.Face.Font, script: input.Script, language: input.Language}
+	coords := font.Face().Coords()
+	if plan, ok := t.simplePlans[key]; ok && sameCoords(plan.coords, coords) {
+		return plan.hasLookups
+	}
+
+	if t.buf == nil {
+		t.buf = harfbuzz.NewBuffer()
+	} else {
+		t.buf.Clear()
+	}
+	t.buf.Props.Direction = input.Direction.Harfbuzz()
+	t.buf.Props.Language = input.Language
+	t.buf.Props.Script = input.Script
+	hasLookups := false
+	for _, tag := range t.buf.PlannedFeatures(font, nil) {
+		switch tag {
+		case fracTag, numrTag, dnomTag:
+			// only applied around U+2044 FRACTION SLASH, which is never in a simple run
+		default:
+			hasLookups = true
+		}
+	}
+
+	if t.simplePlans == nil || len(t.simplePlans) >= maxSimplePlans {
+		t.simplePlans = make(map[simplePlanKey]simplePlan)
+	}
+	t.simplePlans[key] = simplePlan{coords: append([]tables.Coord(nil), coords...), hasLookups: hasLookups}
+	return hasLookups
+}
+
+// maxSimplePlans bounds the size of [HarfbuzzShaper.simplePlans]
+const maxSimplePlans = 4 * defaultFontCacheSize
+
+// simplePlanKey identifies the shaping plans of simple runs,
+// which are always horizontal and left to right.
+type simplePlanKey struct {
+	font     *font.Font
+	script   language.Script
+	language language.Language
+}
+
+// simplePlan caches the result of [HarfbuzzShaper.hasDefaultLookups],
+// which depends on the variation coordinates through the 'GSUB' and 'GPOS' feature variations.
+type simplePlan struct {
+	coords     []tables.Coord
+	hasLookups bool
+}
+
+var (
+	fracTag = ot.NewTag('f', 'r', 'a', 'c')
+	numrTag = ot.NewTag('n', 'u', 'm', 'r')
+	dnomTag = ot.NewTag('d', 'n', 'o', 'm')
+)
+
+// shapeFull uses the whole harfbuzz shaping pipeline.
+func (t *HarfbuzzShaper) shapeFull(input Input) Output {
 	// Prepare to shape the text.
 	if t.buf == nil {
 		t.buf = harfbuzz.NewBuffer()
@@ -84,16 +314,13 @@ func (t *HarfbuzzShaper) Shape(input Input) Output {
 	t.buf.Props.Direction = input.Direction.Harfbuzz()
 	t.buf.Props.Language = input.Language
 	t.buf.Props.Script = input.Script
-
-	// reuse font when possible
-	font, ok := t.fonts.Get(input.Face.Font)
-	if !ok { // create a new font and cache it
-		font = harfbuzz.NewFont(input.Face)
-		t.fonts.Put(input.Face.Font, font)
+	if supportsKashida(input.Script) {
+		// report the kashida positions in [Glyph.Mask],
+		// see [Output.JustificationOpportunities]
+		t.buf.Flags |= harfbuzz.ProduceSafeToInsertTatweel
 	}
-	// adjust the user provided fields
-	font.XScale = int32(input.Size.Ceil()) << scaleShift
-	font.YScale = font.XScale
+
+	font := t.font(input)
 
 	if L := len(input.FontFeatures); cap(t.features) < L {
 		t.features = make([]harfbuzz.Feature, L)
@@ -104,9 +331,12 @@ func (t *HarfbuzzShaper) Shape(input Input) Output {
 		t.features[i] = harfbuzz.Feature{
 			Tag:   f.Tag,
 			Value: f.Value,
-			Start: harfbuzz.FeatureGlobalStart,
+			Start: f.Start, // FeatureGlobalStart is 0
 			End:   harfbuzz.FeatureGlobalEnd,
 		}
+		if f.End != 0 {
+			t.features[i].End = f.End
+		}
 	}
 
 	// Actually use harfbuzz to shape the text.
@@ -114,7 +344,7 @@ func (t *HarfbuzzShaper) Shape(input Input) Output {
 
 	// Convert the shaped text into an Output.
 	isVertical := input.Direction.IsVertical()
-	glyphs := make([]Glyph, len(t.buf.Info))
+	glyphs := t.allocGlyphs(len(t.buf.Info))
 	for i := range glyphs {
 		g := t.buf.Info[i].Glyph
 		glyphs[i] = Glyph{
@@ -122,26 +352,119 @@ func (t *HarfbuzzShaper) Shape(input Input) Output {
 			GlyphID:      g,
 			Mask:         t.buf.Info[i].Mask,
 		}
-		extents, ok := font.GlyphExtents(g)
-		if !ok {
-			// Leave the glyph having zero size if it isn't in the font. There
-			// isn't really anything we can do to recover from such an error.
-			continue
+		glyphs[i].setMetrics(font, t.buf.Pos[i], isVertical)
+	}
+
+	return newOutput(input, font, glyphs, isSideways)
+}
+
+// font returns the (cached) harfbuzz font for [input.Face],
+// scaled to [input.Size], with [input.Variations] applied
+func (t *HarfbuzzShaper) font(input Input) *harfbuzz.Font {
+	var axes []font.Variation
+	if len(input.Variations) != 0 {
+		axes = input.Face.Variations() // nil for non variable fonts
+	}
+	var font *harfbuzz.Font
+	if len(axes) != 0 {
+		font = t.variableFont(input, axes)
+	} else {
+		// reuse font when possible
+		var ok bool
+		font, ok = t.fonts.Get(input.Face.Font)
+		if !ok { // create a new font and cache it
+			font = harfbuzz.NewFont(input.Face)
+			t.fonts.Put(input.Face.Font, font)
 		}
-		glyphs[i].Width = fixed.I(int(extents.Width)) >> scaleShift
-		glyphs[i].Height = fixed.I(int(extents.Height)) >> scaleShift
-		glyphs[i].XBearing = fixed.I(int(extents.XBearing)) >> scaleShift
-		glyphs[i].YBearing = fixed.I(int(extents.YBearing)) >> scaleShift
-		if isVertical {
-			glyphs[i].YAdvance = fixed.I(int(t.buf.Pos[i].YAdvance)) >> scaleShift
-			glyphs[i].Advance = glyphs[i].YAdvance
-		} else {
-			glyphs[i].XAdvance = fixed.I(int(t.buf.Pos[i].XAdvance)) >> scaleShift
-			glyphs[i].Advance = glyphs[i].XAdvance
+	}
+	// adjust the user provided fields
+	font.XScale = int32(input.Size.Ceil()) << scaleShift
+	font.YScale = font.XScale
+	return font
+}
+
+// variableFont returns a font owned by the shaper, using the coordinates
+// of [input.Face], given by [axes], updated by [input.Variations].
+func (t *HarfbuzzShaper) variableFont(input Input, axes []font.Variation) *harfbuzz.Font {
+	if t.varFont == nil || t.varFont.Face().Font != input.Face.Font {
+		t.varFont = harfbuzz.NewFont(font.NewFace(input.Face.Font))
+	}
+	coords := make([]float32, len(axes))
+	for i, axis := range axes {
+		coords[i] = axis.Value
+		// the last setting wins; tags not defined by the font are ignored
+		for _, variation := range input.Variations {
+			if variation.Tag == axis.Tag {
+				coords[i] = variation.Value
+			}
 		}
-		glyphs[i].XOffset = fixed.I(int(t.buf.Pos[i].XOffset)) >> scaleShift
-		glyphs[i].YOffset = fixed.I(int(t.buf.Pos[i].YOffset)) >> scaleShift
 	}
+	// values are clamped to the axis range during normalization;
+	// keep the caches of the face when the instance is the same
+	face := t.varFont.Face()
+	if normalized := face.NormalizeVariations(coords); !sameCoords(face.Coords(), normalized) {
+		face.SetCoords(normalized)
+	}
+	return t.varFont
+}
+
+func sameCoords(c1, c2 []tables.Coord) bool {
+	if len(c1) != len(c2) {
+		return false
+	}
+	for i, c := range c1 {
+		if c != c2[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// shapeSimple is a fast path for runs verifying [Input.IsSimple],
+// where each rune is mapped to its nominal glyph.
+func (t *HarfbuzzShaper) shapeSimple(input Input) Output {
+	font := t.font(input)
+
+	glyphs := t.allocGlyphs(input.RunEnd - input.RunStart)
+	for i := range glyphs {
+		cluster := input.RunStart + i
+		g, _ := input.Face.NominalGlyph(input.Text[cluster])
+		glyphs[i] = Glyph{
+			ClusterIndex: cluster,
+			GlyphID:      g,
+		}
+		glyphs[i].setMetrics(font, harfbuzz.GlyphPosition{XAdvance: font.GlyphHAdvance(g)}, false)
+	}
+
+	return newOutput(input, font, glyphs, false)
+}
+
+// setMetrics converts and applies the extents and the position of the glyph,
+// as computed by harfbuzz.
+func (g *Glyph) setMetrics(font *harfbuzz.Font, pos harfbuzz.GlyphPosition, isVertical bool) {
+	extents, ok := font.GlyphExtents(g.GlyphID)
+	if !ok {
+		// Leave the glyph having zero size if it isn't in the font. There
+		// isn't really anything we can do to recover from such an error.
+		return
+	}
+	g.Width = fixed.I(int(extents.Width)) >> scaleShift
+	g.Height = fixed.I(int(extents.Height)) >> scaleShift
+	g.XBearing = fixed.I(int(extents.XBearing)) >> scaleShift
+	g.YBearing = fixed.I(int(extents.YBearing)) >> scaleShift
+	if isVertical {
+		g.YAdvance = fixed.I(int(pos.YAdvance)) >> scaleShift
+		g.Advance = g.YAdvance
+	} else {
+		g.XAdvance = fixed.I(int(pos.XAdvance)) >> scaleShift
+		g.Advance = g.XAdvance
+	}
+	g.XOffset = fixed.I(int(pos.XOffset)) >> scaleShift
+	g.YOffset = fixed.I(int(pos.YOffset)) >> scaleShift
+}
+
+// newOutput wraps the shaped [glyphs] and computes the run metrics.
+func newOutput(input Input, font *harfbuzz.Font, glyphs []Glyph, isSideways bool) Output {
 	countClusters(glyphs, input.RunEnd, input.Direction.Progression())
 	out := Output{
 		Glyphs:    glyphs,
@@ -165,6 +488,15 @@ func (t *HarfbuzzShaper) Shape(input Input) Output {
 		Descent: fixed.I(int(fontExtents.Descender)) >> scaleShift,
 		Gap:     fixed.I(int(fontExtents.LineGap)) >> scaleShift,
 	}
+	if input.LetterSpacing != 0 {
+		// no spacing is added at the boundaries of the text,
+		// which are swapped for RTL runs, since glyphs are in visual order
+		isStartRun, isEndRun := input.RunStart == 0, input.RunEnd == len(input.Text)
+		if out.Direction.Progression() == di.TowardTopLeft {
+			isStartRun, isEndRun = isEndRun, isStartRun
+		}
+		out.AddLetterSpacing(input.LetterSpacing, isStartRun, isEndRun)
+	}
 	out.RecalculateAll()
 	return out
 }
@@ -209,3 +541,71 @@ func countClusters(glyphs []Glyph, textLen int, dir di.Progression) {
 		glyphs[i].RuneCount = runesInCluster
 	}
 }
+
+// ShapeParams configures the text shaped by [Shape].
+type ShapeParams struct {
+	// Direction is the direction of the context the text is used in,
+	// as for [Segmenter.Split].
+	Direction di.Direction
+	// Size is the requested size of the font, see [Input.Size].
+	Size fixed.Int26_6
+	// Language optionally sets the language of the text.
+	Language language.Language
+
+	// FontFeatures, Variations and LetterSpacing are copied to each run,
+	// see [Input] for their meaning.
+	FontFeatures  []FontFeature
+	Variations    []FontVariation
+	LetterSpacing fixed.Int26_6
+
+	// Options configures the segmentation.
+	Options SegmenterOptions
+}
+
+// ShapedRun is a run returned by [Shape].
+type ShapedRun struct {
+	// Input is the segmented run, which has been shaped.
+	// It notably provides the script and language resolved for the run.
+	Input Input
+	// Output contains the glyphs, their advances and cluster information.
+	Output Output
+}
+
+// shapeContext stores the buffers used by [Shape]
+type shapeContext struct {
+	segmenter Segmenter
+	shaper    HarfbuzzShaper
+}
+
+var shapeContexts = sync.Pool{New: func() any { return new(shapeContext) }}
+
+// Shape is a convenience function which segments [text] with [Segmenter.Split],
+// using [faces] to select the fonts, and shapes each resulting run with a [HarfbuzzShaper].
+// The runs are returned in logical order.
+//
+// The segmenter and the shaper are recycled between calls, so that their
+// internal buffers (and font caches) are reused.
+// Applications requiring more control (like line wrapping or
+// reusing the glyph storage) should use [Segmenter] and [HarfbuzzShaper] directly.
+func Shape(text []rune, faces Fontmap, params ShapeParams) []ShapedRun {
+	ctx := shapeContexts.Get().(*shapeContext)
+	defer shapeContexts.Put(ctx)
+
+	ctx.segmenter.SetOptions(params.Options)
+	runs := ctx.segmenter.Split(Input{
+		Text:          text,
+		RunEnd:        len(text),
+		Direction:     params.Direction,
+		FontFeatures:  params.FontFeatures,
+		Variations:    params.Variations,
+		Size:          params.Size,
+		Language:      params.Language,
+		LetterSpacing: params.LetterSpacing,
+	}, faces)
+
+	out := make([]ShapedRun, len(runs))
+	for i, run := range runs {
+		out[i] = ShapedRun{Input: run, Output: ctx.shaper.Shape(run)}
+	}
+	return out
+}
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 
 	hd "github.com/go-text/typesetting-utils/harfbuzz"
@@ -243,6 +245,156 @@ func BenchmarkShaping(b *testing.B) {
 	}
 }
 
+// BenchmarkShapingReuse shapes a paragraph run by run, either allocating
+// the glyphs of each output, or reusing them with [HarfbuzzShaper.Reset].
+func BenchmarkShapingReuse(b *testing.B) {
+	const runLength = 40
+	for _, langInfo := range benchLangs {
+		var inputs []Input
+		for start := 0; start < len(langInfo.text); start += runLength {
+			end := start + runLength
+			if end > len(langInfo.text) {
+				end = len(langInfo.text)
+			}
+			inputs = append(inputs, Input{
+				Text:      langInfo.text,
+				RunStart:  start,
+				RunEnd:    end,
+				Direction: langInfo.dir,
+				Face:      langInfo.face,
+				Size:      16 * 72,
+				Script:    langInfo.script,
+				Language:  langInfo.lang,
+			})
+		}
+		for _, reuse := range []bool{false, true} {
+			b.Run(fmt.Sprintf("%s-reuse=%v", langInfo.name, reuse), func(b *testing.B) {
+				var shaper HarfbuzzShaper
+				b.ReportAllocs()
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					if reuse {
+						shaper.Reset()
+					}
+					for _, input := range inputs {
+						_ = shaper.Shape(input)
+					}
+				}
+			})
+		}
+	}
+}
+
+func BenchmarkShapingVariations(b *testing.B) {
+	data, err := os.ReadFile("../font/testdata/Selawik-VF-Subset.ttf")
+	tu.AssertNoErr(b, err)
+	face, err := font.ParseTTF(bytes.NewReader(data))
+	tu.AssertNoErr(b, err)
+	wght := ot.MustNewTag("wght")
+
+	text := []rune("The quick brown fox jumps over the lazy dog")
+	input := Input{
+		Text:      text,
+		RunEnd:    len(text),
+		Direction: di.DirectionLTR,
+		Size:      16 * 72,
+		Script:    language.Latin,
+		Language:  language.NewLanguage("EN"),
+	}
+	b.Run("per-call", func(b *testing.B) {
+		input := input
+		input.Face = face
+		input.Variations = []FontVariation{{Tag: wght, Value: 700}}
+		var shaper HarfbuzzShaper
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < 1000; j++ {
+				_ = shaper.Shape(input)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		input := input
+		input.Face = face.WithVariations([]font.Variation{{Tag: wght, Value: 700}})
+		var shaper HarfbuzzShaper
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			for j := 0; j < 1000; j++ {
+				_ = shaper.Shape(input)
+			}
+		}
+	})
+}
+
+func TestShapeConvenience(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	fm := fixedFontmap{latinFont, arabicFont}
+
+	text := []rune("Hello \u0645\u0631\u062D\u0628\u0627 world")
+	params := ShapeParams{Direction: di.DirectionLTR, Size: fixed.I(16)}
+	runs := Shape(text, fm, params)
+	tu.Assert(t, len(runs) == 3)
+
+	var (
+		segmenter Segmenter
+		shaper    HarfbuzzShaper
+	)
+	inputs := segmenter.Split(Input{Text: text, RunEnd: len(text), Direction: di.DirectionLTR, Size: fixed.I(16)}, fm)
+	tu.Assert(t, len(inputs) == len(runs))
+	start := 0
+	for i, run := range runs {
+		tu.Assert(t, run.Input.RunStart == start)
+		start = run.Input.RunEnd
+		tu.Assert(t, reflect.DeepEqual(run.Input, inputs[i]))
+		tu.Assert(t, reflect.DeepEqual(run.Output, shaper.Shape(inputs[i])))
+		tu.Assert(t, run.Output.Advance > 0)
+	}
+	tu.Assert(t, start == len(text))
+	tu.Assert(t, runs[1].Input.Script == language.Arabic && runs[1].Output.Direction == di.DirectionRTL)
+	tu.Assert(t, runs[1].Input.Face == arabicFont)
+
+	// the returned runs are not affected by the following calls
+	first := runs[0].Output
+	Shape([]rune("other text"), fm, params)
+	tu.Assert(t, reflect.DeepEqual(runs[0].Output, first))
+
+	tu.Assert(t, len(Shape(nil, fm, params)) == 1)
+}
+
+func TestShaperReset(t *testing.T) {
+	face := benchEnFace
+	text := []rune("Hello world, hello shaper")
+	inputs := []Input{
+		{Text: text, RunStart: 0, RunEnd: 12, Direction: di.DirectionLTR, Face: face, Size: fixed.I(16), Script: language.Latin},
+		{Text: text, RunStart: 12, RunEnd: len(text), Direction: di.DirectionLTR, Face: face, Size: fixed.I(16), Script: language.Latin},
+	}
+
+	var reference, shaper HarfbuzzShaper
+	shaper.Reset()
+	outputs := make([]Output, len(inputs))
+	for i, input := range inputs {
+		outputs[i] = shaper.Shape(input)
+	}
+	for i, input := range inputs {
+		tu.Assert(t, reflect.DeepEqual(outputs[i], reference.Shape(input)))
+	}
+
+	// appending to an output does not corrupt the next one
+	expected := reference.Shape(inputs[1])
+	_ = append(outputs[0].Glyphs, Glyph{GlyphID: 0xFFFF})
+	tu.Assert(t, reflect.DeepEqual(outputs[1], expected))
+
+	// the storage is recycled (the last output is always in the current storage)
+	first := &outputs[1].Glyphs[0]
+	shaper.Reset()
+	out := shaper.Shape(inputs[1])
+	tu.Assert(t, &out.Glyphs[0] == first)
+	tu.Assert(t, reflect.DeepEqual(out, expected))
+}
+
 func BenchmarkFontLoad(b *testing.B) {
 	arabicBytes, err := os.ReadFile("../font/testdata/Amiri-Regular.ttf")
 	if err != nil {
@@ -494,6 +646,282 @@ func TestFeatures(t *testing.T) {
 	tu.Assert(t, len(out.Glyphs) == 1)
 }
 
+func TestFeaturesRange(t *testing.T) {
+	face := loadOpentypeFont(t, "../font/testdata/UbuntuMono-R.ttf")
+	textInput := []rune("1/2 1/2 1/2")
+	input := Input{
+		Text:      textInput,
+		RunStart:  0,
+		RunEnd:    len(textInput),
+		Direction: di.DirectionLTR,
+		Face:      face,
+		Size:      16 * 72,
+		Script:    language.Latin,
+		Language:  language.NewLanguage("EN"),
+	}
+	frac := ot.MustNewTag("frac")
+	shaper := HarfbuzzShaper{}
+	for _, test := range []struct {
+		features []FontFeature
+		glyphs   int
+	}{
+		{nil, 11},
+		{[]FontFeature{{Tag: frac, Value: 1}}, 5},
+		// only the second fraction
+		{[]FontFeature{{Tag: frac, Value: 1, Start: 4, End: 7}}, 9},
+		// from the second fraction to the end
+		{[]FontFeature{{Tag: frac, Value: 1, Start: 4}}, 7},
+		// up to the second fraction
+		{[]FontFeature{{Tag: frac, Value: 1, End: 7}}, 7},
+		// the last setting wins
+		{[]FontFeature{{Tag: frac, Value: 1}, {Tag: frac, Value: 0, Start: 4, End: 7}}, 7},
+	} {
+		input.FontFeatures = test.features
+		out := shaper.Shape(input)
+		tu.AssertC(t, len(out.Glyphs) == test.glyphs, fmt.Sprint(test.features))
+	}
+
+	// the range uses the indices of the text, not of the run
+	input.RunStart = 4
+	input.FontFeatures = []FontFeature{{Tag: frac, Value: 1, Start: 8, End: 11}}
+	out := shaper.Shape(input)
+	tu.Assert(t, len(out.Glyphs) == 5)
+}
+
+func TestParseFontFeatures(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected []FontFeature
+	}{
+		{"", nil},
+		{"normal", nil},
+		{`"liga" 1, "smcp", 'frac' 0`, []FontFeature{{Tag: LigaturesTag, Value: 1}, {Tag: SmallCapsTag, Value: 1}, {Tag: FractionsTag, Value: 0}}},
+		{` "kern" off , "onum" on `, []FontFeature{{Tag: KerningTag, Value: 0}, {Tag: OldstyleFiguresTag, Value: 1}}},
+		{`"salt" 3`, []FontFeature{{Tag: StylisticAlternatesTag, Value: 3}}},
+		{`"cv1"`, []FontFeature{{Tag: ot.MustNewTag("cv1 "), Value: 1}}},
+	} {
+		got, err := ParseFontFeatures(test.input)
+		tu.AssertNoErr(t, err)
+		tu.AssertC(t, reflect.DeepEqual(got, test.expected), test.input)
+	}
+
+	for _, input := range []string{
+		`liga`,
+		`"liga`,
+		`"ligatures" 1`,
+		`"" 1`,
+		`"liga" -1`,
+		`"liga" yes`,
+		`"liga" 1,`,
+		"\"li\tg\"",
+	} {
+		_, err := ParseFontFeatures(input)
+		tu.AssertC(t, err != nil, input)
+	}
+
+	// the error reports the offending token
+	_, err := ParseFontFeatures(`"liga", "ligatures"`)
+	tu.Assert(t, strings.Contains(err.Error(), "ligatures"))
+}
+
+func TestParseVariations(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		expected []FontVariation
+	}{
+		{"", nil},
+		{"normal", nil},
+		{`"wght" 650, 'wdth' 80.5`, []FontVariation{{Tag: ot.MustNewTag("wght"), Value: 650}, {Tag: ot.MustNewTag("wdth"), Value: 80.5}}},
+		{` "slnt" -8 `, []FontVariation{{Tag: ot.MustNewTag("slnt"), Value: -8}}},
+		{`"XHGT" .7`, []FontVariation{{Tag: ot.MustNewTag("XHGT"), Value: 0.7}}},
+	} {
+		got, err := ParseVariations(test.input)
+		tu.AssertNoErr(t, err)
+		tu.AssertC(t, reflect.DeepEqual(got, test.expected), test.input)
+	}
+
+	for _, input := range []string{
+		`wght 400`,
+		`"wght"`,
+		`"wght" bold`,
+		`"weight" 400`,
+		`"wght" 400,`,
+		`"wght" NaN`,
+	} {
+		_, err := ParseVariations(input)
+		tu.AssertC(t, err != nil, input)
+	}
+
+	// the error reports the offending token
+	_, err := ParseVariations(`"wght" 400, "wdth" narrow`)
+	tu.Assert(t, strings.Contains(err.Error(), "narrow"))
+}
+
+func TestShapeVariations(t *testing.T) {
+	face := loadOpentypeFont(t, "../font/testdata/Selawik-VF-Subset.ttf")
+	wght := ot.MustNewTag("wght")
+
+	// the maximum of the axis
+	maxFace := loadOpentypeFont(t, "../font/testdata/Selawik-VF-Subset.ttf")
+	maxFace.SetVariations([]font.Variation{{Tag: wght, Value: 10000}})
+	maxWeight := maxFace.Variations()[0].Value
+
+	text := []rune("abc")
+	input := Input{
+		Text:      text,
+		RunEnd:    len(text),
+		Direction: di.DirectionLTR,
+		Face:      face,
+		Size:      16 * 72,
+		Script:    language.Latin,
+		Language:  language.NewLanguage("EN"),
+	}
+	var shaper HarfbuzzShaper
+	shape := func(variations ...FontVariation) fixed.Int26_6 {
+		input.Variations = variations
+		return shaper.Shape(input).Advance
+	}
+
+	regular := shape()
+	bold := shape(FontVariation{Tag: wght, Value: maxWeight})
+	tu.Assert(t, bold != regular)
+	// the input face is not modified
+	tu.Assert(t, len(face.Coords()) == 0)
+	tu.Assert(t, shape() == regular)
+
+	// values are clamped
+	tu.Assert(t, shape(FontVariation{Tag: wght, Value: 10 * maxWeight}) == bold)
+	// the last setting wins
+	tu.Assert(t, shape(FontVariation{Tag: wght, Value: 1}, FontVariation{Tag: wght, Value: maxWeight}) == bold)
+	// unknown tags are ignored
+	tu.Assert(t, shape(FontVariation{Tag: ot.MustNewTag("wdth"), Value: 50}) == regular)
+
+	// same as setting the coordinates on the face
+	input.Face = maxFace
+	tu.Assert(t, shape() == bold)
+}
+
+func TestShapeVariationsAdvances(t *testing.T) {
+	face := loadOpentypeFont(t, "../font/testdata/Selawik-VF-Subset.ttf")
+	wght := ot.MustNewTag("wght")
+
+	text := []rune("abcH")
+	input := Input{
+		Text:      text,
+		RunEnd:    len(text),
+		Direction: di.DirectionLTR,
+		Face:      face,
+		Size:      16 * 72,
+		Script:    language.Latin,
+		Language:  language.NewLanguage("EN"),
+	}
+	var shaper HarfbuzzShaper
+	input.Variations = []FontVariation{{Tag: wght, Value: 100}}
+	light := shaper.Shape(input)
+	input.Variations = []FontVariation{{Tag: wght, Value: 900}}
+	bold := shaper.Shape(input)
+
+	tu.Assert(t, light.Advance < bold.Advance)
+	for i, g := range light.Glyphs {
+		tu.Assert(t, g.GlyphID == bold.Glyphs[i].GlyphID)
+		tu.Assert(t, g.Advance < bold.Glyphs[i].Advance)
+	}
+
+	// the advances match the ones of the face, which uses the 'HVAR' table
+	face.SetVariations([]font.Variation{{Tag: wght, Value: 900}})
+	for _, g := range bold.Glyphs {
+		expected := fixed.Int26_6(face.HAdvanceF(g.GlyphID, float32(input.Size)))
+		tu.Assert(t, absFixed(g.Advance-expected) <= 1)
+	}
+}
+
+func TestAppliedFeatures(t *testing.T) {
+	roboto := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	amiri := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+
+	text := []rune("office AV")
+	input := Input{
+		Text:      text,
+		RunEnd:    len(text),
+		Direction: di.DirectionLTR,
+		Face:      roboto,
+		Size:      16 * 72,
+		Script:    language.Latin,
+		Language:  language.NewLanguage("EN"),
+	}
+	tags := AppliedFeatures(input)
+	tu.Assert(t, reflect.DeepEqual(tags, []ot.Tag{ot.MustNewTag("kern"), ot.MustNewTag("liga")}))
+
+	input.FontFeatures = []FontFeature{{Tag: ot.MustNewTag("liga"), Value: 0}}
+	tags = AppliedFeatures(input)
+	tu.Assert(t, reflect.DeepEqual(tags, []ot.Tag{ot.MustNewTag("kern")}))
+	tu.Assert(t, len(input.FontFeatures) == 1) // input is not modified
+
+	text = []rune("سلام عليكم")
+	input = Input{
+		Text:      text,
+		RunEnd:    len(text),
+		Direction: di.DirectionRTL,
+		Face:      amiri,
+		Size:      16 * 72,
+		Script:    language.Arabic,
+		Language:  language.NewLanguage("AR"),
+	}
+	tags = AppliedFeatures(input)
+	applied := map[ot.Tag]bool{}
+	for i, tag := range tags {
+		applied[tag] = true
+		tu.Assert(t, i == 0 || tags[i-1] < tag) // sorted
+	}
+	tu.Assert(t, applied[ot.MustNewTag("init")] && applied[ot.MustNewTag("medi")] && applied[ot.MustNewTag("fina")])
+}
+
+func TestDisableDefaultFeatures(t *testing.T) {
+	roboto := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+
+	text := []rune("fi ffi fl")
+	input := Input{
+		Text:      text,
+		RunEnd:    len(text),
+		Direction: di.DirectionLTR,
+		Face:      roboto,
+		Size:      16 * 72,
+		Script:    language.Latin,
+		Language:  language.NewLanguage("EN"),
+	}
+	var shaper HarfbuzzShaper
+	hasFeature := func(tags []ot.Tag, tag ot.Tag) bool {
+		for _, t := range tags {
+			if t == tag {
+				return true
+			}
+		}
+		return false
+	}
+
+	// ligatures are enabled by default
+	tu.Assert(t, len(shaper.Shape(input).Glyphs) < len(text))
+	tu.Assert(t, hasFeature(PlannedFeatures(input), LigaturesTag))
+
+	// a zero value suppresses them
+	input.FontFeatures = []FontFeature{{Tag: LigaturesTag, Value: 0}}
+	tu.Assert(t, len(shaper.Shape(input).Glyphs) == len(text))
+	tu.Assert(t, !hasFeature(PlannedFeatures(input), LigaturesTag))
+	tu.Assert(t, hasFeature(PlannedFeatures(input), KerningTag))
+
+	// the last setting wins
+	input.FontFeatures = []FontFeature{{Tag: LigaturesTag, Value: 0}, {Tag: LigaturesTag, Value: 1}}
+	tu.Assert(t, len(shaper.Shape(input).Glyphs) < len(text))
+	input.FontFeatures = []FontFeature{{Tag: LigaturesTag, Value: 1}, {Tag: LigaturesTag, Value: 0}}
+	tu.Assert(t, len(shaper.Shape(input).Glyphs) == len(text))
+
+	// restricted to the second word : ligatures are still planned
+	input.FontFeatures = []FontFeature{{Tag: LigaturesTag, Value: 0, Start: 3, End: 6}}
+	out := shaper.Shape(input)
+	tu.Assert(t, len(out.Glyphs) == len(text)-2)
+	tu.Assert(t, hasFeature(PlannedFeatures(input), LigaturesTag))
+}
+
 func TestShapeVertical(t *testing.T) {
 	// consistency check on the internal axis switch
 	// for sideways vertical text
@@ -725,3 +1153,178 @@ func TestShapingLanguage(t *testing.T) {
 	// without the language information, regular space are used
 	tu.Assert(t, output.Glyphs[3].GlyphID == regularSpace)
 }
+
+func TestRequiresComplexShaping(t *testing.T) {
+	for _, test := range []struct {
+		text     string
+		dir      di.Direction
+		script   language.Script
+		expected bool
+	}{
+		{"", di.DirectionLTR, language.Latin, false},
+		{"Hello, world ! 123", di.DirectionLTR, language.Latin, false},
+		{"Œuvre à café", di.DirectionLTR, language.Latin, false},
+		{"Привет, мир", di.DirectionLTR, language.Cyrillic, false},
+		{"Γειά σου", di.DirectionLTR, language.Greek, false},
+		{"12 + 3", di.DirectionLTR, 0, false},
+		// combining marks
+		{"cafe\u0301", di.DirectionLTR, language.Latin, true},
+		// ZWJ and ZWNJ
+		{"a\u200db", di.DirectionLTR, language.Latin, true},
+		{"a\u200cb", di.DirectionLTR, language.Latin, true},
+		// control characters
+		{"Hello\n", di.DirectionLTR, language.Latin, true},
+		// mixed scripts
+		{"Hello мир", di.DirectionLTR, language.Latin, true},
+		// complex scripts
+		{"سماء", di.DirectionLTR, language.Arabic, true},
+		{"नमस्ते", di.DirectionLTR, language.Devanagari, true},
+		{"नमस्ते", di.DirectionLTR, language.Latin, true},
+		{"abc", di.DirectionLTR, language.Arabic, true},
+		// direction
+		{"abc", di.DirectionRTL, language.Latin, true},
+		{"abc", di.DirectionTTB, language.Latin, true},
+	} {
+		text := []rune(test.text)
+		input := Input{Text: text, RunEnd: len(text), Direction: test.dir, Script: test.script}
+		tu.AssertC(t, RequiresComplexShaping(input) == test.expected, test.text)
+	}
+
+	// only the run is inspected
+	text := []rune("سماء abc")
+	tu.Assert(t, !RequiresComplexShaping(Input{Text: text, RunStart: 5, RunEnd: 8, Direction: di.DirectionLTR}))
+
+	text = []rune("ffi")
+	input := Input{Text: text, RunEnd: len(text), Direction: di.DirectionLTR}
+	tu.Assert(t, !RequiresComplexShaping(input))
+	input.FontFeatures = []FontFeature{{Tag: LigaturesTag, Value: 0}}
+	tu.Assert(t, RequiresComplexShaping(input))
+}
+
+func TestShapeSimple(t *testing.T) {
+	face := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	// remove the layout tables
+	ft := *face.Font
+	ft.GSUB, ft.GPOS, ft.Kern = font.GSUB{}, font.GPOS{}, nil
+	simpleFace := font.NewFace(&ft)
+
+	input := Input{
+		Direction: di.DirectionLTR,
+		Face:      simpleFace,
+		Size:      fixed.I(14),
+		Script:    language.Latin,
+		Language:  language.NewLanguage("en"),
+	}
+	for _, text := range []string{
+		"",
+		"Hello, world !",
+		"AVATAR Wo ffi fl 0123456789",
+		"!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~",
+	} {
+		input.Text = []rune(text)
+		input.RunStart, input.RunEnd = 0, len(input.Text)
+		tu.Assert(t, input.IsSimple())
+
+		var shaper HarfbuzzShaper
+		got, exp := shaper.Shape(input), shaper.shapeFull(input)
+		tu.AssertC(t, reflect.DeepEqual(got, exp), text)
+
+		// with a sub run
+		if len(input.Text) > 4 {
+			input.RunStart, input.RunEnd = 2, len(input.Text)-2
+			got, exp = shaper.Shape(input), shaper.shapeFull(input)
+			tu.AssertC(t, reflect.DeepEqual(got, exp), text)
+		}
+	}
+
+	input.Text = []rune("Hello, world !")
+	input.RunStart, input.RunEnd = 0, len(input.Text)
+	for _, modify := range []func(in Input) Input{
+		func(in Input) Input { in.Text = []rune("Héllo"); in.RunEnd = 5; return in },
+		func(in Input) Input { in.Text = []rune("Hello\n"); in.RunEnd = 6; return in },
+		func(in Input) Input { in.Direction = di.DirectionRTL; return in },
+		func(in Input) Input { in.Direction = di.DirectionTTB; return in },
+		func(in Input) Input { in.Script = language.Arabic; return in },
+		func(in Input) Input { in.FontFeatures = []FontFeature{{Tag: ot.MustNewTag("liga")}}; return in },
+		func(in Input) Input { in.RunStart, in.RunEnd = 4, 2; return in },
+	} {
+		tu.Assert(t, !modify(input).IsSimple())
+	}
+}
+
+func TestShapeSimpleLayout(t *testing.T) {
+	// UbuntuMono only has 'locl' for Turkish among the default Latin features
+	face := loadOpentypeFont(t, "../font/testdata/UbuntuMono-R.ttf")
+	text := []rune("Hello, world ! fi ffi AVATAR 0123456789")
+	input := Input{
+		Text:      text,
+		RunEnd:    len(text),
+		Direction: di.DirectionLTR,
+		Face:      face,
+		Size:      fixed.I(14),
+		Script:    language.Latin,
+		Language:  language.NewLanguage("en"),
+	}
+	tu.Assert(t, input.IsSimple())
+
+	var shaper HarfbuzzShaper
+	tu.Assert(t, !shaper.hasDefaultLookups(input))
+	for _, run := range [][2]int{{0, len(text)}, {0, 5}, {7, 20}, {22, len(text)}, {3, 3}} {
+		input.RunStart, input.RunEnd = run[0], run[1]
+		got, exp := shaper.Shape(input), shaper.shapeFull(input)
+		tu.Assert(t, reflect.DeepEqual(got, exp))
+	}
+
+	input.Language = language.NewLanguage("tr")
+	tu.Assert(t, input.IsSimple() && shaper.hasDefaultLookups(input))
+
+	// Roboto has 'kern' and 'liga'
+	input.Face = loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	input.Language = language.NewLanguage("en")
+	tu.Assert(t, input.IsSimple() && shaper.hasDefaultLookups(input))
+
+	// the plans are cached per font and language
+	tu.Assert(t, len(shaper.simplePlans) == 3)
+	tu.Assert(t, shaper.hasDefaultLookups(input) && len(shaper.simplePlans) == 3)
+}
+
+// BenchmarkShapeSimple compares the fast path of [HarfbuzzShaper.Shape]
+// with the full shaping, and measures the overhead of the detection
+// for a font implementing default features.
+func BenchmarkShapeSimple(b *testing.B) {
+	face := loadOpentypeFont(b, "../font/testdata/Roboto-Regular.ttf")
+	ft := *face.Font
+	ft.GSUB, ft.GPOS, ft.Kern = font.GSUB{}, font.GPOS{}, nil
+	simpleFace := font.NewFace(&ft)
+
+	text := []rune("The quick brown fox jumps over the lazy dog, 0123456789 times.")
+	input := Input{
+		Text:      text,
+		RunEnd:    len(text),
+		Direction: di.DirectionLTR,
+		Size:      fixed.I(14),
+		Script:    language.Latin,
+		Language:  language.NewLanguage("en"),
+	}
+	for _, bench := range []struct {
+		name  string
+		face  *font.Face
+		shape func(*HarfbuzzShaper, Input) Output
+	}{
+		{"fast", simpleFace, (*HarfbuzzShaper).Shape},
+		{"full", simpleFace, (*HarfbuzzShaper).shapeFull},
+		{"layout-detect", face, (*HarfbuzzShaper).Shape},
+		{"layout-full", face, (*HarfbuzzShaper).shapeFull},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			input.Face = bench.face
+			var shaper HarfbuzzShaper
+			shaper.Reset()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				shaper.Reset()
+				bench.shape(&shaper, input)
+			}
+		})
+	}
+}
@@ -1,9 +1,37 @@
 package shaping
 
 import (
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
+	"github.com/go-text/typesetting/harfbuzz"
+	"github.com/go-text/typesetting/language"
 	"golang.org/x/image/math/fixed"
 )
 
+// wordSeparators are the runes considered as word separators,
+// when adding word spacing or justifying text.
+//
+// See also https://www.w3.org/TR/css-text-3/#word-separator
+var wordSeparators = [...]rune{
+	'\u0020',                   // space
+	'\u00A0',                   // no-break space
+	'\u1361',                   // Ethiopic word space
+	'\U00010100', '\U00010101', // Aegean word separators
+	'\U0001039F', // Ugaritic word divider
+	'\U0001091F', // Phoenician word separator
+}
+
+func isWordSeparator(r rune) bool {
+	for _, sep := range wordSeparators {
+		if r == sep {
+			return true
+		}
+	}
+	return false
+}
+
 // AddWordSpacing alters the run, adding [additionalSpacing] on each
 // word separator.
 // [text] is the input slice used to create the run.
@@ -21,15 +49,7 @@ func (run *Output) AddWordSpacing(text []rune, additionalSpacing fixed.Int26_6)
 		if !(g.RuneCount == 1 && g.GlyphCount == 1) {
 			continue
 		}
-		r := text[g.ClusterIndex]
-		switch r {
-		case '\u0020', // space
-			'\u00A0',                   // no-break space
-			'\u1361',                   // Ethiopic word space
-			'\U00010100', '\U00010101', // Aegean word separators
-			'\U0001039F', // Ugaritic word divider
-			'\U0001091F': // Phoenician word separator
-		default:
+		if !isWordSeparator(text[g.ClusterIndex]) {
 			continue
 		}
 		// we have a word separator: add space
@@ -129,3 +149,520 @@ func AddSpacing(runs []Output, text []rune, wordSpacing, letterSpacing fixed.Int
 		}
 	}
 }
+
+// JustificationKind classifies the justification opportunities
+// returned by [Output.JustificationOpportunities].
+type JustificationKind uint8
+
+const (
+	// WordSpaceOpportunity marks a word separator glyph, whose advance
+	// may be enlarged, as done by [Output.AddWordSpacing].
+	WordSpaceOpportunity JustificationKind = iota
+	// KashidaOpportunity marks a boundary between two joined clusters,
+	// where a U+0640 TATWEEL may be inserted to elongate the connection.
+	KashidaOpportunity
+)
+
+// JustOpp is a position in a shaped [Output] where space
+// may be added to justify a line.
+type JustOpp struct {
+	// Glyph is an index into [Output.Glyphs].
+	// For [WordSpaceOpportunity], it is the index of the separator glyph.
+	// For [KashidaOpportunity], the opportunity lies between the glyph at
+	// Glyph-1 and the glyph at Glyph, which start a new cluster.
+	Glyph int
+	Kind  JustificationKind
+}
+
+// supportsKashida returns true for the scripts using
+// elongation (kashida) for justification.
+func supportsKashida(s language.Script) bool {
+	switch s {
+	case language.Arabic, language.Syriac, language.Mongolian, language.Nko,
+		language.Mandaic, language.Manichaean, language.Psalter_Pahlavi,
+		language.Adlam, language.Hanifi_Rohingya, language.Sogdian,
+		language.Chorasmian, language.Old_Uyghur:
+		return true
+	default:
+		return false
+	}
+}
+
+// JustificationOpportunities classifies the inter-glyph positions of the run
+// where extra space may be inserted, in visual order.
+//
+// Word separators are detected by comparing the glyphs with the ones
+// mapped by the [Output.Face] cmap, so that the original text is not required.
+//
+// For scripts using elongation (like Arabic), kashida opportunities are
+// reported between clusters joined by the shaper, provided the face
+// lists extender glyphs for the script in its 'JSTF' table, or supports U+0640 TATWEEL.
+// Since the 'JSTF' table does not locate the insertion points, the joining analysis
+// performed during shaping is always used as heuristic.
+//
+// [script] should be the one used when shaping the run.
+func (run *Output) JustificationOpportunities(script language.Script) []JustOpp {
+	if run.Face == nil {
+		return nil
+	}
+
+	// resolve the glyphs used for word separators
+	var separators [len(wordSeparators)]font.GID
+	for i, r := range wordSeparators {
+		gid, ok := run.Face.NominalGlyph(r)
+		if !ok {
+			gid = font.EmptyGlyph // never matched
+		}
+		separators[i] = gid
+	}
+	isSeparator := func(g Glyph) bool {
+		if !(g.RuneCount == 1 && g.GlyphCount == 1) {
+			return false
+		}
+		for _, sep := range separators {
+			if g.GlyphID == sep {
+				return true
+			}
+		}
+		return false
+	}
+
+	withKashida := false
+	if supportsKashida(script) {
+		_, withKashida = run.Face.NominalGlyph('\u0640')
+		withKashida = withKashida || len(jstfExtenders(run.Face, script)) != 0
+	}
+
+	var out []JustOpp
+	for i, g := range run.Glyphs {
+		if isSeparator(g) {
+			out = append(out, JustOpp{Glyph: i, Kind: WordSpaceOpportunity})
+			continue
+		}
+		if !withKashida || i == 0 {
+			continue
+		}
+		prev := run.Glyphs[i-1]
+		if prev.ClusterIndex == g.ClusterIndex || isSeparator(prev) {
+			// not a cluster boundary, or a word boundary
+			continue
+		}
+		// the shaper flags the clusters of each joined pair
+		if prev.Mask&harfbuzz.GlyphSafeToInsertTatweel != 0 && g.Mask&harfbuzz.GlyphSafeToInsertTatweel != 0 {
+			out = append(out, JustOpp{Glyph: i, Kind: KashidaOpportunity})
+		}
+	}
+	return out
+}
+
+// jstfExtenders returns the extender glyphs listed in the 'JSTF' table
+// of [face] for [script], which must verify [supportsKashida].
+func jstfExtenders(face *font.Face, script language.Script) []tables.GlyphID {
+	tag := tables.Tag(script | 0x20000000) // lowercase the ISO 15924 tag
+	if script == language.Nko {
+		tag = ot.NewTag('n', 'k', 'o', ' ')
+	}
+	jstfScript, _ := face.JSTF.Script(tag)
+	return jstfScript.ExtenderGlyph.ExtenderGlyphs
+}
+
+// JustifyMethod is a way of distributing extra space on a line,
+// used by [Justify].
+type JustifyMethod uint8
+
+const (
+	// JustifyWordSpace enlarges the word separators,
+	// as done by [Output.AddWordSpacing].
+	JustifyWordSpace JustifyMethod = iota
+	// JustifyLetterSpace adds space between clusters.
+	JustifyLetterSpace
+	// JustifyKashida inserts U+0640 TATWEEL glyphs between joined clusters,
+	// for the scripts using elongation.
+	JustifyKashida
+)
+
+// DefaultJustifyPriority returns the methods used by [Justify] for [script],
+// in decreasing priority, when [JustifyOptions.Priorities] does not
+// specify them : word separators are enlarged first, then kashidas are
+// inserted for the scripts using elongation (like Arabic), or letter spacing is
+// added for the others.
+func DefaultJustifyPriority(script language.Script) []JustifyMethod {
+	if supportsKashida(script) {
+		return []JustifyMethod{JustifyWordSpace, JustifyKashida}
+	}
+	return []JustifyMethod{JustifyWordSpace, JustifyLetterSpace}
+}
+
+// JustifyOptions provides the context and the settings used by [Justify].
+type JustifyOptions struct {
+	// Face, Size and Direction are the ones used to shape the glyphs,
+	// and are typically copied from the [Output].
+	Face      *font.Face
+	Size      fixed.Int26_6
+	Direction di.Direction
+	// Script is the script used to shape the glyphs.
+	Script language.Script
+
+	// Priorities optionally overrides, per script, the methods used
+	// to distribute the extra width, in decreasing priority.
+	// Scripts not in the map use [DefaultJustifyPriority].
+	Priorities map[language.Script][]JustifyMethod
+
+	// MaxWordSpace and MaxLetterSpace limit the (absolute) space added
+	// to each opportunity before switching to the next method.
+	// Zero means no limit. The last method with opportunities is never limited.
+	MaxWordSpace, MaxLetterSpace fixed.Int26_6
+}
+
+func (opts JustifyOptions) priority() []JustifyMethod {
+	if methods, ok := opts.Priorities[opts.Script]; ok {
+		return methods
+	}
+	return DefaultJustifyPriority(opts.Script)
+}
+
+// Justify distributes [extraWidth] among the [glyphs] of a line (in visual order),
+// trying each method given by [JustifyOptions.Priorities] in turn,
+// and returns the adjusted glyphs. A negative [extraWidth] shrinks the line.
+//
+// Word and letter spacing only change the glyph advances (and offsets).
+// Kashidas are inserted as new TATWEEL glyphs at the positions
+// reported by [Output.JustificationOpportunities], and only when stretching horizontal text.
+// Since they are not stretched, the space which is not a multiple of the
+// TATWEEL advance is left to the following methods.
+// When the face lists extender glyphs for the script in its 'JSTF' table, the first one
+// is inserted instead of the TATWEEL. The 'JSTF' priorities, which enable or disable
+// 'GSUB' and 'GPOS' lookups, are not applied since they require to shape the line again.
+//
+// The glyph identities of the input are never changed, and [glyphs] is not modified.
+// Note that [extraWidth] may only be partially used if the line has not enough opportunities.
+func Justify(glyphs []Glyph, extraWidth fixed.Int26_6, opts JustifyOptions) []Glyph {
+	out := append([]Glyph(nil), glyphs...)
+	if extraWidth == 0 || opts.Face == nil {
+		return out
+	}
+	isVertical := opts.Direction.IsVertical()
+
+	// resolve the opportunities on the input glyphs
+	var (
+		run            = Output{Glyphs: glyphs, Face: opts.Face, Size: opts.Size, Direction: opts.Direction}
+		wordSpaces     []int // separator glyphs
+		letterSpaces   []int // last glyphs of clusters
+		kashidas       []int // glyphs before which a tatweel may be inserted
+		tatweel        Glyph // scaled TATWEEL glyph, if supported
+		tatweelAdvance fixed.Int26_6
+	)
+	for _, opp := range run.JustificationOpportunities(opts.Script) {
+		if opp.Kind == WordSpaceOpportunity {
+			wordSpaces = append(wordSpaces, opp.Glyph)
+		} else {
+			kashidas = append(kashidas, opp.Glyph)
+		}
+	}
+	for i := 0; i < len(glyphs)-1; i++ {
+		if glyphs[i].ClusterIndex != glyphs[i+1].ClusterIndex {
+			letterSpaces = append(letterSpaces, i)
+		}
+	}
+	if extenders := jstfExtenders(opts.Face, opts.Script); len(kashidas) != 0 && len(extenders) != 0 {
+		// use the extender provided by the font, scaling its metrics
+		gid := font.GID(extenders[0])
+		tatweel = Glyph{GlyphID: gid, GlyphCount: 1, RuneCount: 1}
+		tatweel.XAdvance = run.FromFontUnit(opts.Face.HorizontalAdvance(gid))
+		tatweel.Advance = tatweel.XAdvance
+		if extents, ok := opts.Face.GlyphExtents(gid); ok {
+			tatweel.Width = run.FromFontUnit(extents.Width)
+			tatweel.Height = run.FromFontUnit(extents.Height)
+			tatweel.XBearing = run.FromFontUnit(extents.XBearing)
+			tatweel.YBearing = run.FromFontUnit(extents.YBearing)
+		}
+		tatweelAdvance = tatweel.Advance
+	} else if len(kashidas) != 0 {
+		// shape the TATWEEL, since some fonts use a substituted glyph
+		// instead of the nominal one
+		text := []rune{'\u0640'}
+		shaped := (&HarfbuzzShaper{}).Shape(Input{
+			Text: text, RunEnd: len(text),
+			Direction: opts.Direction, Face: opts.Face, Size: opts.Size, Script: opts.Script,
+		})
+		if len(shaped.Glyphs) == 1 {
+			tatweel = shaped.Glyphs[0]
+			tatweelAdvance = tatweel.Advance
+		}
+	}
+	if isVertical || extraWidth < 0 || tatweelAdvance <= 0 {
+		kashidas = nil
+	}
+
+	methods := opts.priority()
+	opportunities := func(method JustifyMethod) []int {
+		switch method {
+		case JustifyWordSpace:
+			return wordSpaces
+		case JustifyLetterSpace:
+			return letterSpaces
+		case JustifyKashida:
+			return kashidas
+		}
+		return nil
+	}
+	isLast := func(index int) bool {
+		for _, method := range methods[index+1:] {
+			if len(opportunities(method)) != 0 {
+				return false
+			}
+		}
+		return true
+	}
+
+	addAdvance := func(g *Glyph, extra fixed.Int26_6, centered bool) {
+		g.Advance += extra
+		if isVertical {
+			g.YAdvance += extra
+			if centered {
+				g.YOffset += extra / 2
+			}
+		} else {
+			g.XAdvance += extra
+			if centered {
+				g.XOffset += extra / 2
+			}
+		}
+	}
+
+	remaining := extraWidth
+	tatweelCounts := make([]int, len(glyphs)) // number of tatweels inserted before each glyph
+	for index, method := range methods {
+		opps := opportunities(method)
+		if len(opps) == 0 || remaining == 0 {
+			continue
+		}
+		budget := remaining
+		var limit fixed.Int26_6
+		switch method {
+		case JustifyWordSpace:
+			limit = opts.MaxWordSpace
+		case JustifyLetterSpace:
+			limit = opts.MaxLetterSpace
+		}
+		if limit := absFixed(limit) * fixed.Int26_6(len(opps)); limit != 0 && !isLast(index) && absFixed(budget) > limit {
+			budget = limit
+			if remaining < 0 {
+				budget = -limit
+			}
+		}
+
+		if method == JustifyKashida {
+			count := int(budget / tatweelAdvance)
+			for i := 0; i < count; i++ {
+				tatweelCounts[kashidas[i%len(kashidas)]]++
+			}
+			remaining -= fixed.Int26_6(count) * tatweelAdvance
+			continue
+		}
+
+		for i, glyphIndex := range opps {
+			addAdvance(&out[glyphIndex], distribute(budget, len(opps), i), method == JustifyWordSpace)
+		}
+		remaining -= budget
+	}
+
+	// insert the kashidas
+	nbTatweels := 0
+	for _, count := range tatweelCounts {
+		nbTatweels += count
+	}
+	if nbTatweels == 0 {
+		return out
+	}
+	withKashidas := make([]Glyph, 0, len(out)+nbTatweels)
+	isRTL := opts.Direction.Progression() == di.TowardTopLeft
+	for i, g := range out {
+		if count := tatweelCounts[i]; count != 0 {
+			// the tatweel belongs to the logically preceding cluster
+			cluster := out[i-1]
+			if isRTL {
+				cluster = g
+			}
+			t := tatweel
+			t.ClusterIndex, t.RuneCount = cluster.ClusterIndex, cluster.RuneCount
+			for ; count > 0; count-- {
+				withKashidas = append(withKashidas, t)
+			}
+		}
+		withKashidas = append(withKashidas, g)
+	}
+	// update the glyph counts of the clusters
+	for start := 0; start < len(withKashidas); {
+		end := start + 1
+		for end < len(withKashidas) && withKashidas[end].ClusterIndex == withKashidas[start].ClusterIndex {
+			end++
+		}
+		for i := start; i < end; i++ {
+			withKashidas[i].GlyphCount = end - start
+		}
+		start = end
+	}
+	return withKashidas
+}
+
+// distribute returns the [i]-th part when splitting [total]
+// in [count] almost equal parts.
+func distribute(total fixed.Int26_6, count, i int) fixed.Int26_6 {
+	q, r := total/fixed.Int26_6(count), total%fixed.Int26_6(count)
+	if fixed.Int26_6(i) < absFixed(r) {
+		if r > 0 {
+			q++
+		} else {
+			q--
+		}
+	}
+	return q
+}
+
+// hangingPunctuation is a punctuation rune which may hang
+// outside the line box, as defined by the CSS 'hanging-punctuation' property.
+//
+// See https://www.w3.org/TR/css-text-4/#hanging-punctuation-property
+type hangingPunctuation struct {
+	r rune
+	// 0 for punctuations used with any script
+	script language.Script
+	// the positions where the punctuation may hang
+	atStart, atEnd bool
+}
+
+var hangingPunctuations = [...]hangingPunctuation{
+	// quotation marks and brackets
+	{'"', 0, true, true},
+	{'\'', 0, true, true},
+	{'(', 0, true, false},
+	{')', 0, false, true},
+	{'[', 0, true, false},
+	{']', 0, false, true},
+	{'\u00AB', 0, true, true},
+	{'\u00BB', 0, true, true},
+	{'\u2018', 0, true, true},
+	{'\u2019', 0, true, true},
+	{'\u201A', 0, true, false},
+	{'\u201C', 0, true, true},
+	{'\u201D', 0, true, true},
+	{'\u201E', 0, true, false},
+	{'\u2039', 0, true, true},
+	{'\u203A', 0, true, true},
+	// stops and commas
+	{',', 0, false, true},
+	{'.', 0, false, true},
+	{'\u060C', language.Arabic, false, true},     // Arabic comma
+	{'\u06D4', language.Arabic, false, true},     // Arabic full stop
+	{'\u0589', language.Armenian, false, true},   // Armenian full stop
+	{'\u0964', language.Devanagari, false, true}, // Devanagari danda
+	{'\u0965', language.Devanagari, false, true}, // Devanagari double danda
+	{'\u1362', language.Ethiopic, false, true},   // Ethiopic full stop
+	{'\u3001', language.Han, false, true},        // ideographic comma
+	{'\u3002', language.Han, false, true},        // ideographic full stop
+	{'\u300C', language.Han, true, false},        // corner brackets
+	{'\u300D', language.Han, false, true},
+	{'\u300E', language.Han, true, false},
+	{'\u300F', language.Han, false, true},
+	{'\uFF08', language.Han, true, false}, // fullwidth parentheses
+	{'\uFF09', language.Han, false, true},
+	{'\uFF0C', language.Han, false, true}, // fullwidth comma
+	{'\uFF0E', language.Han, false, true}, // fullwidth full stop
+	{'\uFF61', language.Han, false, true}, // halfwidth ideographic full stop
+	{'\uFF64', language.Han, false, true}, // halfwidth ideographic comma
+}
+
+// usesHanPunctuation returns true for the scripts
+// using the CJK punctuation.
+func usesHanPunctuation(s language.Script) bool {
+	switch s {
+	case language.Han, language.Hiragana, language.Katakana, language.Bopomofo, language.Hangul:
+		return true
+	default:
+		return false
+	}
+}
+
+// HangInfo describes a punctuation glyph at the boundary of a run,
+// which may hang outside the line box.
+type HangInfo struct {
+	// Glyph is an index into [Output.Glyphs].
+	Glyph int
+	// AtStart is true if the glyph is at the (logical) start of the run,
+	// false if it is at the (logical) end.
+	AtStart bool
+	// Amount is the (absolute) advance of the glyph, that is the
+	// maximum amount by which the glyph may hang into the margin.
+	Amount fixed.Int26_6
+	// InkWidth is the (absolute) extent of the glyph ink along the
+	// run direction, which may be used to only partially hang the glyph
+	// (optical margin alignment).
+	InkWidth fixed.Int26_6
+}
+
+// HangingPunctuation returns the punctuation glyphs of the run which may
+// hang into the margin, that is opening punctuation at the logical start
+// of the run, and closing punctuation, stops and commas at its logical end.
+// The returned slice has at most two elements, and is empty if the run
+// does not start or end with such a punctuation.
+//
+// As for [Output.JustificationOpportunities], punctuation is detected by comparing
+// the glyphs with the ones mapped by the [Output.Face] cmap, so that the original
+// text is not required. Only clusters made of one rune and one glyph are considered.
+//
+// [script] should be the one used when shaping the run : it selects
+// the script specific punctuation (like the Devanagari danda).
+func HangingPunctuation(out Output, script language.Script) []HangInfo {
+	if out.Face == nil || len(out.Glyphs) == 0 {
+		return nil
+	}
+	if usesHanPunctuation(script) {
+		script = language.Han
+	}
+
+	lookup := func(g Glyph) (hangingPunctuation, bool) {
+		if !(g.RuneCount == 1 && g.GlyphCount == 1) {
+			return hangingPunctuation{}, false
+		}
+		for _, hp := range hangingPunctuations {
+			if hp.script != 0 && hp.script != script {
+				continue
+			}
+			if gid, ok := out.Face.NominalGlyph(hp.r); ok && gid == g.GlyphID {
+				return hp, true
+			}
+		}
+		return hangingPunctuation{}, false
+	}
+	newInfo := func(index int, atStart bool) HangInfo {
+		g := out.Glyphs[index]
+		info := HangInfo{Glyph: index, AtStart: atStart, Amount: absFixed(g.Advance), InkWidth: absFixed(g.Width)}
+		if out.Direction.IsVertical() {
+			info.InkWidth = absFixed(g.Height)
+		}
+		return info
+	}
+
+	// glyphs are in visual order
+	first, last := 0, len(out.Glyphs)-1
+	if out.Direction.Progression() == di.TowardTopLeft {
+		first, last = last, first
+	}
+
+	var infos []HangInfo
+	if hp, ok := lookup(out.Glyphs[first]); ok && hp.atStart {
+		infos = append(infos, newInfo(first, true))
+	}
+	if hp, ok := lookup(out.Glyphs[last]); ok && hp.atEnd {
+		infos = append(infos, newInfo(last, false))
+	}
+	return infos
+}
+
+func absFixed(v fixed.Int26_6) fixed.Int26_6 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
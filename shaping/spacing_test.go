@@ -1,10 +1,13 @@
 package shaping
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/go-text/typesetting/di"
 	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	"github.com/go-text/typesetting/font/opentype/tables"
 	"github.com/go-text/typesetting/language"
 	tu "github.com/go-text/typesetting/testutils"
 	"golang.org/x/image/math/fixed"
@@ -87,6 +90,64 @@ func TestOutput_addLetterSpacing(t *testing.T) {
 	}
 }
 
+func TestInputLetterSpacing(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	englishWithLigature := []rune("Hello final")
+	arabic := []rune("تثذرزسشص لمنهويء")
+
+	addSpacing := fixed.I(4)
+	for _, test := range []struct {
+		text           []rune
+		face           *font.Face
+		dir            di.Direction
+		start, end     int
+		isStart, isEnd bool // expected boundaries, in visual order
+	}{
+		{englishWithLigature, latinFont, di.DirectionLTR, 0, 11, true, true},
+		{englishWithLigature, latinFont, di.DirectionLTR, 2, 11, false, true},
+		{englishWithLigature, latinFont, di.DirectionLTR, 0, 5, true, false},
+		{arabic, arabicFont, di.DirectionRTL, 0, 16, true, true},
+		{arabic, arabicFont, di.DirectionRTL, 2, 16, true, false},
+		{arabic, arabicFont, di.DirectionRTL, 0, 5, false, true},
+	} {
+		input := Input{
+			Text:      test.text,
+			RunStart:  test.start,
+			RunEnd:    test.end,
+			Direction: test.dir,
+			Face:      test.face,
+			Size:      fixed.I(16),
+			Script:    language.LookupScript(test.text[0]),
+		}
+		var shaper HarfbuzzShaper
+		expected := shaper.Shape(input)
+		expected.AddLetterSpacing(addSpacing, test.isStart, test.isEnd)
+		expected.RecalculateAll()
+
+		input.LetterSpacing = addSpacing
+		got := shaper.Shape(input)
+		tu.Assert(t, got.Advance == expected.Advance)
+		tu.Assert(t, reflect.DeepEqual(got.Glyphs, expected.Glyphs))
+		tu.Assert(t, got.GlyphBounds == expected.GlyphBounds)
+	}
+
+	// marks are kept with their base
+	input := Input{
+		Text:      []rune("e\u0301e"),
+		RunEnd:    3,
+		Direction: di.DirectionLTR,
+		Face:      latinFont,
+		Size:      fixed.I(16),
+		Script:    language.Latin,
+	}
+	var shaper HarfbuzzShaper
+	withoutSpacing := shaper.Shape(input)
+	input.LetterSpacing = addSpacing
+	withSpacing := shaper.Shape(input)
+	tu.Assert(t, withSpacing.Advance == withoutSpacing.Advance+addSpacing)
+}
+
 func TestCustomSpacing(t *testing.T) {
 	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
 	english := []rune("Hello world ! : the end")
@@ -156,3 +217,179 @@ func TestTrailingSpaces(t *testing.T) {
 		}
 	}
 }
+
+func TestOutput_JustificationOpportunities(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+
+	english := []rune("Hello world ! the end")
+	out := simpleShape(english, latinFont, di.DirectionLTR)
+	opps := out.JustificationOpportunities(language.Latin)
+	tu.Assert(t, len(opps) == 4)
+	for _, opp := range opps {
+		tu.Assert(t, opp.Kind == WordSpaceOpportunity)
+		tu.Assert(t, isWordSeparator(english[out.Glyphs[opp.Glyph].ClusterIndex]))
+	}
+
+	arabic := []rune("تثذرزسشص لمنهويء")
+	out = simpleShape(arabic, arabicFont, di.DirectionRTL)
+	opps = out.JustificationOpportunities(language.Arabic)
+	var spaces, kashidas int
+	for _, opp := range opps {
+		switch opp.Kind {
+		case WordSpaceOpportunity:
+			spaces++
+			tu.Assert(t, arabic[out.Glyphs[opp.Glyph].ClusterIndex] == ' ')
+		case KashidaOpportunity:
+			kashidas++
+			tu.Assert(t, opp.Glyph > 0)
+			tu.Assert(t, out.Glyphs[opp.Glyph].ClusterIndex != out.Glyphs[opp.Glyph-1].ClusterIndex)
+		}
+	}
+	tu.Assert(t, spaces == 1)
+	tu.Assert(t, kashidas > 0)
+
+	// kashida are not used for Latin
+	for _, opp := range out.JustificationOpportunities(language.Latin) {
+		tu.Assert(t, opp.Kind == WordSpaceOpportunity)
+	}
+}
+
+func TestHangingPunctuation(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+
+	out := simpleShape([]rune("\u201CHello.\u201D"), latinFont, di.DirectionLTR)
+	infos := HangingPunctuation(out, language.Latin)
+	tu.Assert(t, len(infos) == 2)
+	tu.Assert(t, infos[0].Glyph == 0 && infos[0].AtStart)
+	tu.Assert(t, infos[1].Glyph == len(out.Glyphs)-1 && !infos[1].AtStart)
+	first := out.Glyphs[0]
+	tu.Assert(t, infos[0].Amount == first.Advance && infos[0].InkWidth == first.Width)
+	tu.Assert(t, infos[0].InkWidth > 0 && infos[0].InkWidth < infos[0].Amount)
+
+	out = simpleShape([]rune("Hello,"), latinFont, di.DirectionLTR)
+	infos = HangingPunctuation(out, language.Latin)
+	tu.Assert(t, len(infos) == 1 && infos[0].Glyph == 5 && !infos[0].AtStart)
+
+	out = simpleShape([]rune("(Hello)"), latinFont, di.DirectionLTR)
+	infos = HangingPunctuation(out, language.Latin)
+	tu.Assert(t, len(infos) == 2)
+
+	// opening punctuation does not hang at the end
+	out = simpleShape([]rune("Hello ("), latinFont, di.DirectionLTR)
+	tu.Assert(t, len(HangingPunctuation(out, language.Latin)) == 0)
+
+	// RTL : glyphs are in visual order
+	out = simpleShape([]rune("\u0633\u0644\u0627\u0645\u060C"), arabicFont, di.DirectionRTL)
+	infos = HangingPunctuation(out, language.Arabic)
+	tu.Assert(t, len(infos) == 1 && infos[0].Glyph == 0 && !infos[0].AtStart)
+	// script specific punctuation
+	tu.Assert(t, len(HangingPunctuation(out, language.Latin)) == 0)
+}
+
+func advances(glyphs []Glyph) (out fixed.Int26_6) {
+	for _, g := range glyphs {
+		out += g.Advance
+	}
+	return out
+}
+
+func TestJustify(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+
+	english := []rune("Hello world ! the end")
+	out := simpleShape(english, latinFont, di.DirectionLTR)
+	opts := JustifyOptions{Face: out.Face, Size: out.Size, Direction: out.Direction, Script: language.Latin}
+	width := advances(out.Glyphs)
+
+	// word spacing only
+	justified := Justify(out.Glyphs, 400, opts)
+	tu.Assert(t, len(justified) == len(out.Glyphs))
+	tu.Assert(t, advances(justified) == width+400)
+	for i, g := range justified {
+		tu.Assert(t, g.GlyphID == out.Glyphs[i].GlyphID)
+		if isWordSeparator(english[g.ClusterIndex]) {
+			tu.Assert(t, g.Advance == out.Glyphs[i].Advance+100)
+		} else {
+			tu.Assert(t, g.Advance == out.Glyphs[i].Advance)
+		}
+	}
+	tu.Assert(t, advances(out.Glyphs) == width) // input is not modified
+
+	// shrinking
+	justified = Justify(out.Glyphs, -41, opts)
+	tu.Assert(t, advances(justified) == width-41)
+
+	// word spacing is limited, then letter spacing is used
+	opts.MaxWordSpace = 10
+	justified = Justify(out.Glyphs, 400, opts)
+	tu.Assert(t, advances(justified) == width+400)
+	tu.Assert(t, justified[0].Advance > out.Glyphs[0].Advance)
+	tu.Assert(t, justified[len(justified)-1].Advance == out.Glyphs[len(justified)-1].Advance)
+
+	// custom priority
+	opts.Priorities = map[language.Script][]JustifyMethod{language.Latin: {JustifyLetterSpace}}
+	justified = Justify(out.Glyphs, 400, opts)
+	tu.Assert(t, advances(justified) == width+400)
+	tu.Assert(t, justified[5].Advance-out.Glyphs[5].Advance == justified[4].Advance-out.Glyphs[4].Advance)
+
+	arabic := []rune("تثذرزسشص لمنهويء")
+	out = simpleShape(arabic, arabicFont, di.DirectionRTL)
+	opts = JustifyOptions{Face: out.Face, Size: out.Size, Direction: out.Direction, Script: language.Arabic, MaxWordSpace: 1}
+	tatweel := simpleShape([]rune("\u0640"), arabicFont, di.DirectionRTL).Glyphs[0].GlyphID // substituted by Amiri
+	width = advances(out.Glyphs)
+
+	justified = Justify(out.Glyphs, 10*out.Size, opts)
+	tu.Assert(t, len(justified) > len(out.Glyphs))
+	tu.Assert(t, advances(justified) <= width+10*out.Size)
+	tu.Assert(t, advances(justified) > width+9*out.Size)
+	j := 0
+	for _, g := range justified {
+		if g.GlyphID == tatweel {
+			continue
+		}
+		tu.Assert(t, g.GlyphID == out.Glyphs[j].GlyphID)
+		j++
+	}
+	tu.Assert(t, j == len(out.Glyphs))
+	// clusters are still consistent
+	for i := 0; i < len(justified); i += justified[i].GlyphCount {
+		for k := i; k < i+justified[i].GlyphCount; k++ {
+			tu.Assert(t, justified[k].ClusterIndex == justified[i].ClusterIndex)
+		}
+	}
+
+	// kashidas are not used when shrinking
+	justified = Justify(out.Glyphs, -out.Size, opts)
+	tu.Assert(t, len(justified) == len(out.Glyphs))
+	tu.Assert(t, advances(justified) == width-out.Size)
+}
+
+func TestJustifyJSTF(t *testing.T) {
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	// any glyph different from the TATWEEL used by default
+	extender, _ := arabicFont.NominalGlyph('-')
+
+	ft := *arabicFont.Font
+	ft.JSTF = tables.JSTF{
+		Records: []tables.TagOffsetRecord{{Tag: ot.MustNewTag("arab")}},
+		Scripts: []tables.JstfScript{{ExtenderGlyph: tables.ExtenderGlyph{ExtenderGlyphs: []tables.GlyphID{tables.GlyphID(extender)}}}},
+	}
+	face := font.NewFace(&ft)
+
+	out := simpleShape([]rune("تثذرزسشص لمنهويء"), face, di.DirectionRTL)
+	opts := JustifyOptions{Face: face, Size: out.Size, Direction: out.Direction, Script: language.Arabic, MaxWordSpace: 1}
+	justified := Justify(out.Glyphs, 10*out.Size, opts)
+	tu.Assert(t, len(justified) > len(out.Glyphs))
+	inserted := 0
+	for _, g := range justified {
+		if g.GlyphID == extender {
+			inserted++
+			tu.Assert(t, g.Advance > 0 && g.Advance == out.FromFontUnit(face.HorizontalAdvance(extender)))
+		}
+	}
+	tu.Assert(t, inserted == len(justified)-len(out.Glyphs))
+	tu.Assert(t, advances(justified) <= advances(out.Glyphs)+10*out.Size)
+}
@@ -0,0 +1,100 @@
+// SPDX-License-Identifier: Unlicense OR BSD-3-Clause
+
+package shaping
+
+// SplitVisual is the same as [Split], but returns the runs in visual order,
+// so that they may be drawn one after the other, from left to right
+// (or from top to bottom for vertical text), without any further reordering.
+// The RunStart and RunEnd fields of the runs still refer to the logical text,
+// and may be used to map positions in the text to the runs (for instance for caret placement).
+//
+// The runs are reordered following the rule L2 of the Unicode Bidirectional Algorithm,
+// using the embedding levels resolved when splitting the text by direction :
+// from the highest embedding level to the lowest odd one, any contiguous sequence
+// of runs at that level or higher is reversed. Each paragraph is reordered independently,
+// as a single line : the separators and the trailing whitespace are reset
+// to the paragraph level (rule L1).
+//
+// Since runs of the same direction may have different levels (for instance numbers
+// inside a right-to-left span of a left-to-right paragraph, or the whitespace reset by the rule L1),
+// a run mixing several levels is split at their boundaries, so that the returned slice may
+// have more runs than the output of [Split].
+//
+// The returned sliced is owned by the [Segmenter] and is only valid until
+// the next call to [Split] or [SplitVisual].
+func (seg *Segmenter) SplitVisual(text Input, faces Fontmap) []Input {
+	runs := seg.Split(text, faces)
+	if len(seg.text) == 0 {
+		return runs
+	}
+
+	// runs never cross a paragraph boundary : reorder each paragraph independently
+	seg.visual, seg.visualLevels = seg.visual[:0], seg.visualLevels[:0]
+	for first := 0; first < len(runs); {
+		end := paragraphEnd(text.Text[:text.RunEnd], runs[first].RunStart)
+		last := first + 1
+		for last < len(runs) && runs[last].RunStart < end {
+			last++
+		}
+
+		// split the runs at level changes, and reorder them
+		paragraphStart := len(seg.visual)
+		for _, run := range runs[first:last] {
+			for i := run.RunStart; i < run.RunEnd; {
+				level := seg.bidiLevels[i-text.RunStart]
+				j := i + 1
+				for j < run.RunEnd && seg.bidiLevels[j-text.RunStart] == level {
+					j++
+				}
+				part := run
+				part.RunStart, part.RunEnd = i, j
+				seg.visual = append(seg.visual, part)
+				seg.visualLevels = append(seg.visualLevels, level)
+				i = j
+			}
+		}
+		reorderLevels(seg.visual[paragraphStart:], seg.visualLevels[paragraphStart:])
+
+		first = last
+	}
+
+	return seg.visual
+}
+
+// reorderLevels applies the rule L2 of the Unicode Bidirectional Algorithm
+// to [runs], whose embedding levels are given by [levels] : from the highest level
+// to the lowest odd level, any contiguous sequence of runs at that
+// level or higher is reversed.
+func reorderLevels(runs []Input, levels []int8) {
+	var highest, lowestOdd int8 = 0, 127
+	for _, level := range levels {
+		if level > highest {
+			highest = level
+		}
+		if level%2 == 1 && level < lowestOdd {
+			lowestOdd = level
+		}
+	}
+
+	for level := highest; level >= lowestOdd; level-- {
+		for start := 0; start < len(runs); {
+			if levels[start] < level {
+				start++
+				continue
+			}
+			end := start + 1
+			for end < len(runs) && levels[end] >= level {
+				end++
+			}
+			reverseRuns(runs[start:end], levels[start:end])
+			start = end
+		}
+	}
+}
+
+func reverseRuns(runs []Input, levels []int8) {
+	for i, j := 0, len(runs)-1; i < j; i, j = i+1, j-1 {
+		runs[i], runs[j] = runs[j], runs[i]
+		levels[i], levels[j] = levels[j], levels[i]
+	}
+}
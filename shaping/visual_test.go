@@ -0,0 +1,102 @@
+package shaping
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	tu "github.com/go-text/typesetting/testutils"
+)
+
+// visualString concatenates the runes of [runs], reversing the right-to-left ones
+func visualString(runs []Input) string {
+	var out []rune
+	for _, run := range runs {
+		runes := run.Text[run.RunStart:run.RunEnd]
+		if run.Direction.Progression() == di.TowardTopLeft {
+			for i := len(runes) - 1; i >= 0; i-- {
+				out = append(out, runes[i])
+			}
+		} else {
+			out = append(out, runes...)
+		}
+	}
+	return string(out)
+}
+
+func reversed(s string) string {
+	runes := []rune(s)
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	return string(runes)
+}
+
+func TestSplitVisual(t *testing.T) {
+	latinFont := loadOpentypeFont(t, "../font/testdata/Roboto-Regular.ttf")
+	arabicFont := loadOpentypeFont(t, "../font/testdata/Amiri-Regular.ttf")
+	fm := fixedFontmap{latinFont, arabicFont}
+
+	var seg Segmenter
+	for _, test := range []struct {
+		text     string
+		dir      di.Direction
+		expected string
+	}{
+		{"", di.DirectionLTR, ""},
+		{"The quick brown fox", di.DirectionLTR, "The quick brown fox"},
+		{"abc سماء שלום def", di.DirectionLTR, "abc " + reversed("שלום") + " " + reversed("سماء") + " def"},
+		// numbers in a right-to-left span are at level 2
+		{"abc سماء 123 def", di.DirectionLTR, "abc 123 " + reversed("سماء") + " def"},
+		{"abc שלום 1.5% def", di.DirectionLTR, "abc 1.5% " + reversed("שלום") + " def"},
+		{"abc 123 def", di.DirectionLTR, "abc 123 def"},
+		// right-to-left paragraphs
+		{"سماء abc def שלום", di.DirectionRTL, reversed("שלום") + " abc def " + reversed("سماء")},
+		{"سماء abc 123 שלום", di.DirectionRTL, reversed("שלום") + " abc 123 " + reversed("سماء")},
+		{"سماء 123 שלום", di.DirectionRTL, reversed("שלום") + " 123 " + reversed("سماء")},
+		// direction resolved from the text
+		{"سماء abc", di.DirectionLTR, "abc " + reversed("سماء")},
+		// each paragraph is reordered independently
+		{"abc سماء\u2029سماء abc", di.DirectionLTR, "abc " + reversed("سماء") + "\u2029abc " + reversed("سماء")},
+		// explicit embedding : "def" is at level 2, inside a right-to-left embedding
+		{"abc \u202Bdef שלום\u202C jkl", di.DirectionLTR, "abc \u202B\u202C" + reversed("שלום") + " def jkl"},
+		// rule L1
+		{"abc سماء ", di.DirectionLTR, "abc " + reversed("سماء") + " "},
+		{"abc سماء\tشلام", di.DirectionLTR, "abc " + reversed("سماء") + "\t" + reversed("شلام")},
+	} {
+		runes := []rune(test.text)
+		logical := seg.Split(Input{Text: runes, RunEnd: len(runes), Direction: test.dir}, fm)
+		logicalCount := len(logical)
+		runs := seg.SplitVisual(Input{Text: runes, RunEnd: len(runes), Direction: test.dir}, fm)
+		tu.AssertC(t, visualString(runs) == test.expected, fmt.Sprintf("%q", visualString(runs)))
+		tu.Assert(t, len(runs) >= logicalCount)
+
+		// the runs still cover the logical text
+		covered := make([]bool, len(runes))
+		for _, run := range runs {
+			for i := run.RunStart; i < run.RunEnd; i++ {
+				tu.Assert(t, !covered[i])
+				covered[i] = true
+			}
+		}
+		for _, c := range covered {
+			tu.Assert(t, c)
+		}
+	}
+}
+
+func TestReorderLevels(t *testing.T) {
+	// example from the rule L2 : levels 0 1 1 2 2 1 0
+	runs := make([]Input, 7)
+	for i := range runs {
+		runs[i].RunStart = i
+	}
+	levels := []int8{0, 1, 1, 2, 2, 1, 0}
+	reorderLevels(runs, levels)
+	var order []int
+	for _, run := range runs {
+		order = append(order, run.RunStart)
+	}
+	tu.AssertC(t, reflect.DeepEqual(order, []int{0, 5, 3, 4, 2, 1, 6}), fmt.Sprint(order))
+}
@@ -300,6 +300,10 @@ type breaker struct {
 	isUnusedWord        bool
 	unusedGraphemeBreak breakOption
 	isUnusedGrapheme    bool
+	// text is the paragraph being broken
+	text []rune
+	// noSoftHyphenBreak discards the UAX#14 candidates following a soft hyphen.
+	noSoftHyphenBreak bool
 }
 
 // newBreaker returns a breaker initialized to break the provided text.
@@ -309,17 +313,21 @@ func newBreaker(seg *segmenter.Segmenter, text []rune) *breaker {
 		wordSegmenter:     seg.LineIterator(),
 		graphemeSegmenter: seg.GraphemeIterator(),
 		totalRunes:        len(text),
+		text:              text,
 	}
 	return br
 }
 
 // nextWordRaw returns a naive break candidate on a uax#14 boundary which may be invalid.
 func (b *breaker) nextWordRaw() (option breakOption, ok bool) {
-	if b.wordSegmenter.Next() {
+	for b.wordSegmenter.Next() {
 		currentSegment := b.wordSegmenter.Line()
 		// Note : we dont use penalties for Mandatory Breaks so far,
 		// we could add it with currentSegment.IsMandatoryBreak
 		breakAtRune := currentSegment.Offset + len(currentSegment.Text) - 1
+		if b.noSoftHyphenBreak && b.text[breakAtRune] == softHyphen && breakAtRune != b.totalRunes-1 {
+			continue
+		}
 		option := breakOption{
 			breakAtRune: breakAtRune,
 			// Don't treat the EOF line break as special. We implicitly always break after
@@ -453,8 +461,21 @@ type WrapConfig struct {
 	// usually want this feature enabled, but for text editors it is frequently
 	// desirable to allow trailing whitespace to occupy space itself.
 	DisableTrailingWhitespaceTrim bool
+	// Dictionary, if provided, is used to find break opportunities in scripts
+	// which do not use spaces between words, like Thai, Lao or Khmer.
+	// See [segmenter.DefaultDictionary] for a minimal bundled word list.
+	Dictionary *segmenter.Dictionary
+	// DisableSoftHyphenBreaks removes the line break opportunities following
+	// a soft hyphen (U+00AD), which are otherwise allowed by UAX#14.
+	// See [WrappedLine.SoftHyphen] for how such breaks are reported.
+	DisableSoftHyphenBreaks bool
 }
 
+// softHyphen (U+00AD) marks a line break opportunity. It is invisible
+// (and shaped with a zero advance) unless the line is broken after it,
+// in which case a hyphen should be displayed at the end of the line.
+const softHyphen = 0x00AD
+
 // LineBreakPolicy specifies when considering a line break within a "word" or UAX#14
 // segment is allowed.
 type LineBreakPolicy uint8
@@ -764,7 +785,9 @@ type LineWrapper struct {
 func (l *LineWrapper) Prepare(config WrapConfig, paragraph []rune, runs RunIterator) {
 	l.config = config
 	l.truncating = l.config.TruncateAfterLines > 0
+	l.seg.SetDictionary(config.Dictionary)
 	l.breaker = newBreaker(&l.seg, paragraph)
+	l.breaker.noSoftHyphenBreak = config.DisableSoftHyphenBreaks
 	l.glyphRuns = runs
 	l.lineStartRune = 0
 	l.more = true
@@ -801,7 +824,9 @@ func (l *LineWrapper) WrapParagraphF(config WrapConfig, maxWidth fixed.Int26_6,
 		// We can only skip wrapping if the text doesn't contain any forced line
 		// breaks that need to be evaluated by the real algorithm, so we need to
 		// quickly scan it for that.
+		l.seg.SetDictionary(config.Dictionary)
 		l.breaker = newBreaker(&l.seg, paragraph)
+		l.breaker.noSoftHyphenBreak = config.DisableSoftHyphenBreaks
 		hasMandatoryBreak := false
 		for {
 			option, ok := l.breaker.nextWordBreak()
@@ -895,6 +920,13 @@ type WrappedLine struct {
 	// It is zero if [DisableTrailingWhitespaceTrim] is set to true,
 	// or if there is no whitespace at the end of the line.
 	TrimmedTrailingWhitespace fixed.Int26_6
+
+	// SoftHyphen is true if the line has been broken right after a
+	// soft hyphen (U+00AD), meaning that a visible hyphen must be rendered
+	// at the end of the line.
+	// Note that the wrapper does not reserve space for the hyphen glyph,
+	// which is not part of [Line].
+	SoftHyphen bool
 }
 
 // swapVisualOrder inverts the visual index of runs in [subline], by swapping pairs of visual indices across the midpoint
@@ -1008,7 +1040,10 @@ func (l *LineWrapper) postProcessLine(finalLine Line, done bool) (WrappedLine, b
 		l.more = false
 	}
 
-	return WrappedLine{finalLine, truncated, l.lineStartRune, trimmed}, done
+	// A soft hyphen ending the text or a truncated line is not a visible break.
+	hyphenated := !done && l.lineStartRune > 0 && l.breaker.text[l.lineStartRune-1] == softHyphen
+
+	return WrappedLine{finalLine, truncated, l.lineStartRune, trimmed, hyphenated}, done
 }
 
 // WrapNextLine wraps the shaped glyphs of a paragraph to a particular max width.
@@ -3623,3 +3623,43 @@ func TestWrapping_oneLine_overflow_bug(t *testing.T) {
 	_, done := l.WrapNextLine(maxWidth)
 	tu.Assert(t, done)
 }
+
+func TestSoftHyphen(t *testing.T) {
+	face := loadOpentypeFont(t, "../font/testdata/UbuntuMono-R.ttf")
+	text := []rune("hyphen­ation rules")
+	run := (&HarfbuzzShaper{}).Shape(Input{
+		Text:   text,
+		Face:   face,
+		Size:   72,
+		RunEnd: len(text),
+	})
+	// the soft hyphen is invisible when the line is not broken
+	tu.Assert(t, len(run.Glyphs) == len(text))
+	tu.Assert(t, run.Glyphs[6].XAdvance == 0)
+	tu.Assert(t, run.Advance == fixed.I(len(text)-1))
+
+	var wrapper LineWrapper
+
+	wrapper.Prepare(WrapConfig{}, text, NewSliceIterator([]Output{run.copy()}))
+	line, _ := wrapper.WrapNextLine(9) // break after the soft hyphen
+	tu.Assert(t, line.NextLine == 7)
+	tu.Assert(t, line.SoftHyphen)
+	line, _ = wrapper.WrapNextLine(9)
+	tu.Assert(t, line.NextLine == 13)
+	tu.Assert(t, !line.SoftHyphen)
+	line, done := wrapper.WrapNextLine(9)
+	tu.Assert(t, done && line.NextLine == len(text))
+	tu.Assert(t, !line.SoftHyphen)
+
+	wrapper.Prepare(WrapConfig{DisableSoftHyphenBreaks: true}, text, NewSliceIterator([]Output{run.copy()}))
+	line, _ = wrapper.WrapNextLine(13)
+	tu.Assert(t, line.NextLine == 13)
+	tu.Assert(t, !line.SoftHyphen)
+
+	// a soft hyphen ending the text is not a break
+	text = []rune("word­")
+	run = (&HarfbuzzShaper{}).Shape(Input{Text: text, Face: face, Size: 72, RunEnd: len(text)})
+	wrapper.Prepare(WrapConfig{}, text, NewSliceIterator([]Output{run}))
+	line, done = wrapper.WrapNextLine(100)
+	tu.Assert(t, done && !line.SoftHyphen)
+}